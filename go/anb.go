@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-syntax/#anb-microsyntax
+// The An+B notation represents the indices An+B for every non-negative integer n.
+type AnPlusB struct {
+	a int
+	b int
+}
+
+// https://drafts.csswg.org/css-syntax/#the-anb-type
+func parse_an_plus_b(list []ComponentValue) (AnPlusB, error) {
+	stream := NewComponentValueStream(list)
+	stream.discard_whitespace()
+
+	first := stream.consume_value()
+	// '+'?† n, '+'?† <ndashdigit-ident>, '+'?† n- <signless-integer>...
+	// †: When a plus sign (+) precedes an ident starting with "n", there must be no whitespace between the two tokens.
+	plus := false
+	if first.is_delim(PLUS_SIGN_CHAR) {
+		plus = true
+		first = stream.consume_value()
+		if first.is_token(IDENT_TOKEN) == false {
+			return AnPlusB{}, fmt.Errorf("Parse Error: expected 'n' directly after '+' in An+B, found %s", describe_value(first))
+		}
+	}
+
+	var result AnPlusB
+	switch {
+	// odd
+	case first.is_ident("odd") && plus == false:
+		result = AnPlusB{a: 2, b: 1}
+	// even
+	case first.is_ident("even") && plus == false:
+		result = AnPlusB{a: 2, b: 0}
+	// <integer>
+	case first.is_token(NUMBER_TOKEN) && first.token.type_flag == TYPE_INTEGER:
+		result = AnPlusB{a: 0, b: int(first.token.numeric)}
+	// <n-dimension>, <ndash-dimension>, <ndashdigit-dimension>
+	case first.is_token(DIMENSION_TOKEN) && first.token.type_flag == TYPE_INTEGER:
+		b, err := stream.consume_an_plus_b_remainder(strings.ToLower(string(first.token.unit)))
+		if err != nil {
+			return AnPlusB{}, err
+		}
+		result = AnPlusB{a: int(first.token.numeric), b: b}
+	// n, -n, <ndashdigit-ident>, <dashndashdigit-ident>, n-, -n-
+	case first.is_token(IDENT_TOKEN):
+		value := strings.ToLower(string(first.token.value))
+		a := 1
+		if strings.HasPrefix(value, "-") && plus == false {
+			a = -1
+			value = value[1:]
+		}
+		if strings.HasPrefix(value, "n") == false {
+			return AnPlusB{}, fmt.Errorf("Parse Error: invalid An+B value %s", describe_value(first))
+		}
+		b, err := stream.consume_an_plus_b_remainder(value)
+		if err != nil {
+			return AnPlusB{}, err
+		}
+		result = AnPlusB{a: a, b: b}
+	default:
+		return AnPlusB{}, fmt.Errorf("Parse Error: invalid An+B value %s", describe_value(first))
+	}
+
+	stream.discard_whitespace()
+	if stream.empty() == false {
+		return AnPlusB{}, fmt.Errorf("Parse Error: unexpected %s after An+B value", describe_value(stream.next_value()))
+	}
+
+	return result, nil
+}
+
+// Consume the B part of an An+B value, given the "n..." part of the unit or ident that held the A part.
+func (cs *ComponentValueStream) consume_an_plus_b_remainder(n_part string) (int, error) {
+	switch {
+	// <n-dimension> | <n-dimension> <signed-integer> | <n-dimension> ['+' | '-'] <signless-integer>
+	case n_part == "n":
+		cs.discard_whitespace()
+		next := cs.next_value()
+		switch {
+		case cs.empty():
+			return 0, nil
+		case next.is_token(NUMBER_TOKEN) && next.token.type_flag == TYPE_INTEGER && len(next.token.sign) > 0:
+			cs.discard_value()
+			return int(next.token.numeric), nil
+		case next.is_delim(PLUS_SIGN_CHAR), next.is_delim(HYPHEN_MINUS_CHAR):
+			cs.discard_value()
+			cs.discard_whitespace()
+			b, err := cs.consume_signless_integer()
+			if next.is_delim(HYPHEN_MINUS_CHAR) {
+				b = -b
+			}
+			return b, err
+		}
+		return 0, fmt.Errorf("Parse Error: unexpected %s in An+B value", describe_value(next))
+	// <ndash-dimension> <signless-integer>
+	case n_part == "n-":
+		cs.discard_whitespace()
+		b, err := cs.consume_signless_integer()
+		return -b, err
+	// <ndashdigit-dimension>, <ndashdigit-ident>, <dashndashdigit-ident>
+	case strings.HasPrefix(n_part, "n-") && is_digits(n_part[2:]):
+		b := 0
+		for _, char := range n_part[2:] {
+			b = b*10 + int(char-'0')
+		}
+		return -b, nil
+	}
+
+	return 0, fmt.Errorf("Parse Error: invalid An+B value '%s'", n_part)
+}
+
+// <signless-integer>: a <number-token> with its type flag set to "integer", and without a sign character.
+func (cs *ComponentValueStream) consume_signless_integer() (int, error) {
+	next := cs.consume_value()
+	if next.is_token(NUMBER_TOKEN) && next.token.type_flag == TYPE_INTEGER && len(next.token.sign) == 0 {
+		return int(next.token.numeric), nil
+	}
+
+	return 0, fmt.Errorf("Parse Error: expected an unsigned integer in An+B value, found %s", describe_value(next))
+}
+
+func is_digits(str string) bool {
+	if len(str) == 0 {
+		return false
+	}
+	for _, char := range str {
+		if is_digit(char) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Parse a string (e.g. the text between the parentheses of :nth-child()) as An+B.
+func parse_an_plus_b_string(input string) (AnPlusB, error) {
+	return parse_an_plus_b(parse_component_value_list(input))
+}
+
+func (anb AnPlusB) String() string {
+	var sb strings.Builder
+	stringify_an_plus_b(&sb, anb)
+	return sb.String()
+}
+
+// https://drafts.csswg.org/css-syntax/#serializing-anb
+func stringify_an_plus_b(sb *strings.Builder, anb AnPlusB) {
+	// 1. If A is zero, return the serialization of B.
+	if anb.a == 0 {
+		sb.WriteString(strconv.Itoa(anb.b))
+		return
+	}
+
+	// 2. Otherwise, let result initially be an empty string.
+	// 3. If A is 1, append "n" to result. If A is -1, append "-n" to result.
+	//    Otherwise, append the serialization of A to result, followed by "n".
+	switch anb.a {
+	case 1:
+		sb.WriteString("n")
+	case -1:
+		sb.WriteString("-n")
+	default:
+		sb.WriteString(fmt.Sprintf("%dn", anb.a))
+	}
+
+	// 4. If B is greater than zero, append "+" to result, followed by the serialization of B.
+	// 5. If B is less than zero, append the serialization of B to result.
+	switch {
+	case anb.b > 0:
+		sb.WriteString(fmt.Sprintf("%c%d", PLUS_SIGN_CHAR, anb.b))
+	case anb.b < 0:
+		sb.WriteString(strconv.Itoa(anb.b))
+	}
+
+	// 6. Return result.
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseAnPlusB(t *testing.T) {
+	tests := []struct {
+		input string
+		want  AnPlusB
+		// The canonical serialization.
+		text string
+	}{
+		{input: "odd", want: AnPlusB{a: 2, b: 1}, text: "2n+1"},
+		{input: "EVEN", want: AnPlusB{a: 2}, text: "2n"},
+		{input: "5", want: AnPlusB{b: 5}, text: "5"},
+		{input: "+5", want: AnPlusB{b: 5}, text: "5"},
+		{input: "-5", want: AnPlusB{b: -5}, text: "-5"},
+		{input: "n", want: AnPlusB{a: 1}, text: "n"},
+		{input: "+n", want: AnPlusB{a: 1}, text: "n"},
+		{input: "-n", want: AnPlusB{a: -1}, text: "-n"},
+		{input: "2n", want: AnPlusB{a: 2}, text: "2n"},
+		{input: "2n+1", want: AnPlusB{a: 2, b: 1}, text: "2n+1"},
+		{input: "2n-1", want: AnPlusB{a: 2, b: -1}, text: "2n-1"},
+		{input: "2n- 1", want: AnPlusB{a: 2, b: -1}, text: "2n-1"},
+		{input: "2n -1", want: AnPlusB{a: 2, b: -1}, text: "2n-1"},
+		{input: " 2n + 1 ", want: AnPlusB{a: 2, b: 1}, text: "2n+1"},
+		{input: "-n+3", want: AnPlusB{a: -1, b: 3}, text: "-n+3"},
+		{input: "n-2", want: AnPlusB{a: 1, b: -2}, text: "n-2"},
+		{input: "-n- 2", want: AnPlusB{a: -1, b: -2}, text: "-n-2"},
+		{input: "0n+0", want: AnPlusB{}, text: "0"},
+		{input: "3N+2", want: AnPlusB{a: 3, b: 2}, text: "3n+2"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			anb, err := parse_an_plus_b_string(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if anb != test.want {
+				t.Errorf("got %+v, want %+v", anb, test.want)
+			}
+			if got := anb.String(); got != test.text {
+				t.Errorf("serialized as %q, want %q", got, test.text)
+			}
+		})
+	}
+}
+
+func TestParseAnPlusBErrors(t *testing.T) {
+	for _, input := range []string{"", "1.5", "n+", "2n + - 1", "2n+-1", "2n 1", "+ 2n", "- n+3", "2 n", "n-n", "oddd", "2n+1 3", "1px"} {
+		t.Run(input, func(t *testing.T) {
+			if anb, err := parse_an_plus_b_string(input); err == nil {
+				t.Errorf("expected an error, got %v", anb)
+			}
+		})
+	}
+}
+
+func TestAnPlusBMatches(t *testing.T) {
+	tests := []struct {
+		anb  AnPlusB
+		want []int
+	}{
+		{anb: AnPlusB{a: 2, b: 1}, want: []int{1, 3, 5, 7}},
+		{anb: AnPlusB{a: 2}, want: []int{2, 4, 6}},
+		{anb: AnPlusB{b: 3}, want: []int{3}},
+		{anb: AnPlusB{a: -1, b: 3}, want: []int{1, 2, 3}},
+		{anb: AnPlusB{a: 3, b: -1}, want: []int{2, 5}},
+		{anb: AnPlusB{a: -2, b: -1}, want: nil},
+	}
+
+	for _, test := range tests {
+		var got []int
+		for index := 1; index <= 7; index += 1 {
+			if test.anb.matches(index) {
+				got = append(got, index)
+			}
+		}
+		if slices.Equal(got, test.want) == false {
+			t.Errorf("%v: got %v, want %v", test.anb, got, test.want)
+		}
+	}
+}
+
+func TestNthChildMatching(t *testing.T) {
+	root, err := parse_html_document(`<html><body><ul><li id="1" class="a"></li><p id="2"></p><li id="3"></li><li id="4" class="a"></li><li id="5" class="a"></li></ul></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		selector string
+		// The ids of the matching elements, in document order.
+		want string
+	}{
+		{selector: "ul > :nth-child(odd)", want: "1 3 5"},
+		{selector: "ul > :nth-child(-n+2)", want: "1 2"},
+		{selector: "ul > :nth-last-child(2)", want: "4"},
+		{selector: "li:nth-of-type(2)", want: "3"},
+		{selector: "li:nth-last-of-type(n+3)", want: "1 3"},
+		{selector: "ul > :nth-child(2n of .a)", want: "4"},
+		{selector: "ul > :nth-last-child(1 of .a)", want: "5"},
+		{selector: "ul > :nth-child(0)", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selectors, err := parse_selector_list(parse_component_value_list(test.selector))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, element := range query_selector_all(root, selectors) {
+				id, _ := get_attribute(element, "id")
+				ids = append(ids, id)
+			}
+			if got := strings.Join(ids, " "); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// A Loader fetches the stylesheets referenced by @import rules.
+type Loader interface {
+	// Resolve the url of an @import rule against the location of the stylesheet that contains it.
+	Resolve(base string, url string) string
+	// Load the contents of the stylesheet at a resolved location.
+	Load(location string) ([]byte, error)
+}
+
+// Loads stylesheets from the filesystem, resolving imports relative to the importing file.
+type FileSystemLoader struct{}
+
+func (FileSystemLoader) Resolve(base string, url string) string {
+	url = strings.TrimPrefix(url, "file://")
+	if filepath.IsAbs(url) {
+		return filepath.Clean(url)
+	}
+
+	return filepath.Join(filepath.Dir(base), filepath.FromSlash(url))
+}
+
+func (FileSystemLoader) Load(location string) ([]byte, error) {
+	return os.ReadFile(location)
+}
+
+// Loads stylesheets from a map of slash-separated paths to their contents.
+type MemoryLoader map[string]string
+
+func (MemoryLoader) Resolve(base string, url string) string {
+	if strings.HasPrefix(url, "/") {
+		return path.Clean(url)
+	}
+
+	return path.Join(path.Dir(base), url)
+}
+
+func (m MemoryLoader) Load(location string) ([]byte, error) {
+	contents, ok := m[location]
+	if ok == false {
+		return nil, fmt.Errorf("no stylesheet at '%s'", location)
+	}
+
+	return []byte(contents), nil
+}
+
+// Stylesheets on other origins can't be bundled, so their @import rules are kept as they are.
+func is_remote_url(url string) bool {
+	lower := strings.ToLower(url)
+	return strings.HasPrefix(lower, "//") || strings.HasPrefix(lower, "data:") ||
+		(strings.Contains(lower, "://") && strings.HasPrefix(lower, "file://") == false)
+}
+
+type bundler struct {
+	loader Loader
+	// The locations of the stylesheets currently being bundled, from the entry point down, used to detect import cycles.
+	stack []string
+	// Whether a local import has been inlined. Remote @import rules are kept as they are, so they must all come before it.
+	inlined_import bool
+	// The namespaces declared by the bundled stylesheets, and the @namespace rules declaring them,
+	// which are hoisted after the @import rules, as they must come before any other rule.
+	namespaces      map[string]string
+	namespace_rules []Rule
+}
+
+// Recursively inline the @import rules of the entry stylesheet, producing a single flattened stylesheet.
+// Imports with layer, supports() or media conditions are wrapped in the equivalent @layer, @supports and @media blocks.
+func Bundle(entry string, loader Loader) (Stylesheet, error) {
+	b := bundler{loader: loader}
+	rules, err := b.bundle_stylesheet(entry, true)
+	if err != nil {
+		return Stylesheet{}, err
+	}
+
+	// Any @charset rule must stay first, followed by the @layer statements and remote @import rules that preceded the entry's local imports.
+	var result []Rule
+	for len(rules) > 0 && allows_following_import(rules[0]) {
+		result = append(result, rules[0])
+		rules = rules[1:]
+	}
+	result = append(result, b.namespace_rules...)
+	result = append(result, rules...)
+
+	return Stylesheet{rules: result, namespaces: b.namespaces}, nil
+}
+
+func (b *bundler) bundle_stylesheet(location string, is_entry bool) ([]Rule, error) {
+	for i, loading := range b.stack {
+		if loading == location {
+			cycle := append(append([]string{}, b.stack[i:]...), location)
+			return nil, fmt.Errorf("Bundle Error: import cycle detected: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	contents, err := b.loader.Load(location)
+	if err != nil {
+		return nil, fmt.Errorf("Bundle Error: failed to load '%s': %w", location, err)
+	}
+
+	b.stack = append(b.stack, location)
+	defer func() { b.stack = b.stack[:len(b.stack)-1] }()
+
+	sheet := parse_stylesheet(bytes.NewReader(contents))
+	if err := b.declare_namespaces(sheet, location, is_entry); err != nil {
+		return nil, err
+	}
+
+	var rules []Rule
+	for _, rule := range sheet.rules {
+		switch {
+		case is_namespace_rule(rule):
+			continue
+		// Only the entry stylesheet's encoding declaration is meaningful in the bundle.
+		case rule.kind == AT_RULE && strings.EqualFold(rule.name, "charset") && is_entry == false:
+			continue
+		case is_import_rule(rule):
+			imported, err := rule.import_rule()
+			if err != nil {
+				return nil, err
+			}
+
+			if is_remote_url(imported.url) {
+				// A remote import nested in a conditional import would need its conditions combined, which @import can't express.
+				if len(b.stack) > 1 {
+					return nil, fmt.Errorf("Bundle Error: cannot bundle remote import '%s' from '%s'", imported.url, location)
+				}
+				// An @import rule can't follow the rules of an inlined stylesheet, and moving it before them would change its
+				// position in the cascade and in the layer order.
+				if b.inlined_import {
+					return nil, fmt.Errorf("Bundle Error: cannot bundle remote import '%s' after a local import in '%s'", imported.url, location)
+				}
+				rules = append(rules, rule)
+				continue
+			}
+
+			children, err := b.bundle_stylesheet(b.loader.Resolve(location, imported.url), false)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, wrap_import_conditions(imported, children)...)
+			b.inlined_import = true
+		default:
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules, nil
+}
+
+// Add the namespaces the stylesheet declares to the bundle's.
+// A stylesheet's namespace prefixes only apply to its own selectors, so the bundled stylesheets can't declare the same prefix
+// for different namespaces. And as the default namespace applies to every selector without a prefix, they must all declare
+// the same default namespace, or none.
+func (b *bundler) declare_namespaces(sheet Stylesheet, location string, is_entry bool) error {
+	if is_entry {
+		b.namespaces = sheet.namespaces
+		for _, rule := range sheet.rules {
+			if is_namespace_rule(rule) {
+				b.namespace_rules = append(b.namespace_rules, rule)
+			}
+		}
+		return nil
+	}
+
+	entry_default, entry_has_default := b.namespaces[""]
+	if default_namespace, has_default := sheet.namespaces[""]; has_default != entry_has_default || default_namespace != entry_default {
+		return fmt.Errorf("Bundle Error: '%s' has a different default namespace than the entry stylesheet", location)
+	}
+	for _, rule := range sheet.rules {
+		if is_namespace_rule(rule) == false {
+			continue
+		}
+		namespace, _ := rule.namespace_rule()
+		if url, ok := b.namespaces[namespace.prefix]; ok {
+			if url != sheet.namespaces[namespace.prefix] {
+				return fmt.Errorf("Bundle Error: '%s' declares the namespace prefix '%s' for a different namespace", location, namespace.prefix)
+			}
+			continue
+		}
+		if b.namespaces == nil {
+			b.namespaces = map[string]string{}
+		}
+		b.namespaces[namespace.prefix] = sheet.namespaces[namespace.prefix]
+		b.namespace_rules = append(b.namespace_rules, rule)
+	}
+
+	return nil
+}
+
+// Wrap the rules of an imported stylesheet in blocks equivalent to the import's conditions,
+// as if the stylesheet was written inside @media <media> { @supports <supports> { @layer <layer> { ... } } }.
+func wrap_import_conditions(imported ImportRule, rules []Rule) []Rule {
+	if imported.has_layer {
+		var prelude []ComponentValue
+		if len(imported.layer) > 0 {
+			prelude = append([]ComponentValue{whitespace_value()}, parse_component_value_list(imported.layer_name())...)
+		}
+		prelude = append(prelude, whitespace_value())
+		rules = []Rule{{kind: AT_RULE, name: "layer", prelude: prelude, children: rules, has_block: true}}
+	}
+
+	if imported.has_supports {
+		prelude := append([]ComponentValue{whitespace_value()}, parse_component_value_list(imported.supports.String())...)
+		prelude = append(prelude, whitespace_value())
+		rules = []Rule{{kind: AT_RULE, name: "supports", prelude: prelude, children: rules, has_block: true}}
+	}
+
+	if len(imported.media) > 0 {
+		prelude := []ComponentValue{whitespace_value()}
+		for i, query := range imported.media {
+			if i > 0 {
+				prelude = append(prelude, ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: COMMA_TOKEN}}, whitespace_value())
+			}
+			prelude = append(prelude, query...)
+		}
+		prelude = append(prelude, whitespace_value())
+		rules = []Rule{{kind: AT_RULE, name: "media", prelude: prelude, children: rules, has_block: true}}
+	}
+
+	return rules
+}
+
+func whitespace_value() ComponentValue {
+	return ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: WHITESPACE_TOKEN}}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		sheets  MemoryLoader
+		want    string
+		wantErr string
+	}{
+		{
+			name: "inlines local imports in place",
+			sheets: MemoryLoader{
+				"/main.css": `@import "a.css"; p { color: red }`,
+				"/a.css":    `a { color: blue }`,
+			},
+			want: "a {\ncolor: blue;\n}\np {\ncolor: red;\n}\n",
+		},
+		{
+			name: "wraps conditional imports",
+			sheets: MemoryLoader{
+				"/main.css": `@import "a.css" layer(base) supports(display: grid) screen;`,
+				"/a.css":    `a { color: blue }`,
+			},
+			want: "@media screen {\n@supports (display: grid) {\n@layer base {\na {\ncolor: blue;\n}\n}\n}\n}\n",
+		},
+		{
+			name: "keeps remote imports before local ones",
+			sheets: MemoryLoader{
+				"/main.css": `@charset "utf-8"; @import "https://example.com/r.css"; @import "a.css";`,
+				"/a.css":    `a { color: blue }`,
+			},
+			want: "@charset \"utf-8\";@import \"https://example.com/r.css\";a {\ncolor: blue;\n}\n",
+		},
+		{
+			name: "rejects a remote import after a local one",
+			sheets: MemoryLoader{
+				"/main.css": `@import "a.css"; @import "https://example.com/r.css";`,
+				"/a.css":    `a { color: blue }`,
+			},
+			wantErr: "after a local import",
+		},
+		{
+			name: "rejects import cycles",
+			sheets: MemoryLoader{
+				"/main.css": `@import "a.css";`,
+				"/a.css":    `@import "main.css";`,
+			},
+			wantErr: "import cycle detected: /main.css -> /a.css -> /main.css",
+		},
+		{
+			name: "rejects conflicting namespace prefixes",
+			sheets: MemoryLoader{
+				"/main.css": `@import "a.css"; @namespace svg url(http://www.w3.org/2000/svg);`,
+				"/a.css":    `@namespace svg url(other);`,
+			},
+			wantErr: "namespace prefix 'svg'",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sheet, err := Bundle("/main.css", test.sheets)
+			if test.wantErr != "" {
+				if err == nil || strings.Contains(err.Error(), test.wantErr) == false {
+					t.Fatalf("expected an error containing %q, got %v", test.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := sheet.Stringify(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,1031 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type CalcNodeKind uint8
+
+// https://drafts.csswg.org/css-values-4/#calculation-tree
+const (
+	// A number, percentage or dimension.
+	CALC_VALUE CalcNodeKind = iota
+	CALC_SUM
+	CALC_PRODUCT
+	CALC_NEGATE
+	CALC_INVERT
+	// A math function other than calc(), e.g. min() or round().
+	CALC_FUNCTION
+)
+
+func (k CalcNodeKind) String() string {
+	switch k {
+	case CALC_VALUE:
+		return "CALC_VALUE"
+	case CALC_SUM:
+		return "CALC_SUM"
+	case CALC_PRODUCT:
+		return "CALC_PRODUCT"
+	case CALC_NEGATE:
+		return "CALC_NEGATE"
+	case CALC_INVERT:
+		return "CALC_INVERT"
+	case CALC_FUNCTION:
+		return "CALC_FUNCTION"
+	}
+	return "<UNKNOWN CALC NODE>"
+}
+
+// https://drafts.csswg.org/css-values-4/#calc-internal
+type CalcNode struct {
+	kind CalcNodeKind
+	// For CALC_VALUE: the value and its unit, which is "" for a number, "%" for a percentage, or a lowercased dimension unit.
+	value float64
+	unit  string
+	// For CALC_FUNCTION: the lowercased function name.
+	name string
+	// For round(): one of nearest, up, down or to-zero.
+	strategy string
+	children []CalcNode
+}
+
+// https://drafts.csswg.org/css-values-4/#math
+var math_functions = map[string]bool{
+	"calc": true, "min": true, "max": true, "clamp": true, "round": true, "mod": true, "rem": true, "sin": true,
+	"cos": true, "tan": true, "asin": true, "acos": true, "atan": true, "atan2": true, "pow": true, "sqrt": true,
+	"hypot": true, "log": true, "exp": true, "abs": true, "sign": true,
+}
+
+// The minimum and maximum number of arguments of each math function other than calc(), where -1 is unbounded.
+var math_function_arity = map[string][2]int{
+	"min": {1, -1}, "max": {1, -1}, "clamp": {3, 3}, "round": {1, 2}, "mod": {2, 2}, "rem": {2, 2}, "sin": {1, 1},
+	"cos": {1, 1}, "tan": {1, 1}, "asin": {1, 1}, "acos": {1, 1}, "atan": {1, 1}, "atan2": {2, 2}, "pow": {2, 2},
+	"sqrt": {1, 1}, "hypot": {1, -1}, "log": {1, 2}, "exp": {1, 1}, "abs": {1, 1}, "sign": {1, 1},
+}
+
+// https://drafts.csswg.org/css-values-4/#round-func
+var rounding_strategies = []string{"nearest", "up", "down", "to-zero"}
+
+func is_math_function(value ComponentValue) bool {
+	return value.kind == FUNCTION && math_functions[strings.ToLower(value.name)]
+}
+
+// Parse a math function, e.g. calc(100% - 2em), into a calculation tree, checking that its type is consistent.
+func ParseMathFunction(value ComponentValue) (CalcNode, error) {
+	return parse_math_function(value, nil)
+}
+
+// Identifiers in the variables, e.g. the channel keywords of relative color syntax, are replaced with their number value.
+func parse_math_function(value ComponentValue, variables map[string]float64) (CalcNode, error) {
+	if is_math_function(value) == false {
+		return CalcNode{}, fmt.Errorf("Parse Error: expected a math function, found %s", describe_value(value))
+	}
+
+	node, err := parse_math_function_node(value, variables)
+	if err != nil {
+		return CalcNode{}, err
+	}
+	if _, err := node.calc_type(); err != nil {
+		return CalcNode{}, err
+	}
+
+	return node, nil
+}
+
+func parse_math_function_node(function ComponentValue, variables map[string]float64) (CalcNode, error) {
+	name := strings.ToLower(function.name)
+	arguments := split_on_commas(function.value)
+	if name == "calc" {
+		if len(arguments) != 1 {
+			return CalcNode{}, fmt.Errorf("Parse Error: calc() takes a single calculation")
+		}
+		return parse_calc_sum(function.value, variables)
+	}
+
+	node := CalcNode{kind: CALC_FUNCTION, name: name}
+	if name == "round" {
+		node.strategy = "nearest"
+		first := trim_whitespace(arguments[0])
+		if len(first) == 1 && is_ident_in(first[0], rounding_strategies...) {
+			node.strategy = strings.ToLower(string(first[0].token.value))
+			arguments = arguments[1:]
+		}
+	}
+
+	arity := math_function_arity[name]
+	if len(arguments) < arity[0] || (arity[1] >= 0 && len(arguments) > arity[1]) {
+		return CalcNode{}, fmt.Errorf("Parse Error: wrong number of arguments to %s()", name)
+	}
+	for _, argument := range arguments {
+		child, err := parse_calc_sum(argument, variables)
+		if err != nil {
+			return CalcNode{}, err
+		}
+		node.children = append(node.children, child)
+	}
+
+	return node, nil
+}
+
+// https://drafts.csswg.org/css-values-4/#typedef-calc-sum
+// <calc-sum> = <calc-product> [ [ '+' | '-' ] <calc-product> ]*
+func parse_calc_sum(list []ComponentValue, variables map[string]float64) (CalcNode, error) {
+	list = trim_whitespace(list)
+	if len(list) == 0 {
+		return CalcNode{}, fmt.Errorf("Parse Error: expected a calculation")
+	}
+
+	var terms []CalcNode
+	start := 0
+	negate := false
+	add_term := func(end int) error {
+		term, err := parse_calc_product(list[start:end], variables)
+		if err != nil {
+			return err
+		}
+		if negate {
+			term = CalcNode{kind: CALC_NEGATE, children: []CalcNode{term}}
+		}
+		terms = append(terms, term)
+		return nil
+	}
+
+	for i, value := range list {
+		if value.is_delim('+') == false && value.is_delim('-') == false {
+			continue
+		}
+		// Whitespace is required on both sides, otherwise e.g. "1px -2px" would be ambiguous.
+		if i == 0 || i == len(list)-1 || list[i-1].is_token(WHITESPACE_TOKEN) == false || list[i+1].is_token(WHITESPACE_TOKEN) == false {
+			return CalcNode{}, fmt.Errorf("Parse Error: '%c' must be surrounded by whitespace in a calculation", value.token.value[0])
+		}
+		if err := add_term(i); err != nil {
+			return CalcNode{}, err
+		}
+		negate = value.is_delim('-')
+		start = i + 1
+	}
+	if err := add_term(len(list)); err != nil {
+		return CalcNode{}, err
+	}
+
+	if len(terms) == 1 {
+		return terms[0], nil
+	}
+	return CalcNode{kind: CALC_SUM, children: terms}, nil
+}
+
+// https://drafts.csswg.org/css-values-4/#typedef-calc-product
+// <calc-product> = <calc-value> [ [ '*' | '/' ] <calc-value> ]*
+func parse_calc_product(list []ComponentValue, variables map[string]float64) (CalcNode, error) {
+	var factors []CalcNode
+	invert := false
+	expect_value := true
+	for _, value := range list {
+		switch {
+		case value.is_token(WHITESPACE_TOKEN):
+		case value.is_delim('*') || value.is_delim('/'):
+			if expect_value {
+				return CalcNode{}, fmt.Errorf("Parse Error: unexpected %s in calculation", describe_value(value))
+			}
+			invert = value.is_delim('/')
+			expect_value = true
+		default:
+			if expect_value == false {
+				return CalcNode{}, fmt.Errorf("Parse Error: unexpected %s in calculation", describe_value(value))
+			}
+			factor, err := parse_calc_value(value, variables)
+			if err != nil {
+				return CalcNode{}, err
+			}
+			if invert {
+				factor = CalcNode{kind: CALC_INVERT, children: []CalcNode{factor}}
+			}
+			factors = append(factors, factor)
+			expect_value = false
+		}
+	}
+	if expect_value {
+		return CalcNode{}, fmt.Errorf("Parse Error: expected a value in calculation")
+	}
+
+	if len(factors) == 1 {
+		return factors[0], nil
+	}
+	return CalcNode{kind: CALC_PRODUCT, children: factors}, nil
+}
+
+// https://drafts.csswg.org/css-values-4/#typedef-calc-value
+// <calc-value> = <number> | <dimension> | <percentage> | <calc-keyword> | ( <calc-sum> )
+func parse_calc_value(value ComponentValue, variables map[string]float64) (CalcNode, error) {
+	switch {
+	case value.is_token(NUMBER_TOKEN):
+		return CalcNode{kind: CALC_VALUE, value: value.token.numeric}, nil
+	case value.is_token(PERCENTAGE_TOKEN):
+		return CalcNode{kind: CALC_VALUE, value: value.token.numeric, unit: string(PERCENT_SIGN_CHAR)}, nil
+	case value.is_token(DIMENSION_TOKEN):
+		unit := strings.ToLower(string(value.token.unit))
+		if _, ok := units[unit]; ok == false {
+			return CalcNode{}, fmt.Errorf("Parse Error: unknown unit '%s'", string(value.token.unit))
+		}
+		return CalcNode{kind: CALC_VALUE, value: value.token.numeric, unit: unit}, nil
+	case value.is_token(IDENT_TOKEN):
+		name := strings.ToLower(string(value.token.value))
+		if number, ok := variables[name]; ok {
+			return CalcNode{kind: CALC_VALUE, value: number}, nil
+		}
+		// https://drafts.csswg.org/css-values-4/#calc-constants
+		switch name {
+		case "e":
+			return CalcNode{kind: CALC_VALUE, value: math.E}, nil
+		case "pi":
+			return CalcNode{kind: CALC_VALUE, value: math.Pi}, nil
+		case "infinity":
+			return CalcNode{kind: CALC_VALUE, value: math.Inf(1)}, nil
+		case "-infinity":
+			return CalcNode{kind: CALC_VALUE, value: math.Inf(-1)}, nil
+		case "nan":
+			return CalcNode{kind: CALC_VALUE, value: math.NaN()}, nil
+		}
+	case value.is_block(OPEN_PAREN_TOKEN):
+		return parse_calc_sum(value.value, variables)
+	case is_math_function(value):
+		return parse_math_function_node(value, variables)
+	}
+
+	return CalcNode{}, fmt.Errorf("Parse Error: unexpected %s in calculation", describe_value(value))
+}
+
+// The index of percentages in a calc_type's exponents, after the dimension kinds.
+const calc_percent = int(FLEX) + 1
+
+// https://drafts.csswg.org/css-typed-om/#cssnumericvalue-type
+type calc_type struct {
+	// The power of each dimension kind, and of percentages.
+	exponents [calc_percent + 1]int
+	// The dimension kind that the percentages in the calculation resolve to, or -1 if it isn't known yet.
+	percent_hint int
+}
+
+func number_type() calc_type {
+	return calc_type{percent_hint: -1}
+}
+
+func (t calc_type) is_number() bool {
+	return t.exponents == [calc_percent + 1]int{}
+}
+
+// Whether the type matches <dimension>, or <dimension-percentage> if percentages are allowed.
+func (t calc_type) is_dimension(kind DimensionKind, allow_percent bool) bool {
+	var dimension, percentage [calc_percent + 1]int
+	dimension[kind] = 1
+	percentage[calc_percent] = 1
+	if t.percent_hint >= 0 {
+		return allow_percent && t.percent_hint == int(kind) && t.exponents == dimension
+	}
+
+	return t.exponents == dimension || (allow_percent && t.exponents == percentage)
+}
+
+// https://drafts.csswg.org/css-typed-om/#apply-the-percent-hint
+func (t calc_type) with_percent_hint(kind int) calc_type {
+	t.exponents[kind] += t.exponents[calc_percent]
+	t.exponents[calc_percent] = 0
+	t.percent_hint = kind
+
+	return t
+}
+
+func (t calc_type) String() string {
+	var parts []string
+	for kind, exponent := range t.exponents {
+		if exponent == 0 {
+			continue
+		}
+		name := "percentage"
+		if kind < calc_percent {
+			name = strings.ToLower(DimensionKind(kind).String())
+		}
+		if exponent != 1 {
+			name += fmt.Sprintf("^%d", exponent)
+		}
+		parts = append(parts, name)
+	}
+
+	if len(parts) == 0 {
+		return "number"
+	}
+	return strings.Join(parts, "*")
+}
+
+// https://drafts.csswg.org/css-typed-om/#cssnumericvalue-add-two-types
+func add_types(a calc_type, b calc_type) (calc_type, error) {
+	switch {
+	case a.percent_hint >= 0 && b.percent_hint >= 0 && a.percent_hint != b.percent_hint:
+		return calc_type{}, fmt.Errorf("Parse Error: can't add %s to %s in a calculation", b, a)
+	case a.percent_hint >= 0 && b.percent_hint < 0:
+		b = b.with_percent_hint(a.percent_hint)
+	case b.percent_hint >= 0 && a.percent_hint < 0:
+		a = a.with_percent_hint(b.percent_hint)
+	}
+
+	if a.exponents == b.exponents {
+		return a, nil
+	}
+
+	// Percentages added to a dimension resolve to that dimension, e.g. 100% - 1em is a length.
+	if a.exponents[calc_percent] != 0 || b.exponents[calc_percent] != 0 {
+		for kind := 0; kind < calc_percent; kind += 1 {
+			if a.exponents[kind] == 0 && b.exponents[kind] == 0 {
+				continue
+			}
+			hinted_a, hinted_b := a.with_percent_hint(kind), b.with_percent_hint(kind)
+			if hinted_a.exponents == hinted_b.exponents {
+				return hinted_a, nil
+			}
+		}
+	}
+
+	return calc_type{}, fmt.Errorf("Parse Error: can't add %s to %s in a calculation", b, a)
+}
+
+// https://drafts.csswg.org/css-typed-om/#cssnumericvalue-multiply-two-types
+func multiply_types(a calc_type, b calc_type) (calc_type, error) {
+	switch {
+	case a.percent_hint >= 0 && b.percent_hint >= 0 && a.percent_hint != b.percent_hint:
+		return calc_type{}, fmt.Errorf("Parse Error: can't multiply %s by %s in a calculation", a, b)
+	case a.percent_hint >= 0 && b.percent_hint < 0:
+		b = b.with_percent_hint(a.percent_hint)
+	case b.percent_hint >= 0 && a.percent_hint < 0:
+		a = a.with_percent_hint(b.percent_hint)
+	}
+
+	for kind := range a.exponents {
+		a.exponents[kind] += b.exponents[kind]
+	}
+
+	return a, nil
+}
+
+func invert_type(t calc_type) calc_type {
+	for kind := range t.exponents {
+		t.exponents[kind] = -t.exponents[kind]
+	}
+
+	return t
+}
+
+// https://drafts.csswg.org/css-values-4/#determine-the-type-of-a-calculation
+func (n CalcNode) calc_type() (calc_type, error) {
+	switch n.kind {
+	case CALC_VALUE:
+		result := number_type()
+		switch n.unit {
+		case "":
+		case string(PERCENT_SIGN_CHAR):
+			result.exponents[calc_percent] = 1
+		default:
+			result.exponents[units[n.unit].kind] = 1
+		}
+		return result, nil
+	case CALC_NEGATE:
+		return n.children[0].calc_type()
+	case CALC_INVERT:
+		child, err := n.children[0].calc_type()
+		return invert_type(child), err
+	}
+
+	var types []calc_type
+	for _, child := range n.children {
+		child_type, err := child.calc_type()
+		if err != nil {
+			return calc_type{}, err
+		}
+		types = append(types, child_type)
+	}
+
+	if n.kind == CALC_PRODUCT {
+		result := types[0]
+		for _, child_type := range types[1:] {
+			var err error
+			if result, err = multiply_types(result, child_type); err != nil {
+				return calc_type{}, err
+			}
+		}
+		return result, nil
+	}
+
+	// The arguments of a sum, and of most math functions, must have consistent types.
+	result := types[0]
+	for _, child_type := range types[1:] {
+		var err error
+		if result, err = add_types(result, child_type); err != nil {
+			return calc_type{}, err
+		}
+	}
+	if n.kind == CALC_SUM {
+		return result, nil
+	}
+
+	var angle calc_type
+	angle.exponents[ANGLE] = 1
+	angle.percent_hint = -1
+	switch n.name {
+	case "min", "max", "clamp", "hypot", "mod", "rem", "abs":
+		return result, nil
+	case "round":
+		// Without a rounding interval, the value is rounded to an integer, so it must be a number.
+		if len(n.children) == 1 && result.is_number() == false {
+			return calc_type{}, fmt.Errorf("Parse Error: round() of a %s needs a rounding interval", result)
+		}
+		return result, nil
+	case "sign":
+		return number_type(), nil
+	case "sin", "cos", "tan":
+		if result.is_number() == false && result.is_dimension(ANGLE, false) == false {
+			return calc_type{}, fmt.Errorf("Parse Error: %s() takes a number or an angle, found %s", n.name, result)
+		}
+		return number_type(), nil
+	case "atan2":
+		return angle, nil
+	}
+
+	// The remaining functions only take numbers.
+	if result.is_number() == false {
+		return calc_type{}, fmt.Errorf("Parse Error: %s() takes numbers, found %s", n.name, result)
+	}
+	if n.name == "asin" || n.name == "acos" || n.name == "atan" {
+		return angle, nil
+	}
+	return number_type(), nil
+}
+
+// The type of the value, if it's a valid math function.
+func math_function_type(value ComponentValue) (calc_type, bool) {
+	if is_math_function(value) == false {
+		return calc_type{}, false
+	}
+	node, err := ParseMathFunction(value)
+	if err != nil {
+		return calc_type{}, false
+	}
+	result, _ := node.calc_type()
+
+	return result, true
+}
+
+// Whether the value is a math function that resolves to the dimension, or to a percentage of it if allowed.
+func math_function_resolves_to(value ComponentValue, kind DimensionKind, allow_percent bool) bool {
+	result, ok := math_function_type(value)
+	return ok && result.is_dimension(kind, allow_percent)
+}
+
+func math_function_resolves_to_number(value ComponentValue) bool {
+	result, ok := math_function_type(value)
+	return ok && result.is_number()
+}
+
+func math_function_resolves_to_percentage(value ComponentValue) bool {
+	result, ok := math_function_type(value)
+	return ok && result.percent_hint < 0 && result.exponents == [calc_percent + 1]int{calc_percent: 1}
+}
+
+// What relative lengths and percentages resolve against. Lengths are in px, and a zero means the size isn't known.
+type CalcContext struct {
+	font_size        float64
+	root_font_size   float64
+	line_height      float64
+	root_line_height float64
+	viewport_width   float64
+	viewport_height  float64
+	// The size of the query container. If unknown, container units use the small viewport size.
+	container_width  float64
+	container_height float64
+	// The dimension that percentages are a percentage of, and the size of 100% in its canonical unit.
+	percentage_kind  DimensionKind
+	percentage_basis float64
+}
+
+// The length in px, if the context has what the unit is relative to.
+// Without font metrics, ex and ch are taken to be 0.5em and ic to be 1em, as the spec allows.
+func (c *CalcContext) resolve_length(value float64, unit string) (float64, bool) {
+	var basis float64
+	switch unit {
+	case "em":
+		basis = c.font_size
+	case "rem":
+		basis = c.root_font_size
+	case "ex", "ch":
+		basis = c.font_size / 2
+	case "rex", "rch":
+		basis = c.root_font_size / 2
+	case "ic":
+		basis = c.font_size
+	case "ric":
+		basis = c.root_font_size
+	case "lh":
+		basis = c.line_height
+	case "rlh":
+		basis = c.root_line_height
+	case "vw", "svw", "lvw", "dvw", "vi", "svi", "lvi", "dvi":
+		basis = c.viewport_width / 100
+	case "vh", "svh", "lvh", "dvh", "vb", "svb", "lvb", "dvb":
+		basis = c.viewport_height / 100
+	case "vmin", "svmin", "lvmin", "dvmin":
+		basis = min(c.viewport_width, c.viewport_height) / 100
+	case "vmax", "svmax", "lvmax", "dvmax":
+		basis = max(c.viewport_width, c.viewport_height) / 100
+	case "cqw", "cqi", "cqh", "cqb", "cqmin", "cqmax":
+		width, height := c.container_width, c.container_height
+		if width == 0 || height == 0 {
+			width, height = c.viewport_width, c.viewport_height
+		}
+		switch unit {
+		case "cqw", "cqi":
+			basis = width / 100
+		case "cqh", "cqb":
+			basis = height / 100
+		case "cqmin":
+			basis = min(width, height) / 100
+		case "cqmax":
+			basis = max(width, height) / 100
+		}
+	}
+
+	return value * basis, basis != 0
+}
+
+// https://drafts.csswg.org/css-values-4/#calc-simplification
+// Absolute units are converted to their canonical unit. With a context, relative units and percentages are resolved too.
+func (n CalcNode) Simplify(context *CalcContext) CalcNode {
+	if n.kind == CALC_VALUE {
+		return n.simplify_value(context)
+	}
+
+	var children []CalcNode
+	for _, child := range n.children {
+		children = append(children, child.Simplify(context))
+	}
+	n.children = children
+
+	switch n.kind {
+	case CALC_NEGATE:
+		child := n.children[0]
+		switch child.kind {
+		case CALC_VALUE:
+			child.value = -child.value
+			return child
+		case CALC_NEGATE:
+			return child.children[0]
+		}
+	case CALC_INVERT:
+		child := n.children[0]
+		switch {
+		case child.kind == CALC_VALUE && child.unit == "":
+			child.value = 1 / child.value
+			return child
+		case child.kind == CALC_INVERT:
+			return child.children[0]
+		}
+	case CALC_SUM:
+		return simplify_sum(n.children)
+	case CALC_PRODUCT:
+		return simplify_product(n.children)
+	case CALC_FUNCTION:
+		return n.simplify_function()
+	}
+
+	return n
+}
+
+func (n CalcNode) simplify_value(context *CalcContext) CalcNode {
+	switch n.unit {
+	case "":
+		return n
+	case string(PERCENT_SIGN_CHAR):
+		if context != nil && context.percentage_basis != 0 {
+			return CalcNode{kind: CALC_VALUE, value: n.value / 100 * context.percentage_basis, unit: canonical_units[context.percentage_kind]}
+		}
+		return n
+	}
+
+	if value, unit, ok := to_canonical_unit(n.value, n.unit); ok {
+		return CalcNode{kind: CALC_VALUE, value: value, unit: unit}
+	}
+	if context != nil && units[n.unit].kind == LENGTH {
+		if value, ok := context.resolve_length(n.value, n.unit); ok {
+			return CalcNode{kind: CALC_VALUE, value: value, unit: "px"}
+		}
+	}
+
+	return n
+}
+
+// Values with the same unit are added together, and nested sums are flattened.
+func simplify_sum(terms []CalcNode) CalcNode {
+	var flattened []CalcNode
+	for _, term := range terms {
+		if term.kind == CALC_SUM {
+			flattened = append(flattened, term.children...)
+		} else {
+			flattened = append(flattened, term)
+		}
+	}
+
+	var result []CalcNode
+	index_of_unit := map[string]int{}
+	for _, term := range flattened {
+		if term.kind != CALC_VALUE {
+			result = append(result, term)
+			continue
+		}
+		if index, ok := index_of_unit[term.unit]; ok {
+			result[index].value += term.value
+			continue
+		}
+		index_of_unit[term.unit] = len(result)
+		result = append(result, term)
+	}
+
+	if len(result) == 1 {
+		return result[0]
+	}
+	return CalcNode{kind: CALC_SUM, children: result}
+}
+
+// Numbers are multiplied together, a number is distributed over a sum of values, and a product of values
+// whose units reduce to a single unit (e.g. 2px * 3px / 1px) becomes a value.
+func simplify_product(factors []CalcNode) CalcNode {
+	var flattened []CalcNode
+	for _, factor := range factors {
+		if factor.kind == CALC_PRODUCT {
+			flattened = append(flattened, factor.children...)
+		} else {
+			flattened = append(flattened, factor)
+		}
+	}
+
+	number := CalcNode{kind: CALC_VALUE, value: 1}
+	has_number := false
+	var rest []CalcNode
+	for _, factor := range flattened {
+		if factor.kind == CALC_VALUE && factor.unit == "" {
+			number.value *= factor.value
+			has_number = true
+		} else {
+			rest = append(rest, factor)
+		}
+	}
+
+	if len(rest) == 0 {
+		return number
+	}
+	if len(rest) == 1 && rest[0].kind == CALC_VALUE {
+		rest[0].value *= number.value
+		return rest[0]
+	}
+	if len(rest) == 1 && rest[0].kind == CALC_SUM && all_values(rest[0].children) {
+		var terms []CalcNode
+		for _, term := range rest[0].children {
+			term.value *= number.value
+			terms = append(terms, term)
+		}
+		return CalcNode{kind: CALC_SUM, children: terms}
+	}
+
+	// A product of values and inverted values can be combined if their units cancel out down to at most one.
+	value := number.value
+	exponents := map[string]int{}
+	combinable := true
+	for _, factor := range rest {
+		switch {
+		case factor.kind == CALC_VALUE:
+			value *= factor.value
+			exponents[factor.unit] += 1
+		case factor.kind == CALC_INVERT && factor.children[0].kind == CALC_VALUE:
+			value /= factor.children[0].value
+			exponents[factor.children[0].unit] -= 1
+		default:
+			combinable = false
+		}
+	}
+	if combinable {
+		remaining, count := "", 0
+		for unit, exponent := range exponents {
+			switch exponent {
+			case 0:
+			case 1:
+				remaining = unit
+				count += 1
+			default:
+				count += 2
+			}
+		}
+		if count <= 1 {
+			return CalcNode{kind: CALC_VALUE, value: value, unit: remaining}
+		}
+	}
+
+	if has_number && number.value != 1 {
+		rest = append([]CalcNode{number}, rest...)
+	}
+	if len(rest) == 1 {
+		return rest[0]
+	}
+	return CalcNode{kind: CALC_PRODUCT, children: rest}
+}
+
+func all_values(nodes []CalcNode) bool {
+	for _, node := range nodes {
+		if node.kind != CALC_VALUE {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Math functions whose arguments are all values with the same unit are evaluated. min() and max() drop the arguments
+// that can't win against another with the same unit, and are replaced by their argument if only one is left.
+func (n CalcNode) simplify_function() CalcNode {
+	if n.name == "min" || n.name == "max" {
+		var children []CalcNode
+		index_of_unit := map[string]int{}
+		for _, child := range n.children {
+			if child.kind != CALC_VALUE {
+				children = append(children, child)
+				continue
+			}
+			index, ok := index_of_unit[child.unit]
+			if ok == false {
+				index_of_unit[child.unit] = len(children)
+				children = append(children, child)
+				continue
+			}
+			if (n.name == "min" && child.value < children[index].value) || (n.name == "max" && child.value > children[index].value) {
+				children[index] = child
+			}
+		}
+		if len(children) == 1 {
+			return children[0]
+		}
+		n.children = children
+		return n
+	}
+
+	if all_values(n.children) == false {
+		return n
+	}
+	unit := n.children[0].unit
+	var arguments []float64
+	for _, child := range n.children {
+		if child.unit != unit {
+			return n
+		}
+		arguments = append(arguments, child.value)
+	}
+
+	switch n.name {
+	case "sign", "pow", "sqrt", "log", "exp", "sin", "cos", "tan":
+		// The argument of a trigonometric function is an angle in deg, or a number of radians.
+		if unit == "deg" {
+			arguments[0] = arguments[0] * math.Pi / 180
+		}
+		unit = ""
+	case "asin", "acos", "atan", "atan2":
+		unit = "deg"
+	}
+
+	return CalcNode{kind: CALC_VALUE, value: evaluate_math_function(n.name, n.strategy, arguments), unit: unit}
+}
+
+// The result of a math function, where angles are in radians and results that are angles are in deg.
+func evaluate_math_function(name string, strategy string, arguments []float64) float64 {
+	to_degrees := 180 / math.Pi
+	switch name {
+	case "min":
+		result := arguments[0]
+		for _, argument := range arguments[1:] {
+			result = math.Min(result, argument)
+		}
+		return result
+	case "max":
+		result := arguments[0]
+		for _, argument := range arguments[1:] {
+			result = math.Max(result, argument)
+		}
+		return result
+	case "clamp":
+		return math.Max(arguments[0], math.Min(arguments[1], arguments[2]))
+	case "round":
+		interval := 1.0
+		if len(arguments) == 2 {
+			interval = arguments[1]
+		}
+		return round_to_interval(arguments[0], interval, strategy)
+	case "mod":
+		// The result has the same sign as the divisor.
+		return arguments[0] - arguments[1]*math.Floor(arguments[0]/arguments[1])
+	case "rem":
+		// The result has the same sign as the dividend.
+		return math.Mod(arguments[0], arguments[1])
+	case "sin":
+		return math.Sin(arguments[0])
+	case "cos":
+		return math.Cos(arguments[0])
+	case "tan":
+		return math.Tan(arguments[0])
+	case "asin":
+		return math.Asin(arguments[0]) * to_degrees
+	case "acos":
+		return math.Acos(arguments[0]) * to_degrees
+	case "atan":
+		return math.Atan(arguments[0]) * to_degrees
+	case "atan2":
+		return math.Atan2(arguments[0], arguments[1]) * to_degrees
+	case "pow":
+		return math.Pow(arguments[0], arguments[1])
+	case "sqrt":
+		return math.Sqrt(arguments[0])
+	case "hypot":
+		var sum float64
+		for _, argument := range arguments {
+			sum += argument * argument
+		}
+		return math.Sqrt(sum)
+	case "log":
+		if len(arguments) == 2 {
+			return math.Log(arguments[0]) / math.Log(arguments[1])
+		}
+		return math.Log(arguments[0])
+	case "exp":
+		return math.Exp(arguments[0])
+	case "abs":
+		return math.Abs(arguments[0])
+	case "sign":
+		if arguments[0] == 0 || math.IsNaN(arguments[0]) {
+			return arguments[0]
+		}
+		return math.Copysign(1, arguments[0])
+	}
+
+	return math.NaN()
+}
+
+// https://drafts.csswg.org/css-values-4/#round-func
+func round_to_interval(value float64, interval float64, strategy string) float64 {
+	switch {
+	case interval == 0 || math.IsInf(value, 0) && math.IsInf(interval, 0):
+		return math.NaN()
+	case math.IsInf(value, 0):
+		return value
+	case math.IsInf(interval, 0):
+		switch {
+		case strategy == "up" && value > 0:
+			return math.Inf(1)
+		case strategy == "down" && value < 0:
+			return math.Inf(-1)
+		}
+		return math.Copysign(0, value)
+	}
+
+	interval = math.Abs(interval)
+	quotient := value / interval
+	switch strategy {
+	case "up":
+		quotient = math.Ceil(quotient)
+	case "down":
+		quotient = math.Floor(quotient)
+	case "to-zero":
+		quotient = math.Trunc(quotient)
+	default:
+		// Halfway values round up, towards positive infinity.
+		quotient = math.Floor(quotient + 0.5)
+	}
+
+	return quotient * interval
+}
+
+// Resolve the calculation to a single typed value, as returned by ParseNumericValue.
+func (n CalcNode) Evaluate(context CalcContext) (any, error) {
+	result := n.Simplify(&context)
+	if result.kind != CALC_VALUE {
+		return nil, fmt.Errorf("Calc Error: can't resolve %s", result)
+	}
+
+	switch result.unit {
+	case "":
+		return Number{Value: result.value, Integer: result.value == math.Trunc(result.value)}, nil
+	case string(PERCENT_SIGN_CHAR):
+		return Percentage{Value: result.value}, nil
+	}
+	switch units[result.unit].kind {
+	case LENGTH:
+		return Length{Value: result.value, Unit: result.unit}, nil
+	case ANGLE:
+		return Angle{Value: result.value, Unit: result.unit}, nil
+	case TIME:
+		return Time{Value: result.value, Unit: result.unit}, nil
+	case FREQUENCY:
+		return Frequency{Value: result.value, Unit: result.unit}, nil
+	case RESOLUTION:
+		return Resolution{Value: result.value, Unit: result.unit}, nil
+	}
+	return Flex{Value: result.value}, nil
+}
+
+// https://drafts.csswg.org/css-values-4/#serialize-a-math-function
+func (n CalcNode) String() string {
+	var sb strings.Builder
+	if n.kind == CALC_FUNCTION {
+		n.serialize(&sb, false)
+		return sb.String()
+	}
+
+	sb.WriteString("calc(")
+	n.serialize(&sb, false)
+	sb.WriteString(")")
+
+	return sb.String()
+}
+
+// Sums nested in a product (or in a negation or inversion) are wrapped in parentheses.
+func (n CalcNode) serialize(sb *strings.Builder, nested bool) {
+	switch n.kind {
+	case CALC_VALUE:
+		switch {
+		case math.IsNaN(n.value):
+			sb.WriteString("NaN")
+		case math.IsInf(n.value, 1):
+			sb.WriteString("infinity")
+		case math.IsInf(n.value, -1):
+			sb.WriteString("-infinity")
+		default:
+			sb.WriteString(format_rounded_number(n.value) + n.unit)
+			return
+		}
+		if n.unit != "" {
+			sb.WriteString(" * 1" + n.unit)
+		}
+	case CALC_SUM:
+		if nested {
+			sb.WriteString("(")
+		}
+		for i, term := range n.children {
+			switch {
+			case i == 0:
+				term.serialize(sb, false)
+			case term.kind == CALC_NEGATE:
+				sb.WriteString(" - ")
+				term.children[0].serialize(sb, true)
+			case term.kind == CALC_VALUE && term.value < 0:
+				sb.WriteString(" - ")
+				term.value = -term.value
+				term.serialize(sb, true)
+			default:
+				sb.WriteString(" + ")
+				term.serialize(sb, true)
+			}
+		}
+		if nested {
+			sb.WriteString(")")
+		}
+	case CALC_PRODUCT:
+		for i, factor := range n.children {
+			switch {
+			case factor.kind == CALC_INVERT:
+				if i == 0 {
+					sb.WriteString("1")
+				}
+				sb.WriteString(" / ")
+				factor.children[0].serialize(sb, true)
+			default:
+				if i > 0 {
+					sb.WriteString(" * ")
+				}
+				factor.serialize(sb, true)
+			}
+		}
+	case CALC_NEGATE:
+		sb.WriteString("(-1 * ")
+		n.children[0].serialize(sb, true)
+		sb.WriteString(")")
+	case CALC_INVERT:
+		sb.WriteString("(1 / ")
+		n.children[0].serialize(sb, true)
+		sb.WriteString(")")
+	case CALC_FUNCTION:
+		sb.WriteString(n.name + "(")
+		if n.name == "round" && n.strategy != "nearest" {
+			sb.WriteString(n.strategy + ", ")
+		}
+		for i, child := range n.children {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			child.serialize(sb, false)
+		}
+		sb.WriteString(")")
+	}
+}
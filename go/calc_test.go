@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMathFunctions(t *testing.T) {
+	context := CalcContext{font_size: 16, root_font_size: 10, viewport_width: 1000, viewport_height: 500, percentage_kind: LENGTH, percentage_basis: 200}
+	tests := []struct {
+		function string
+		// The serialization of the simplified calculation, without a context.
+		simplified string
+		// The result of evaluating it with the context.
+		want string
+	}{
+		{function: "calc(1px + 2px)", simplified: "calc(3px)", want: "3px"},
+		{function: "calc(1in - 6px)", simplified: "calc(90px)", want: "90px"},
+		{function: "calc((1px + 2px) * 3)", simplified: "calc(9px)", want: "9px"},
+		{function: "calc(-1 * (1px - 2px))", simplified: "calc(1px)", want: "1px"},
+		{function: "calc(10px / 2)", simplified: "calc(5px)", want: "5px"},
+		{function: "calc(2em * 3)", simplified: "calc(6em)", want: "96px"},
+		{function: "calc(100% - 10px)", simplified: "calc(100% - 10px)", want: "190px"},
+		{function: "calc(50% + 1em)", simplified: "calc(50% + 1em)", want: "116px"},
+		{function: "min(1px, 2em, 3rem)", simplified: "min(1px, 2em, 3rem)", want: "1px"},
+		{function: "max(1px, 5%)", simplified: "max(1px, 5%)", want: "10px"},
+		{function: "clamp(1px, 50vw, 100px)", simplified: "clamp(1px, 50vw, 100px)", want: "100px"},
+		{function: "round(up, 7px, 5px)", simplified: "calc(10px)", want: "10px"},
+		{function: "round(7, 5)", simplified: "calc(5)", want: "5"},
+		{function: "mod(-7, 5)", simplified: "calc(3)", want: "3"},
+		{function: "rem(-7, 5)", simplified: "calc(-2)", want: "-2"},
+		{function: "sin(90deg)", simplified: "calc(1)", want: "1"},
+		{function: "atan2(1, 1)", simplified: "calc(45deg)", want: "45deg"},
+		{function: "pow(2, 10)", simplified: "calc(1024)", want: "1024"},
+		{function: "sqrt(16)", simplified: "calc(4)", want: "4"},
+		{function: "log(8, 2)", simplified: "calc(3)", want: "3"},
+		{function: "exp(0)", simplified: "calc(1)", want: "1"},
+		{function: "hypot(3px, 4px)", simplified: "calc(5px)", want: "5px"},
+		{function: "abs(-3px)", simplified: "calc(3px)", want: "3px"},
+		{function: "sign(-3px)", simplified: "calc(-1)", want: "-1"},
+		{function: "calc(infinity)", simplified: "calc(infinity)", want: "calc(infinity)"},
+		{function: "calc(-infinity * 1px)", simplified: "calc(-infinity * 1px)", want: "calc(-infinity * 1px)"},
+		{function: "calc(NaN)", simplified: "calc(NaN)", want: "calc(NaN)"},
+		{function: "calc(infinity * 1%)", simplified: "calc(infinity * 1%)", want: "calc(infinity * 1px)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.function, func(t *testing.T) {
+			node, err := ParseMathFunction(parse_component_value_list(test.function)[0])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := node.String(); got != test.function {
+				t.Errorf("serialized as %q", got)
+			}
+			if got := node.Simplify(nil).String(); got != test.simplified {
+				t.Errorf("simplified to %q, want %q", got, test.simplified)
+			}
+			result, err := node.Evaluate(context)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := fmt.Sprint(result); got != test.want {
+				t.Errorf("evaluated to %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMathFunctionErrors(t *testing.T) {
+	for _, function := range []string{"calc()", "calc(1px + 1)", "calc(1s + 1px)", "calc(1px+2px)", "calc(1px + )", "min()", "clamp(1px, 2px)", "round(sideways, 1, 1)", "calc(1px 2px)", "calc(red)"} {
+		t.Run(function, func(t *testing.T) {
+			if node, err := ParseMathFunction(parse_component_value_list(function)[0]); err == nil {
+				t.Errorf("expected an error, got %q", node.String())
+			}
+		})
+	}
+}
+
+func TestMathFunctionWithoutContext(t *testing.T) {
+	node, err := ParseMathFunction(parse_component_value_list("calc(100% - 1em)")[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result, err := node.Evaluate(CalcContext{}); err == nil {
+		t.Errorf("expected an error, got %v", result)
+	}
+}
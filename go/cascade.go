@@ -0,0 +1,454 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+type Origin uint8
+
+// https://drafts.csswg.org/css-cascade-5/#cascading-origins
+const (
+	ORIGIN_USER_AGENT Origin = iota
+	ORIGIN_USER
+	ORIGIN_AUTHOR
+)
+
+func (o Origin) String() string {
+	switch o {
+	case ORIGIN_USER_AGENT:
+		return "ORIGIN_USER_AGENT"
+	case ORIGIN_USER:
+		return "ORIGIN_USER"
+	case ORIGIN_AUTHOR:
+		return "ORIGIN_AUTHOR"
+	}
+	return "<UNKNOWN ORIGIN>"
+}
+
+// A stylesheet taking part in the cascade, and the origin it belongs to.
+type CascadeStylesheet struct {
+	sheet  Stylesheet
+	origin Origin
+}
+
+type CascadeOptions struct {
+	// Decides whether the media query list of an @media rule matches. If nil, the contents of @media rules are ignored.
+	media_matches func(prelude []ComponentValue) bool
+	// Decides @supports conditions. If nil, the contents of @supports rules are ignored.
+	supports SupportsOracle
+	// Whether to include the declarations of elements' style attributes.
+	style_attributes bool
+}
+
+// The winning declaration for a property on an element, and where it came from.
+type CascadedValue struct {
+	decl   Declaration
+	origin Origin
+	// The dotted name of the cascade layer the declaration was in, empty if it was unlayered or in an anonymous layer.
+	layer string
+	// The selector that matched the element, empty if the declaration came from the style attribute.
+	selector    string
+	specificity specificity
+	// The position of the declaration in the order of appearance across all stylesheets.
+	order int
+	// Whether the declaration came from the element's style attribute.
+	from_style_attribute bool
+	// Whether the value was inherited from an ancestor (by default, or by the "inherit" keyword), rather than declared on the element.
+	inherited bool
+	// The layer the declaration was in, nil if it came from the style attribute.
+	layer_node *layer_tree
+	// The shorthand declaration the value's longhand declaration was expanded from, if any.
+	shorthand Declaration
+}
+
+// A style rule collected from the stylesheets, with everything the cascade needs to sort its declarations.
+type cascade_rule struct {
+	selectors SelectorList
+	decls     []Declaration
+	origin    Origin
+	layer     *layer_tree
+	order     int
+}
+
+// A declaration that applies to an element, with the information used to sort it in the cascade.
+type cascade_candidate struct {
+	decl        Declaration
+	origin      Origin
+	layer       *layer_tree
+	selector    string
+	specificity specificity
+	order       int
+	from_style  bool
+	// The shorthand declaration decl was expanded from, if any.
+	shorthand Declaration
+}
+
+type Cascade struct {
+	rules   []cascade_rule
+	options CascadeOptions
+	// Declares the layers of the @layer rules and @import rules with layers, as they're collected.
+	layers *layer_declarer
+	// Declarations are numbered in order of appearance, continuing into the style attributes.
+	next_order int
+	// The computed results, keyed by element.
+	styles map[Element]map[string]CascadedValue
+}
+
+// Collect the style rules of the stylesheets, in order, ready to cascade them against elements.
+func NewCascade(sheets []CascadeStylesheet, options CascadeOptions) *Cascade {
+	c := &Cascade{options: options, layers: new_layer_declarer(), styles: map[Element]map[string]CascadedValue{}}
+	// Each origin has its own, independent, set of cascade layers.
+	layers := map[Origin]*layer_tree{}
+	for _, sheet := range sheets {
+		tree, ok := layers[sheet.origin]
+		if ok == false {
+			tree = new_layer_tree()
+			layers[sheet.origin] = tree
+		}
+		c.collect_rules(sheet.sheet.rules, sheet.sheet.namespaces, sheet.origin, tree, nil)
+	}
+
+	return c
+}
+
+// Rules nested in a style rule have their selectors resolved against the parent's selectors,
+// and the namespace prefixes of selectors are resolved against the stylesheet's namespaces.
+// Layers are declared as the rules are collected, so those declared in conditional rules that don't apply are never declared.
+func (c *Cascade) collect_rules(rules []Rule, namespaces map[string]string, origin Origin, layer *layer_tree, parent SelectorList) {
+	for _, rule := range rules {
+		if rule.kind == QUALIFIED_RULE || rule.kind == NESTED_DECLARATIONS_RULE {
+			selectors, err := rule.nested_selectors(parent)
+			if err == nil {
+				selectors, err = selectors.resolve_namespaces(namespaces)
+			}
+			if err != nil {
+				// A style rule with an invalid selector is ignored.
+				continue
+			}
+			c.rules = append(c.rules, cascade_rule{selectors: selectors, decls: rule.decls, origin: origin, layer: layer, order: c.next_order})
+			c.next_order += len(rule.decls)
+			// The rules nested in a style rule come after its declarations in the order of appearance.
+			c.collect_rules(rule.children, namespaces, origin, layer, selectors)
+			continue
+		}
+
+		switch strings.ToLower(rule.name) {
+		case "media":
+			if c.options.media_matches != nil && c.options.media_matches(rule.prelude) {
+				c.collect_rules(rule.children, namespaces, origin, layer, parent)
+			}
+		case "supports":
+			if c.options.supports == nil {
+				continue
+			}
+			condition, err := parse_supports_condition(rule.prelude)
+			if err == nil && condition.Evaluate(c.options.supports) {
+				c.collect_rules(rule.children, namespaces, origin, layer, parent)
+			}
+		case "layer", "import":
+			sublayer, ok := c.layers.declare_rule(rule, layer, false)
+			if ok && is_layer_rule(rule) && rule.has_block {
+				c.collect_rules(rule.children, namespaces, origin, sublayer, parent)
+			}
+		}
+	}
+}
+
+// Compute the cascaded values of every element in the subtree rooted at root.
+func (c *Cascade) ComputeStyles(root Element) map[Element]map[string]CascadedValue {
+	var walk func(Element)
+	walk = func(element Element) {
+		c.compute(element)
+		for _, child := range element.Children() {
+			walk(child)
+		}
+	}
+	walk(root)
+
+	return c.styles
+}
+
+// The cascaded values of the properties of the element, including inherited properties.
+func (c *Cascade) Style(element Element) map[string]CascadedValue {
+	return c.compute(element)
+}
+
+func (c *Cascade) compute(element Element) map[string]CascadedValue {
+	if style, ok := c.styles[element]; ok {
+		return style
+	}
+
+	var parent_style map[string]CascadedValue
+	if parent, ok := element.Parent(); ok {
+		parent_style = c.compute(parent)
+	}
+
+	candidates := c.candidates(element)
+	// Sort the declarations by descending precedence, so the first declaration for each property is the winner.
+	sort.SliceStable(candidates, func(i int, j int) bool {
+		return compare_candidates(candidates[i], candidates[j]) > 0
+	})
+
+	by_property := map[string][]cascade_candidate{}
+	var properties []string
+	for _, candidate := range candidates {
+		name := property_name(candidate.decl.name)
+		if _, ok := by_property[name]; ok == false {
+			properties = append(properties, name)
+		}
+		by_property[name] = append(by_property[name], candidate)
+	}
+
+	style := map[string]CascadedValue{}
+	for _, name := range properties {
+		if value, ok := cascade_property(name, by_property[name], parent_style); ok {
+			style[name] = value
+		}
+	}
+
+	// https://drafts.csswg.org/css-cascade-5/#inheriting
+	// Properties with no cascaded value take their parent's value if they are inherited properties.
+	for name, value := range parent_style {
+		if _, ok := style[name]; ok == false && is_inherited_property(name) {
+			value.inherited = true
+			style[name] = value
+		}
+	}
+
+	c.styles[element] = style
+	return style
+}
+
+// The declarations from all rules that match the element, and its style attribute.
+func (c *Cascade) candidates(element Element) []cascade_candidate {
+	var candidates []cascade_candidate
+	for _, rule := range c.rules {
+		// The specificity is that of the most specific selector in the list that matches the element.
+		matched := false
+		var best ComplexSelector
+		var best_specificity specificity
+		for _, selector := range rule.selectors {
+			if matches_complex_selector(selector, element) == false {
+				continue
+			}
+			if s := complex_specificity(selector); matched == false || s.compare(best_specificity) > 0 {
+				best, best_specificity = selector, s
+			}
+			matched = true
+		}
+		if matched == false {
+			continue
+		}
+
+		selector := best.String()
+		for i, decl := range rule.decls {
+			for _, longhand := range cascaded_longhands(decl) {
+				candidates = append(candidates, cascade_candidate{decl: longhand, origin: rule.origin, layer: rule.layer, selector: selector, specificity: best_specificity, order: rule.order + i, shorthand: expanded_shorthand(decl, longhand)})
+			}
+		}
+	}
+
+	if c.options.style_attributes {
+		if style, ok := get_attribute(element, "style"); ok {
+			decls, _ := parse_block_contents(style)
+			for i, decl := range decls {
+				for _, longhand := range cascaded_longhands(decl) {
+					candidates = append(candidates, cascade_candidate{decl: longhand, origin: ORIGIN_AUTHOR, from_style: true, order: c.next_order + i, shorthand: expanded_shorthand(decl, longhand)})
+				}
+			}
+		}
+	}
+
+	return candidates
+}
+
+// https://drafts.csswg.org/css-cascade-5/#shorthand-property
+// Shorthands are cascaded as their longhands, so that a longhand overrides its part of a shorthand, and vice versa.
+// A shorthand whose value can't be expanded is invalid, so it's ignored.
+func cascaded_longhands(decl Declaration) []Declaration {
+	longhands, err := ExpandShorthand(decl)
+	if err != nil {
+		return nil
+	}
+
+	return longhands
+}
+
+// The declaration if the longhand was expanded from it, or an empty declaration if it's the declaration itself.
+func expanded_shorthand(decl Declaration, longhand Declaration) Declaration {
+	if property_name(decl.name) == property_name(longhand.name) {
+		return Declaration{}
+	}
+
+	return decl
+}
+
+// https://drafts.csswg.org/css-cascade-5/#cascade-sort
+// Returns a positive number if a takes precedence over b, a negative number if b takes precedence, and zero if they are equal.
+func compare_candidates(a cascade_candidate, b cascade_candidate) int {
+	// Origin and Importance
+	if rank_a, rank_b := origin_importance_rank(a), origin_importance_rank(b); rank_a != rank_b {
+		return rank_a - rank_b
+	}
+
+	// Element-Attached Styles: declarations from the style attribute win over those mapped via selectors.
+	if a.from_style != b.from_style {
+		if a.from_style {
+			return 1
+		}
+		return -1
+	}
+
+	// Layers: for normal rules later layers (and unlayered styles) win, for important rules the order is reversed.
+	if a.from_style == false {
+		if order := compare_layer_positions(a.layer.position(), b.layer.position()); order != 0 {
+			if a.decl.important {
+				return -order
+			}
+			return order
+		}
+	}
+
+	// Specificity
+	if order := a.specificity.compare(b.specificity); order != 0 {
+		return order
+	}
+
+	// Order of Appearance
+	return a.order - b.order
+}
+
+// Compare the precedence of the declarations two cascaded values came from.
+func compare_cascaded_values(a CascadedValue, b CascadedValue) int {
+	return compare_candidates(a.candidate(), b.candidate())
+}
+
+func (v CascadedValue) candidate() cascade_candidate {
+	return cascade_candidate{decl: v.decl, origin: v.origin, layer: v.layer_node, selector: v.selector, specificity: v.specificity, order: v.order, from_style: v.from_style_attribute, shorthand: v.shorthand}
+}
+
+// The precedence of the origin and importance, in ascending order:
+// normal user-agent, normal user, normal author, important author, important user, important user-agent.
+func origin_importance_rank(candidate cascade_candidate) int {
+	if candidate.decl.important {
+		return 5 - int(candidate.origin)
+	}
+
+	return int(candidate.origin)
+}
+
+// Find the cascaded value of a property from its declarations, sorted by descending precedence.
+// https://drafts.csswg.org/css-cascade-5/#defaulting-keywords
+func cascade_property(name string, candidates []cascade_candidate, parent_style map[string]CascadedValue) (CascadedValue, bool) {
+	for i := 0; i < len(candidates); i += 1 {
+		candidate := candidates[i]
+		value := CascadedValue{decl: candidate.decl, origin: candidate.origin, selector: candidate.selector, specificity: candidate.specificity, order: candidate.order, from_style_attribute: candidate.from_style, layer_node: candidate.layer, shorthand: candidate.shorthand}
+		if candidate.from_style == false {
+			value.layer = candidate.layer.name
+		}
+
+		switch css_wide_keyword(candidate.decl) {
+		case "inherit":
+			return inherit_value(name, parent_style)
+		case "unset":
+			// Acts as inherit if the property is inherited, and initial otherwise.
+			if is_inherited_property(name) {
+				return inherit_value(name, parent_style)
+			}
+			return value, true
+		// https://drafts.csswg.org/css-cascade-5/#default
+		case "revert":
+			// The user-agent origin rolls back to unset.
+			if candidate.origin == ORIGIN_USER_AGENT {
+				if is_inherited_property(name) {
+					return inherit_value(name, parent_style)
+				}
+				return value, true
+			}
+			// Otherwise roll back the cascade to the previous origin, as if no declarations from this origin existed.
+			for i+1 < len(candidates) && candidates[i+1].origin >= candidate.origin {
+				i += 1
+			}
+		// https://drafts.csswg.org/css-cascade-5/#revert-layer
+		case "revert-layer":
+			// Roll back the cascade to the previous cascade layer, as if no declarations from this layer existed.
+			for i+1 < len(candidates) && same_cascade_layer(candidates[i+1], candidate) {
+				i += 1
+			}
+		default:
+			return value, true
+		}
+	}
+
+	// A property that reverted past every origin behaves as unset.
+	if is_inherited_property(name) {
+		return inherit_value(name, parent_style)
+	}
+
+	return CascadedValue{}, false
+}
+
+// Style attributes are treated as a layer of their own, above all of the origin's other layers.
+func same_cascade_layer(a cascade_candidate, b cascade_candidate) bool {
+	if a.origin != b.origin || a.decl.important != b.decl.important || a.from_style != b.from_style {
+		return false
+	}
+
+	return a.from_style || a.layer == b.layer
+}
+
+func inherit_value(name string, parent_style map[string]CascadedValue) (CascadedValue, bool) {
+	value, ok := parent_style[name]
+	if ok {
+		value.inherited = true
+	}
+
+	return value, ok
+}
+
+// The CSS-wide keyword the declaration's value consists of, lowercased, or the empty string.
+func css_wide_keyword(decl Declaration) string {
+	value := trim_whitespace(decl.value)
+	if len(value) == 1 && value[0].is_token(IDENT_TOKEN) && is_css_wide_keyword(string(value[0].token.value)) {
+		return strings.ToLower(string(value[0].token.value))
+	}
+
+	return ""
+}
+
+// Property names are ASCII case-insensitive, except for custom properties.
+func property_name(name string) string {
+	if strings.HasPrefix(name, "--") {
+		return name
+	}
+
+	return strings.ToLower(name)
+}
+
+// https://www.w3.org/TR/CSS22/propidx.html and the "Inherited: yes" properties of later modules.
+// Only longhands are listed, as shorthands are cascaded as their longhands.
+var inherited_properties = map[string]bool{
+	"border-collapse": true, "border-spacing": true, "caption-side": true, "caret-color": true, "color": true,
+	"color-scheme": true, "cursor": true, "direction": true, "empty-cells": true, "font-family": true,
+	"font-feature-settings": true, "font-kerning": true, "font-language-override": true, "font-optical-sizing": true,
+	"font-size": true, "font-size-adjust": true, "font-stretch": true, "font-style": true, "font-variant-alternates": true,
+	"font-variant-caps": true, "font-variant-east-asian": true, "font-variant-emoji": true, "font-variant-ligatures": true,
+	"font-variant-numeric": true, "font-variant-position": true, "font-variation-settings": true, "font-weight": true,
+	"hyphens": true, "image-rendering": true, "letter-spacing": true, "line-break": true, "line-height": true, "list-style-image": true,
+	"list-style-position": true, "list-style-type": true, "orphans": true, "overflow-wrap": true, "paint-order": true,
+	"pointer-events": true, "quotes": true, "tab-size": true, "text-align": true, "text-align-last": true,
+	"text-decoration-skip-ink": true, "text-indent": true, "text-justify": true, "text-rendering": true,
+	"text-shadow": true, "text-size-adjust": true, "text-transform": true, "text-underline-position": true,
+	"visibility": true, "white-space": true, "white-space-collapse": true, "widows": true, "word-break": true,
+	"word-spacing": true, "word-wrap": true, "writing-mode": true, "accent-color": true, "fill": true,
+	"fill-opacity": true, "fill-rule": true, "stroke": true, "stroke-dasharray": true, "stroke-dashoffset": true,
+	"stroke-linecap": true, "stroke-linejoin": true, "stroke-miterlimit": true, "stroke-opacity": true,
+	"stroke-width": true, "text-anchor": true, "-webkit-text-fill-color": true, "-webkit-text-stroke-color": true,
+	"-webkit-text-stroke-width": true,
+}
+
+// Custom properties are inherited, unless registered otherwise.
+func is_inherited_property(name string) bool {
+	return strings.HasPrefix(name, "--") || inherited_properties[name]
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// The element with the id in the subtree rooted at root.
+func find_element_by_id(root Element, id string) (Element, bool) {
+	if value, ok := get_attribute(root, "id"); ok && value == id {
+		return root, true
+	}
+	for _, child := range root.Children() {
+		if element, ok := find_element_by_id(child, id); ok {
+			return element, true
+		}
+	}
+
+	return nil, false
+}
+
+func cascaded_value_text(value CascadedValue) string {
+	return strings.TrimSpace(serialize_component_value_list(value.decl.value))
+}
+
+func TestCascade(t *testing.T) {
+	const document = `<html><body><div id="parent"><p id="target" class="c" style="padding: 3px">text</p></div></body></html>`
+	tests := []struct {
+		name       string
+		user_agent string
+		author     string
+		property   string
+		// The expected value, or empty if the property has no cascaded value.
+		want string
+	}{
+		{name: "specificity", author: `#target { color: red } p { color: blue }`, property: "color", want: "red"},
+		{name: "order of appearance", author: `.c { color: red } .c { color: blue }`, property: "color", want: "blue"},
+		{name: "importance beats specificity", author: `p { color: red !important } #target { color: blue }`, property: "color", want: "red"},
+		{name: "author beats user agent", user_agent: `#target { color: red }`, author: `p { color: blue }`, property: "color", want: "blue"},
+		{name: "important user agent beats important author", user_agent: `p { color: red !important }`, author: `p { color: blue !important }`, property: "color", want: "red"},
+		{name: "style attribute beats selectors", author: `#target { padding: 1px }`, property: "padding-top", want: "3px"},
+		{name: "unlayered beats layered", author: `p { color: blue } @layer a { #target { color: red } }`, property: "color", want: "blue"},
+		{name: "later layers win", author: `@layer a, b; @layer b { p { color: blue } } @layer a { #target { color: red } }`, property: "color", want: "blue"},
+		{name: "important layers are reversed", author: `@layer a { p { color: red !important } } p { color: blue !important }`, property: "color", want: "red"},
+		{name: "import declares its layer", author: `@import url(x.css) layer(b); @layer a { p { color: red } } @layer b { p { color: blue } }`, property: "color", want: "red"},
+		{name: "nested layers come before their parent", author: `@layer a { p { color: red } @layer b { #target { color: blue } } }`, property: "color", want: "red"},
+		{name: "conditional layers that don't apply aren't declared", author: `@media print { @layer b {} } @layer a { p { color: red } } @layer b { p { color: blue } }`, property: "color", want: "blue"},
+		{name: "inherited property", author: `div { color: red }`, property: "color", want: "red"},
+		{name: "non-inherited property", author: `div { margin-top: 1px }`, property: "margin-top", want: ""},
+		{name: "inherit keyword", author: `div { margin-top: 1px } p { margin-top: inherit }`, property: "margin-top", want: "1px"},
+		{name: "unset on inherited property", author: `div { color: red } p { color: unset }`, property: "color", want: "red"},
+		{name: "unset on non-inherited property", author: `p { margin-top: unset }`, property: "margin-top", want: "unset"},
+		{name: "revert rolls back to the user agent", user_agent: `p { color: red }`, author: `p { color: blue } #target { color: revert }`, property: "color", want: "red"},
+		{name: "revert-layer rolls back to the previous layer", author: `@layer a { p { color: red } } @layer b { p { color: revert-layer } }`, property: "color", want: "red"},
+		{name: "longhand overrides part of an earlier shorthand", author: `p { margin: 1px } p { margin-top: 2px }`, property: "margin-top", want: "2px"},
+		{name: "shorthand keeps the other longhands", author: `p { margin: 1px } p { margin-top: 2px }`, property: "margin-left", want: "1px"},
+		{name: "later shorthand overrides a longhand", author: `p { margin-top: 2px } p { margin: 1px }`, property: "margin-top", want: "1px"},
+		{name: "more specific longhand beats later shorthand", author: `#target { margin-top: 2px } p { margin: 1px }`, property: "margin-top", want: "2px"},
+		{name: "inherited longhands of a shorthand", author: `div { font: italic 12px serif }`, property: "font-style", want: "italic"},
+		{name: "background none", author: `p { background-color: blue } p { background: none red }`, property: "background-color", want: "red"},
+		{name: "invalid shorthand is ignored", author: `p { margin-top: 2px } p { background: url(a.png) red blue }`, property: "margin-top", want: "2px"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			root, err := parse_html_document(document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			target, ok := find_element_by_id(root, "target")
+			if ok == false {
+				t.Fatal("no target element")
+			}
+
+			sheets := []CascadeStylesheet{
+				{sheet: parse_stylesheet(strings.NewReader(test.user_agent)), origin: ORIGIN_USER_AGENT},
+				{sheet: parse_stylesheet(strings.NewReader(test.author)), origin: ORIGIN_AUTHOR},
+			}
+			cascade := NewCascade(sheets, CascadeOptions{style_attributes: true})
+			value, ok := cascade.Style(target)[test.property]
+			switch {
+			case test.want == "" && ok:
+				t.Errorf("expected no value for %s, got %q", test.property, cascaded_value_text(value))
+			case test.want != "" && ok == false:
+				t.Errorf("expected %q for %s, got no value", test.want, test.property)
+			case ok && cascaded_value_text(value) != test.want:
+				t.Errorf("got %q for %s, want %q", cascaded_value_text(value), test.property, test.want)
+			}
+		})
+	}
+}
+
+func TestCascadeProvenance(t *testing.T) {
+	root, err := parse_html_document(`<html><body><p id="target">text</p></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, _ := find_element_by_id(root, "target")
+	sheet := parse_stylesheet(strings.NewReader(`@layer base.inner { p, #target { color: red } }`))
+	value, ok := NewCascade([]CascadeStylesheet{{sheet: sheet, origin: ORIGIN_AUTHOR}}, CascadeOptions{}).Style(target)["color"]
+	if ok == false {
+		t.Fatal("expected a value for color")
+	}
+	if value.layer != "base.inner" || value.selector != "#target" || value.specificity != (specificity{a: 1}) || value.origin != ORIGIN_AUTHOR {
+		t.Errorf("unexpected provenance: layer %q, selector %q, specificity %v, origin %v", value.layer, value.selector, value.specificity, value.origin)
+	}
+}
@@ -0,0 +1,1277 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type ColorSpace uint8
+
+// https://drafts.csswg.org/css-color-4/#color-type
+const (
+	SRGB ColorSpace = iota
+	SRGB_LINEAR
+	DISPLAY_P3
+	A98_RGB
+	PROPHOTO_RGB
+	REC2020
+	XYZ_D50
+	XYZ_D65
+	HSL
+	HWB
+	LAB
+	LCH
+	OKLAB
+	OKLCH
+)
+
+// The names of the color spaces, as used by color() and color-mix().
+var color_space_names = map[ColorSpace]string{
+	SRGB: "srgb", SRGB_LINEAR: "srgb-linear", DISPLAY_P3: "display-p3", A98_RGB: "a98-rgb", PROPHOTO_RGB: "prophoto-rgb",
+	REC2020: "rec2020", XYZ_D50: "xyz-d50", XYZ_D65: "xyz-d65", HSL: "hsl", HWB: "hwb", LAB: "lab", LCH: "lch",
+	OKLAB: "oklab", OKLCH: "oklch",
+}
+
+func (s ColorSpace) String() string {
+	name, ok := color_space_names[s]
+	if ok == false {
+		return "<UNKNOWN COLOR SPACE>"
+	}
+
+	return name
+}
+
+func parse_color_space_name(name string) (ColorSpace, bool) {
+	name = strings.ToLower(name)
+	// xyz is an alias for xyz-d65.
+	if name == "xyz" {
+		return XYZ_D65, true
+	}
+	for space, space_name := range color_space_names {
+		if space_name == name {
+			return space, true
+		}
+	}
+
+	return 0, false
+}
+
+// The RGB color spaces, whose components are in the range [0, 1] when in gamut.
+func (s ColorSpace) is_rgb() bool {
+	return s <= REC2020
+}
+
+// The index of the hue component of a polar color space.
+func (s ColorSpace) hue_index() (int, bool) {
+	switch s {
+	case HSL, HWB:
+		return 0, true
+	case LCH, OKLCH:
+		return 2, true
+	}
+
+	return 0, false
+}
+
+// https://drafts.csswg.org/css-color-4/#color-type
+// A color in a color space. Missing components (the "none" keyword) are NaN.
+type Color struct {
+	Space      ColorSpace
+	Components [3]float64
+	Alpha      float64
+	// Whether the color was given in one of the legacy sRGB syntaxes (hex, named colors, rgb(), hsl() or hwb()),
+	// which are serialized as rgb().
+	legacy bool
+}
+
+// Parse a <color> that can be resolved on its own, i.e. not currentcolor, a system color or light-dark().
+func ParseColor(value ComponentValue) (Color, error) {
+	switch {
+	case value.is_token(HASH_TOKEN):
+		return parse_hex_color(string(value.token.value))
+	case value.is_token(IDENT_TOKEN):
+		return parse_named_color(string(value.token.value))
+	case value.kind == FUNCTION:
+		return parse_color_function(value)
+	}
+
+	return Color{}, fmt.Errorf("Parse Error: expected a color, found %s", describe_value(value))
+}
+
+func parse_color_string(input string) (Color, error) {
+	values := trim_whitespace(parse_component_value_list(input))
+	if len(values) != 1 {
+		return Color{}, fmt.Errorf("Parse Error: expected a single color, found '%s'", input)
+	}
+
+	return ParseColor(values[0])
+}
+
+// Whether the value is syntactically a <color>, including those that can only be resolved in context.
+// https://drafts.csswg.org/css-color/#typedef-color
+func is_color(value ComponentValue) bool {
+	switch {
+	case value.is_token(HASH_TOKEN):
+		_, err := parse_hex_color(string(value.token.value))
+		return err == nil
+	case value.is_token(IDENT_TOKEN):
+		name := strings.ToLower(string(value.token.value))
+		_, named := named_colors[name]
+		return named || system_colors[name] || name == "transparent" || name == "currentcolor"
+	case value.kind == FUNCTION:
+		switch strings.ToLower(value.name) {
+		case "rgb", "rgba", "hsl", "hsla", "hwb", "lab", "lch", "oklab", "oklch", "color", "color-mix", "light-dark", "contrast-color", "device-cmyk":
+			return true
+		}
+	}
+
+	return false
+}
+
+// https://drafts.csswg.org/css-color-4/#hex-notation
+func parse_hex_color(hex string) (Color, error) {
+	length := len(hex)
+	if (length != 3 && length != 4 && length != 6 && length != 8) || is_hex_string(hex) == false {
+		return Color{}, fmt.Errorf("Parse Error: '#%s' is not a valid hex color", hex)
+	}
+
+	// The short forms repeat each digit.
+	if length <= 4 {
+		var sb strings.Builder
+		for _, char := range hex {
+			sb.WriteRune(char)
+			sb.WriteRune(char)
+		}
+		hex = sb.String()
+	}
+	if len(hex) == 6 {
+		hex += "ff"
+	}
+
+	channels, _ := strconv.ParseUint(hex, 16, 32)
+	return Color{
+		Space:      SRGB,
+		Components: [3]float64{float64(channels>>24&0xff) / 255, float64(channels>>16&0xff) / 255, float64(channels>>8&0xff) / 255},
+		Alpha:      float64(channels&0xff) / 255,
+		legacy:     true,
+	}, nil
+}
+
+func is_hex_string(str string) bool {
+	for _, char := range str {
+		if is_hex_digit(char) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func parse_named_color(name string) (Color, error) {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == "transparent":
+		return Color{Space: SRGB, Alpha: 0, legacy: true}, nil
+	case lower == "currentcolor", system_colors[lower]:
+		return Color{}, fmt.Errorf("Parse Error: '%s' can only be resolved on an element", name)
+	}
+
+	rgb, ok := named_colors[lower]
+	if ok == false {
+		return Color{}, fmt.Errorf("Parse Error: unknown color '%s'", name)
+	}
+
+	return Color{Space: SRGB, Components: [3]float64{float64(rgb>>16&0xff) / 255, float64(rgb>>8&0xff) / 255, float64(rgb&0xff) / 255}, Alpha: 1, legacy: true}, nil
+}
+
+// How a channel of a color function is parsed.
+type color_channel struct {
+	// The value that 100% corresponds to, or zero if percentages aren't allowed.
+	percent_reference float64
+	// Whether the channel is a <hue>, which can also be an <angle>.
+	hue bool
+}
+
+// The channels of each color function, and the keywords that refer to them in relative color syntax.
+var color_function_channels = map[ColorSpace]struct {
+	names    [3]string
+	channels [3]color_channel
+}{
+	SRGB:  {[3]string{"r", "g", "b"}, [3]color_channel{{percent_reference: 255}, {percent_reference: 255}, {percent_reference: 255}}},
+	HSL:   {[3]string{"h", "s", "l"}, [3]color_channel{{hue: true}, {percent_reference: 100}, {percent_reference: 100}}},
+	HWB:   {[3]string{"h", "w", "b"}, [3]color_channel{{hue: true}, {percent_reference: 100}, {percent_reference: 100}}},
+	LAB:   {[3]string{"l", "a", "b"}, [3]color_channel{{percent_reference: 100}, {percent_reference: 125}, {percent_reference: 125}}},
+	LCH:   {[3]string{"l", "c", "h"}, [3]color_channel{{percent_reference: 100}, {percent_reference: 150}, {hue: true}}},
+	OKLAB: {[3]string{"l", "a", "b"}, [3]color_channel{{percent_reference: 1}, {percent_reference: 0.4}, {percent_reference: 0.4}}},
+	OKLCH: {[3]string{"l", "c", "h"}, [3]color_channel{{percent_reference: 1}, {percent_reference: 0.4}, {hue: true}}},
+}
+
+func parse_color_function(function ComponentValue) (Color, error) {
+	name := strings.ToLower(function.name)
+	var space ColorSpace
+	switch name {
+	case "rgb", "rgba":
+		space = SRGB
+	case "hsl", "hsla":
+		space = HSL
+	case "hwb":
+		space = HWB
+	case "lab":
+		space = LAB
+	case "lch":
+		space = LCH
+	case "oklab":
+		space = OKLAB
+	case "oklch":
+		space = OKLCH
+	case "color":
+		return parse_color_color_function(function.value)
+	case "color-mix":
+		return parse_color_mix(function.value)
+	default:
+		return Color{}, fmt.Errorf("Parse Error: %s() can't be resolved to a color", function.name)
+	}
+
+	// The legacy syntax separates the channels with commas.
+	if (space == SRGB || space == HSL) && len(split_on_commas(function.value)) > 1 {
+		return parse_legacy_color_function(function.value, space)
+	}
+
+	items := non_whitespace_values(function.value)
+	color, err := parse_modern_color_arguments(items, space, color_function_channels[space].names, color_function_channels[space].channels)
+	if err != nil {
+		return Color{}, fmt.Errorf("%w (in %s())", err, name)
+	}
+	// Relative colors are serialized with color(), even in the legacy color functions.
+	relative := len(items) > 0 && items[0].is_ident("from")
+	color.legacy = (space == SRGB || space == HSL || space == HWB) && relative == false
+
+	return normalize_color(color), nil
+}
+
+// https://drafts.csswg.org/css-color-4/#color-function
+// color( [ from <color> ]? <colorspace-params> [ / [ <alpha-value> | none ] ]? )
+func parse_color_color_function(arguments []ComponentValue) (Color, error) {
+	items := non_whitespace_values(arguments)
+	index := 0
+	if len(items) > 0 && items[0].is_ident("from") {
+		index = 2
+	}
+	if index >= len(items) || items[index].is_token(IDENT_TOKEN) == false {
+		return Color{}, fmt.Errorf("Parse Error: expected a color space in color()")
+	}
+
+	space, ok := parse_color_space_name(string(items[index].token.value))
+	if ok == false || (space.is_rgb() == false && space != XYZ_D50 && space != XYZ_D65) {
+		return Color{}, fmt.Errorf("Parse Error: unknown color space %s in color()", describe_value(items[index]))
+	}
+
+	names := [3]string{"r", "g", "b"}
+	if space == XYZ_D50 || space == XYZ_D65 {
+		names = [3]string{"x", "y", "z"}
+	}
+	channel := color_channel{percent_reference: 1}
+
+	// Without the color space, the remaining arguments have the same shape as the other color functions.
+	rest := append(append([]ComponentValue{}, items[:index]...), items[index+1:]...)
+	color, err := parse_modern_color_arguments(rest, space, names, [3]color_channel{channel, channel, channel})
+	if err != nil {
+		return Color{}, fmt.Errorf("%w (in color())", err)
+	}
+
+	return color, nil
+}
+
+// [ from <color> ]? <channel>{3} [ / [ <alpha-value> | none ] ]?
+// The items are the non-whitespace arguments.
+func parse_modern_color_arguments(items []ComponentValue, space ColorSpace, names [3]string, channels [3]color_channel) (Color, error) {
+	color := Color{Space: space, Alpha: 1}
+
+	// https://drafts.csswg.org/css-color-5/#relative-colors
+	// The channel keywords refer to the channels of the origin color, converted to the color space of the function.
+	keywords := map[string]float64{}
+	// rgb() channels are given in the range [0, 255].
+	scale := 1.0
+	if channels[0].percent_reference == 255 {
+		scale = 255
+	}
+	if len(items) > 0 && items[0].is_ident("from") {
+		if len(items) < 2 {
+			return Color{}, fmt.Errorf("Parse Error: expected an origin color after 'from'")
+		}
+		origin, err := ParseColor(items[1])
+		if err != nil {
+			return Color{}, err
+		}
+		origin = origin.To(space)
+		for i, name := range names {
+			keywords[name] = zero_if_missing(origin.Components[i]) * scale
+		}
+		keywords["alpha"] = zero_if_missing(origin.Alpha)
+		color.Alpha = origin.Alpha
+		items = items[2:]
+	}
+
+	parts := split_on_delim(items, FORWARD_SLASH_CHAR)
+	if len(parts) > 2 {
+		return Color{}, fmt.Errorf("Parse Error: unexpected '/'")
+	}
+	if len(parts[0]) != 3 {
+		return Color{}, fmt.Errorf("Parse Error: expected 3 channels, found %d", len(parts[0]))
+	}
+	for i, item := range parts[0] {
+		value, err := parse_color_channel(item, channels[i], keywords)
+		if err != nil {
+			return Color{}, err
+		}
+		color.Components[i] = value
+	}
+
+	if len(parts) == 2 {
+		if len(parts[1]) != 1 {
+			return Color{}, fmt.Errorf("Parse Error: expected a single alpha value after '/'")
+		}
+		alpha, err := parse_color_channel(parts[1][0], color_channel{percent_reference: 1}, keywords)
+		if err != nil {
+			return Color{}, err
+		}
+		color.Alpha = alpha
+	}
+
+	for i := range color.Components {
+		color.Components[i] /= scale
+	}
+
+	return color, nil
+}
+
+// <number> | <percentage> | <angle> (for hues) | none, or a channel keyword in relative color syntax.
+func parse_color_channel(value ComponentValue, channel color_channel, keywords map[string]float64) (float64, error) {
+	switch {
+	case value.is_ident("none"):
+		return math.NaN(), nil
+	case value.is_token(IDENT_TOKEN):
+		keyword, ok := keywords[strings.ToLower(string(value.token.value))]
+		if ok {
+			return keyword, nil
+		}
+	case value.is_token(NUMBER_TOKEN):
+		return value.token.numeric, nil
+	case value.is_token(PERCENTAGE_TOKEN) && channel.percent_reference != 0:
+		return value.token.numeric / 100 * channel.percent_reference, nil
+	// The channel keywords can also be used in calculations, as numbers.
+	case is_math_function(value):
+		node, err := parse_math_function(value, keywords)
+		if err != nil {
+			return 0, err
+		}
+		result := node.Simplify(nil)
+		switch {
+		case result.kind != CALC_VALUE:
+		case result.unit == "":
+			return result.value, nil
+		case result.unit == "%" && channel.percent_reference != 0:
+			return result.value / 100 * channel.percent_reference, nil
+		case result.unit == "deg" && channel.hue:
+			return result.value, nil
+		}
+	case channel.hue:
+		if angle, ok := parse_angle(value); ok {
+			canonical, _ := angle.Canonical()
+			return canonical.Value, nil
+		}
+	}
+
+	return 0, fmt.Errorf("Parse Error: unexpected %s in color channel", describe_value(value))
+}
+
+// https://drafts.csswg.org/css-color-4/#rgb-functions
+// rgb( <percentage>#{3} , <alpha-value>? ) | rgb( <number>#{3} , <alpha-value>? )
+// hsl( <hue>, <percentage>, <percentage>, <alpha-value>? )
+func parse_legacy_color_function(arguments []ComponentValue, space ColorSpace) (Color, error) {
+	parts := split_on_commas(arguments)
+	if len(parts) != 3 && len(parts) != 4 {
+		return Color{}, fmt.Errorf("Parse Error: expected 3 or 4 comma-separated values in color function, found %d", len(parts))
+	}
+
+	var values []ComponentValue
+	for _, part := range parts {
+		items := non_whitespace_values(part)
+		if len(items) != 1 {
+			return Color{}, fmt.Errorf("Parse Error: expected a single value between commas in color function")
+		}
+		values = append(values, items[0])
+	}
+
+	color := Color{Space: space, Alpha: 1, legacy: true}
+	for i := 0; i < 3; i += 1 {
+		value := values[i]
+		switch {
+		case space == SRGB && value.kind == values[0].kind && value.token.kind == values[0].token.kind && value.is_token(NUMBER_TOKEN):
+			color.Components[i] = value.token.numeric / 255
+		case space == SRGB && value.kind == values[0].kind && value.token.kind == values[0].token.kind && value.is_token(PERCENTAGE_TOKEN):
+			color.Components[i] = value.token.numeric / 100
+		case space == HSL && i == 0 && (value.is_token(NUMBER_TOKEN) || is_angle(value)) && is_math_function(value) == false:
+			hue, _ := parse_color_channel(value, color_channel{hue: true}, nil)
+			color.Components[i] = hue
+		case space == HSL && i > 0 && value.is_token(PERCENTAGE_TOKEN):
+			color.Components[i] = value.token.numeric
+		default:
+			return Color{}, fmt.Errorf("Parse Error: unexpected %s in legacy color function", describe_value(value))
+		}
+	}
+
+	if len(values) == 4 {
+		if values[3].is_ident("none") {
+			return Color{}, fmt.Errorf("Parse Error: 'none' isn't allowed in legacy color functions")
+		}
+		alpha, err := parse_color_channel(values[3], color_channel{percent_reference: 1}, nil)
+		if err != nil {
+			return Color{}, err
+		}
+		color.Alpha = alpha
+	}
+
+	return normalize_color(color), nil
+}
+
+// Clamp the components of a parsed color to their valid ranges.
+func normalize_color(color Color) Color {
+	clamp := func(value float64, min float64, max float64) float64 {
+		if math.IsNaN(value) {
+			return value
+		}
+		return math.Max(min, math.Min(max, value))
+	}
+
+	color.Alpha = clamp(color.Alpha, 0, 1)
+	switch color.Space {
+	case SRGB:
+		if color.legacy {
+			for i := range color.Components {
+				color.Components[i] = clamp(color.Components[i], 0, 1)
+			}
+		}
+	case HSL:
+		color.Components[1] = clamp(color.Components[1], 0, math.Inf(1))
+	case LAB:
+		color.Components[0] = clamp(color.Components[0], 0, 100)
+	case LCH:
+		color.Components[0] = clamp(color.Components[0], 0, 100)
+		color.Components[1] = clamp(color.Components[1], 0, math.Inf(1))
+	case OKLAB:
+		color.Components[0] = clamp(color.Components[0], 0, 1)
+	case OKLCH:
+		color.Components[0] = clamp(color.Components[0], 0, 1)
+		color.Components[1] = clamp(color.Components[1], 0, math.Inf(1))
+	}
+	if index, ok := color.Space.hue_index(); ok {
+		color.Components[index] = normalize_hue(color.Components[index])
+	}
+
+	return color
+}
+
+func normalize_hue(hue float64) float64 {
+	if math.IsNaN(hue) {
+		return hue
+	}
+	hue = math.Mod(hue, 360)
+	if hue < 0 {
+		hue += 360
+	}
+
+	return hue
+}
+
+func zero_if_missing(value float64) float64 {
+	if math.IsNaN(value) {
+		return 0
+	}
+
+	return value
+}
+
+// https://drafts.csswg.org/css-color-5/#color-mix
+// color-mix( <color-interpolation-method> , [ <color> && <percentage [0,100]>? ]#{2} )
+func parse_color_mix(arguments []ComponentValue) (Color, error) {
+	parts := split_on_commas(arguments)
+	if len(parts) != 3 {
+		return Color{}, fmt.Errorf("Parse Error: expected an interpolation method and two colors in color-mix()")
+	}
+
+	// in <rectangular-color-space> | in <polar-color-space> <hue-interpolation-method>?
+	method := non_whitespace_values(parts[0])
+	if len(method) < 2 || method[0].is_ident("in") == false || method[1].is_token(IDENT_TOKEN) == false {
+		return Color{}, fmt.Errorf("Parse Error: expected 'in <color-space>' in color-mix()")
+	}
+	space, ok := parse_color_space_name(string(method[1].token.value))
+	if ok == false {
+		return Color{}, fmt.Errorf("Parse Error: unknown color space %s in color-mix()", describe_value(method[1]))
+	}
+	hue_method := "shorter"
+	if len(method) > 2 {
+		_, polar := space.hue_index()
+		if polar == false || len(method) != 4 || method[3].is_ident("hue") == false || is_ident_in(method[2], "shorter", "longer", "increasing", "decreasing") == false {
+			return Color{}, fmt.Errorf("Parse Error: invalid interpolation method in color-mix()")
+		}
+		hue_method = strings.ToLower(string(method[2].token.value))
+	}
+
+	var colors [2]Color
+	var percentages [2]float64
+	var has_percentage [2]bool
+	for i, part := range parts[1:] {
+		items := non_whitespace_values(part)
+		if len(items) == 0 || len(items) > 2 {
+			return Color{}, fmt.Errorf("Parse Error: expected a color and an optional percentage in color-mix()")
+		}
+		// Each color is given exactly once, with at most one percentage either side of it.
+		has_color := false
+		for _, item := range items {
+			if item.is_token(PERCENTAGE_TOKEN) && has_percentage[i] == false {
+				if item.token.numeric < 0 || item.token.numeric > 100 {
+					return Color{}, fmt.Errorf("Parse Error: color-mix() percentages must be between 0%% and 100%%")
+				}
+				percentages[i], has_percentage[i] = item.token.numeric/100, true
+				continue
+			}
+			if has_color {
+				return Color{}, fmt.Errorf("Parse Error: expected a color and an optional percentage in color-mix()")
+			}
+			color, err := ParseColor(item)
+			if err != nil {
+				return Color{}, err
+			}
+			colors[i], has_color = color, true
+		}
+		if has_color == false {
+			return Color{}, fmt.Errorf("Parse Error: expected a color and an optional percentage in color-mix()")
+		}
+	}
+
+	// https://drafts.csswg.org/css-color-5/#color-mix-percent-norm
+	switch {
+	case has_percentage[0] == false && has_percentage[1] == false:
+		percentages = [2]float64{0.5, 0.5}
+	case has_percentage[0] == false:
+		percentages[0] = 1 - percentages[1]
+	case has_percentage[1] == false:
+		percentages[1] = 1 - percentages[0]
+	}
+	sum := percentages[0] + percentages[1]
+	if sum == 0 {
+		return Color{}, fmt.Errorf("Parse Error: color-mix() percentages must not both be 0%%")
+	}
+	// When the percentages add up to less than 100%, the result is made more transparent.
+	alpha_multiplier := math.Min(sum, 1)
+
+	mixed := interpolate_colors(colors[0], colors[1], percentages[1]/sum, space, hue_method)
+	mixed.Alpha *= alpha_multiplier
+
+	return mixed, nil
+}
+
+// https://drafts.csswg.org/css-color-4/#interpolation
+// Interpolate between two colors in the color space, with premultiplied alpha. A progress of 0 is the first color, and 1 the second.
+func interpolate_colors(a Color, b Color, progress float64, space ColorSpace, hue_method string) Color {
+	a, b = a.To(space), b.To(space)
+	hue, polar := space.hue_index()
+
+	// A missing component takes the value from the other color.
+	for i := 0; i < 3; i += 1 {
+		if math.IsNaN(a.Components[i]) {
+			a.Components[i] = b.Components[i]
+		}
+		if math.IsNaN(b.Components[i]) {
+			b.Components[i] = a.Components[i]
+		}
+	}
+	if math.IsNaN(a.Alpha) {
+		a.Alpha = b.Alpha
+	}
+	if math.IsNaN(b.Alpha) {
+		b.Alpha = a.Alpha
+	}
+
+	// https://drafts.csswg.org/css-color-4/#hue-interpolation
+	if polar && math.IsNaN(a.Components[hue]) == false {
+		from, to := a.Components[hue], b.Components[hue]
+		difference := to - from
+		switch hue_method {
+		case "shorter":
+			if difference > 180 {
+				from += 360
+			} else if difference < -180 {
+				to += 360
+			}
+		case "longer":
+			if difference > 0 && difference < 180 {
+				from += 360
+			} else if difference > -180 && difference <= 0 {
+				to += 360
+			}
+		case "increasing":
+			if to < from {
+				to += 360
+			}
+		case "decreasing":
+			if from < to {
+				from += 360
+			}
+		}
+		a.Components[hue], b.Components[hue] = from, to
+	}
+
+	alpha_a, alpha_b := a.Alpha, b.Alpha
+	if math.IsNaN(alpha_a) {
+		alpha_a, alpha_b = 1, 1
+	}
+	alpha := alpha_a + (alpha_b-alpha_a)*progress
+
+	result := Color{Space: space, Alpha: alpha}
+	for i := 0; i < 3; i += 1 {
+		// The hue isn't premultiplied.
+		if polar && i == hue {
+			result.Components[i] = normalize_hue(a.Components[i] + (b.Components[i]-a.Components[i])*progress)
+			continue
+		}
+		premultiplied := a.Components[i]*alpha_a + (b.Components[i]*alpha_b-a.Components[i]*alpha_a)*progress
+		if alpha != 0 {
+			premultiplied /= alpha
+		}
+		result.Components[i] = premultiplied
+	}
+
+	return result
+}
+
+type matrix [3][3]float64
+
+func (m matrix) multiply(v [3]float64) [3]float64 {
+	var result [3]float64
+	for i := 0; i < 3; i += 1 {
+		result[i] = m[i][0]*v[0] + m[i][1]*v[1] + m[i][2]*v[2]
+	}
+
+	return result
+}
+
+func (m matrix) inverse() matrix {
+	determinant := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) - m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) + m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+	var inverse matrix
+	for i := 0; i < 3; i += 1 {
+		for j := 0; j < 3; j += 1 {
+			// The cofactor of the transposed element.
+			a, b := m[(j+1)%3][(i+1)%3], m[(j+2)%3][(i+2)%3]
+			c, d := m[(j+1)%3][(i+2)%3], m[(j+2)%3][(i+1)%3]
+			inverse[i][j] = (a*b - c*d) / determinant
+		}
+	}
+
+	return inverse
+}
+
+// https://drafts.csswg.org/css-color-4/#color-conversion-code
+var (
+	linear_srgb_to_xyz = matrix{
+		{506752.0 / 1228815, 87881.0 / 245763, 12673.0 / 70218},
+		{87098.0 / 409605, 175762.0 / 245763, 12673.0 / 175545},
+		{7918.0 / 409605, 87881.0 / 737289, 1001167.0 / 1053270},
+	}
+	linear_display_p3_to_xyz = matrix{
+		{608311.0 / 1250200, 189793.0 / 714400, 198249.0 / 1000160},
+		{35783.0 / 156275, 247089.0 / 357200, 198249.0 / 2500400},
+		{0, 32229.0 / 714400, 5220557.0 / 5000800},
+	}
+	linear_a98_rgb_to_xyz = matrix{
+		{573536.0 / 994567, 263643.0 / 1420810, 187206.0 / 994567},
+		{591459.0 / 1989134, 6239551.0 / 9945670, 374412.0 / 4972835},
+		{53769.0 / 1989134, 351524.0 / 4972835, 4929758.0 / 4972835},
+	}
+	// ProPhoto RGB has a D50 white point.
+	linear_prophoto_rgb_to_xyz_d50 = matrix{
+		{0.7977666449006423, 0.13518129740053308, 0.0313477341283922858},
+		{0.2880748288194013, 0.711835234241873, 0.00008993693872564},
+		{0, 0, 0.8251046025104602},
+	}
+	linear_rec2020_to_xyz = matrix{
+		{63426534.0 / 99577255, 20160776.0 / 139408157, 47086771.0 / 278816314},
+		{26158966.0 / 99577255, 472592308.0 / 697040785, 8267143.0 / 139408157},
+		{0, 19567812.0 / 697040785, 295819943.0 / 278816314},
+	}
+	// Bradford chromatic adaptation.
+	xyz_d50_to_xyz_d65 = matrix{
+		{0.955473421488075, -0.02309845494876471, 0.06325924320057072},
+		{-0.0283697093338637, 1.0099953980813041, 0.021041441191917323},
+		{0.012314014864481998, -0.020507649298898964, 1.330365926242124},
+	}
+	xyz_to_oklab_lms = matrix{
+		{0.8190224379967030, 0.3619062600528904, -0.1288737815209879},
+		{0.0329836539323885, 0.9292868615863434, 0.0361446663506424},
+		{0.0481771893596242, 0.2642395317527308, 0.6335478284694309},
+	}
+	oklab_lms_to_oklab = matrix{
+		{0.2104542683093140, 0.7936177747023054, -0.0040720430116193},
+		{1.9779985324311684, -2.4285922420485799, 0.4505937096174110},
+		{0.0259040424655478, 0.7827717124575296, -0.8086757549230774},
+	}
+
+	xyz_to_linear_srgb         = linear_srgb_to_xyz.inverse()
+	xyz_to_linear_display_p3   = linear_display_p3_to_xyz.inverse()
+	xyz_to_linear_a98_rgb      = linear_a98_rgb_to_xyz.inverse()
+	xyz_d50_to_linear_prophoto = linear_prophoto_rgb_to_xyz_d50.inverse()
+	xyz_to_linear_rec2020      = linear_rec2020_to_xyz.inverse()
+	xyz_d65_to_xyz_d50         = xyz_d50_to_xyz_d65.inverse()
+	oklab_lms_to_xyz           = xyz_to_oklab_lms.inverse()
+	oklab_to_oklab_lms         = oklab_lms_to_oklab.inverse()
+	d50_white                  = [3]float64{0.3457 / 0.3585, 1, (1 - 0.3457 - 0.3585) / 0.3585}
+)
+
+// Convert the color to another color space.
+// Missing components are treated as zero, unless the color is already in that space.
+func (c Color) To(space ColorSpace) Color {
+	if c.Space == space {
+		return c
+	}
+
+	components := space.from_xyz_d65(c.Space.to_xyz_d65(c.Components))
+	result := Color{Space: space, Components: components, Alpha: c.Alpha}
+	// The hue of an achromatic color is powerless, so it's missing.
+	switch space {
+	case HSL:
+		if math.Abs(components[1]) < 1e-9 {
+			result.Components[0] = math.NaN()
+		}
+	case HWB:
+		if components[1]+components[2] >= 100-1e-9 {
+			result.Components[0] = math.NaN()
+		}
+	case LCH, OKLCH:
+		if components[1] < 1e-9 {
+			result.Components[2] = math.NaN()
+		}
+	}
+
+	return result
+}
+
+func (s ColorSpace) to_xyz_d65(components [3]float64) [3]float64 {
+	for i := range components {
+		components[i] = zero_if_missing(components[i])
+	}
+
+	switch s {
+	case SRGB:
+		return linear_srgb_to_xyz.multiply(map_components(components, srgb_to_linear))
+	case SRGB_LINEAR:
+		return linear_srgb_to_xyz.multiply(components)
+	case DISPLAY_P3:
+		return linear_display_p3_to_xyz.multiply(map_components(components, srgb_to_linear))
+	case A98_RGB:
+		return linear_a98_rgb_to_xyz.multiply(map_components(components, a98_rgb_to_linear))
+	case PROPHOTO_RGB:
+		return xyz_d50_to_xyz_d65.multiply(linear_prophoto_rgb_to_xyz_d50.multiply(map_components(components, prophoto_rgb_to_linear)))
+	case REC2020:
+		return linear_rec2020_to_xyz.multiply(map_components(components, rec2020_to_linear))
+	case XYZ_D50:
+		return xyz_d50_to_xyz_d65.multiply(components)
+	case XYZ_D65:
+		return components
+	case HSL:
+		return SRGB.to_xyz_d65(hsl_to_srgb(components))
+	case HWB:
+		return SRGB.to_xyz_d65(hwb_to_srgb(components))
+	case LAB:
+		return xyz_d50_to_xyz_d65.multiply(lab_to_xyz_d50(components))
+	case LCH:
+		return LAB.to_xyz_d65(polar_to_rectangular(components))
+	case OKLAB:
+		return oklab_lms_to_xyz.multiply(map_components(oklab_to_oklab_lms.multiply(components), func(v float64) float64 { return v * v * v }))
+	case OKLCH:
+		return OKLAB.to_xyz_d65(polar_to_rectangular(components))
+	}
+
+	return components
+}
+
+func (s ColorSpace) from_xyz_d65(xyz [3]float64) [3]float64 {
+	switch s {
+	case SRGB:
+		return map_components(xyz_to_linear_srgb.multiply(xyz), linear_to_srgb)
+	case SRGB_LINEAR:
+		return xyz_to_linear_srgb.multiply(xyz)
+	case DISPLAY_P3:
+		return map_components(xyz_to_linear_display_p3.multiply(xyz), linear_to_srgb)
+	case A98_RGB:
+		return map_components(xyz_to_linear_a98_rgb.multiply(xyz), linear_to_a98_rgb)
+	case PROPHOTO_RGB:
+		return map_components(xyz_d50_to_linear_prophoto.multiply(xyz_d65_to_xyz_d50.multiply(xyz)), linear_to_prophoto_rgb)
+	case REC2020:
+		return map_components(xyz_to_linear_rec2020.multiply(xyz), linear_to_rec2020)
+	case XYZ_D50:
+		return xyz_d65_to_xyz_d50.multiply(xyz)
+	case XYZ_D65:
+		return xyz
+	case HSL:
+		return srgb_to_hsl(SRGB.from_xyz_d65(xyz))
+	case HWB:
+		return srgb_to_hwb(SRGB.from_xyz_d65(xyz))
+	case LAB:
+		return xyz_d50_to_lab(xyz_d65_to_xyz_d50.multiply(xyz))
+	case LCH:
+		return rectangular_to_polar(LAB.from_xyz_d65(xyz))
+	case OKLAB:
+		return oklab_lms_to_oklab.multiply(map_components(xyz_to_oklab_lms.multiply(xyz), math.Cbrt))
+	case OKLCH:
+		return rectangular_to_polar(OKLAB.from_xyz_d65(xyz))
+	}
+
+	return xyz
+}
+
+func map_components(components [3]float64, f func(float64) float64) [3]float64 {
+	for i := range components {
+		components[i] = f(components[i])
+	}
+
+	return components
+}
+
+// The transfer functions are extended to negative values by symmetry.
+func srgb_to_linear(value float64) float64 {
+	abs := math.Abs(value)
+	if abs <= 0.04045 {
+		return value / 12.92
+	}
+
+	return math.Copysign(math.Pow((abs+0.055)/1.055, 2.4), value)
+}
+
+func linear_to_srgb(value float64) float64 {
+	abs := math.Abs(value)
+	if abs <= 0.0031308 {
+		return value * 12.92
+	}
+
+	return math.Copysign(1.055*math.Pow(abs, 1/2.4)-0.055, value)
+}
+
+func a98_rgb_to_linear(value float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), 563.0/256), value)
+}
+
+func linear_to_a98_rgb(value float64) float64 {
+	return math.Copysign(math.Pow(math.Abs(value), 256.0/563), value)
+}
+
+func prophoto_rgb_to_linear(value float64) float64 {
+	abs := math.Abs(value)
+	if abs <= 16.0/512 {
+		return value / 16
+	}
+
+	return math.Copysign(math.Pow(abs, 1.8), value)
+}
+
+func linear_to_prophoto_rgb(value float64) float64 {
+	abs := math.Abs(value)
+	if abs < 1.0/512 {
+		return value * 16
+	}
+
+	return math.Copysign(math.Pow(abs, 1/1.8), value)
+}
+
+const (
+	rec2020_alpha = 1.09929682680944
+	rec2020_beta  = 0.018053968510807
+)
+
+func rec2020_to_linear(value float64) float64 {
+	abs := math.Abs(value)
+	if abs < rec2020_beta*4.5 {
+		return value / 4.5
+	}
+
+	return math.Copysign(math.Pow((abs+rec2020_alpha-1)/rec2020_alpha, 1/0.45), value)
+}
+
+func linear_to_rec2020(value float64) float64 {
+	abs := math.Abs(value)
+	if abs < rec2020_beta {
+		return value * 4.5
+	}
+
+	return math.Copysign(rec2020_alpha*math.Pow(abs, 0.45)-(rec2020_alpha-1), value)
+}
+
+// https://drafts.csswg.org/css-color-4/#hsl-to-rgb
+func hsl_to_srgb(hsl [3]float64) [3]float64 {
+	hue, saturation, lightness := normalize_hue(hsl[0]), hsl[1]/100, hsl[2]/100
+	f := func(n float64) float64 {
+		k := math.Mod(n+hue/30, 12)
+		a := saturation * math.Min(lightness, 1-lightness)
+		return lightness - a*math.Max(-1, math.Min(math.Min(k-3, 9-k), 1))
+	}
+
+	return [3]float64{f(0), f(8), f(4)}
+}
+
+// https://drafts.csswg.org/css-color-4/#rgb-to-hsl
+func srgb_to_hsl(rgb [3]float64) [3]float64 {
+	red, green, blue := rgb[0], rgb[1], rgb[2]
+	max := math.Max(red, math.Max(green, blue))
+	min := math.Min(red, math.Min(green, blue))
+	hue, saturation, lightness := math.NaN(), 0.0, (min+max)/2
+	difference := max - min
+
+	if difference != 0 {
+		if lightness != 0 && lightness != 1 {
+			saturation = (max - lightness) / math.Min(lightness, 1-lightness)
+		}
+		switch max {
+		case red:
+			hue = (green - blue) / difference
+			if green < blue {
+				hue += 6
+			}
+		case green:
+			hue = (blue-red)/difference + 2
+		case blue:
+			hue = (red-green)/difference + 4
+		}
+		hue *= 60
+	}
+	// A negative saturation means the hue points the other way.
+	if saturation < 0 {
+		hue += 180
+		saturation = math.Abs(saturation)
+	}
+
+	return [3]float64{normalize_hue(hue), saturation * 100, lightness * 100}
+}
+
+// https://drafts.csswg.org/css-color-4/#hwb-to-rgb
+func hwb_to_srgb(hwb [3]float64) [3]float64 {
+	white, black := hwb[1]/100, hwb[2]/100
+	if white+black >= 1 {
+		gray := white / (white + black)
+		return [3]float64{gray, gray, gray}
+	}
+
+	rgb := hsl_to_srgb([3]float64{hwb[0], 100, 50})
+	for i := range rgb {
+		rgb[i] = rgb[i]*(1-white-black) + white
+	}
+
+	return rgb
+}
+
+func srgb_to_hwb(rgb [3]float64) [3]float64 {
+	hsl := srgb_to_hsl(rgb)
+	white := math.Min(rgb[0], math.Min(rgb[1], rgb[2]))
+	black := 1 - math.Max(rgb[0], math.Max(rgb[1], rgb[2]))
+
+	return [3]float64{hsl[0], white * 100, black * 100}
+}
+
+// https://drafts.csswg.org/css-color-4/#color-conversion-code
+const (
+	lab_kappa   = 24389.0 / 27
+	lab_epsilon = 216.0 / 24389
+)
+
+func xyz_d50_to_lab(xyz [3]float64) [3]float64 {
+	var f [3]float64
+	for i := range xyz {
+		value := xyz[i] / d50_white[i]
+		if value > lab_epsilon {
+			f[i] = math.Cbrt(value)
+		} else {
+			f[i] = (lab_kappa*value + 16) / 116
+		}
+	}
+
+	return [3]float64{116*f[1] - 16, 500 * (f[0] - f[1]), 200 * (f[1] - f[2])}
+}
+
+func lab_to_xyz_d50(lab [3]float64) [3]float64 {
+	f1 := (lab[0] + 16) / 116
+	f0 := lab[1]/500 + f1
+	f2 := f1 - lab[2]/200
+
+	xyz := [3]float64{(116*f0 - 16) / lab_kappa, lab[0] / lab_kappa, (116*f2 - 16) / lab_kappa}
+	if math.Pow(f0, 3) > lab_epsilon {
+		xyz[0] = math.Pow(f0, 3)
+	}
+	if lab[0] > lab_kappa*lab_epsilon {
+		xyz[1] = math.Pow(f1, 3)
+	}
+	if math.Pow(f2, 3) > lab_epsilon {
+		xyz[2] = math.Pow(f2, 3)
+	}
+
+	return [3]float64{xyz[0] * d50_white[0], xyz[1] * d50_white[1], xyz[2] * d50_white[2]}
+}
+
+// Lightness, chroma and hue from lightness and the a and b axes, as used by LCH and OkLCh.
+func rectangular_to_polar(lab [3]float64) [3]float64 {
+	hue := math.Atan2(lab[2], lab[1]) * 180 / math.Pi
+
+	return [3]float64{lab[0], math.Hypot(lab[1], lab[2]), normalize_hue(hue)}
+}
+
+func polar_to_rectangular(lch [3]float64) [3]float64 {
+	hue := zero_if_missing(lch[2]) * math.Pi / 180
+
+	return [3]float64{lch[0], lch[1] * math.Cos(hue), lch[1] * math.Sin(hue)}
+}
+
+// Whether the color is within the gamut of the RGB color space.
+func (c Color) InGamut(space ColorSpace) bool {
+	const epsilon = 0.000075
+	for _, component := range c.To(space).Components {
+		if component < -epsilon || component > 1+epsilon {
+			return false
+		}
+	}
+
+	return true
+}
+
+// https://drafts.csswg.org/css-color-4/#binsearch
+// Map the color into the gamut of the RGB color space, by reducing its chroma in OkLCh until clipping it is no longer noticeable.
+func (c Color) GamutMap(space ColorSpace) Color {
+	const just_noticeable_difference = 0.02
+	const epsilon = 0.0001
+
+	if c.InGamut(space) {
+		return clip_color(c.To(space))
+	}
+
+	origin := c.To(OKLCH)
+	if origin.Components[0] >= 1 {
+		return Color{Space: space, Components: [3]float64{1, 1, 1}, Alpha: c.Alpha}
+	}
+	if origin.Components[0] <= 0 {
+		return Color{Space: space, Alpha: c.Alpha}
+	}
+
+	current := origin
+	clipped := clip_color(current.To(space))
+	if delta_e_ok(clipped, current) < just_noticeable_difference {
+		return clipped
+	}
+
+	min, max := 0.0, origin.Components[1]
+	min_in_gamut := true
+	for max-min > epsilon {
+		chroma := (min + max) / 2
+		current.Components[1] = chroma
+		if min_in_gamut && current.InGamut(space) {
+			min = chroma
+			continue
+		}
+
+		clipped = clip_color(current.To(space))
+		difference := delta_e_ok(clipped, current)
+		if difference < just_noticeable_difference {
+			if just_noticeable_difference-difference < epsilon {
+				return clipped
+			}
+			min_in_gamut = false
+			min = chroma
+		} else {
+			max = chroma
+		}
+	}
+
+	return clipped
+}
+
+func clip_color(c Color) Color {
+	for i := range c.Components {
+		c.Components[i] = math.Max(0, math.Min(1, zero_if_missing(c.Components[i])))
+	}
+
+	return c
+}
+
+// https://drafts.csswg.org/css-color-4/#color-difference-OK
+func delta_e_ok(a Color, b Color) float64 {
+	lab_a, lab_b := a.To(OKLAB).Components, b.To(OKLAB).Components
+
+	return math.Sqrt(math.Pow(lab_a[0]-lab_b[0], 2) + math.Pow(lab_a[1]-lab_b[1], 2) + math.Pow(lab_a[2]-lab_b[2], 2))
+}
+
+// https://drafts.csswg.org/css-color-4/#serializing-color-values
+func (c Color) String() string {
+	var sb strings.Builder
+	stringify_color(&sb, c)
+	return sb.String()
+}
+
+func stringify_color(sb *strings.Builder, c Color) {
+	alpha := zero_if_missing(c.Alpha)
+	// hsl() and hwb() colors are always serialized as rgb(), as they can't represent anything outside of sRGB.
+	if c.legacy || c.Space == HSL || c.Space == HWB {
+		rgb := c.srgb_bytes()
+		if alpha == 1 {
+			sb.WriteString(fmt.Sprintf("rgb(%d, %d, %d)", rgb[0], rgb[1], rgb[2]))
+		} else {
+			sb.WriteString(fmt.Sprintf("rgba(%d, %d, %d, %s)", rgb[0], rgb[1], rgb[2], format_legacy_alpha(alpha)))
+		}
+		return
+	}
+
+	switch c.Space {
+	case LAB, LCH, OKLAB, OKLCH:
+		sb.WriteString(c.Space.String())
+		sb.WriteRune(OPEN_PAREN_CHAR)
+	default:
+		sb.WriteString("color(")
+		sb.WriteString(c.Space.String())
+		sb.WriteRune(SPACE_CHAR)
+	}
+	for i, component := range c.Components {
+		if i > 0 {
+			sb.WriteRune(SPACE_CHAR)
+		}
+		if math.IsNaN(component) {
+			sb.WriteString("none")
+		} else {
+			sb.WriteString(format_rounded_number(component))
+		}
+	}
+	if math.IsNaN(c.Alpha) {
+		sb.WriteString(" / none")
+	} else if alpha != 1 {
+		sb.WriteString(" / ")
+		sb.WriteString(format_rounded_number(alpha))
+	}
+	sb.WriteRune(CLOSE_PAREN_CHAR)
+}
+
+// The shortest serialization of the color, for minification.
+// Legacy sRGB colors are 8 bits per channel, so they can be written as a hex color or a named color,
+// whereas other colors keep their color space so that they don't lose precision or gamut.
+func (c Color) Shortest() string {
+	if c.legacy == false {
+		return c.String()
+	}
+
+	rgb := c.srgb_bytes()
+	alpha := int(math.Round(zero_if_missing(c.Alpha) * 255))
+	var hex string
+	if rgb[0]%17 == 0 && rgb[1]%17 == 0 && rgb[2]%17 == 0 && alpha%17 == 0 {
+		hex = fmt.Sprintf("#%x%x%x", rgb[0]/17, rgb[1]/17, rgb[2]/17)
+		if alpha != 255 {
+			hex += fmt.Sprintf("%x", alpha/17)
+		}
+	} else {
+		hex = fmt.Sprintf("#%02x%02x%02x", rgb[0], rgb[1], rgb[2])
+		if alpha != 255 {
+			hex += fmt.Sprintf("%02x", alpha)
+		}
+	}
+
+	if alpha == 255 {
+		if name, ok := shortest_color_names[uint32(rgb[0])<<16|uint32(rgb[1])<<8|uint32(rgb[2])]; ok && len(name) < len(hex) {
+			return name
+		}
+	}
+
+	return hex
+}
+
+// The color's channels in sRGB, mapped into gamut and rounded to 8 bits.
+func (c Color) srgb_bytes() [3]int {
+	srgb := c.GamutMap(SRGB)
+	var rgb [3]int
+	for i, component := range srgb.Components {
+		// Round away the floating-point noise first, so that e.g. 127.49999999 rounds up as 127.5 would.
+		rgb[i] = int(math.Round(math.Round(math.Max(0, math.Min(1, zero_if_missing(component)))*255*1e6) / 1e6))
+	}
+
+	return rgb
+}
+
+// The alpha of a legacy color is 8 bits, so it's written with the fewest decimal places that round-trip through 8 bits.
+func format_legacy_alpha(alpha float64) string {
+	rounded := math.Round(alpha*100) / 100
+	if math.Round(rounded*255) != math.Round(alpha*255) {
+		rounded = math.Round(alpha*1000) / 1000
+	}
+
+	return format_number(rounded)
+}
+
+// Numbers are rounded to remove floating-point noise from the conversions.
+func format_rounded_number(value float64) string {
+	value = math.Round(value*1e6) / 1e6
+	if value == 0 {
+		value = 0
+	}
+
+	return format_number(value)
+}
+
+// https://drafts.csswg.org/css-color/#named-colors
+var named_colors = map[string]uint32{
+	"aliceblue": 0xf0f8ff, "antiquewhite": 0xfaebd7, "aqua": 0x00ffff, "aquamarine": 0x7fffd4, "azure": 0xf0ffff,
+	"beige": 0xf5f5dc, "bisque": 0xffe4c4, "black": 0x000000, "blanchedalmond": 0xffebcd, "blue": 0x0000ff,
+	"blueviolet": 0x8a2be2, "brown": 0xa52a2a, "burlywood": 0xdeb887, "cadetblue": 0x5f9ea0, "chartreuse": 0x7fff00,
+	"chocolate": 0xd2691e, "coral": 0xff7f50, "cornflowerblue": 0x6495ed, "cornsilk": 0xfff8dc, "crimson": 0xdc143c,
+	"cyan": 0x00ffff, "darkblue": 0x00008b, "darkcyan": 0x008b8b, "darkgoldenrod": 0xb8860b, "darkgray": 0xa9a9a9,
+	"darkgreen": 0x006400, "darkgrey": 0xa9a9a9, "darkkhaki": 0xbdb76b, "darkmagenta": 0x8b008b,
+	"darkolivegreen": 0x556b2f, "darkorange": 0xff8c00, "darkorchid": 0x9932cc, "darkred": 0x8b0000,
+	"darksalmon": 0xe9967a, "darkseagreen": 0x8fbc8f, "darkslateblue": 0x483d8b, "darkslategray": 0x2f4f4f,
+	"darkslategrey": 0x2f4f4f, "darkturquoise": 0x00ced1, "darkviolet": 0x9400d3, "deeppink": 0xff1493,
+	"deepskyblue": 0x00bfff, "dimgray": 0x696969, "dimgrey": 0x696969, "dodgerblue": 0x1e90ff,
+	"firebrick": 0xb22222, "floralwhite": 0xfffaf0, "forestgreen": 0x228b22, "fuchsia": 0xff00ff,
+	"gainsboro": 0xdcdcdc, "ghostwhite": 0xf8f8ff, "gold": 0xffd700, "goldenrod": 0xdaa520, "gray": 0x808080,
+	"green": 0x008000, "greenyellow": 0xadff2f, "grey": 0x808080, "honeydew": 0xf0fff0, "hotpink": 0xff69b4,
+	"indianred": 0xcd5c5c, "indigo": 0x4b0082, "ivory": 0xfffff0, "khaki": 0xf0e68c, "lavender": 0xe6e6fa,
+	"lavenderblush": 0xfff0f5, "lawngreen": 0x7cfc00, "lemonchiffon": 0xfffacd, "lightblue": 0xadd8e6,
+	"lightcoral": 0xf08080, "lightcyan": 0xe0ffff, "lightgoldenrodyellow": 0xfafad2, "lightgray": 0xd3d3d3,
+	"lightgreen": 0x90ee90, "lightgrey": 0xd3d3d3, "lightpink": 0xffb6c1, "lightsalmon": 0xffa07a,
+	"lightseagreen": 0x20b2aa, "lightskyblue": 0x87cefa, "lightslategray": 0x778899, "lightslategrey": 0x778899,
+	"lightsteelblue": 0xb0c4de, "lightyellow": 0xffffe0, "lime": 0x00ff00, "limegreen": 0x32cd32, "linen": 0xfaf0e6,
+	"magenta": 0xff00ff, "maroon": 0x800000, "mediumaquamarine": 0x66cdaa, "mediumblue": 0x0000cd,
+	"mediumorchid": 0xba55d3, "mediumpurple": 0x9370db, "mediumseagreen": 0x3cb371, "mediumslateblue": 0x7b68ee,
+	"mediumspringgreen": 0x00fa9a, "mediumturquoise": 0x48d1cc, "mediumvioletred": 0xc71585,
+	"midnightblue": 0x191970, "mintcream": 0xf5fffa, "mistyrose": 0xffe4e1, "moccasin": 0xffe4b5,
+	"navajowhite": 0xffdead, "navy": 0x000080, "oldlace": 0xfdf5e6, "olive": 0x808000, "olivedrab": 0x6b8e23,
+	"orange": 0xffa500, "orangered": 0xff4500, "orchid": 0xda70d6, "palegoldenrod": 0xeee8aa, "palegreen": 0x98fb98,
+	"paleturquoise": 0xafeeee, "palevioletred": 0xdb7093, "papayawhip": 0xffefd5, "peachpuff": 0xffdab9,
+	"peru": 0xcd853f, "pink": 0xffc0cb, "plum": 0xdda0dd, "powderblue": 0xb0e0e6, "purple": 0x800080,
+	"rebeccapurple": 0x663399, "red": 0xff0000, "rosybrown": 0xbc8f8f, "royalblue": 0x4169e1,
+	"saddlebrown": 0x8b4513, "salmon": 0xfa8072, "sandybrown": 0xf4a460, "seagreen": 0x2e8b57, "seashell": 0xfff5ee,
+	"sienna": 0xa0522d, "silver": 0xc0c0c0, "skyblue": 0x87ceeb, "slateblue": 0x6a5acd, "slategray": 0x708090,
+	"slategrey": 0x708090, "snow": 0xfffafa, "springgreen": 0x00ff7f, "steelblue": 0x4682b4, "tan": 0xd2b48c,
+	"teal": 0x008080, "thistle": 0xd8bfd8, "tomato": 0xff6347, "turquoise": 0x40e0d0, "violet": 0xee82ee,
+	"wheat": 0xf5deb3, "white": 0xffffff, "whitesmoke": 0xf5f5f5, "yellow": 0xffff00, "yellowgreen": 0x9acd32,
+}
+
+// The shortest name of each named color, for serialization. Where names are the same length, the first alphabetically is used.
+var shortest_color_names = func() map[uint32]string {
+	names := map[uint32]string{}
+	for name, rgb := range named_colors {
+		existing, ok := names[rgb]
+		if ok == false || len(name) < len(existing) || (len(name) == len(existing) && name < existing) {
+			names[rgb] = name
+		}
+	}
+	return names
+}()
+
+// https://drafts.csswg.org/css-color/#css-system-colors
+var system_colors = map[string]bool{
+	"accentcolor": true, "accentcolortext": true, "activetext": true, "buttonborder": true, "buttonface": true,
+	"buttontext": true, "canvas": true, "canvastext": true, "field": true, "fieldtext": true, "graytext": true,
+	"highlight": true, "highlighttext": true, "linktext": true, "mark": true, "marktext": true,
+	"selecteditem": true, "selecteditemtext": true, "visitedtext": true,
+}
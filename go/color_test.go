@@ -0,0 +1,152 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		input string
+		// The serialization of the parsed color.
+		want string
+	}{
+		// Legacy syntax.
+		{input: "#f00", want: "rgb(255, 0, 0)"},
+		{input: "#ff000080", want: "rgba(255, 0, 0, 0.5)"},
+		{input: "RebeccaPurple", want: "rgb(102, 51, 153)"},
+		{input: "transparent", want: "rgba(0, 0, 0, 0)"},
+		{input: "rgb(255, 0, 0)", want: "rgb(255, 0, 0)"},
+		{input: "rgba(100%, 0%, 0%, 0.25)", want: "rgba(255, 0, 0, 0.25)"},
+		{input: "rgb(300, -10, 0)", want: "rgb(255, 0, 0)"},
+		{input: "hsl(120, 100%, 50%)", want: "rgb(0, 255, 0)"},
+		{input: "hsla(0.5turn, 100%, 50%, 50%)", want: "rgba(0, 255, 255, 0.5)"},
+		// Modern syntax.
+		{input: "rgb(255 0 0 / 50%)", want: "rgba(255, 0, 0, 0.5)"},
+		{input: "rgb(none 0 0)", want: "rgb(0, 0, 0)"},
+		{input: "hsl(240deg 100% 50%)", want: "rgb(0, 0, 255)"},
+		{input: "hwb(0 0% 0%)", want: "rgb(255, 0, 0)"},
+		{input: "lab(50 20 -30)", want: "lab(50 20 -30)"},
+		{input: "lch(50% 75 -90)", want: "lch(50 75 270)"},
+		{input: "oklab(0.5 0.1 none / 0.5)", want: "oklab(0.5 0.1 none / 0.5)"},
+		{input: "oklch(150% 0.2 30)", want: "oklch(1 0.2 30)"},
+		{input: "color(display-p3 1 0 0)", want: "color(display-p3 1 0 0)"},
+		{input: "color(xyz 0.5 0.5 0.5 / 25%)", want: "color(xyz-d65 0.5 0.5 0.5 / 0.25)"},
+		// Relative color syntax.
+		{input: "rgb(from red r g 255)", want: "color(srgb 1 0 1)"},
+		{input: "rgb(from #00f b g r / alpha)", want: "color(srgb 1 0 0)"},
+		{input: "hsl(from red 120 s l)", want: "rgb(0, 255, 0)"},
+		{input: "oklch(from oklch(0.5 0.1 30) l c 60)", want: "oklch(0.5 0.1 60)"},
+		{input: "color(from red srgb r 1 b)", want: "color(srgb 1 1 0)"},
+		// Mixing colors.
+		{input: "color-mix(in srgb, red, blue)", want: "color(srgb 0.5 0 0.5)"},
+		{input: "color-mix(in srgb, red 25%, blue)", want: "color(srgb 0.25 0 0.75)"},
+		{input: "color-mix(in srgb, 25% red, blue 25%)", want: "color(srgb 0.5 0 0.5 / 0.5)"},
+		{input: "color-mix(in oklch, oklch(0.5 0.1 350), oklch(0.5 0.1 10))", want: "oklch(0.5 0.1 0)"},
+		{input: "color-mix(in oklch longer hue, oklch(0.5 0.1 350), oklch(0.5 0.1 10))", want: "oklch(0.5 0.1 180)"},
+		{input: "color-mix(in srgb, transparent, blue)", want: "color(srgb 0 0 1 / 0.5)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			color, err := parse_color_string(test.input)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := color.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseColorErrors(t *testing.T) {
+	for _, input := range []string{
+		"#ff",
+		"notacolor",
+		"currentcolor",
+		"rgb(255, 0)",
+		"rgb(255, 0%, 0)",
+		"rgb(255 0 0 0)",
+		"rgb(255, 0, 0, none)",
+		"hsl(120 100% 50% / 0.5 / 1)",
+		"lab(50, 20, 30)",
+		"color(unknown 1 0 0)",
+		"color(hsl 1 0 0)",
+		"rgb(from red x g b)",
+		"color-mix(srgb, red, blue)",
+		"color-mix(in srgb, red, blue, green)",
+		"color-mix(in srgb longer hue, red, blue)",
+		"color-mix(in srgb, red 150%, blue)",
+		"color-mix(in srgb, red 0%, blue 0%)",
+		"color-mix(in srgb, 50%, blue)",
+		"color-mix(in srgb, red blue, green)",
+		"color-mix(in srgb, 25% red 50%, blue)",
+	} {
+		t.Run(input, func(t *testing.T) {
+			if color, err := parse_color_string(input); err == nil {
+				t.Errorf("expected an error, got %q", color.String())
+			}
+		})
+	}
+}
+
+func TestGamutMap(t *testing.T) {
+	tests := []struct {
+		input string
+		// Whether the color is in the sRGB gamut.
+		in_gamut bool
+		// The serialization of the color mapped into the sRGB gamut.
+		want string
+	}{
+		{input: "rgb(10 20 30)", in_gamut: true, want: "rgb(10, 20, 30)"},
+		{input: "color(display-p3 1 0 0)", in_gamut: false, want: "color(srgb 1 0.04457 0.045932)"},
+		{input: "oklch(0.7 0.4 150)", in_gamut: false, want: "color(srgb 0 0.760678 0.280818)"},
+		{input: "oklch(1.2 0.1 150)", in_gamut: false, want: "color(srgb 1 1 1)"},
+		{input: "lab(0 0 0)", in_gamut: true, want: "color(srgb 0 0 0)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			color, err := parse_color_string(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := color.InGamut(SRGB); got != test.in_gamut {
+				t.Errorf("in gamut: got %v, want %v", got, test.in_gamut)
+			}
+			mapped := color.GamutMap(SRGB)
+			if mapped.InGamut(SRGB) == false {
+				t.Errorf("%v is still out of gamut", mapped)
+			}
+			if got := mapped.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestShortestColor(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "#ff0000", want: "red"},
+		{input: "rgb(0, 0, 0)", want: "#000"},
+		{input: "#11223380", want: "#11223380"},
+		{input: "rgba(255, 255, 255, 0)", want: "#fff0"},
+		{input: "rgb(1, 2, 3)", want: "#010203"},
+		{input: "lab(50 20 -30)", want: "lab(50 20 -30)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			color, err := parse_color_string(test.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := color.Shortest(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,861 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type ContainerQueryKind uint8
+
+// https://drafts.csswg.org/css-conditional-5/#typedef-container-query
+const (
+	CONTAINER_NOT ContainerQueryKind = iota
+	CONTAINER_AND
+	CONTAINER_OR
+	// ( <size-feature> )
+	CONTAINER_SIZE_FEATURE
+	// style( <style-query> ), whose query is the only child.
+	CONTAINER_STYLE
+	// ( <style-feature> ), inside style()
+	CONTAINER_STYLE_FEATURE
+	// scroll-state( <scroll-state-query> ), whose query is the only child.
+	CONTAINER_SCROLL_STATE
+	// ( <scroll-state-feature> ), inside scroll-state()
+	CONTAINER_SCROLL_STATE_FEATURE
+	// <general-enclosed>
+	CONTAINER_GENERAL_ENCLOSED
+)
+
+func (k ContainerQueryKind) String() string {
+	switch k {
+	case CONTAINER_NOT:
+		return "CONTAINER_NOT"
+	case CONTAINER_AND:
+		return "CONTAINER_AND"
+	case CONTAINER_OR:
+		return "CONTAINER_OR"
+	case CONTAINER_SIZE_FEATURE:
+		return "CONTAINER_SIZE_FEATURE"
+	case CONTAINER_STYLE:
+		return "CONTAINER_STYLE"
+	case CONTAINER_STYLE_FEATURE:
+		return "CONTAINER_STYLE_FEATURE"
+	case CONTAINER_SCROLL_STATE:
+		return "CONTAINER_SCROLL_STATE"
+	case CONTAINER_SCROLL_STATE_FEATURE:
+		return "CONTAINER_SCROLL_STATE_FEATURE"
+	case CONTAINER_GENERAL_ENCLOSED:
+		return "CONTAINER_GENERAL_ENCLOSED"
+	}
+	return "<UNKNOWN CONTAINER QUERY>"
+}
+
+// https://drafts.csswg.org/css-conditional-5/#container-rule
+// <container-condition> = [ <container-name>? <container-query>? ]!
+type ContainerCondition struct {
+	// The name of the query container to query, or empty for the nearest one that can answer the query.
+	name      string
+	has_query bool
+	query     ContainerQuery
+}
+
+// @container <container-condition>#
+// The rule applies when any of its conditions are true.
+type ContainerConditionList []ContainerCondition
+
+type ContainerQuery struct {
+	kind ContainerQueryKind
+	// CONTAINER_NOT, CONTAINER_STYLE and CONTAINER_SCROLL_STATE have exactly one child, CONTAINER_AND and CONTAINER_OR have two or more.
+	children []ContainerQuery
+	// CONTAINER_SIZE_FEATURE, CONTAINER_SCROLL_STATE_FEATURE
+	feature QueryFeature
+	// CONTAINER_STYLE_FEATURE: the declaration to compare the container's computed value with. It has no value when it only
+	// tests that the property doesn't have its initial value.
+	decl Declaration
+	// CONTAINER_GENERAL_ENCLOSED: the whole function or block.
+	value []ComponentValue
+}
+
+// https://drafts.csswg.org/mediaqueries-5/#mq-features
+// A feature in a query, as the comparisons of its value with each given value, e.g. "(400px <= width < 800px)" compares
+// width with >= 400px and < 800px, and "(min-width: 400px)" with >= 400px. A feature in a boolean context has no comparisons.
+type QueryFeature struct {
+	// The lowercased name, without a min- or max- prefix.
+	name        string
+	comparisons []FeatureComparison
+}
+
+type FeatureComparison struct {
+	// "=", "<", "<=", ">" or ">=", with the feature on the left.
+	operator string
+	value    []ComponentValue
+}
+
+// How a feature's value is given.
+type query_feature_type uint8
+
+const (
+	LENGTH_FEATURE query_feature_type = iota
+	RATIO_FEATURE
+	// Discrete features take one of their keywords, and can't be compared as a range.
+	KEYWORD_FEATURE
+)
+
+type query_feature_definition struct {
+	value_type query_feature_type
+	keywords   []string
+}
+
+// https://drafts.csswg.org/css-conditional-5/#container-features
+var container_size_features = map[string]query_feature_definition{
+	"width":        {value_type: LENGTH_FEATURE},
+	"height":       {value_type: LENGTH_FEATURE},
+	"inline-size":  {value_type: LENGTH_FEATURE},
+	"block-size":   {value_type: LENGTH_FEATURE},
+	"aspect-ratio": {value_type: RATIO_FEATURE},
+	"orientation":  {value_type: KEYWORD_FEATURE, keywords: []string{"portrait", "landscape"}},
+}
+
+// https://drafts.csswg.org/css-conditional-5/#scroll-state-container
+var container_scroll_state_features = map[string]query_feature_definition{
+	"stuck": {value_type: KEYWORD_FEATURE, keywords: []string{
+		"none", "top", "right", "bottom", "left", "block-start", "inline-start", "block-end", "inline-end",
+	}},
+	"snapped": {value_type: KEYWORD_FEATURE, keywords: []string{"none", "x", "y", "block", "inline", "both"}},
+	"scrollable": {value_type: KEYWORD_FEATURE, keywords: []string{
+		"none", "top", "right", "bottom", "left", "block-start", "inline-start", "block-end", "inline-end", "x", "y", "block", "inline",
+	}},
+	"scrolled": {value_type: KEYWORD_FEATURE, keywords: []string{
+		"none", "top", "right", "bottom", "left", "block-start", "inline-start", "block-end", "inline-end", "x", "y", "block", "inline",
+	}},
+}
+
+// https://drafts.csswg.org/mediaqueries-4/#evaluating
+// Queries are evaluated with three-valued logic, where what can't be evaluated (e.g. an unknown feature) is unknown.
+// "not" leaves unknown as it is, and a condition that is unknown at the top level is false.
+type QueryResult uint8
+
+const (
+	QUERY_FALSE QueryResult = iota
+	QUERY_TRUE
+	QUERY_UNKNOWN
+)
+
+func (r QueryResult) String() string {
+	switch r {
+	case QUERY_FALSE:
+		return "QUERY_FALSE"
+	case QUERY_TRUE:
+		return "QUERY_TRUE"
+	case QUERY_UNKNOWN:
+		return "QUERY_UNKNOWN"
+	}
+	return "<UNKNOWN QUERY RESULT>"
+}
+
+func query_result(value bool) QueryResult {
+	if value {
+		return QUERY_TRUE
+	}
+	return QUERY_FALSE
+}
+
+// An ancestor of the element a container query is evaluated for, and what its queries can be answered with.
+// Every element is a container for style queries, but only those with a container-type are containers for size and
+// scroll-state queries.
+type ContainerContext struct {
+	// The names given by container-name.
+	names []string
+	// The size container-type: "size", "inline-size" (for queries on the inline axis only), or "" for neither.
+	size_type string
+	// Whether container-type includes scroll-state.
+	scroll_state bool
+	// The size of the container's content box, in px.
+	width  float64
+	height float64
+	// Whether the inline axis is vertical, in which case inline-size is the height.
+	vertical bool
+	// The computed values of the container's properties (including custom properties), for style queries.
+	style map[string]string
+	// The values of the scroll-state features, e.g. "stuck" is {"top"} when the container is stuck to the top.
+	scroll_states map[string][]string
+	// What relative lengths in size queries resolve against: they're relative to the container's computed values.
+	calc CalcContext
+}
+
+// The conditions of an @container rule.
+func (r Rule) container_conditions() (ContainerConditionList, error) {
+	if r.kind != AT_RULE || strings.EqualFold(r.name, "container") == false {
+		return nil, fmt.Errorf("Parse Error: rule is not an @container rule")
+	}
+
+	return parse_container_condition_list(r.prelude)
+}
+
+func parse_container_condition_list(prelude []ComponentValue) (ContainerConditionList, error) {
+	var conditions ContainerConditionList
+	for _, item := range split_on_commas(prelude) {
+		condition, err := parse_container_condition(item)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	return conditions, nil
+}
+
+// <container-name> = <custom-ident>, excluding none and the keywords of the query syntax.
+func parse_container_condition(values []ComponentValue) (ContainerCondition, error) {
+	stream := NewComponentValueStream(values)
+	stream.discard_whitespace()
+	if stream.empty() {
+		return ContainerCondition{}, fmt.Errorf("Parse Error: @container condition is empty")
+	}
+
+	var condition ContainerCondition
+	if next := stream.next_value(); next.is_token(IDENT_TOKEN) && next.is_ident("not") == false {
+		name := string(next.token.value)
+		if lower := strings.ToLower(name); is_css_wide_keyword(lower) || lower == "default" || lower == "none" || lower == "and" || lower == "or" {
+			return ContainerCondition{}, fmt.Errorf("Parse Error: '%s' can't be used as a container name", name)
+		}
+		condition.name = name
+		stream.discard_value()
+		stream.discard_whitespace()
+		if stream.empty() {
+			return condition, nil
+		}
+	}
+
+	query, err := stream.consume_container_query(CONTAINER_SIZE_FEATURE)
+	if err != nil {
+		return ContainerCondition{}, err
+	}
+	stream.discard_whitespace()
+	if stream.empty() == false {
+		return ContainerCondition{}, fmt.Errorf("Parse Error: unexpected %s after @container condition", describe_value(stream.next_value()))
+	}
+	condition.has_query, condition.query = true, query
+
+	return condition, nil
+}
+
+// Parse the values as a whole container query (or style query, or scroll-state query, depending on the kind of its features).
+func parse_container_query(values []ComponentValue, feature_kind ContainerQueryKind) (ContainerQuery, error) {
+	stream := NewComponentValueStream(values)
+	stream.discard_whitespace()
+	query, err := stream.consume_container_query(feature_kind)
+	if err != nil {
+		return ContainerQuery{}, err
+	}
+	stream.discard_whitespace()
+	if stream.empty() == false {
+		return ContainerQuery{}, fmt.Errorf("Parse Error: unexpected %s in container query", describe_value(stream.next_value()))
+	}
+
+	return query, nil
+}
+
+// <container-query> = not <query-in-parens>
+//
+//	| <query-in-parens> [ [ and <query-in-parens> ]* | [ or <query-in-parens> ]* ]
+//
+// <style-query> and <scroll-state-query> have the same grammar, with their own features in parentheses.
+func (cs *ComponentValueStream) consume_container_query(feature_kind ContainerQueryKind) (ContainerQuery, error) {
+	if cs.next_value().is_ident("not") {
+		cs.discard_value()
+		cs.discard_whitespace()
+		child, err := cs.consume_query_in_parens(feature_kind)
+		if err != nil {
+			return ContainerQuery{}, err
+		}
+
+		return ContainerQuery{kind: CONTAINER_NOT, children: []ContainerQuery{child}}, nil
+	}
+
+	first, err := cs.consume_query_in_parens(feature_kind)
+	if err != nil {
+		return ContainerQuery{}, err
+	}
+
+	query := ContainerQuery{children: []ContainerQuery{first}}
+	for {
+		cs.mark()
+		cs.discard_whitespace()
+
+		var kind ContainerQueryKind
+		switch next := cs.next_value(); {
+		case next.is_ident("and"):
+			kind = CONTAINER_AND
+		case next.is_ident("or"):
+			kind = CONTAINER_OR
+		default:
+			cs.restore_mark()
+			if len(query.children) == 1 {
+				return first, nil
+			}
+			return query, nil
+		}
+		cs.discard_mark()
+
+		// "and" and "or" can't be mixed at the same level without parentheses.
+		if len(query.children) > 1 && query.kind != kind {
+			return ContainerQuery{}, fmt.Errorf("Parse Error: cannot mix 'and' and 'or' in container query without parentheses")
+		}
+		query.kind = kind
+
+		cs.discard_value()
+		cs.discard_whitespace()
+		child, err := cs.consume_query_in_parens(feature_kind)
+		if err != nil {
+			return ContainerQuery{}, err
+		}
+		query.children = append(query.children, child)
+	}
+}
+
+// <query-in-parens> = ( <container-query> ) | ( <size-feature> ) | style( <style-query> ) | scroll-state( <scroll-state-query> )
+//
+//	| <general-enclosed>
+//
+// In style() and scroll-state(), the features in parentheses are their own, and there are no nested functions.
+func (cs *ComponentValueStream) consume_query_in_parens(feature_kind ContainerQueryKind) (ContainerQuery, error) {
+	next := cs.next_value()
+	switch {
+	case next.is_block(OPEN_PAREN_TOKEN):
+		cs.discard_value()
+		if query, err := parse_container_query(next.value, feature_kind); err == nil {
+			return query, nil
+		}
+		if feature, ok := parse_container_feature(next.value, feature_kind); ok {
+			return feature, nil
+		}
+		return ContainerQuery{kind: CONTAINER_GENERAL_ENCLOSED, value: []ComponentValue{next}}, nil
+	case next.kind == FUNCTION:
+		cs.discard_value()
+		var kind, inner_kind ContainerQueryKind
+		switch {
+		case feature_kind == CONTAINER_SIZE_FEATURE && next.is_function("style"):
+			kind, inner_kind = CONTAINER_STYLE, CONTAINER_STYLE_FEATURE
+		case feature_kind == CONTAINER_SIZE_FEATURE && next.is_function("scroll-state"):
+			kind, inner_kind = CONTAINER_SCROLL_STATE, CONTAINER_SCROLL_STATE_FEATURE
+		default:
+			return ContainerQuery{kind: CONTAINER_GENERAL_ENCLOSED, value: []ComponentValue{next}}, nil
+		}
+		// The argument is either a query, or a single feature without parentheses, e.g. style(--theme: dark).
+		query, err := parse_container_query(next.value, inner_kind)
+		if err != nil {
+			feature, ok := parse_container_feature(next.value, inner_kind)
+			if ok == false {
+				return ContainerQuery{}, fmt.Errorf("Parse Error: invalid query in %s()", next.name)
+			}
+			query = feature
+		}
+		return ContainerQuery{kind: kind, children: []ContainerQuery{query}}, nil
+	case next.is_token(EOF_TOKEN):
+		return ContainerQuery{}, fmt.Errorf("Parse Error: unexpected end of container query")
+	default:
+		return ContainerQuery{}, fmt.Errorf("Parse Error: unexpected %s in container query", describe_value(next))
+	}
+}
+
+// Parse the contents of parentheses as a feature of the kind, if they are one.
+func parse_container_feature(values []ComponentValue, feature_kind ContainerQueryKind) (ContainerQuery, bool) {
+	switch feature_kind {
+	case CONTAINER_SIZE_FEATURE:
+		feature, ok := parse_query_feature(values, container_size_features)
+		return ContainerQuery{kind: CONTAINER_SIZE_FEATURE, feature: feature}, ok
+	case CONTAINER_SCROLL_STATE_FEATURE:
+		feature, ok := parse_query_feature(values, container_scroll_state_features)
+		return ContainerQuery{kind: CONTAINER_SCROLL_STATE_FEATURE, feature: feature}, ok
+	}
+
+	// <style-feature> = <declaration> | <custom-property-name>
+	if name, ok := single_ident_argument(values); ok {
+		return ContainerQuery{kind: CONTAINER_STYLE_FEATURE, decl: Declaration{name: name}}, true
+	}
+	decl, err := parse_supports_declaration(values)
+	return ContainerQuery{kind: CONTAINER_STYLE_FEATURE, decl: decl}, err == nil
+}
+
+// https://drafts.csswg.org/mediaqueries-5/#mq-syntax
+// <mf-plain> = <mf-name> : <mf-value>
+// <mf-boolean> = <mf-name>
+// <mf-range> = <mf-name> <mf-comparison> <mf-value> | <mf-value> <mf-comparison> <mf-name>
+//
+//	| <mf-value> <mf-lt> <mf-name> <mf-lt> <mf-value> | <mf-value> <mf-gt> <mf-name> <mf-gt> <mf-value>
+//
+// A feature that isn't one of the known features, or with an invalid value, isn't a feature (but is <general-enclosed>).
+func parse_query_feature(values []ComponentValue, features map[string]query_feature_definition) (QueryFeature, bool) {
+	values = non_whitespace_values(values)
+	if len(values) == 0 {
+		return QueryFeature{}, false
+	}
+
+	// <mf-boolean>, <mf-plain>
+	if values[0].is_token(IDENT_TOKEN) && (len(values) == 1 || values[1].is_token(COLON_TOKEN)) {
+		name := strings.ToLower(string(values[0].token.value))
+		if len(values) == 1 {
+			_, ok := features[name]
+			return QueryFeature{name: name}, ok
+		}
+		operator := "="
+		if prefix, rest, ok := strings.Cut(name, "-"); ok && (prefix == "min" || prefix == "max") {
+			name, operator = rest, map[string]string{"min": ">=", "max": "<="}[prefix]
+		}
+		definition, ok := features[name]
+		if ok == false || (operator != "=" && definition.value_type == KEYWORD_FEATURE) {
+			return QueryFeature{}, false
+		}
+		comparison := FeatureComparison{operator: operator, value: values[2:]}
+		return QueryFeature{name: name, comparisons: []FeatureComparison{comparison}}, is_feature_value(comparison.value, definition)
+	}
+
+	// <mf-range>: split the values on the comparisons, one of whose sides is the feature's name.
+	var operands [][]ComponentValue
+	var operators []string
+	start := 0
+	for i := 0; i < len(values); i += 1 {
+		if values[i].is_delim('<') == false && values[i].is_delim('>') == false && values[i].is_delim('=') == false {
+			continue
+		}
+		operator := string(values[i].token.value)
+		if operator != "=" && i+1 < len(values) && values[i+1].is_delim('=') {
+			operator += "="
+		}
+		operands = append(operands, values[start:i])
+		operators = append(operators, operator)
+		i += len(operator) - 1
+		start = i + 1
+	}
+	operands = append(operands, values[start:])
+
+	name_index := slices.IndexFunc(operands, func(operand []ComponentValue) bool {
+		return len(operand) == 1 && operand[0].is_token(IDENT_TOKEN)
+	})
+	if len(operators) == 0 || len(operators) > 2 || name_index < 0 || (len(operators) == 2 && name_index != 1) {
+		return QueryFeature{}, false
+	}
+	name := strings.ToLower(string(operands[name_index][0].token.value))
+	definition, ok := features[name]
+	if ok == false || definition.value_type == KEYWORD_FEATURE {
+		return QueryFeature{}, false
+	}
+	// Both comparisons of a double-sided range go the same way.
+	if len(operators) == 2 && (operators[0][0] != operators[1][0] || operators[0][0] == '=') {
+		return QueryFeature{}, false
+	}
+
+	feature := QueryFeature{name: name}
+	for i, operator := range operators {
+		value := operands[i+1]
+		// The feature is on the right of the first comparison, so the comparison is flipped.
+		if i+1 == name_index {
+			value = operands[i]
+			operator = flip_comparison(operator)
+		}
+		if is_feature_value(value, definition) == false {
+			return QueryFeature{}, false
+		}
+		feature.comparisons = append(feature.comparisons, FeatureComparison{operator: operator, value: value})
+	}
+
+	return feature, true
+}
+
+func flip_comparison(operator string) string {
+	switch operator[0] {
+	case '<':
+		return strings.Replace(operator, "<", ">", 1)
+	case '>':
+		return strings.Replace(operator, ">", "<", 1)
+	}
+
+	return operator
+}
+
+func is_feature_value(values []ComponentValue, definition query_feature_definition) bool {
+	switch definition.value_type {
+	case LENGTH_FEATURE:
+		if len(values) != 1 {
+			return false
+		}
+		_, is_length := parse_length(values[0])
+		return is_length || math_function_resolves_to(values[0], LENGTH, false)
+	case RATIO_FEATURE:
+		_, _, ok := parse_ratio(values)
+		return ok
+	}
+
+	return len(values) == 1 && is_ident_in(values[0], definition.keywords...)
+}
+
+// <ratio> = <number [0,∞]> [ / <number [0,∞]> ]?
+func parse_ratio(values []ComponentValue) (float64, float64, bool) {
+	if len(values) != 1 && (len(values) != 3 || values[1].is_delim('/') == false) {
+		return 0, 0, false
+	}
+	numerator, denominator := values[0], ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: NUMBER_TOKEN, numeric: 1}}
+	if len(values) == 3 {
+		denominator = values[2]
+	}
+	if numerator.is_token(NUMBER_TOKEN) == false || denominator.is_token(NUMBER_TOKEN) == false {
+		return 0, 0, false
+	}
+	if numerator.token.numeric < 0 || denominator.token.numeric < 0 {
+		return 0, 0, false
+	}
+
+	return numerator.token.numeric, denominator.token.numeric, true
+}
+
+// Whether any of the conditions is true, for an element with the ancestors, nearest first.
+func (l ContainerConditionList) Evaluate(ancestors []ContainerContext) bool {
+	for _, condition := range l {
+		if condition.Evaluate(ancestors) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// https://drafts.csswg.org/css-conditional-5/#container-rule
+// The condition is evaluated against the nearest of the ancestors (given nearest first) that has the condition's name,
+// and that is a container for all the features the query uses, e.g. an inline-size container can't answer a query on its height.
+// Without such a container, the condition is false.
+func (c ContainerCondition) Evaluate(ancestors []ContainerContext) bool {
+	for _, container := range ancestors {
+		if c.name != "" && slices.Contains(container.names, c.name) == false {
+			continue
+		}
+		if c.has_query == false {
+			return true
+		}
+		if c.query.can_be_queried(container) == false {
+			continue
+		}
+		return c.query.Evaluate(container) == QUERY_TRUE
+	}
+
+	return false
+}
+
+// Whether the container is a container for every size and scroll-state feature in the query.
+func (q ContainerQuery) can_be_queried(container ContainerContext) bool {
+	switch q.kind {
+	case CONTAINER_SIZE_FEATURE:
+		return container.can_query_size_feature(q.feature.name)
+	case CONTAINER_SCROLL_STATE_FEATURE:
+		return container.scroll_state
+	}
+
+	return slices.IndexFunc(q.children, func(child ContainerQuery) bool { return child.can_be_queried(container) == false }) < 0
+}
+
+// Whether the container's size on the axes the size feature depends on is known to its queries.
+// An inline-size container only knows its size on the inline axis, which is its height when the inline axis is vertical.
+func (c ContainerContext) can_query_size_feature(name string) bool {
+	width_known := c.size_type == "size" || (c.size_type == "inline-size" && c.vertical == false)
+	height_known := c.size_type == "size" || (c.size_type == "inline-size" && c.vertical)
+	switch name {
+	case "width":
+		return width_known
+	case "height":
+		return height_known
+	case "inline-size":
+		return c.size_type != ""
+	}
+
+	// block-size, aspect-ratio and orientation
+	return c.size_type == "size"
+}
+
+// Evaluate the query against the container.
+func (q ContainerQuery) Evaluate(container ContainerContext) QueryResult {
+	switch q.kind {
+	case CONTAINER_NOT:
+		switch q.children[0].Evaluate(container) {
+		case QUERY_TRUE:
+			return QUERY_FALSE
+		case QUERY_FALSE:
+			return QUERY_TRUE
+		}
+		return QUERY_UNKNOWN
+	case CONTAINER_AND:
+		result := QUERY_TRUE
+		for _, child := range q.children {
+			switch child.Evaluate(container) {
+			case QUERY_FALSE:
+				return QUERY_FALSE
+			case QUERY_UNKNOWN:
+				result = QUERY_UNKNOWN
+			}
+		}
+		return result
+	case CONTAINER_OR:
+		result := QUERY_FALSE
+		for _, child := range q.children {
+			switch child.Evaluate(container) {
+			case QUERY_TRUE:
+				return QUERY_TRUE
+			case QUERY_UNKNOWN:
+				result = QUERY_UNKNOWN
+			}
+		}
+		return result
+	case CONTAINER_STYLE, CONTAINER_SCROLL_STATE:
+		return q.children[0].Evaluate(container)
+	case CONTAINER_SIZE_FEATURE:
+		return container.evaluate_size_feature(q.feature)
+	case CONTAINER_STYLE_FEATURE:
+		return container.evaluate_style_feature(q.decl)
+	case CONTAINER_SCROLL_STATE_FEATURE:
+		return container.evaluate_scroll_state_feature(q.feature)
+	}
+
+	return QUERY_UNKNOWN
+}
+
+// A size feature is unknown when the container's size on the axis it queries isn't known to its queries.
+func (c ContainerContext) evaluate_size_feature(feature QueryFeature) QueryResult {
+	if c.can_query_size_feature(feature.name) == false {
+		return QUERY_UNKNOWN
+	}
+	inline_size, block_size := c.width, c.height
+	if c.vertical {
+		inline_size, block_size = c.height, c.width
+	}
+
+	var size float64
+	switch feature.name {
+	case "width", "height", "inline-size", "block-size":
+		size = map[string]float64{"width": c.width, "height": c.height, "inline-size": inline_size, "block-size": block_size}[feature.name]
+		if len(feature.comparisons) == 0 {
+			return query_result(size != 0)
+		}
+	case "aspect-ratio", "orientation":
+		if feature.name == "orientation" {
+			if len(feature.comparisons) == 0 {
+				return QUERY_TRUE
+			}
+			orientation := "landscape"
+			if c.height >= c.width {
+				orientation = "portrait"
+			}
+			return query_result(feature.comparisons[0].value[0].is_ident(orientation))
+		}
+		if c.height == 0 {
+			return QUERY_UNKNOWN
+		}
+		size = c.width / c.height
+		if len(feature.comparisons) == 0 {
+			return query_result(size != 0)
+		}
+	default:
+		return QUERY_UNKNOWN
+	}
+
+	for _, comparison := range feature.comparisons {
+		var value float64
+		var ok bool
+		if feature.name == "aspect-ratio" {
+			numerator, denominator, _ := parse_ratio(comparison.value)
+			value, ok = numerator/denominator, denominator != 0
+		} else {
+			value, ok = c.resolve_query_length(comparison.value[0])
+		}
+		if ok == false {
+			return QUERY_UNKNOWN
+		}
+		if compare_feature_value(size, comparison.operator, value) == false {
+			return QUERY_FALSE
+		}
+	}
+
+	return QUERY_TRUE
+}
+
+// The length in px, resolving relative lengths and math functions against the container.
+func (c ContainerContext) resolve_query_length(value ComponentValue) (float64, bool) {
+	length, ok := parse_length(value)
+	if ok == false {
+		node, err := ParseMathFunction(value)
+		if err != nil {
+			return 0, false
+		}
+		result, err := node.Evaluate(c.calc)
+		if length, ok = result.(Length); err != nil || ok == false {
+			return 0, false
+		}
+	}
+	if px, _, ok := to_canonical_unit(length.Value, length.Unit); ok {
+		return px, true
+	}
+
+	return c.calc.resolve_length(length.Value, length.Unit)
+}
+
+func compare_feature_value(feature float64, operator string, value float64) bool {
+	switch operator {
+	case "<":
+		return feature < value
+	case "<=":
+		return feature <= value
+	case ">":
+		return feature > value
+	case ">=":
+		return feature >= value
+	}
+
+	return feature == value
+}
+
+// https://drafts.csswg.org/css-conditional-5/#style-container
+// A style feature compares the container's computed value of the property with the given value, once both are serialized
+// the same way. Without a value, it's true when the property has a value other than its initial one. A value that isn't
+// valid for the property, e.g. display: bogus, is an unknown feature.
+func (c ContainerContext) evaluate_style_feature(decl Declaration) QueryResult {
+	if len(decl.value) > 0 && ValidateDeclaration(decl) != nil {
+		return QUERY_UNKNOWN
+	}
+	computed, ok := c.style[decl.name]
+	if is_custom_property_name(decl.name) == false {
+		computed, ok = c.style[strings.ToLower(decl.name)]
+	}
+	normalize := func(value string) string {
+		return strings.TrimSpace(serialize_component_value_list(trim_whitespace(parse_component_value_list(value))))
+	}
+	if len(decl.value) == 0 {
+		return query_result(ok && normalize(computed) != "" && strings.EqualFold(normalize(computed), "initial") == false)
+	}
+	if ok == false {
+		return QUERY_FALSE
+	}
+
+	return query_result(normalize(computed) == normalize(serialize_component_value_list(decl.value)))
+}
+
+// https://drafts.csswg.org/css-conditional-5/#scroll-state-container
+// A scroll-state feature in a boolean context is true when its value isn't none.
+func (c ContainerContext) evaluate_scroll_state_feature(feature QueryFeature) QueryResult {
+	states := c.scroll_states[feature.name]
+	if len(feature.comparisons) == 0 {
+		return query_result(len(states) > 0)
+	}
+	value := strings.ToLower(string(feature.comparisons[0].value[0].token.value))
+	if value == "none" {
+		return query_result(len(states) == 0)
+	}
+
+	return query_result(slices.Contains(states, value))
+}
+
+func (l ContainerConditionList) String() string {
+	var conditions []string
+	for _, condition := range l {
+		conditions = append(conditions, condition.String())
+	}
+
+	return strings.Join(conditions, ", ")
+}
+
+func (c ContainerCondition) String() string {
+	var sb strings.Builder
+	if c.name != "" {
+		stringify_identifier(&sb, c.name)
+		if c.has_query {
+			sb.WriteRune(SPACE_CHAR)
+		}
+	}
+	if c.has_query {
+		stringify_container_query(&sb, c.query)
+	}
+
+	return sb.String()
+}
+
+func (q ContainerQuery) String() string {
+	var sb strings.Builder
+	stringify_container_query(&sb, q)
+	return sb.String()
+}
+
+func stringify_container_query(sb *strings.Builder, q ContainerQuery) {
+	switch q.kind {
+	case CONTAINER_NOT:
+		sb.WriteString("not ")
+		stringify_query_in_parens(sb, q.children[0])
+	case CONTAINER_AND, CONTAINER_OR:
+		keyword := " and "
+		if q.kind == CONTAINER_OR {
+			keyword = " or "
+		}
+		for i, child := range q.children {
+			if i > 0 {
+				sb.WriteString(keyword)
+			}
+			stringify_query_in_parens(sb, child)
+		}
+	case CONTAINER_STYLE, CONTAINER_SCROLL_STATE:
+		if q.kind == CONTAINER_STYLE {
+			sb.WriteString("style(")
+		} else {
+			sb.WriteString("scroll-state(")
+		}
+		// A single feature is written without its parentheses.
+		child := q.children[0]
+		if child.kind == CONTAINER_STYLE_FEATURE || child.kind == CONTAINER_SCROLL_STATE_FEATURE {
+			stringify_query_feature(sb, child)
+		} else {
+			stringify_container_query(sb, child)
+		}
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	case CONTAINER_SIZE_FEATURE, CONTAINER_STYLE_FEATURE, CONTAINER_SCROLL_STATE_FEATURE:
+		sb.WriteRune(OPEN_PAREN_CHAR)
+		stringify_query_feature(sb, q)
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	case CONTAINER_GENERAL_ENCLOSED:
+		stringify_component_value_list(sb, q.value)
+	}
+}
+
+// Compound queries must be wrapped in parentheses when they appear as an operand.
+func stringify_query_in_parens(sb *strings.Builder, q ContainerQuery) {
+	switch q.kind {
+	case CONTAINER_NOT, CONTAINER_AND, CONTAINER_OR:
+		sb.WriteRune(OPEN_PAREN_CHAR)
+		stringify_container_query(sb, q)
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	default:
+		stringify_container_query(sb, q)
+	}
+}
+
+// The feature, without its parentheses. Ranges use the range syntax, e.g. "(min-width: 400px)" is "width >= 400px".
+func stringify_query_feature(sb *strings.Builder, q ContainerQuery) {
+	if q.kind == CONTAINER_STYLE_FEATURE {
+		sb.WriteString(q.decl.name)
+		if len(q.decl.value) > 0 {
+			sb.WriteString(fmt.Sprintf("%c%c", COLON_CHAR, SPACE_CHAR))
+			stringify_component_value_list(sb, q.decl.value)
+		}
+		return
+	}
+
+	feature := q.feature
+	switch {
+	case len(feature.comparisons) == 0:
+		sb.WriteString(feature.name)
+	case len(feature.comparisons) == 1 && feature.comparisons[0].operator == "=":
+		sb.WriteString(fmt.Sprintf("%s%c%c%s", feature.name, COLON_CHAR, SPACE_CHAR, serialize_spaced_values(feature.comparisons[0].value)))
+	case len(feature.comparisons) == 1:
+		comparison := feature.comparisons[0]
+		sb.WriteString(fmt.Sprintf("%s %s %s", feature.name, comparison.operator, serialize_spaced_values(comparison.value)))
+	default:
+		lower, upper := feature.comparisons[0], feature.comparisons[1]
+		sb.WriteString(fmt.Sprintf("%s %s %s %s %s", serialize_spaced_values(lower.value), flip_comparison(lower.operator), feature.name,
+			upper.operator, serialize_spaced_values(upper.value)))
+	}
+}
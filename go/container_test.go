@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestContainerConditionEvaluate(t *testing.T) {
+	card := ContainerContext{names: []string{"card"}, size_type: "inline-size", width: 300, height: 50}
+	page := ContainerContext{names: []string{"page"}, size_type: "size", width: 1000, height: 800}
+	vertical := ContainerContext{names: []string{"vertical"}, size_type: "inline-size", width: 50, height: 400, vertical: true}
+	scroller := ContainerContext{names: []string{"scroller"}, scroll_state: true, scroll_states: map[string][]string{"stuck": {"top"}}}
+	plain := ContainerContext{style: map[string]string{"--theme": "dark", "display": "block"}}
+
+	tests := []struct {
+		condition string
+		// The ancestors, nearest first.
+		ancestors []ContainerContext
+		want      bool
+	}{
+		{condition: "(width > 200px)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(width > 500px)", ancestors: []ContainerContext{card, page}, want: false},
+		{condition: "(inline-size > 200px)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(height > 100px)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(block-size > 100px)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(orientation: landscape)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(aspect-ratio > 1)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(width > 200px) and (height > 100px)", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(height > 100px)", ancestors: []ContainerContext{card}, want: false},
+		{condition: "(height > 100px)", ancestors: []ContainerContext{vertical, page}, want: true},
+		{condition: "(width > 100px)", ancestors: []ContainerContext{vertical, page}, want: true},
+		{condition: "(inline-size > 100px)", ancestors: []ContainerContext{vertical, page}, want: true},
+		{condition: "card (width > 200px)", ancestors: []ContainerContext{page, card}, want: true},
+		{condition: "card (height > 10px)", ancestors: []ContainerContext{page, card}, want: false},
+		{condition: "missing (width > 0px)", ancestors: []ContainerContext{card, page}, want: false},
+		{condition: "page", ancestors: []ContainerContext{card, page}, want: true},
+		{condition: "(width > 200px)", ancestors: []ContainerContext{plain, card}, want: true},
+		{condition: "style(--theme: dark)", ancestors: []ContainerContext{plain, card}, want: true},
+		{condition: "style(display: block)", ancestors: []ContainerContext{plain, card}, want: true},
+		{condition: "style(display: bogus)", ancestors: []ContainerContext{plain, card}, want: false},
+		{condition: "not style(display: bogus)", ancestors: []ContainerContext{plain, card}, want: false},
+		{condition: "not style(display: flex)", ancestors: []ContainerContext{plain, card}, want: true},
+		{condition: "scroll-state(stuck: top)", ancestors: []ContainerContext{card, scroller}, want: true},
+		{condition: "scroll-state(stuck: top)", ancestors: []ContainerContext{card, page}, want: false},
+		{condition: "not (width > 500px)", ancestors: []ContainerContext{card}, want: true},
+		{condition: "(width > 500px) or (height > 500px)", ancestors: []ContainerContext{page}, want: true},
+		{condition: "(unknown > 1px)", ancestors: []ContainerContext{page}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.condition, func(t *testing.T) {
+			conditions, err := parse_container_condition_list(parse_component_value_list(test.condition))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := conditions.Evaluate(test.ancestors); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"strings"
+)
+
+// How FlattenNesting rewrites nesting selectors.
+type FlattenOptions struct {
+	// Whether a nesting selector standing for a parent selector list is replaced by a copy of the nested selector for each
+	// of the parent's selectors, rather than by :is(), which browsers that don't support nesting often don't support either.
+	// Each copy has the specificity of its own parent selector, rather than that of the most specific one.
+	expand_selector_lists bool
+}
+
+// https://drafts.csswg.org/css-nesting/
+// Rewrite the stylesheet without nesting, for browsers that don't support it. Nested style rules and nested declarations
+// become top-level style rules with their selectors resolved, and group rules (e.g. @media) nested in style rules are hoisted
+// out of them, with their conditions combined with those of the same kind of group rule they end up in.
+// The rules keep their order, so declarations that follow nested rules still take precedence over them.
+func (s Stylesheet) FlattenNesting(options FlattenOptions) Stylesheet {
+	return Stylesheet{rules: flatten_rules(s.rules, nil, options), namespaces: s.namespaces}
+}
+
+func flatten_rules(rules []Rule, parent SelectorList, options FlattenOptions) []Rule {
+	var result []Rule
+	for _, rule := range rules {
+		switch {
+		case rule.kind == QUALIFIED_RULE || rule.kind == NESTED_DECLARATIONS_RULE:
+			result = append(result, flatten_style_rule(rule, parent, options)...)
+		case property_context_at_rules[strings.ToLower(rule.name)] && rule.has_block:
+			rule.children = flatten_rules(rule.children, parent, options)
+			result = append(result, combine_group_rules(rule)...)
+		default:
+			result = append(result, rule)
+		}
+	}
+
+	return result
+}
+
+// The style rule, followed by the flattened rules nested in it.
+func flatten_style_rule(rule Rule, parent SelectorList, options FlattenOptions) []Rule {
+	selectors, err := options.nested_selectors(rule, parent)
+	if err != nil {
+		// Keep top-level rules we don't understand as they are. A nested rule with an invalid selector is ignored, as a browser would.
+		if parent == nil {
+			return []Rule{rule}
+		}
+		return nil
+	}
+
+	children := rule.children
+	rule.children = nil
+	if parent != nil {
+		rule = rule_with_selectors(rule, selectors)
+		rule.kind = QUALIFIED_RULE
+	}
+
+	var result []Rule
+	// A style rule that only held nested rules isn't needed any more.
+	if len(rule.decls) > 0 || len(children) == 0 {
+		result = append(result, rule)
+	}
+
+	return append(result, flatten_rules(children, selectors, options)...)
+}
+
+func (o FlattenOptions) nested_selectors(rule Rule, parent SelectorList) (SelectorList, error) {
+	if o.expand_selector_lists == false || rule.kind != QUALIFIED_RULE || len(parent) < 2 {
+		return rule.nested_selectors(parent)
+	}
+
+	selectors, err := parse_nested_selector_list(rule.prelude)
+	if err != nil {
+		return nil, err
+	}
+	parent, err = nestable_selectors(parent)
+	if err != nil {
+		return nil, err
+	}
+	var expanded SelectorList
+	for _, selector := range selectors {
+		// With more than one nesting selector, copies for each parent selector would leave out the combinations of different ones.
+		if count_nesting_selectors(selector) != 1 {
+			expanded = append(expanded, resolve_nesting_selector(selector, parent))
+			continue
+		}
+		for _, parent_selector := range parent {
+			expanded = append(expanded, resolve_nesting_selector(selector, SelectorList{parent_selector}))
+		}
+	}
+
+	return expanded, nil
+}
+
+// Hoist the group rules of the same kind directly inside the (already flattened) group rule out of it,
+// with their conditions combined with its own, e.g. "@media screen { @media (width > 1px) { ... } }" becomes
+// "@media screen and (width > 1px) { ... }". The group rule's other children are split around them to keep their order.
+func combine_group_rules(rule Rule) []Rule {
+	var result []Rule
+	var pending []Rule
+	flush := func() {
+		if len(pending) > 0 {
+			group := rule
+			group.children = pending
+			result = append(result, group)
+			rule.decls = nil
+			pending = nil
+		}
+	}
+
+	for _, child := range rule.children {
+		if child.kind == AT_RULE && strings.EqualFold(child.name, rule.name) && child.has_block {
+			if prelude, ok := combine_conditions(strings.ToLower(rule.name), rule.prelude, child.prelude); ok {
+				flush()
+				child.prelude = prelude
+				result = append(result, child)
+				continue
+			}
+		}
+		pending = append(pending, child)
+	}
+	flush()
+
+	if len(result) == 0 {
+		return []Rule{rule}
+	}
+
+	return result
+}
+
+// The prelude of a group rule whose condition holds when both the outer and inner group rules' conditions do.
+// Only @media, @supports and @container conditions can be combined, and not all of those can be expressed as one.
+func combine_conditions(name string, outer []ComponentValue, inner []ComponentValue) ([]ComponentValue, bool) {
+	var combined string
+	var ok bool
+	switch name {
+	case "media":
+		combined, ok = combine_media_queries(outer, inner)
+	case "supports":
+		combined, ok = combine_supports_conditions(outer, inner)
+	case "container":
+		combined, ok = combine_container_queries(outer, inner)
+	}
+	if ok == false {
+		return nil, false
+	}
+
+	prelude := append([]ComponentValue{whitespace_value()}, parse_component_value_list(combined)...)
+	return append(prelude, whitespace_value()), true
+}
+
+// https://drafts.csswg.org/mediaqueries-4/#mq-syntax
+// Each must be a single media query, and only the outer one may have a media type, which can't be negated.
+func combine_media_queries(outer []ComponentValue, inner []ComponentValue) (string, bool) {
+	if len(split_on_commas(outer)) != 1 || len(split_on_commas(inner)) != 1 {
+		return "", false
+	}
+	outer_type, outer_condition, ok := split_media_type(non_whitespace_values(outer))
+	if ok == false {
+		return "", false
+	}
+	inner_type, inner_condition, ok := split_media_type(non_whitespace_values(inner))
+	if ok == false || len(inner_type) > 0 || len(inner_condition) == 0 {
+		return "", false
+	}
+
+	var parts []string
+	if len(outer_type) > 0 {
+		parts = append(parts, serialize_spaced_values(outer_type))
+	}
+	if len(outer_condition) > 0 {
+		parts = append(parts, wrap_condition(outer_condition))
+	}
+	parts = append(parts, wrap_condition(inner_condition))
+
+	return strings.Join(parts, " and "), true
+}
+
+// <media-query> = <media-condition> | [ not | only ]? <media-type> [ and <media-condition-without-or> ]?
+// Split the media query into its media type (with "only") and its condition. A negated media type can't be split.
+func split_media_type(values []ComponentValue) ([]ComponentValue, []ComponentValue, bool) {
+	if len(values) == 0 || values[0].is_token(IDENT_TOKEN) == false {
+		return nil, values, true
+	}
+	if values[0].is_ident("not") {
+		negates_type := len(values) > 1 && values[1].is_token(IDENT_TOKEN)
+		return nil, values, negates_type == false
+	}
+
+	length := 1
+	if values[0].is_ident("only") {
+		length = 2
+	}
+	if len(values) < length {
+		return nil, nil, false
+	}
+	media_type, rest := values[:length], values[length:]
+	if len(rest) == 0 {
+		return media_type, nil, true
+	}
+	if rest[0].is_ident("and") == false {
+		return nil, nil, false
+	}
+
+	return media_type, rest[1:], true
+}
+
+// https://drafts.csswg.org/css-conditional-3/#at-supports
+func combine_supports_conditions(outer []ComponentValue, inner []ComponentValue) (string, bool) {
+	outer_condition, err := parse_supports_condition(outer)
+	if err != nil {
+		return "", false
+	}
+	inner_condition, err := parse_supports_condition(inner)
+	if err != nil {
+		return "", false
+	}
+
+	combined := SupportsCondition{kind: SUPPORTS_AND}
+	for _, condition := range []SupportsCondition{outer_condition, inner_condition} {
+		if condition.kind == SUPPORTS_AND {
+			combined.children = append(combined.children, condition.children...)
+		} else {
+			combined.children = append(combined.children, condition)
+		}
+	}
+
+	return combined.String(), true
+}
+
+// https://drafts.csswg.org/css-conditional-5/#container-rule
+// <container-condition> = [ <container-name> ]? <container-query>
+// Both must query the same container: either both are unnamed, or they have the same name.
+func combine_container_queries(outer []ComponentValue, inner []ComponentValue) (string, bool) {
+	if len(split_on_commas(outer)) != 1 || len(split_on_commas(inner)) != 1 {
+		return "", false
+	}
+	outer_name, outer_query := split_container_name(non_whitespace_values(outer))
+	inner_name, inner_query := split_container_name(non_whitespace_values(inner))
+	if outer_name != inner_name || len(outer_query) == 0 || len(inner_query) == 0 {
+		return "", false
+	}
+
+	combined := wrap_condition(outer_query) + " and " + wrap_condition(inner_query)
+	if outer_name != "" {
+		combined = outer_name + " " + combined
+	}
+
+	return combined, true
+}
+
+func split_container_name(values []ComponentValue) (string, []ComponentValue) {
+	if len(values) > 0 && values[0].is_token(IDENT_TOKEN) && values[0].is_ident("not") == false {
+		return string(values[0].token.value), values[1:]
+	}
+
+	return "", values
+}
+
+// The condition, in parentheses unless it's a single condition in parentheses or a function, or only joins those with "and",
+// so that it can be joined to another condition with "and".
+func wrap_condition(values []ComponentValue) string {
+	for i, value := range values {
+		if i%2 == 1 && value.is_ident("and") == false {
+			return "(" + serialize_spaced_values(values) + ")"
+		}
+		if i%2 == 0 && value.kind != SIMPLE_BLOCK && value.kind != FUNCTION {
+			return "(" + serialize_spaced_values(values) + ")"
+		}
+	}
+
+	return serialize_spaced_values(values)
+}
+
+// Serialize the values separated by whitespace.
+func serialize_spaced_values(values []ComponentValue) string {
+	parts := make([][]ComponentValue, len(values))
+	for i, value := range values {
+		parts[i] = []ComponentValue{value}
+	}
+
+	return serialize_component_value_list(join_values(parts...))
+}
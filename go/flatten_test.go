@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFlattenNesting(t *testing.T) {
+	tests := []struct {
+		name    string
+		css     string
+		options FlattenOptions
+		want    string
+	}{
+		{
+			name: "resolves nested selectors",
+			css:  `.a { &:hover { color: red } .b & { color: blue } > .c { color: green } }`,
+			want: ".a:hover {\ncolor: red;\n}\n.b .a {\ncolor: blue;\n}\n.a > .c {\ncolor: green;\n}\n",
+		},
+		{
+			name: "keeps declarations after nested rules in order",
+			css:  `.a { color: red; .b { color: blue } color: green }`,
+			want: ".a {\ncolor: red;\n}\n.a .b {\ncolor: blue;\n}\n.a {\ncolor: green;\n}\n",
+		},
+		{
+			name: "uses :is() for a parent selector list",
+			css:  `.a, .b { & .c { color: red } }`,
+			want: ":is(.a, .b) .c {\ncolor: red;\n}\n",
+		},
+		{
+			name:    "expands a parent selector list",
+			css:     `.a, .b { & .c { color: red } }`,
+			options: FlattenOptions{expand_selector_lists: true},
+			want:    ".a .c, .b .c {\ncolor: red;\n}\n",
+		},
+		{
+			name: "merges a type selector into the nesting selector",
+			css:  `div { span& { color: red } }`,
+			want: "span:is(div) {\ncolor: red;\n}\n",
+		},
+		{
+			name: "hoists group rules out of style rules",
+			css:  `.a { @supports (display: grid) { .b { color: red } } }`,
+			want: "@supports (display: grid) {\n.a .b {\ncolor: red;\n}\n}\n",
+		},
+		{
+			name: "combines nested media queries",
+			css:  `@media screen { .a { @media (width > 1px) { color: red } } }`,
+			want: "@media screen and (width > 1px) {\n.a {\ncolor: red;\n}\n}\n",
+		},
+		{
+			name: "drops rules nested in a pseudo-element",
+			css:  `.a::before { color: red; .b & { color: blue } }`,
+			want: ".a::before {\ncolor: red;\n}\n",
+		},
+		{
+			name: "leaves pseudo-elements out of the parent selector list",
+			css:  `.a::before, .c { .b & { color: blue } }`,
+			want: ".b .c {\ncolor: blue;\n}\n",
+		},
+		{
+			name: "drops nested rules with invalid selectors",
+			css:  `.a { color: red; .b!c { color: blue } }`,
+			want: ".a {\ncolor: red;\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := parse_stylesheet(strings.NewReader(test.css)).FlattenNesting(test.options).Stringify()
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,602 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-fonts-4/#font-face-rule
+// @font-face { <declaration-list> }
+type FontFace struct {
+	// The family name the font face is used for, by the font-family property.
+	family string
+	// The font resources, in order of preference.
+	sources []FontSource
+	// The ranges of font-weight (as numbers), font-stretch (as percentages) and oblique angles (in degrees) the font face covers.
+	weight  FontRange
+	stretch FontRange
+	// auto, normal, italic or oblique. An oblique style has a range of angles.
+	style          string
+	oblique_angles FontRange
+	// The code points the font face covers. Empty means all of them.
+	unicode_ranges []UnicodeRange
+	// auto, block, swap, fallback or optional.
+	display string
+	// https://drafts.csswg.org/css-fonts-4/#font-metrics-override-desc
+	ascent_override   MetricOverride
+	descent_override  MetricOverride
+	line_gap_override MetricOverride
+	// The percentage the glyphs are scaled by.
+	size_adjust float64
+	// The descriptors that aren't modeled (e.g. font-feature-settings), kept as they are.
+	other []Declaration
+}
+
+// https://drafts.csswg.org/css-fonts-4/#src-desc
+// <font-src> = <url> [ format( <font-format> ) ]? [ tech( <font-tech># ) ]? | local( <family-name> )
+type FontSource struct {
+	// A local() source is looked up among the fonts installed on the system, by its full name or postscript name.
+	is_local bool
+	// The url of a downloaded font, or the name of a local one.
+	url  string
+	name string
+	// The lowercased format() and tech() hints, which let the browser skip fonts it doesn't support without downloading them.
+	format string
+	techs  []string
+}
+
+// A range of values, where a single value is a range of itself. An auto range is decided by the font's own data.
+type FontRange struct {
+	auto bool
+	min  float64
+	max  float64
+}
+
+// https://drafts.csswg.org/css-fonts-4/#unicode-range-desc
+// An inclusive range of code points.
+type UnicodeRange struct {
+	start rune
+	end   rune
+}
+
+// A metric override is either normal, where the metric comes from the font, or a percentage of the font size.
+type MetricOverride struct {
+	has_percentage bool
+	percentage     float64
+}
+
+// Why an @font-face rule, or one of its descriptors, is invalid. Family is empty when the font-family descriptor is missing or invalid.
+type FontFaceError struct {
+	Family     string
+	Descriptor string
+	Reason     string
+}
+
+func (e FontFaceError) Error() string {
+	if e.Descriptor == "" {
+		return fmt.Sprintf("Parse Error: invalid @font-face rule: %s", e.Reason)
+	}
+
+	return fmt.Sprintf("Parse Error: invalid '%s' descriptor in @font-face for '%s': %s", e.Descriptor, e.Family, e.Reason)
+}
+
+// https://drafts.csswg.org/css-fonts-4/#font-format-values
+var font_formats = map[string]bool{
+	"collection": true, "embedded-opentype": true, "opentype": true, "svg": true, "truetype": true, "woff": true, "woff2": true,
+}
+
+// https://drafts.csswg.org/css-fonts-4/#font-tech-values
+var font_techs = map[string]bool{
+	"features-opentype": true, "features-aat": true, "features-graphite": true, "color-colrv0": true, "color-colrv1": true,
+	"color-svg": true, "color-sbix": true, "color-cbdt": true, "variations": true, "palettes": true, "incremental": true,
+}
+
+// https://drafts.csswg.org/css-fonts-4/#generic-font-families
+var generic_font_families = map[string]bool{
+	"serif": true, "sans-serif": true, "cursive": true, "fantasy": true, "monospace": true, "system-ui": true, "emoji": true,
+	"math": true, "fangsong": true, "ui-serif": true, "ui-sans-serif": true, "ui-monospace": true, "ui-rounded": true,
+}
+
+// https://drafts.csswg.org/css-fonts-4/#font-stretch-prop
+var font_stretch_keywords = map[string]float64{
+	"ultra-condensed": 50, "extra-condensed": 62.5, "condensed": 75, "semi-condensed": 87.5, "normal": 100,
+	"semi-expanded": 112.5, "expanded": 125, "extra-expanded": 150, "ultra-expanded": 200,
+}
+
+// The descriptors that are kept as they are, without being modeled.
+var unmodeled_font_face_descriptors = map[string]bool{
+	"font-feature-settings": true, "font-variation-settings": true, "font-named-instance": true, "font-language-override": true,
+}
+
+func is_font_face_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && strings.EqualFold(rule.name, "font-face")
+}
+
+// Extract the font face described by an @font-face rule. Invalid descriptors are reported and ignored, as a browser would,
+// except that the rule is invalid (and the returned errors include why) when font-family or src is missing or invalid.
+func (r Rule) font_face() (FontFace, []error) {
+	face := FontFace{
+		weight:      FontRange{auto: true},
+		stretch:     FontRange{auto: true},
+		style:       "auto",
+		display:     "auto",
+		size_adjust: 100,
+	}
+	if is_font_face_rule(r) == false {
+		return face, []error{FontFaceError{Reason: "rule is not an @font-face rule"}}
+	}
+	if len(trim_whitespace(r.prelude)) > 0 || r.has_block == false {
+		return face, []error{FontFaceError{Reason: "@font-face must have an empty prelude and a block"}}
+	}
+
+	var errs []error
+	invalid := func(descriptor string, format string, args ...any) {
+		errs = append(errs, FontFaceError{Family: face.family, Descriptor: descriptor, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	// The family is found first, to name the font face in the errors of the other descriptors.
+	for _, decl := range r.decls {
+		if strings.EqualFold(decl.name, "font-family") {
+			if family, err := parse_family_name(decl.value); err == nil {
+				face.family = family
+			} else {
+				invalid("font-family", "%s", err)
+			}
+		}
+	}
+
+	// Later declarations of a descriptor override earlier ones, unless they're invalid.
+	for _, decl := range r.decls {
+		name := strings.ToLower(decl.name)
+		values := non_whitespace_values(decl.value)
+		var err error
+		switch name {
+		case "font-family":
+			continue
+		case "src":
+			var sources []FontSource
+			sources, err = parse_font_sources(decl.value, func(err error) { invalid("src", "%s", err) })
+			if err == nil {
+				face.sources = sources
+			}
+		case "font-weight":
+			err = parse_font_range(values, &face.weight, parse_font_weight)
+		case "font-stretch", "font-width":
+			err = parse_font_range(values, &face.stretch, parse_font_stretch)
+		case "font-style":
+			err = face.parse_font_style(values)
+		case "unicode-range":
+			var ranges []UnicodeRange
+			ranges, err = parse_unicode_ranges(decl.value)
+			if err == nil {
+				face.unicode_ranges = ranges
+			}
+		case "font-display":
+			if len(values) == 1 && is_ident_in(values[0], "auto", "block", "swap", "fallback", "optional") {
+				face.display = strings.ToLower(string(values[0].token.value))
+			} else {
+				err = fmt.Errorf("expected auto, block, swap, fallback or optional")
+			}
+		case "ascent-override":
+			err = parse_metric_override(values, &face.ascent_override)
+		case "descent-override":
+			err = parse_metric_override(values, &face.descent_override)
+		case "line-gap-override":
+			err = parse_metric_override(values, &face.line_gap_override)
+		case "size-adjust":
+			if len(values) == 1 && values[0].is_token(PERCENTAGE_TOKEN) && values[0].token.numeric >= 0 {
+				face.size_adjust = values[0].token.numeric
+			} else {
+				err = fmt.Errorf("expected a non-negative percentage")
+			}
+		default:
+			if unmodeled_font_face_descriptors[name] {
+				face.other = append(face.other, decl)
+			} else {
+				err = fmt.Errorf("unknown descriptor")
+			}
+		}
+		if err != nil {
+			invalid(name, "%s", err)
+		}
+	}
+
+	// An invalid descriptor has already been reported.
+	declared := func(name string) bool {
+		return slices.ContainsFunc(r.decls, func(decl Declaration) bool { return strings.EqualFold(decl.name, name) })
+	}
+	if face.family == "" && declared("font-family") == false {
+		invalid("font-family", "the descriptor is required")
+	}
+	if len(face.sources) == 0 && declared("src") == false {
+		invalid("src", "the descriptor is required")
+	}
+
+	return face, errs
+}
+
+// The valid font faces of the stylesheet's @font-face rules (including those in conditional rules and layers), in order,
+// and the errors of their descriptors. Rules without a valid font-family and src are left out.
+func (s Stylesheet) FontFaces() ([]FontFace, []error) {
+	var faces []FontFace
+	var errors []error
+	var walk func(rules []Rule)
+	walk = func(rules []Rule) {
+		for _, rule := range rules {
+			if is_font_face_rule(rule) == false {
+				walk(rule.children)
+				continue
+			}
+			face, errs := rule.font_face()
+			errors = append(errors, errs...)
+			if face.family != "" && len(face.sources) > 0 {
+				faces = append(faces, face)
+			}
+		}
+	}
+	walk(s.rules)
+
+	return faces, errors
+}
+
+// https://drafts.csswg.org/css-fonts-4/#family-name-syntax
+// <family-name> = <string> | <custom-ident>+
+// A name made of identifiers is joined by single spaces, and a single identifier can't be a generic family or a CSS-wide keyword.
+func parse_family_name(value []ComponentValue) (string, error) {
+	values := non_whitespace_values(value)
+	if len(values) == 1 && values[0].is_token(STRING_TOKEN) {
+		return string(values[0].token.value), nil
+	}
+	if len(values) == 0 {
+		return "", fmt.Errorf("expected a family name")
+	}
+
+	var names []string
+	for _, value := range values {
+		if value.is_token(IDENT_TOKEN) == false {
+			return "", fmt.Errorf("unexpected %s in family name", describe_value(value))
+		}
+		names = append(names, string(value.token.value))
+	}
+	if first := strings.ToLower(names[0]); is_css_wide_keyword(first) || first == "default" || (len(names) == 1 && generic_font_families[first]) {
+		return "", fmt.Errorf("'%s' can't be used as a family name without quotes", names[0])
+	}
+
+	return strings.Join(names, " "), nil
+}
+
+// <font-src-list> = [ <url> [ format( <font-format> ) ]? [ tech( <font-tech># ) ]? | local( <family-name> ) ]#
+// Sources that can't be parsed are reported and left out, and the descriptor is only invalid if none are left.
+func parse_font_sources(value []ComponentValue, report func(error)) ([]FontSource, error) {
+	var sources []FontSource
+	for _, item := range split_on_commas(value) {
+		source, err := parse_font_source(non_whitespace_values(item))
+		if err != nil {
+			report(err)
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("no valid font sources")
+	}
+
+	return sources, nil
+}
+
+func parse_font_source(values []ComponentValue) (FontSource, error) {
+	if len(values) == 0 {
+		return FontSource{}, fmt.Errorf("expected a font source")
+	}
+
+	if values[0].is_function("local") {
+		if len(values) > 1 {
+			return FontSource{}, fmt.Errorf("unexpected %s after local()", describe_value(values[1]))
+		}
+		name, err := parse_family_name(values[0].value)
+		if err != nil {
+			return FontSource{}, err
+		}
+		return FontSource{is_local: true, name: name}, nil
+	}
+
+	url, ok := "", false
+	if values[0].is_token(URL_TOKEN) || values[0].is_function("url") || values[0].is_function("src") {
+		url, ok = url_value(values[0])
+	}
+	if ok == false {
+		return FontSource{}, fmt.Errorf("expected url() or local(), found %s", describe_value(values[0]))
+	}
+
+	source := FontSource{url: url}
+	rest := values[1:]
+	if len(rest) > 0 && rest[0].is_function("format") {
+		arguments := non_whitespace_values(rest[0].value)
+		switch {
+		case len(arguments) == 1 && arguments[0].is_token(STRING_TOKEN):
+			source.format = strings.ToLower(string(arguments[0].token.value))
+		case len(arguments) == 1 && arguments[0].is_token(IDENT_TOKEN) && font_formats[strings.ToLower(string(arguments[0].token.value))]:
+			source.format = strings.ToLower(string(arguments[0].token.value))
+		default:
+			return FontSource{}, fmt.Errorf("invalid font format '%s'", serialize_component_value_list(rest[0].value))
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 && rest[0].is_function("tech") {
+		for _, argument := range split_on_commas(rest[0].value) {
+			tech := non_whitespace_values(argument)
+			if len(tech) != 1 || tech[0].is_token(IDENT_TOKEN) == false || font_techs[strings.ToLower(string(tech[0].token.value))] == false {
+				return FontSource{}, fmt.Errorf("invalid font technology '%s'", strings.TrimSpace(serialize_component_value_list(argument)))
+			}
+			source.techs = append(source.techs, strings.ToLower(string(tech[0].token.value)))
+		}
+		rest = rest[1:]
+	}
+	if len(rest) > 0 {
+		return FontSource{}, fmt.Errorf("unexpected %s in font source", describe_value(rest[0]))
+	}
+
+	return source, nil
+}
+
+// auto | <value>{1,2}, where a range given in the wrong order is swapped.
+func parse_font_range(values []ComponentValue, result *FontRange, parse_value func(ComponentValue) (float64, bool)) error {
+	if len(values) == 1 && values[0].is_ident("auto") {
+		*result = FontRange{auto: true}
+		return nil
+	}
+	if len(values) != 1 && len(values) != 2 {
+		return fmt.Errorf("expected auto, or one or two values")
+	}
+
+	var bounds []float64
+	for _, value := range values {
+		bound, ok := parse_value(value)
+		if ok == false {
+			return fmt.Errorf("invalid value %s", describe_value(value))
+		}
+		bounds = append(bounds, bound)
+	}
+	*result = FontRange{min: min(bounds[0], bounds[len(bounds)-1]), max: max(bounds[0], bounds[len(bounds)-1])}
+
+	return nil
+}
+
+// <font-weight-absolute> = normal | bold | <number [1,1000]>
+func parse_font_weight(value ComponentValue) (float64, bool) {
+	switch {
+	case value.is_ident("normal"):
+		return 400, true
+	case value.is_ident("bold"):
+		return 700, true
+	case value.is_token(NUMBER_TOKEN):
+		return value.token.numeric, value.token.numeric >= 1 && value.token.numeric <= 1000
+	}
+
+	return 0, false
+}
+
+// <'font-stretch'> = normal | <percentage [0,∞]> | ultra-condensed | ... | ultra-expanded
+func parse_font_stretch(value ComponentValue) (float64, bool) {
+	if value.is_token(PERCENTAGE_TOKEN) {
+		return value.token.numeric, value.token.numeric >= 0
+	}
+	if value.is_token(IDENT_TOKEN) {
+		percentage, ok := font_stretch_keywords[strings.ToLower(string(value.token.value))]
+		return percentage, ok
+	}
+
+	return 0, false
+}
+
+// auto | normal | italic | oblique [ <angle [-90deg,90deg]>{1,2} ]?
+// A plain oblique is 14 degrees.
+func (f *FontFace) parse_font_style(values []ComponentValue) error {
+	if len(values) == 1 && is_ident_in(values[0], "auto", "normal", "italic") {
+		f.style = strings.ToLower(string(values[0].token.value))
+		return nil
+	}
+	if len(values) == 0 || values[0].is_ident("oblique") == false {
+		return fmt.Errorf("expected auto, normal, italic or oblique")
+	}
+	if len(values) == 1 {
+		f.style, f.oblique_angles = "oblique", FontRange{min: 14, max: 14}
+		return nil
+	}
+
+	var angles FontRange
+	err := parse_font_range(values[1:], &angles, func(value ComponentValue) (float64, bool) {
+		angle, ok := parse_angle(value)
+		degrees, _, _ := to_canonical_unit(angle.Value, angle.Unit)
+		return degrees, ok && degrees >= -90 && degrees <= 90
+	})
+	if err != nil || angles.auto {
+		return fmt.Errorf("expected one or two angles between -90deg and 90deg after oblique")
+	}
+	f.style, f.oblique_angles = "oblique", angles
+
+	return nil
+}
+
+// <unicode-range-token>#
+// Ranges that end beyond the last code point are clamped to it.
+func parse_unicode_ranges(value []ComponentValue) ([]UnicodeRange, error) {
+	var ranges []UnicodeRange
+	for _, item := range split_on_commas(value) {
+		values := non_whitespace_values(item)
+		if len(values) != 1 || values[0].is_token(UNICODE_RANGE_TOKEN) == false {
+			return nil, fmt.Errorf("expected a unicode range, found '%s'", strings.TrimSpace(serialize_component_value_list(item)))
+		}
+		start, end := values[0].token.range_start, values[0].token.range_end
+		if start > rune(MAX_CODE_POINT) || start > end {
+			return nil, fmt.Errorf("'%s' is not a valid unicode range", serialize_component_value_list(values))
+		}
+		ranges = append(ranges, UnicodeRange{start: start, end: min(end, rune(MAX_CODE_POINT))})
+	}
+
+	return ranges, nil
+}
+
+// normal | <percentage [0,∞]>
+func parse_metric_override(values []ComponentValue, result *MetricOverride) error {
+	switch {
+	case len(values) == 1 && values[0].is_ident("normal"):
+		*result = MetricOverride{}
+	case len(values) == 1 && values[0].is_token(PERCENTAGE_TOKEN) && values[0].token.numeric >= 0:
+		*result = MetricOverride{has_percentage: true, percentage: values[0].token.numeric}
+	default:
+		return fmt.Errorf("expected normal or a non-negative percentage")
+	}
+
+	return nil
+}
+
+// Whether the font face is used for the code point.
+func (f FontFace) Covers(char rune) bool {
+	if len(f.unicode_ranges) == 0 {
+		return true
+	}
+	for _, r := range f.unicode_ranges {
+		if char >= r.start && char <= r.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The @font-face rule describing the font face. Descriptors with their initial value are left out.
+func (f FontFace) Rule() Rule {
+	rule := Rule{kind: AT_RULE, name: "font-face", prelude: []ComponentValue{whitespace_value()}, has_block: true}
+	add := func(name string, value string) {
+		rule.decls = append(rule.decls, Declaration{name: name, value: parse_component_value_list(value)})
+	}
+
+	var sb strings.Builder
+	stringify_string(&sb, f.family)
+	add("font-family", sb.String())
+
+	var sources []string
+	for _, source := range f.sources {
+		sources = append(sources, source.String())
+	}
+	add("src", strings.Join(sources, ", "))
+
+	switch {
+	case f.style == "oblique" && (f.oblique_angles.min != 14 || f.oblique_angles.max != 14):
+		add("font-style", "oblique "+f.oblique_angles.serialize("deg"))
+	case f.style != "auto":
+		add("font-style", f.style)
+	}
+	if f.weight.auto == false {
+		add("font-weight", f.weight.serialize(""))
+	}
+	if f.stretch.auto == false {
+		add("font-stretch", f.stretch.serialize("%"))
+	}
+	if len(f.unicode_ranges) > 0 {
+		// The ranges are re-tokenized as unicode ranges only when read from a stylesheet, so they're kept as their tokens.
+		decl := Declaration{name: "unicode-range"}
+		for i, r := range f.unicode_ranges {
+			if i > 0 {
+				decl.value = append(decl.value, ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: COMMA_TOKEN}}, whitespace_value())
+			}
+			decl.value = append(decl.value, ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: UNICODE_RANGE_TOKEN, range_start: r.start, range_end: r.end}})
+		}
+		rule.decls = append(rule.decls, decl)
+	}
+	if f.display != "auto" {
+		add("font-display", f.display)
+	}
+	for _, override := range []struct {
+		name  string
+		value MetricOverride
+	}{{"ascent-override", f.ascent_override}, {"descent-override", f.descent_override}, {"line-gap-override", f.line_gap_override}} {
+		if override.value.has_percentage {
+			add(override.name, format_rounded_number(override.value.percentage)+"%")
+		}
+	}
+	if f.size_adjust != 100 {
+		add("size-adjust", format_rounded_number(f.size_adjust)+"%")
+	}
+	rule.decls = append(rule.decls, f.other...)
+
+	return rule
+}
+
+func (f FontFace) String() string {
+	var sb strings.Builder
+	stringify_rule(&sb, f.Rule())
+	return sb.String()
+}
+
+func (s FontSource) String() string {
+	var sb strings.Builder
+	if s.is_local {
+		sb.WriteString("local(")
+		stringify_string(&sb, s.name)
+		sb.WriteString(")")
+		return sb.String()
+	}
+
+	sb.WriteString("url(")
+	stringify_string(&sb, s.url)
+	sb.WriteString(")")
+	if s.format != "" {
+		sb.WriteString(" format(")
+		if font_formats[s.format] {
+			sb.WriteString(s.format)
+		} else {
+			stringify_string(&sb, s.format)
+		}
+		sb.WriteString(")")
+	}
+	if len(s.techs) > 0 {
+		sb.WriteString(fmt.Sprintf(" tech(%s)", strings.Join(s.techs, ", ")))
+	}
+
+	return sb.String()
+}
+
+// A single value when the range is a single value, otherwise both bounds.
+func (r FontRange) serialize(unit string) string {
+	if r.min == r.max {
+		return format_rounded_number(r.min) + unit
+	}
+
+	return format_rounded_number(r.min) + unit + " " + format_rounded_number(r.max) + unit
+}
+
+func (r UnicodeRange) String() string {
+	if r.start == r.end {
+		return fmt.Sprintf("U+%X", r.start)
+	}
+
+	return fmt.Sprintf("U+%X-%X", r.start, r.end)
+}
+
+// The code points the font face covers, as sorted ranges with the overlapping and adjacent ones merged, for subsetting.
+// A font face without a unicode-range descriptor covers every code point.
+func (f FontFace) UnicodeRanges() []UnicodeRange {
+	if len(f.unicode_ranges) == 0 {
+		return []UnicodeRange{{start: 0, end: rune(MAX_CODE_POINT)}}
+	}
+
+	sorted := slices.Clone(f.unicode_ranges)
+	sort.Slice(sorted, func(i int, j int) bool {
+		return sorted[i].start < sorted[j].start
+	})
+
+	var merged []UnicodeRange
+	for _, r := range sorted {
+		if last := len(merged) - 1; last >= 0 && r.start <= merged[last].end+1 {
+			merged[last].end = max(merged[last].end, r.end)
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	return merged
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func parse_test_font_faces(t *testing.T, css string) ([]FontFace, []error) {
+	t.Helper()
+	return parse_stylesheet(strings.NewReader(css)).FontFaces()
+}
+
+func TestFontFace(t *testing.T) {
+	tests := []struct {
+		css string
+		// The serialization of the font face.
+		want string
+	}{
+		{
+			css:  `@font-face { font-family: Open Sans; src: url(a.woff2) format("woff2"), local("Open Sans Regular") }`,
+			want: "@font-face {\nfont-family: \"Open Sans\";\nsrc: url(\"a.woff2\") format(woff2), local(\"Open Sans Regular\");\n}\n",
+		},
+		{
+			css:  `@font-face { font-family: "A"; src: url("a.otf") format(opentype) tech(color-COLRv1, variations); font-display: swap }`,
+			want: "@font-face {\nfont-family: \"A\";\nsrc: url(\"a.otf\") format(opentype) tech(color-colrv1, variations);\nfont-display: swap;\n}\n",
+		},
+		{
+			css:  `@font-face { font-family: A; src: url(a.woff) format("x-custom") }`,
+			want: "@font-face {\nfont-family: \"A\";\nsrc: url(\"a.woff\") format(\"x-custom\");\n}\n",
+		},
+		{
+			css:  `@font-face { font-family: A; src: url(a.woff); font-weight: 700 100; font-stretch: condensed 125%; font-style: oblique 20deg 10deg }`,
+			want: "@font-face {\nfont-family: \"A\";\nsrc: url(\"a.woff\");\nfont-style: oblique 10deg 20deg;\nfont-weight: 100 700;\nfont-stretch: 75% 125%;\n}\n",
+		},
+		{
+			css:  `@font-face { font-family: A; src: url(a.woff); font-weight: bold; font-style: oblique; size-adjust: 90%; ascent-override: 80% }`,
+			want: "@font-face {\nfont-family: \"A\";\nsrc: url(\"a.woff\");\nfont-style: oblique;\nfont-weight: 700;\nascent-override: 80%;\nsize-adjust: 90%;\n}\n",
+		},
+		{
+			css:  `@font-face { font-family: A; src: url(a.woff); unicode-range: U+0-7F, U+4??, u+1f600 }`,
+			want: "@font-face {\nfont-family: \"A\";\nsrc: url(\"a.woff\");\nunicode-range: U+0-7F, U+400-4FF, U+1F600;\n}\n",
+		},
+		{
+			css:  `@font-face { font-family: A; src: url(a.woff); font-feature-settings: "liga" 0 }`,
+			want: "@font-face {\nfont-family: \"A\";\nsrc: url(\"a.woff\");\nfont-feature-settings: \"liga\" 0;\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			faces, errs := parse_test_font_faces(t, test.css)
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if len(faces) != 1 {
+				t.Fatalf("got %d font faces", len(faces))
+			}
+			got := faces[0].String()
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+
+			// The serialization reads back as the same font face.
+			again, errs := parse_test_font_faces(t, got)
+			if len(errs) > 0 || len(again) != 1 {
+				t.Fatalf("the serialization doesn't read back: %v", errs)
+			}
+			if round_trip := again[0].String(); round_trip != got {
+				t.Errorf("read back as %q", round_trip)
+			}
+		})
+	}
+}
+
+func TestFontFaceErrors(t *testing.T) {
+	tests := []struct {
+		css string
+		// Whether the font face is still valid.
+		valid bool
+		// The texts of the errors reported.
+		want []string
+	}{
+		{css: `@font-face { src: url(a.woff) }`, valid: false, want: []string{"invalid 'font-family' descriptor in @font-face for '': the descriptor is required"}},
+		{css: `@font-face { font-family: A }`, valid: false, want: []string{"invalid 'src' descriptor in @font-face for 'A': the descriptor is required"}},
+		{css: `@font-face { font-family: serif; src: url(a.woff) }`, valid: false, want: []string{"'serif' can't be used as a family name without quotes"}},
+		{css: `@font-face { font-family: A; src: a.woff }`, valid: false, want: []string{"expected url() or local(), found", "no valid font sources"}},
+		{css: `@font-face { font-family: A; src: url(a.woff) format(bogus), url(b.woff) }`, valid: true, want: []string{"invalid font format 'bogus'"}},
+		{css: `@font-face { font-family: A; src: url(a.woff) tech(bogus) }`, valid: false, want: []string{"invalid font technology 'bogus'", "no valid font sources"}},
+		{css: `@font-face { font-family: A; src: local(a) format(woff) }`, valid: false, want: []string{"unexpected", "no valid font sources"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); font-weight: 1001 }`, valid: true, want: []string{"invalid 'font-weight' descriptor in @font-face for 'A'"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); font-weight: 100 200 300 }`, valid: true, want: []string{"expected auto, or one or two values"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); font-style: oblique 100deg }`, valid: true, want: []string{"between -90deg and 90deg"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); unicode-range: U+110000 }`, valid: true, want: []string{"not a valid unicode range"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); unicode-range: U+30-20 }`, valid: true, want: []string{"not a valid unicode range"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); unicode-range: latin }`, valid: true, want: []string{"expected a unicode range"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); font-display: fast }`, valid: true, want: []string{"invalid 'font-display' descriptor"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); size-adjust: -1% }`, valid: true, want: []string{"expected a non-negative percentage"}},
+		{css: `@font-face { font-family: A; src: url(a.woff); color: red }`, valid: true, want: []string{"invalid 'color' descriptor in @font-face for 'A': unknown descriptor"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			faces, errs := parse_test_font_faces(t, test.css)
+			if valid := len(faces) == 1; valid != test.valid {
+				t.Errorf("valid: got %v, want %v", valid, test.valid)
+			}
+			if len(errs) != len(test.want) {
+				t.Fatalf("got errors %v, want %q", errs, test.want)
+			}
+			for i, err := range errs {
+				if strings.Contains(err.Error(), test.want[i]) == false {
+					t.Errorf("got error %q, want one containing %q", err, test.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFontFaceDescriptorOverrides(t *testing.T) {
+	faces, errs := parse_test_font_faces(t, `@font-face { font-family: A; src: url(a.woff); font-weight: 300; font-weight: 500; font-weight: heavy }`)
+	if len(errs) != 1 || len(faces) != 1 {
+		t.Fatalf("got %d faces and errors %v", len(faces), errs)
+	}
+	if weight := faces[0].weight; weight != (FontRange{min: 500, max: 500}) {
+		t.Errorf("got %+v, the last valid declaration should win", weight)
+	}
+}
+
+func TestFontFacesInConditionalRules(t *testing.T) {
+	faces, errs := parse_test_font_faces(t, `@font-face { font-family: A; src: url(a.woff) }
+		@media print { @font-face { font-family: B; src: url(b.woff) } }
+		@layer base { @font-face { font-family: C } }`)
+	var families []string
+	for _, face := range faces {
+		families = append(families, face.family)
+	}
+	if fmt.Sprint(families) != "[A B]" || len(errs) != 1 {
+		t.Errorf("got %v and errors %v", families, errs)
+	}
+}
+
+func TestUnicodeRanges(t *testing.T) {
+	faces, errs := parse_test_font_faces(t, `@font-face { font-family: A; src: url(a.woff); unicode-range: U+100-1FF, U+0-7F, U+80-9F, U+150-160, U+10FFFF }`)
+	if len(errs) > 0 || len(faces) != 1 {
+		t.Fatalf("got %d faces and errors %v", len(faces), errs)
+	}
+	face := faces[0]
+	if got := fmt.Sprint(face.UnicodeRanges()); got != "[U+0-9F U+100-1FF U+10FFFF]" {
+		t.Errorf("got %s", got)
+	}
+	for char, want := range map[rune]bool{'a': true, 0x9F: true, 0xA0: false, 0x155: true, 0x10FFFF: true} {
+		if got := face.Covers(char); got != want {
+			t.Errorf("covers %U: got %v, want %v", char, got, want)
+		}
+	}
+
+	everything := FontFace{}
+	if got := fmt.Sprint(everything.UnicodeRanges()); got != "[U+0-10FFFF]" || everything.Covers(0x1F600) == false {
+		t.Errorf("a font face without a unicode-range should cover every code point, got %s", got)
+	}
+}
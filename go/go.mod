@@ -1,3 +1,5 @@
 module github.com/JamesErrington/css-parser
 
-go 1.21
\ No newline at end of file
+go 1.21
+
+require golang.org/x/net v0.30.0
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// An Element backed by a node of a document parsed by golang.org/x/net/html.
+type HTMLElement struct {
+	node *html.Node
+}
+
+// Wrap a node as an Element. Returns false if the node is not an element node.
+func NewHTMLElement(node *html.Node) (Element, bool) {
+	if node == nil || node.Type != html.ElementNode {
+		return nil, false
+	}
+
+	return HTMLElement{node: node}, true
+}
+
+// The root element of a parsed document (usually <html>).
+func html_document_element(document *html.Node) (Element, bool) {
+	for child := document.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			return NewHTMLElement(child)
+		}
+	}
+
+	return nil, false
+}
+
+func (e HTMLElement) LocalName() string {
+	return e.node.Data
+}
+
+// The html package records foreign elements with a short namespace name rather than the URL.
+func (e HTMLElement) NamespaceURI() string {
+	switch e.node.Namespace {
+	case "":
+		return HTML_NAMESPACE
+	case "svg":
+		return SVG_NAMESPACE
+	case "math":
+		return MATHML_NAMESPACE
+	}
+
+	return e.node.Namespace
+}
+
+func (e HTMLElement) Attributes() []ElementAttribute {
+	attributes := make([]ElementAttribute, 0, len(e.node.Attr))
+	for _, attribute := range e.node.Attr {
+		attributes = append(attributes, ElementAttribute{namespace: html_attribute_namespace(attribute.Namespace), name: attribute.Key, value: attribute.Val})
+	}
+
+	return attributes
+}
+
+func html_attribute_namespace(namespace string) string {
+	switch namespace {
+	case "xlink":
+		return "http://www.w3.org/1999/xlink"
+	case "xml":
+		return "http://www.w3.org/XML/1998/namespace"
+	case "xmlns":
+		return "http://www.w3.org/2000/xmlns/"
+	}
+
+	return namespace
+}
+
+func (e HTMLElement) Parent() (Element, bool) {
+	return NewHTMLElement(e.node.Parent)
+}
+
+func (e HTMLElement) PreviousSibling() (Element, bool) {
+	for sibling := e.node.PrevSibling; sibling != nil; sibling = sibling.PrevSibling {
+		if sibling.Type == html.ElementNode {
+			return NewHTMLElement(sibling)
+		}
+	}
+
+	return nil, false
+}
+
+func (e HTMLElement) NextSibling() (Element, bool) {
+	for sibling := e.node.NextSibling; sibling != nil; sibling = sibling.NextSibling {
+		if sibling.Type == html.ElementNode {
+			return NewHTMLElement(sibling)
+		}
+	}
+
+	return nil, false
+}
+
+func (e HTMLElement) Children() []Element {
+	var children []Element
+	for child := e.node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode {
+			children = append(children, HTMLElement{node: child})
+		}
+	}
+
+	return children
+}
+
+func (e HTMLElement) HasTextContent() bool {
+	for child := e.node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.TextNode && child.Data != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Element names are only case-insensitive for elements in the HTML namespace.
+func (e HTMLElement) IsHTML() bool {
+	return e.node.Namespace == ""
+}
+
+// Parse an HTML document and return its root element.
+func parse_html_document(source string) (Element, error) {
+	document, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return nil, err
+	}
+
+	root, _ := html_document_element(document)
+	return root, nil
+}
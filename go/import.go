@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-cascade-5/#at-import
+// @import [ <url> | <string> ] [ layer | layer(<layer-name>) ]? <import-conditions> ;
+// <import-conditions> = [ supports( [ <supports-condition> | <declaration> ] ) ]? <media-query-list>?
+type ImportRule struct {
+	url string
+	// Whether the stylesheet is imported into a cascade layer, either by the "layer" keyword or the layer() function.
+	has_layer bool
+	// The segments of the dotted layer name. Empty when the layer is anonymous.
+	layer []string
+	// Whether the import is conditional on a supports() condition.
+	has_supports bool
+	supports     SupportsCondition
+	// The media queries of the trailing <media-query-list>, split on top-level commas.
+	media [][]ComponentValue
+}
+
+func is_import_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && strings.EqualFold(rule.name, "import")
+}
+
+func is_valid_import_rule(rule Rule) bool {
+	_, err := rule.import_rule()
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+
+	return true
+}
+
+// Whether an @import rule may still follow this rule.
+func allows_following_import(rule Rule) bool {
+	if rule.kind != AT_RULE {
+		return false
+	}
+
+	switch {
+	case strings.EqualFold(rule.name, "charset"), strings.EqualFold(rule.name, "import"):
+		return true
+	// @layer statements (but not @layer blocks) may appear before @import rules.
+	case strings.EqualFold(rule.name, "layer"):
+		return rule.has_block == false
+	}
+
+	return false
+}
+
+// Extract the typed model of an @import rule from its prelude.
+func (r Rule) import_rule() (ImportRule, error) {
+	if is_import_rule(r) == false {
+		return ImportRule{}, fmt.Errorf("Parse Error: rule is not an @import rule")
+	}
+	if r.has_block {
+		return ImportRule{}, fmt.Errorf("Parse Error: @import rule must not have a block")
+	}
+
+	return parse_import_prelude(r.prelude)
+}
+
+func parse_import_prelude(prelude []ComponentValue) (ImportRule, error) {
+	var rule ImportRule
+	stream := NewComponentValueStream(prelude)
+	stream.discard_whitespace()
+
+	// [ <url> | <string> ]
+	url, ok := url_value(stream.consume_value())
+	if ok == false {
+		return rule, fmt.Errorf("Parse Error: @import rule must start with a url or string")
+	}
+	rule.url = url
+	stream.discard_whitespace()
+
+	// [ layer | layer(<layer-name>) ]?
+	switch next := stream.next_value(); {
+	case next.is_ident("layer"):
+		stream.discard_value()
+		rule.has_layer = true
+	case next.is_function("layer"):
+		stream.discard_value()
+		name, err := parse_layer_name(next.value)
+		if err != nil {
+			return rule, err
+		}
+		rule.has_layer = true
+		rule.layer = name
+	}
+	stream.discard_whitespace()
+
+	// supports( [ <supports-condition> | <declaration> ] )?
+	if next := stream.next_value(); next.is_function("supports") {
+		stream.discard_value()
+		condition, err := parse_supports_condition(next.value)
+		if err != nil {
+			decl, decl_err := parse_supports_declaration(next.value)
+			if decl_err != nil {
+				return rule, fmt.Errorf("Parse Error: invalid supports() condition in @import rule: %v", err)
+			}
+			condition = SupportsCondition{kind: SUPPORTS_DECLARATION, decl: decl}
+		}
+		rule.has_supports = true
+		rule.supports = condition
+	}
+	stream.discard_whitespace()
+
+	// <media-query-list>?
+	if stream.empty() == false {
+		media, err := split_media_query_list(stream.remaining())
+		if err != nil {
+			return rule, err
+		}
+		rule.media = media
+	}
+
+	return rule, nil
+}
+
+// The value of a <url> (either a <url-token> or a url() function containing a <string-token>) or a <string>.
+func url_value(value ComponentValue) (string, bool) {
+	switch {
+	case value.is_token(URL_TOKEN), value.is_token(STRING_TOKEN):
+		return string(value.token.value), true
+	case value.is_function("url"), value.is_function("src"):
+		args := trim_whitespace(value.value)
+		if len(args) == 1 && args[0].is_token(STRING_TOKEN) {
+			return string(args[0].token.value), true
+		}
+	}
+
+	return "", false
+}
+
+// https://drafts.csswg.org/css-cascade-5/#typedef-layer-name
+// <layer-name> = <ident> [ '.' <ident> ]*
+func parse_layer_name(list []ComponentValue) ([]string, error) {
+	list = trim_whitespace(list)
+	if len(list) == 0 {
+		return nil, fmt.Errorf("Parse Error: expected a layer name")
+	}
+
+	var segments []string
+	// Segments alternate between an <ident-token> and a '.' <delim-token>, with no whitespace in between.
+	for i, value := range list {
+		if i%2 == 1 {
+			if value.is_delim(FULL_STOP_CHAR) == false {
+				return nil, fmt.Errorf("Parse Error: unexpected %s in layer name", value)
+			}
+			continue
+		}
+
+		if value.is_token(IDENT_TOKEN) == false {
+			return nil, fmt.Errorf("Parse Error: unexpected %s in layer name", value)
+		}
+		name := string(value.token.value)
+		// The CSS-wide keywords are reserved for future use, and cause the rule to be invalid.
+		if is_css_wide_keyword(name) {
+			return nil, fmt.Errorf("Parse Error: '%s' is not a valid layer name", name)
+		}
+		segments = append(segments, name)
+	}
+
+	if len(list)%2 == 0 {
+		return nil, fmt.Errorf("Parse Error: layer name must not end with '.'")
+	}
+
+	return segments, nil
+}
+
+// https://drafts.csswg.org/css-values-4/#common-keywords
+func is_css_wide_keyword(name string) bool {
+	switch strings.ToLower(name) {
+	case "initial", "inherit", "unset", "revert", "revert-layer":
+		return true
+	}
+
+	return false
+}
+
+// Split a <media-query-list> into its comma-separated media queries.
+func split_media_query_list(list []ComponentValue) ([][]ComponentValue, error) {
+	var queries [][]ComponentValue
+	for _, query := range split_on_commas(list) {
+		query = trim_whitespace(query)
+		if len(query) == 0 {
+			return nil, fmt.Errorf("Parse Error: empty media query in media query list")
+		}
+		queries = append(queries, query)
+	}
+
+	return queries, nil
+}
+
+// Split a list of component values on its top-level <comma-token>s.
+func split_on_commas(list []ComponentValue) [][]ComponentValue {
+	var result [][]ComponentValue
+	start := 0
+	for i, value := range list {
+		if value.is_token(COMMA_TOKEN) {
+			result = append(result, list[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(result, list[start:])
+}
+
+// The @import rules of the stylesheet, in order.
+func (s Stylesheet) import_rules() []ImportRule {
+	var imports []ImportRule
+	for _, rule := range s.rules {
+		if is_import_rule(rule) == false {
+			continue
+		}
+		// Invalid @import rules have already been dropped by the parser.
+		if imported, err := rule.import_rule(); err == nil {
+			imports = append(imports, imported)
+		}
+	}
+
+	return imports
+}
+
+func (i ImportRule) layer_name() string {
+	return strings.Join(i.layer, string(FULL_STOP_CHAR))
+}
+
+func (i ImportRule) String() string {
+	var sb strings.Builder
+	sb.WriteString("@import url(")
+	stringify_string(&sb, i.url)
+	sb.WriteRune(CLOSE_PAREN_CHAR)
+
+	if i.has_layer {
+		if len(i.layer) > 0 {
+			sb.WriteString(fmt.Sprintf(" layer(%s)", i.layer_name()))
+		} else {
+			sb.WriteString(" layer")
+		}
+	}
+
+	if i.has_supports {
+		sb.WriteString(" supports(")
+		stringify_supports_condition(&sb, i.supports)
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	}
+
+	for j, query := range i.media {
+		if j == 0 {
+			sb.WriteRune(SPACE_CHAR)
+		} else {
+			sb.WriteString(", ")
+		}
+		stringify_component_value_list(&sb, query)
+	}
+
+	sb.WriteRune(SEMICOLON_CHAR)
+	return sb.String()
+}
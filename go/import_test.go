@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestImportRule(t *testing.T) {
+	tests := []struct {
+		css  string
+		want ImportRule
+		// The serialization of the parsed rule.
+		text string
+	}{
+		{css: `@import "a.css";`, want: ImportRule{url: "a.css"}, text: `@import url("a.css");`},
+		{css: `@import url(a.css);`, want: ImportRule{url: "a.css"}, text: `@import url("a.css");`},
+		{css: `@import url("a.css");`, want: ImportRule{url: "a.css"}, text: `@import url("a.css");`},
+		{css: `@import "a.css" layer;`, want: ImportRule{url: "a.css", has_layer: true}, text: `@import url("a.css") layer;`},
+		{css: `@import "a.css" layer(base.reset);`, want: ImportRule{url: "a.css", has_layer: true, layer: []string{"base", "reset"}}, text: `@import url("a.css") layer(base.reset);`},
+		{css: `@import "a.css" supports(display: grid);`, want: ImportRule{url: "a.css", has_supports: true}, text: `@import url("a.css") supports((display: grid));`},
+		{css: `@import "a.css" supports(not (display: grid));`, want: ImportRule{url: "a.css", has_supports: true}, text: `@import url("a.css") supports(not (display: grid));`},
+		{css: `@import "a.css" screen, print and (orientation: landscape);`, want: ImportRule{url: "a.css"}, text: `@import url("a.css") screen, print and (orientation: landscape);`},
+		{css: `@import "a.css" layer(a) supports(display: grid) print;`, want: ImportRule{url: "a.css", has_layer: true, layer: []string{"a"}, has_supports: true}, text: `@import url("a.css") layer(a) supports((display: grid)) print;`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			imports := parse_stylesheet(strings.NewReader(test.css)).import_rules()
+			if len(imports) != 1 {
+				t.Fatalf("got %d @import rules", len(imports))
+			}
+			got := imports[0]
+			if got.url != test.want.url || got.has_layer != test.want.has_layer || got.layer_name() != test.want.layer_name() || got.has_supports != test.want.has_supports {
+				t.Errorf("got %+v, want %+v", got, test.want)
+			}
+			if text := got.String(); text != test.text {
+				t.Errorf("serialized as %q, want %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestImportRuleErrors(t *testing.T) {
+	for _, prelude := range []string{
+		"",
+		"a.css",
+		`"a.css" layer()`,
+		`"a.css" layer(a.)`,
+		`"a.css" layer(initial)`,
+		`"a.css" supports()`,
+		`"a.css" print,`,
+	} {
+		t.Run(prelude, func(t *testing.T) {
+			if rule, err := parse_import_prelude(parse_component_value_list(prelude)); err == nil {
+				t.Errorf("expected an error, got %q", rule.String())
+			}
+		})
+	}
+}
+
+func TestImportPlacement(t *testing.T) {
+	tests := []struct {
+		css string
+		// The urls of the @import rules kept by the parser.
+		want string
+	}{
+		{css: `@import "a.css"; @import "b.css"; a { color: red }`, want: "a.css b.css"},
+		{css: `@charset "utf-8"; @import "a.css";`, want: "a.css"},
+		{css: `@layer base; @import "a.css";`, want: "a.css"},
+		{css: `@layer base {} @import "a.css";`, want: ""},
+		{css: `a { color: red } @import "a.css";`, want: ""},
+		{css: `@media print {} @import "a.css";`, want: ""},
+		{css: `@import "a.css" {} @import "b.css";`, want: "b.css"},
+		{css: `@import a.css; @import "b.css";`, want: "b.css"},
+		{css: `@media print { @import "a.css"; }`, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			var urls []string
+			for _, imported := range parse_stylesheet(strings.NewReader(test.css)).import_rules() {
+				urls = append(urls, imported.url)
+			}
+			if got := strings.Join(urls, " "); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Pseudo-classes that depend on user interaction or other state that can't be captured in a style attribute.
+var dynamic_pseudo_classes = map[string]bool{
+	"active": true, "autofill": true, "checked": true, "current": true, "focus": true, "focus-visible": true,
+	"focus-within": true, "fullscreen": true, "future": true, "hover": true, "indeterminate": true, "modal": true,
+	"open": true, "closed": true, "past": true, "paused": true, "picture-in-picture": true,
+	"placeholder-shown": true, "playing": true, "popover-open": true, "target": true, "target-within": true,
+	"user-invalid": true, "user-valid": true, "visited": true,
+}
+
+// Move the declarations of the stylesheet that match elements of the HTML document into their style attributes, as required by most email clients.
+// Rules that can't be inlined (at-rules such as @media, and selectors with pseudo-elements or dynamic pseudo-classes such as :hover)
+// are kept in a <style> element added to the document's <head>.
+// Existing style attributes take part in the cascade, so they keep their precedence over the stylesheet's rules,
+// and declarations that win because they are !important stay !important in the style attribute.
+func InlineStyles(source string, css string) (string, error) {
+	document, err := html.Parse(strings.NewReader(source))
+	if err != nil {
+		return "", err
+	}
+
+	// Nested rules are flattened first, so that each of them is inlined or kept on its own.
+	sheet := parse_stylesheet(strings.NewReader(css)).FlattenNesting(FlattenOptions{})
+	inlinable, residual := split_inlinable_rules(sheet.rules)
+
+	root, ok := html_document_element(document)
+	if ok {
+		cascade := NewCascade([]CascadeStylesheet{{sheet: Stylesheet{rules: inlinable, namespaces: sheet.namespaces}, origin: ORIGIN_AUTHOR}}, CascadeOptions{style_attributes: true})
+		for element, style := range cascade.ComputeStyles(root) {
+			set_inline_style(element.(HTMLElement).node, style)
+		}
+	}
+
+	if len(residual) > 0 {
+		insert_style_element(document, Stylesheet{rules: residual}.Stringify())
+	}
+
+	var sb strings.Builder
+	if err := html.Render(&sb, document); err != nil {
+		return "", err
+	}
+
+	return sb.String(), nil
+}
+
+// Split rules into those whose declarations can be moved into style attributes, and those that must be kept in a stylesheet.
+// A style rule whose selector list mixes the two is split into two rules. The rules must already be flattened.
+func split_inlinable_rules(rules []Rule) ([]Rule, []Rule) {
+	var inlinable []Rule
+	var residual []Rule
+	for _, rule := range rules {
+		switch {
+		case rule.kind == QUALIFIED_RULE:
+			selectors, err := rule.selectors()
+			// Keep rules we don't understand as they are, so the email client can decide what to do with them.
+			if err != nil {
+				residual = append(residual, rule)
+				continue
+			}
+
+			var inline_selectors SelectorList
+			var residual_selectors SelectorList
+			for _, selector := range selectors {
+				if is_inlinable_selector(selector) {
+					inline_selectors = append(inline_selectors, selector)
+				} else {
+					residual_selectors = append(residual_selectors, selector)
+				}
+			}
+			if len(inline_selectors) > 0 {
+				inlinable = append(inlinable, rule_with_selectors(rule, inline_selectors))
+			}
+			if len(residual_selectors) > 0 {
+				residual = append(residual, rule_with_selectors(rule, residual_selectors))
+			}
+		// The contents of a layer can be inlined, as the cascade accounts for their layer, but any remaining rules must stay in the same layer.
+		case is_layer_rule(rule) && rule.has_block:
+			layer_inlinable, layer_residual := split_inlinable_rules(rule.children)
+			inline_layer, residual_layer := rule, rule
+			inline_layer.children, residual_layer.children = layer_inlinable, layer_residual
+			inlinable = append(inlinable, inline_layer)
+			if len(layer_residual) > 0 {
+				residual = append(residual, residual_layer)
+			}
+		case is_layer_rule(rule):
+			inlinable = append(inlinable, rule)
+			residual = append(residual, rule)
+		// The encoding of the stylesheet is irrelevant once it's inside the document.
+		case strings.EqualFold(rule.name, "charset"):
+		default:
+			residual = append(residual, rule)
+		}
+	}
+
+	return inlinable, residual
+}
+
+// A copy of the style rule with its prelude replaced by the selector list.
+func rule_with_selectors(rule Rule, selectors SelectorList) Rule {
+	rule.prelude = append(parse_component_value_list(selectors.String()), whitespace_value())
+	return rule
+}
+
+// Whether the selector only depends on the document tree, so that its matches can be decided ahead of time.
+func is_inlinable_selector(selector ComplexSelector) bool {
+	for _, compound := range selector.compounds {
+		for _, simple := range compound.selectors {
+			switch {
+			case simple.kind == PSEUDO_ELEMENT_SELECTOR:
+				return false
+			case simple.kind == PSEUDO_CLASS_SELECTOR:
+				if dynamic_pseudo_classes[simple.name] || strings.HasPrefix(simple.name, "-") {
+					return false
+				}
+				for _, argument := range simple.selectors {
+					if is_inlinable_selector(argument) == false {
+						return false
+					}
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// Replace the style attribute of the node with the declarations that apply to it.
+// The cascade expands shorthands, so a shorthand declaration is written back where its longhands come from it, followed by
+// the declarations that override some of them, and the other longhands are merged into shorthands where they can be.
+func set_inline_style(node *html.Node, style map[string]CascadedValue) {
+	var values []CascadedValue
+	for _, value := range style {
+		// Inherited values will be inherited from the ancestor's style attribute.
+		if value.inherited == false {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return
+	}
+	// The longhands of a shorthand have the same precedence, so they're sorted by name first to keep the output stable.
+	sort.Slice(values, func(i int, j int) bool {
+		return values[i].decl.name < values[j].decl.name
+	})
+	sort.SliceStable(values, func(i int, j int) bool {
+		return compare_cascaded_values(values[i], values[j]) < 0
+	})
+
+	var decls []Declaration
+	written := map[int]bool{}
+	for i, value := range values {
+		if value.shorthand.name == "" || can_write_shorthand(values, i) == false {
+			decls = append(decls, value.decl)
+			continue
+		}
+		if written[value.order] == false {
+			decls = append(decls, value.shorthand)
+			written[value.order] = true
+		}
+	}
+
+	var sb strings.Builder
+	for i, decl := range merge_longhands(decls) {
+		if i > 0 {
+			sb.WriteRune(SPACE_CHAR)
+		}
+		stringify_declaration(&sb, decl)
+	}
+
+	for i, attribute := range node.Attr {
+		if attribute.Namespace == "" && strings.EqualFold(attribute.Key, "style") {
+			node.Attr[i].Val = sb.String()
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: "style", Val: sb.String()})
+}
+
+// Whether the shorthand declaration the value at index i was expanded from can be written instead of its longhands:
+// every longhand must have a value, and those that don't come from the shorthand must be written after it.
+func can_write_shorthand(values []CascadedValue, i int) bool {
+	from_shorthand := func(value CascadedValue) bool {
+		return value.order == values[i].order && value.from_style_attribute == values[i].from_style_attribute && value.shorthand.name == values[i].shorthand.name
+	}
+	first := slices.IndexFunc(values, from_shorthand)
+
+	for _, longhand := range shorthand_properties[property_name(values[i].shorthand.name)].longhands {
+		index := slices.IndexFunc(values, func(value CascadedValue) bool { return value.decl.name == longhand })
+		if index < 0 || (index < first && from_shorthand(values[index]) == false) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Add a <style> element containing the CSS to the end of the document's <head>.
+func insert_style_element(document *html.Node, css string) {
+	head := find_html_element(document, atom.Head)
+	if head == nil {
+		head = document
+	}
+
+	style := &html.Node{Type: html.ElementNode, DataAtom: atom.Style, Data: "style"}
+	style.AppendChild(&html.Node{Type: html.TextNode, Data: css})
+	head.AppendChild(style)
+}
+
+func find_html_element(node *html.Node, tag atom.Atom) *html.Node {
+	if node.Type == html.ElementNode && node.DataAtom == tag {
+		return node
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if found := find_html_element(child, tag); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInlineStyles(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		css  string
+		want string
+	}{
+		{
+			name: "writes shorthands back",
+			html: `<p>x</p>`,
+			css:  `p { margin: 1px 2px; font: 12px serif }`,
+			want: `<p style="margin: 1px 2px; font: 12px serif;">x</p>`,
+		},
+		{
+			name: "overrides part of a shorthand after it",
+			html: `<p class="a" style="font-size: 9px">x</p>`,
+			css:  `p { font: 12px serif } .a { margin-top: 3px }`,
+			want: `<p class="a" style="font: 12px serif; margin-top: 3px; font-size: 9px;">x</p>`,
+		},
+		{
+			name: "merges longhands into shorthands",
+			html: `<p>x</p>`,
+			css:  `p { margin-top: 1px; margin-right: 1px } p { margin-bottom: 1px; margin-left: 1px }`,
+			want: `<p style="margin: 1px;">x</p>`,
+		},
+		{
+			name: "keeps rules that can't be inlined",
+			html: `<a href="#">x</a>`,
+			css:  `a { color: red } a:hover { color: blue }`,
+			want: `<a href="#" style="color: red;">x</a>`,
+		},
+		{
+			name: "inlines nested rules",
+			html: `<p><a href="#">x</a></p>`,
+			css:  `p { color: red; a { color: blue; &:hover { color: green } } }`,
+			want: `<p style="color: red;"><a href="#" style="color: blue;">x</a></p>`,
+		},
+		{
+			name: "keeps nested rules that can't be inlined",
+			html: `<p><a href="#">x</a></p>`,
+			css:  `p { a { &:hover { color: green } } }`,
+			want: `p a:hover {`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := InlineStyles("<html><head></head><body>"+test.html+"</body></html>", test.css)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(got, test.want) == false {
+				t.Errorf("expected the output to contain %q, got %q", test.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-animations-1/#keyframes
+// @keyframes <keyframes-name> { <qualified-rule-list> }
+type Keyframes struct {
+	name string
+	// The vendor prefix of the at-rule's name (e.g. "-webkit-" for @-webkit-keyframes), or empty.
+	prefix string
+	// The valid keyframes, in order of appearance.
+	keyframes []Keyframe
+}
+
+// A keyframe rule, e.g. "from, 50% { opacity: 0 }".
+type Keyframe struct {
+	selectors []KeyframeSelector
+	decls     []Declaration
+}
+
+// https://drafts.csswg.org/css-animations-1/#typedef-keyframe-selector
+// <keyframe-selector> = from | to | <percentage [0,100]> | <timeline-range-name> <percentage>
+// from and to are normalized to 0% and 100%.
+type KeyframeSelector struct {
+	// https://drafts.csswg.org/scroll-animations-1/#named-ranges
+	// The named timeline range the percentage is in (e.g. "entry"), or empty for the whole timeline.
+	range_name string
+	percentage float64
+}
+
+// Why an @keyframes rule, one of its keyframes or one of their declarations is invalid, or a keyframe selector is duplicated.
+type KeyframesError struct {
+	Name     string
+	Selector string
+	Reason   string
+}
+
+func (e KeyframesError) Error() string {
+	if e.Selector == "" {
+		return fmt.Sprintf("Parse Error: invalid @keyframes '%s': %s", e.Name, e.Reason)
+	}
+
+	return fmt.Sprintf("Parse Error: keyframe '%s' in @keyframes '%s': %s", e.Selector, e.Name, e.Reason)
+}
+
+// https://drafts.csswg.org/scroll-animations-1/#typedef-timeline-range-name
+var timeline_range_names = map[string]bool{
+	"cover": true, "contain": true, "entry": true, "exit": true, "entry-crossing": true, "exit-crossing": true,
+}
+
+// The animation properties that are ignored in keyframes, as they apply to the animation rather than to a keyframe.
+// animation-timing-function and animation-composition apply to the keyframe's interval, so they're kept.
+func is_ignored_in_keyframe(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasPrefix(name, "animation") && name != "animation-timing-function" && name != "animation-composition"
+}
+
+// The vendor prefix of an @keyframes rule's name, and whether it's one.
+func keyframes_rule_prefix(rule Rule) (string, bool) {
+	if rule.kind != AT_RULE {
+		return "", false
+	}
+	name := strings.ToLower(rule.name)
+	prefix, ok := strings.CutSuffix(name, "keyframes")
+	if ok == false {
+		return "", false
+	}
+	// e.g. "-webkit-"
+	is_vendor_prefix := len(prefix) > 2 && prefix[0] == '-' && strings.Count(prefix, "-") == 2 && strings.HasSuffix(prefix, "-")
+
+	return prefix, prefix == "" || is_vendor_prefix
+}
+
+func is_keyframes_rule(rule Rule) bool {
+	_, ok := keyframes_rule_prefix(rule)
+	return ok
+}
+
+// Extract the keyframes of an @keyframes rule. The rule is invalid if its name is.
+// Keyframes with an invalid selector are reported and ignored, as are !important declarations and animation properties,
+// and duplicated keyframe selectors are reported, though they're valid.
+func (r Rule) keyframes_rule() (Keyframes, []error) {
+	prefix, ok := keyframes_rule_prefix(r)
+	if ok == false {
+		return Keyframes{}, []error{fmt.Errorf("Parse Error: rule is not an @keyframes rule")}
+	}
+	name, err := parse_keyframes_name(r.prelude)
+	if err != nil {
+		return Keyframes{}, []error{KeyframesError{Name: strings.TrimSpace(serialize_component_value_list(r.prelude)), Reason: err.Error()}}
+	}
+	if r.has_block == false {
+		return Keyframes{}, []error{KeyframesError{Name: name, Reason: "missing block"}}
+	}
+
+	keyframes := Keyframes{name: name, prefix: prefix}
+	var errs []error
+	seen := map[KeyframeSelector]bool{}
+	for _, child := range r.children {
+		if child.kind != QUALIFIED_RULE {
+			errs = append(errs, KeyframesError{Name: name, Reason: fmt.Sprintf("unexpected @%s rule", child.name)})
+			continue
+		}
+		text := strings.TrimSpace(serialize_component_value_list(child.prelude))
+		selectors, err := parse_keyframe_selectors(child.prelude)
+		if err != nil {
+			errs = append(errs, KeyframesError{Name: name, Selector: text, Reason: err.Error()})
+			continue
+		}
+
+		for _, selector := range selectors {
+			if seen[selector] {
+				errs = append(errs, KeyframesError{Name: name, Selector: selector.String(), Reason: "duplicate keyframe selector"})
+			}
+			seen[selector] = true
+		}
+
+		keyframe := Keyframe{selectors: selectors}
+		for _, decl := range child.decls {
+			switch {
+			case decl.important:
+				errs = append(errs, KeyframesError{Name: name, Selector: text, Reason: fmt.Sprintf("!important declaration of '%s' is ignored", decl.name)})
+			case is_ignored_in_keyframe(decl.name):
+				errs = append(errs, KeyframesError{Name: name, Selector: text, Reason: fmt.Sprintf("'%s' is ignored in keyframes", decl.name)})
+			default:
+				keyframe.decls = append(keyframe.decls, decl)
+			}
+		}
+		keyframes.keyframes = append(keyframes.keyframes, keyframe)
+	}
+
+	return keyframes, errs
+}
+
+// The stylesheet's @keyframes rules (including those in conditional rules and layers), in order.
+// Of several with the same name, an animation uses the last one whose conditions hold.
+func (s Stylesheet) Keyframes() ([]Keyframes, []error) {
+	var result []Keyframes
+	var errors []error
+	var walk func(rules []Rule)
+	walk = func(rules []Rule) {
+		for _, rule := range rules {
+			if is_keyframes_rule(rule) == false {
+				walk(rule.children)
+				continue
+			}
+			keyframes, errs := rule.keyframes_rule()
+			errors = append(errors, errs...)
+			if keyframes.name != "" {
+				result = append(result, keyframes)
+			}
+		}
+	}
+	walk(s.rules)
+
+	return result, errors
+}
+
+// <keyframes-name> = <custom-ident> | <string>
+func parse_keyframes_name(prelude []ComponentValue) (string, error) {
+	values := non_whitespace_values(prelude)
+	if len(values) != 1 {
+		return "", fmt.Errorf("expected a single keyframes name")
+	}
+
+	switch value := values[0]; {
+	case value.is_token(STRING_TOKEN) && len(value.token.value) > 0:
+		return string(value.token.value), nil
+	case value.is_token(IDENT_TOKEN):
+		name := string(value.token.value)
+		if is_keyframes_name_keyword(name) {
+			return "", fmt.Errorf("'%s' can't be used as a keyframes name without quotes", name)
+		}
+		return name, nil
+	}
+
+	return "", fmt.Errorf("expected an identifier or a string, found %s", describe_value(values[0]))
+}
+
+// <keyframe-selector>#
+func parse_keyframe_selectors(prelude []ComponentValue) ([]KeyframeSelector, error) {
+	var selectors []KeyframeSelector
+	for _, item := range split_on_commas(prelude) {
+		selector, err := parse_keyframe_selector(non_whitespace_values(item))
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+func parse_keyframe_selector(values []ComponentValue) (KeyframeSelector, error) {
+	switch {
+	case len(values) == 1 && values[0].is_ident("from"):
+		return KeyframeSelector{percentage: 0}, nil
+	case len(values) == 1 && values[0].is_ident("to"):
+		return KeyframeSelector{percentage: 100}, nil
+	case len(values) == 1 && values[0].is_token(PERCENTAGE_TOKEN):
+		percentage := values[0].token.numeric
+		if percentage < 0 || percentage > 100 {
+			return KeyframeSelector{}, fmt.Errorf("%s is out of the range 0%% to 100%%", describe_value(values[0]))
+		}
+		return KeyframeSelector{percentage: percentage}, nil
+	case len(values) == 2 && values[0].is_token(IDENT_TOKEN) && values[1].is_token(PERCENTAGE_TOKEN):
+		range_name := strings.ToLower(string(values[0].token.value))
+		if timeline_range_names[range_name] == false {
+			return KeyframeSelector{}, fmt.Errorf("unknown timeline range '%s'", string(values[0].token.value))
+		}
+		return KeyframeSelector{range_name: range_name, percentage: values[1].token.numeric}, nil
+	case len(values) == 0:
+		return KeyframeSelector{}, fmt.Errorf("expected a keyframe selector")
+	}
+
+	return KeyframeSelector{}, fmt.Errorf("invalid keyframe selector '%s'", serialize_spaced_values(values))
+}
+
+// https://drafts.csswg.org/css-animations-1/#keyframes
+// The keyframes as the animation uses them: one for each keyframe selector (and timing function, as keyframes with the same
+// selector but different timing functions are kept apart), sorted by offset, with the declarations of the keyframes that
+// share it cascaded, so the last declaration of each property wins.
+// Keyframes in a named timeline range follow those that aren't, grouped by range, as their offsets depend on the timeline.
+func (k Keyframes) Merged() []Keyframe {
+	type key struct {
+		selector        KeyframeSelector
+		timing_function string
+	}
+	var keys []key
+	merged := map[key]*Keyframe{}
+	for _, keyframe := range k.keyframes {
+		timing_function := ""
+		for _, decl := range keyframe.decls {
+			if strings.EqualFold(decl.name, "animation-timing-function") {
+				timing_function = serialize_component_value_list(decl.value)
+			}
+		}
+		for _, selector := range keyframe.selectors {
+			id := key{selector, timing_function}
+			target, ok := merged[id]
+			if ok == false {
+				target = &Keyframe{selectors: []KeyframeSelector{selector}}
+				merged[id] = target
+				keys = append(keys, id)
+			}
+			for _, decl := range keyframe.decls {
+				target.decls = cascade_keyframe_declaration(target.decls, decl)
+			}
+		}
+	}
+
+	sort.SliceStable(keys, func(i int, j int) bool {
+		a, b := keys[i].selector, keys[j].selector
+		if a.range_name != b.range_name {
+			return a.range_name < b.range_name
+		}
+		return a.percentage < b.percentage
+	})
+	result := make([]Keyframe, len(keys))
+	for i, id := range keys {
+		result[i] = *merged[id]
+	}
+
+	return result
+}
+
+// Replace the earlier declaration of the same property, moving it to the end so that it still overrides any shorthand
+// or longhand declared between them.
+func cascade_keyframe_declaration(decls []Declaration, decl Declaration) []Declaration {
+	var result []Declaration
+	for _, existing := range decls {
+		if strings.EqualFold(existing.name, decl.name) == false {
+			result = append(result, existing)
+		}
+	}
+
+	return append(result, decl)
+}
+
+// The @keyframes rule, with its keyframe selectors normalized.
+func (k Keyframes) Rule() Rule {
+	var sb strings.Builder
+	// A name that was given as a string is kept as one, unless it's a valid identifier.
+	if is_keyframes_name_keyword(k.name) || strings.IndexFunc(k.name, func(char rune) bool { return is_ident(char) == false }) >= 0 {
+		stringify_string(&sb, k.name)
+	} else {
+		stringify_identifier(&sb, k.name)
+	}
+	prelude := append([]ComponentValue{whitespace_value()}, parse_component_value_list(sb.String())...)
+	rule := Rule{kind: AT_RULE, name: k.prefix + "keyframes", prelude: append(prelude, whitespace_value()), has_block: true}
+
+	for _, keyframe := range k.keyframes {
+		var selectors []string
+		for _, selector := range keyframe.selectors {
+			selectors = append(selectors, selector.String())
+		}
+		prelude := append(parse_component_value_list(strings.Join(selectors, ", ")), whitespace_value())
+		rule.children = append(rule.children, Rule{kind: QUALIFIED_RULE, prelude: prelude, decls: keyframe.decls, has_block: true})
+	}
+
+	return rule
+}
+
+func (k Keyframes) String() string {
+	var sb strings.Builder
+	stringify_rule(&sb, k.Rule())
+	return sb.String()
+}
+
+func (s KeyframeSelector) String() string {
+	if s.range_name == "" {
+		return format_rounded_number(s.percentage) + "%"
+	}
+
+	return s.range_name + " " + format_rounded_number(s.percentage) + "%"
+}
+
+func is_keyframes_name_keyword(name string) bool {
+	name = strings.ToLower(name)
+	return is_css_wide_keyword(name) || name == "default" || name == "none"
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// The keyframes' selectors and declarations, e.g. "0% 50%{opacity: 0;} 100%{opacity: 1;}".
+func describe_keyframes(keyframes []Keyframe) string {
+	var parts []string
+	for _, keyframe := range keyframes {
+		var selectors []string
+		for _, selector := range keyframe.selectors {
+			selectors = append(selectors, selector.String())
+		}
+		var decls []string
+		for _, decl := range keyframe.decls {
+			decls = append(decls, decl.name+": "+serialize_component_value_list(decl.value)+";")
+		}
+		parts = append(parts, strings.Join(selectors, " ")+"{"+strings.Join(decls, " ")+"}")
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func TestKeyframes(t *testing.T) {
+	tests := []struct {
+		css  string
+		name string
+		// The keyframes, as described by describe_keyframes.
+		want string
+		// The serialization of the @keyframes rule.
+		text string
+	}{
+		{
+			css:  `@keyframes fade { from { opacity: 0 } to { opacity: 1 } }`,
+			name: "fade",
+			want: "0%{opacity: 0;} 100%{opacity: 1;}",
+			text: "@keyframes fade {\n0% {\nopacity: 0;\n}\n100% {\nopacity: 1;\n}\n}\n",
+		},
+		{
+			css:  `@keyframes "a b" { FROM, 50% { opacity: 0 } }`,
+			name: "a b",
+			want: "0% 50%{opacity: 0;}",
+			text: "@keyframes \"a b\" {\n0%, 50% {\nopacity: 0;\n}\n}\n",
+		},
+		{
+			css:  `@keyframes "none" { 25% { opacity: 0 } }`,
+			name: "none",
+			want: "25%{opacity: 0;}",
+			text: "@keyframes \"none\" {\n25% {\nopacity: 0;\n}\n}\n",
+		},
+		{
+			css:  `@-webkit-keyframes spin { entry 0%, exit 100% { transform: none } }`,
+			name: "spin",
+			want: "entry 0% exit 100%{transform: none;}",
+			text: "@-webkit-keyframes spin {\nentry 0%, exit 100% {\ntransform: none;\n}\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			keyframes, errs := parse_stylesheet(strings.NewReader(test.css)).Keyframes()
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if len(keyframes) != 1 {
+				t.Fatalf("got %d @keyframes rules", len(keyframes))
+			}
+			if keyframes[0].name != test.name {
+				t.Errorf("name: got %q, want %q", keyframes[0].name, test.name)
+			}
+			if got := describe_keyframes(keyframes[0].keyframes); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if got := keyframes[0].String(); got != test.text {
+				t.Errorf("serialized as %q, want %q", got, test.text)
+			}
+		})
+	}
+}
+
+func TestKeyframesErrors(t *testing.T) {
+	tests := []struct {
+		css string
+		// The keyframes kept, as described by describe_keyframes, or "-" when the rule is invalid.
+		want string
+		// The texts of the errors reported.
+		errors []string
+	}{
+		{css: `@keyframes none { from { opacity: 0 } }`, want: "-", errors: []string{"invalid @keyframes 'none': 'none' can't be used as a keyframes name without quotes"}},
+		{css: `@keyframes "" { from { opacity: 0 } }`, want: "-", errors: []string{"expected an identifier or a string"}},
+		{css: `@keyframes a b { from { opacity: 0 } }`, want: "-", errors: []string{"expected a single keyframes name"}},
+		{css: `@-moz-foo-keyframes a { from { opacity: 0 } }`, want: "-", errors: nil},
+		{
+			css:    `@keyframes a { 150% { opacity: 0 } to { opacity: 1 } }`,
+			want:   "100%{opacity: 1;}",
+			errors: []string{"keyframe '150%' in @keyframes 'a': '150%' is out of the range 0% to 100%"},
+		},
+		{
+			css:    `@keyframes a { -1%, to { opacity: 0 } }`,
+			want:   "",
+			errors: []string{"'-1%' is out of the range 0% to 100%"},
+		},
+		{
+			css:    `@keyframes a { middle { opacity: 0 } sideways 10% { opacity: 0 } }`,
+			want:   "",
+			errors: []string{"invalid keyframe selector 'middle'", "unknown timeline range 'sideways'"},
+		},
+		{
+			css:    `@keyframes a { from { opacity: 0 } 0% { opacity: 1 } }`,
+			want:   "0%{opacity: 0;} 0%{opacity: 1;}",
+			errors: []string{"keyframe '0%' in @keyframes 'a': duplicate keyframe selector"},
+		},
+		{
+			css:    `@keyframes a { to { opacity: 1 !important; animation-duration: 1s; animation-timing-function: ease } }`,
+			want:   "100%{animation-timing-function: ease;}",
+			errors: []string{"!important declaration of 'opacity' is ignored", "'animation-duration' is ignored in keyframes"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			keyframes, errs := parse_stylesheet(strings.NewReader(test.css)).Keyframes()
+			got := "-"
+			if len(keyframes) == 1 {
+				got = describe_keyframes(keyframes[0].keyframes)
+			}
+			if got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if len(errs) != len(test.errors) {
+				t.Fatalf("got errors %v, want %q", errs, test.errors)
+			}
+			for i, err := range errs {
+				if strings.Contains(err.Error(), test.errors[i]) == false {
+					t.Errorf("got error %q, want one containing %q", err, test.errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestKeyframesMerged(t *testing.T) {
+	tests := []struct {
+		css string
+		// The merged keyframes, as described by describe_keyframes.
+		want string
+	}{
+		{
+			css:  `@keyframes a { to { opacity: 1 } from { opacity: 0 } }`,
+			want: "0%{opacity: 0;} 100%{opacity: 1;}",
+		},
+		{
+			css:  `@keyframes a { from, to { opacity: 0; color: red } 0% { opacity: 1 } }`,
+			want: "0%{color: red; opacity: 1;} 100%{opacity: 0; color: red;}",
+		},
+		{
+			css:  `@keyframes a { 0% { margin-top: 1px } 0% { margin: 0 } 0% { margin-top: 2px } }`,
+			want: "0%{margin: 0; margin-top: 2px;}",
+		},
+		{
+			css:  `@keyframes a { 0% { opacity: 0 } 0% { opacity: 1; animation-timing-function: ease-in } }`,
+			want: "0%{opacity: 0;} 0%{opacity: 1; animation-timing-function: ease-in;}",
+		},
+		{
+			css:  `@keyframes a { exit 50% { opacity: 0 } entry 100% { opacity: 1 } 50% { opacity: 1 } }`,
+			want: "50%{opacity: 1;} entry 100%{opacity: 1;} exit 50%{opacity: 0;}",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			keyframes, errs := parse_stylesheet(strings.NewReader(test.css)).Keyframes()
+			// Duplicated keyframe selectors are reported, but merging them is what they're for.
+			if len(keyframes) != 1 {
+				t.Fatalf("got %d @keyframes rules and errors %v", len(keyframes), errs)
+			}
+			if got := describe_keyframes(keyframes[0].Merged()); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStylesheetKeyframes(t *testing.T) {
+	keyframes, _ := parse_stylesheet(strings.NewReader(`@keyframes a {} @media print { @keyframes b {} } @supports (display: grid) { @-webkit-keyframes c {} }`)).Keyframes()
+	var names []string
+	for _, rule := range keyframes {
+		names = append(names, rule.prefix+rule.name)
+	}
+	if fmt.Sprint(names) != "[a b -webkit-c]" {
+		t.Errorf("got %v", names)
+	}
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-cascade-5/#layer-ordering
+// The tree of cascade layers declared so far in an origin, in the order they were first declared.
+type layer_tree struct {
+	// The layer's position: its index within each of its ancestors, from the outermost layer down.
+	path []int
+	// The dotted name of the layer, empty for the root and anonymous layers.
+	name string
+	// The layer's own segment of the name, empty for the root and anonymous layers.
+	segment  string
+	children map[string]*layer_tree
+	// The sublayers (named and anonymous) declared so far, in the order they were declared.
+	sublayers []*layer_tree
+	// The number of sublayers (named and anonymous) declared so far.
+	count int
+}
+
+func new_layer_tree() *layer_tree {
+	return &layer_tree{children: map[string]*layer_tree{}}
+}
+
+// Find the sublayer with the (possibly dotted) name, declaring each segment that doesn't exist yet.
+func (t *layer_tree) declare(names []string) *layer_tree {
+	node := t
+	for _, name := range names {
+		child, ok := node.children[name]
+		if ok == false {
+			child = node.new_child(name)
+			node.children[name] = child
+		}
+		node = child
+	}
+
+	return node
+}
+
+// Each anonymous layer is a new, unique layer that can't be referenced again.
+func (t *layer_tree) declare_anonymous() *layer_tree {
+	return t.new_child("")
+}
+
+func (t *layer_tree) new_child(name string) *layer_tree {
+	path := append(append([]int{}, t.path...), t.count)
+	t.count += 1
+
+	full_name := name
+	if t.name != "" && name != "" {
+		full_name = t.name + string(FULL_STOP_CHAR) + name
+	}
+
+	child := &layer_tree{path: path, name: full_name, segment: name, children: map[string]*layer_tree{}}
+	t.sublayers = append(t.sublayers, child)
+	return child
+}
+
+// The layers in the tree from lowest to highest precedence, not including the root.
+// A layer's sublayers come before it, as declarations directly in a layer win over those in its sublayers.
+func (t *layer_tree) order() []*layer_tree {
+	var result []*layer_tree
+	for _, sublayer := range t.sublayers {
+		result = append(result, sublayer.order()...)
+		result = append(result, sublayer)
+	}
+
+	return result
+}
+
+// The position of declarations placed directly in this layer.
+// Declarations that aren't in any sublayer come after all of the layer's sublayers, so they sort last at this level.
+func (t *layer_tree) position() []int {
+	return append(append([]int{}, t.path...), math.MaxInt)
+}
+
+// Compare two layer positions, returning a negative number if a comes before b, a positive number if after, and zero if they are equal.
+func compare_layer_positions(a []int, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i += 1 {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return len(a) - len(b)
+}
+
+// https://drafts.csswg.org/css-cascade-5/#layering
+// An @layer rule: either a statement declaring the order of one or more layers, or a block of rules placed in a layer.
+type LayerRule struct {
+	// The segments of each dotted layer name. A block has at most one name, and none when its layer is anonymous.
+	names     [][]string
+	has_block bool
+}
+
+func is_layer_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && strings.EqualFold(rule.name, "layer")
+}
+
+// https://drafts.csswg.org/css-cascade-5/#layer-empty
+// @layer <layer-name>#;
+func parse_layer_statement(prelude []ComponentValue) ([][]string, error) {
+	var names [][]string
+	for _, item := range split_on_commas(prelude) {
+		name, err := parse_layer_name(item)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+
+	return names, nil
+}
+
+// https://drafts.csswg.org/css-cascade-5/#layer-block
+// @layer <layer-name>? { <rule-list> }
+// Returns nil for an anonymous layer.
+func parse_layer_block_name(prelude []ComponentValue) ([]string, error) {
+	if len(trim_whitespace(prelude)) == 0 {
+		return nil, nil
+	}
+
+	return parse_layer_name(prelude)
+}
+
+// Extract the typed model of an @layer rule from its prelude.
+func (r Rule) layer_rule() (LayerRule, error) {
+	if is_layer_rule(r) == false {
+		return LayerRule{}, fmt.Errorf("Parse Error: rule is not an @layer rule")
+	}
+
+	if r.has_block == false {
+		names, err := parse_layer_statement(r.prelude)
+		if err != nil {
+			return LayerRule{}, err
+		}
+		if len(names) == 0 {
+			return LayerRule{}, fmt.Errorf("Parse Error: @layer statement requires at least one layer name")
+		}
+		return LayerRule{names: names}, nil
+	}
+
+	name, err := parse_layer_block_name(r.prelude)
+	if err != nil {
+		return LayerRule{}, err
+	}
+	if name == nil {
+		return LayerRule{has_block: true}, nil
+	}
+
+	return LayerRule{names: [][]string{name}, has_block: true}, nil
+}
+
+// Whether the rule is an @layer block whose layer has no name.
+func (l LayerRule) is_anonymous() bool {
+	return l.has_block && len(l.names) == 0
+}
+
+// https://drafts.csswg.org/css-cascade-5/#layer-ordering
+// The names of the stylesheet's cascade layers, from lowest to highest precedence. Unlayered styles take precedence over all of them.
+// The order is found as if the conditions of all conditional rules (e.g. @media) were true, and layers whose position would
+// be different if they weren't are reported. Anonymous layers are named "<anonymous>", e.g. a layer "a" in one is "<anonymous>.a".
+func (s Stylesheet) LayerOrder() ([]string, []error) {
+	tree := new_layer_tree()
+	declarer := new_layer_declarer()
+	declarer.declare_rules(s.rules, tree, false)
+
+	var walk func(layer *layer_tree, prefix string) []string
+	walk = func(layer *layer_tree, prefix string) []string {
+		var names []string
+		for _, sublayer := range layer.sublayers {
+			name := sublayer.segment
+			if name == "" {
+				name = "<anonymous>"
+			}
+			if prefix != "" {
+				name = prefix + string(FULL_STOP_CHAR) + name
+			}
+			names = append(names, walk(sublayer, name)...)
+			names = append(names, name)
+		}
+		return names
+	}
+
+	return walk(tree, ""), declarer.errs
+}
+
+// Declares the layers of a stylesheet's @layer rules, and @import rules with layers, in a tree.
+// A layer declared inside a conditional rule only exists if the condition is true, so when the layer is declared again later,
+// its position depends on the condition.
+type layer_declarer struct {
+	// The layers first declared inside a conditional rule, until they are declared again.
+	conditional map[*layer_tree]bool
+	errs        []error
+}
+
+func new_layer_declarer() *layer_declarer {
+	return &layer_declarer{conditional: map[*layer_tree]bool{}}
+}
+
+// Declare the layers of the rules in order, including those nested in layers and conditional rules.
+func (d *layer_declarer) declare_rules(rules []Rule, layer *layer_tree, in_condition bool) {
+	for _, rule := range rules {
+		switch {
+		case is_layer_rule(rule), is_import_rule(rule):
+			if sublayer, ok := d.declare_rule(rule, layer, in_condition); ok && is_layer_rule(rule) && rule.has_block {
+				d.declare_rules(rule.children, sublayer, in_condition)
+			}
+		// Layers can be declared by @layer rules nested in style rules.
+		case rule.kind == QUALIFIED_RULE:
+			d.declare_rules(rule.children, layer, in_condition)
+		case rule.kind == AT_RULE && property_context_at_rules[strings.ToLower(rule.name)]:
+			d.declare_rules(rule.children, layer, true)
+		}
+	}
+}
+
+// Declare the layers of an @layer or @import rule, returning the layer a block's contents (or an imported stylesheet) belong to,
+// or false if the rule is invalid or doesn't declare a layer.
+func (d *layer_declarer) declare_rule(rule Rule, layer *layer_tree, in_condition bool) (*layer_tree, bool) {
+	if is_import_rule(rule) {
+		imported, err := rule.import_rule()
+		switch {
+		case err != nil || imported.has_layer == false:
+			return nil, false
+		case len(imported.layer) == 0:
+			return layer.declare_anonymous(), true
+		}
+		return d.declare(layer, imported.layer, in_condition), true
+	}
+
+	model, err := rule.layer_rule()
+	if err != nil {
+		d.errs = append(d.errs, err)
+		return nil, false
+	}
+	if model.is_anonymous() {
+		return layer.declare_anonymous(), true
+	}
+
+	sublayer := layer
+	for _, name := range model.names {
+		sublayer = d.declare(layer, name, in_condition)
+	}
+	if model.has_block == false {
+		return layer, true
+	}
+
+	return sublayer, true
+}
+
+func (d *layer_declarer) declare(layer *layer_tree, name []string, in_condition bool) *layer_tree {
+	node := layer
+	for _, segment := range name {
+		child, ok := node.children[segment]
+		switch {
+		case ok && d.conditional[child]:
+			d.errs = append(d.errs, fmt.Errorf("Layer Error: the position of layer '%s' depends on the condition of the rule it was first declared in", child.name))
+			delete(d.conditional, child)
+		case ok == false:
+			child = node.declare([]string{segment})
+			if in_condition {
+				d.conditional[child] = true
+			}
+		}
+		node = child
+	}
+
+	return node
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestLayerOrder(t *testing.T) {
+	tests := []struct {
+		css  string
+		want []string
+		// The text of the error reported, if any.
+		wantErr string
+	}{
+		{css: `p { color: red }`, want: nil},
+		{css: `@layer a, b; @layer b {} @layer a {}`, want: []string{"a", "b"}},
+		{css: `@layer b {} @layer a {} @layer b {}`, want: []string{"b", "a"}},
+		{css: `@layer a { @layer c, b; } @layer a.b {}`, want: []string{"a.c", "a.b", "a"}},
+		{css: `@layer a.b {} @layer c {}`, want: []string{"a.b", "a", "c"}},
+		{css: `@layer { @layer a {} } @layer {}`, want: []string{"<anonymous>.a", "<anonymous>", "<anonymous>"}},
+		{css: `@import url(x.css) layer(b); @layer a {}`, want: []string{"b", "a"}},
+		{css: `@import url(x.css) layer; @layer a {}`, want: []string{"<anonymous>", "a"}},
+		{css: `p { @layer a { color: red } }`, want: []string{"a"}},
+		{css: `@media print { @layer b {} } @layer a {} @layer b {}`, want: []string{"b", "a"}, wantErr: "the position of layer 'b' depends on the condition"},
+		{css: `@layer a.;`, want: nil, wantErr: "Parse Error"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			order, errs := parse_stylesheet(strings.NewReader(test.css)).LayerOrder()
+			if fmt.Sprint(order) != fmt.Sprint(test.want) {
+				t.Errorf("got %q, want %q", order, test.want)
+			}
+			switch {
+			case test.wantErr == "" && len(errs) > 0:
+				t.Errorf("unexpected errors: %v", errs)
+			case test.wantErr != "" && (len(errs) != 1 || strings.Contains(errs[0].Error(), test.wantErr) == false):
+				t.Errorf("expected an error containing %q, got %v", test.wantErr, errs)
+			}
+		})
+	}
+}
@@ -390,6 +390,8 @@ type Tokenizer struct {
 	input  []rune
 	length int
 	index  int
+	// Whether <unicode-range-token>s are produced, which is only the case when re-reading the value of a unicode-range descriptor.
+	unicode_ranges_allowed bool
 }
 
 func NewTokenizer(input []rune) *Tokenizer {
@@ -541,6 +543,9 @@ type Token struct {
 	// If the ending code point is before the starting code point, it represents an empty range.
 	range_start rune
 	range_end   rune
+	// The indexes of the input code points the token was consumed from, so that its original text can be read again.
+	start int
+	end   int
 }
 
 func (t Token) String() string {
@@ -576,11 +581,16 @@ func (t *Tokenizer) Tokenize() []Token {
 }
 
 // https://drafts.csswg.org/css-syntax/#consume-token
-func (t *Tokenizer) ConsumeToken() Token {
+func (t *Tokenizer) ConsumeToken() (token Token) {
 	// Additionally takes an optional boolean unicode ranges allowed, defaulting to false.
-	unicode_ranges_allowed := false
+	unicode_ranges_allowed := t.unicode_ranges_allowed
 	// Consume comments.
 	t.consume_comments()
+	// The token starts after the comments, and ends wherever consuming it stops.
+	start := t.index + 1
+	defer func() {
+		token.start, token.end = start, t.index+1
+	}()
 	// Consume the next input code point.
 	char := t.consume_next()
 	switch {
@@ -726,9 +736,9 @@ func (t *Tokenizer) ConsumeToken() Token {
 	case char == UPPER_U_CHAR, char == LOWER_U_CHAR:
 		// If unicode ranges allowed is true and the input stream would start a unicode-range,
 		// reconsume the current input code point, consume a unicode-range token, and return it.
-		// @TODO: implement unicode ranges
-		if unicode_ranges_allowed {
-			log.Fatal("Parse Error: Unicode Ranges are currently not supported")
+		if unicode_ranges_allowed && t.starts_unicode_range() {
+			t.reconsume_current()
+			return t.consume_unicode_range_token()
 		}
 		// Otherwise, reconsume the current input code point, consume an ident-like token, and return it.
 		t.reconsume_current()
@@ -748,6 +758,67 @@ func (t *Tokenizer) ConsumeToken() Token {
 	}
 }
 
+// https://drafts.csswg.org/css-syntax/#would-start-a-unicode-range
+func (t *Tokenizer) starts_unicode_range() bool {
+	// The first code point is either U+0055 LATIN CAPITAL LETTER U (U) or U+0075 LATIN SMALL LETTER U (u), the second is U+002B PLUS SIGN (+),
+	// and the third is either U+003F QUESTION MARK (?) or a hex digit.
+	first, second, third := t.current_rune(), t.next_rune(), t.second_rune()
+	return (first == UPPER_U_CHAR || first == LOWER_U_CHAR) && second == PLUS_SIGN_CHAR && (third == '?' || is_hex_digit(third))
+}
+
+// https://drafts.csswg.org/css-syntax/#consume-unicode-range-token
+func (t *Tokenizer) consume_unicode_range_token() Token {
+	// Consume two input code points and discard them.
+	t.consume_runes(2)
+	// Consume as many hex digits as possible, but no more than 6.
+	var digits []rune
+	for len(digits) < 6 && is_hex_digit(t.next_rune()) {
+		digits = append(digits, t.consume_next())
+	}
+	// If less than 6 hex digits were consumed, consume as many U+003F QUESTION MARK (?) code points as possible,
+	// but no more than enough to make the total of hex digits and U+003F QUESTION MARK (?) code points equal to 6.
+	question_marks := false
+	for len(digits) < 6 && t.next_rune() == '?' {
+		digits = append(digits, t.consume_next())
+		question_marks = true
+	}
+
+	// If any U+003F QUESTION MARK (?) code points were consumed, then:
+	if question_marks {
+		// Interpret the consumed code points as a hexadecimal number, with the U+003F QUESTION MARK (?) code points replaced by U+0030 DIGIT ZERO (0) code points.
+		// This is the start of the range.
+		// Interpret the consumed code points as a hexadecimal number again, with the U+003F QUESTION MARK (?) code point replaced by U+0046 LATIN CAPITAL LETTER F (F) code points.
+		// This is the end of the range.
+		start := parse_hex_code_point(strings.ReplaceAll(string(digits), "?", "0"))
+		end := parse_hex_code_point(strings.ReplaceAll(string(digits), "?", "F"))
+		// Return a new <unicode-range-token> with the above start and end.
+		return Token{kind: UNICODE_RANGE_TOKEN, range_start: start, range_end: end}
+	}
+
+	// Otherwise, interpret the digits as a hexadecimal number. This is the start of the range.
+	start := parse_hex_code_point(string(digits))
+	end := start
+	// If the next 2 input code point are U+002D HYPHEN-MINUS (-) followed by a hex digit, then:
+	if t.next_rune() == HYPHEN_MINUS_CHAR && is_hex_digit(t.second_rune()) {
+		// Consume the next input code point.
+		t.consume_next()
+		// Consume as many hex digits as possible, but no more than 6. Interpret the digits as a hexadecimal number. This is the end of the range.
+		digits = nil
+		for len(digits) < 6 && is_hex_digit(t.next_rune()) {
+			digits = append(digits, t.consume_next())
+		}
+		end = parse_hex_code_point(string(digits))
+	}
+	// Return a new <unicode-range-token> with the above start and end.
+	return Token{kind: UNICODE_RANGE_TOKEN, range_start: start, range_end: end}
+}
+
+// At most six hex digits, so the number always fits.
+func parse_hex_code_point(digits string) rune {
+	value, _ := strconv.ParseInt(digits, 16, 32)
+	return rune(value)
+}
+
 func (t *Tokenizer) peek_rune(number int) rune {
 	index := t.index + number
 	if index < 0 || index >= t.length {
@@ -1239,6 +1310,19 @@ type TokenStream struct {
 	index int
 	// A stack of index values, representing points that the parser might return to. It starts empty initially.
 	marked_indexes Stack[int]
+	// The lowercased names of the at-rules whose blocks are being consumed, innermost last, where a style rule's block is "".
+	// Declarations are only validated against the property grammars where they are properties rather than descriptors.
+	block_contexts []string
+	// Whether declarations are validated against the property grammars, dropping (and reporting) the invalid ones.
+	// Only set when parsing a stylesheet or a style attribute: a declaration re-parsed from e.g. an @supports condition is
+	// judged by its caller.
+	validate_declarations bool
+	// The code points the tokens were consumed from, when known, for re-reading the original text of a unicode-range descriptor
+	// or a custom property's value.
+	source []rune
+	// The namespaces declared by the @namespace rules consumed so far, keyed by prefix, with "" for the default namespace.
+	// They all come before the first style rule, whose selectors may only use the prefixes declared.
+	namespaces map[string]string
 }
 
 func NewTokenStream(tokens []Token) TokenStream {
@@ -1312,6 +1396,115 @@ func (ts *TokenStream) discard_whitespace() {
 	}
 }
 
+// A component value stream is the token stream counterpart for lists of component values that have already been parsed,
+// e.g. a rule's prelude or a declaration's value. It is used by the grammars that are defined on top of the core syntax.
+type ComponentValueStream struct {
+	values []ComponentValue
+	length int
+	index  int
+	// A stack of index values, representing points that the parser might return to. It starts empty initially.
+	marked_indexes Stack[int]
+}
+
+func NewComponentValueStream(values []ComponentValue) ComponentValueStream {
+	return ComponentValueStream{
+		values:         values,
+		length:         len(values),
+		index:          0,
+		marked_indexes: NewStack[int](),
+	}
+}
+
+// The item of values at index. If that index would be out-of-bounds past the end of the list, it's instead an <eof-token>.
+func (cs *ComponentValueStream) next_value() ComponentValue {
+	if cs.index >= cs.length {
+		return ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: EOF_TOKEN}}
+	}
+
+	return cs.values[cs.index]
+}
+
+// A component value stream is empty if the next value is an <eof-token>.
+func (cs *ComponentValueStream) empty() bool {
+	return cs.index >= cs.length
+}
+
+func (cs *ComponentValueStream) consume_value() ComponentValue {
+	value := cs.next_value()
+	cs.index += 1
+	return value
+}
+
+func (cs *ComponentValueStream) discard_value() {
+	if cs.empty() == false {
+		cs.index += 1
+	}
+}
+
+func (cs *ComponentValueStream) mark() {
+	cs.marked_indexes.Push(cs.index)
+}
+
+func (cs *ComponentValueStream) restore_mark() {
+	value, ok := cs.marked_indexes.Pop()
+	if ok {
+		cs.index = value
+	}
+}
+
+func (cs *ComponentValueStream) discard_mark() {
+	cs.marked_indexes.Pop()
+}
+
+func (cs *ComponentValueStream) discard_whitespace() {
+	for cs.empty() == false && cs.next_value().is_token(WHITESPACE_TOKEN) {
+		cs.discard_value()
+	}
+}
+
+// The values that have not yet been consumed.
+func (cs *ComponentValueStream) remaining() []ComponentValue {
+	if cs.index >= cs.length {
+		return nil
+	}
+
+	return cs.values[cs.index:]
+}
+
+// Flatten a list of component values back into the tokens they were consumed from,
+// so that the token-level parsing algorithms (e.g. consume a declaration) can be re-run over them.
+func tokens_from_component_values(list []ComponentValue) []Token {
+	var tokens []Token
+	for _, elem := range list {
+		switch elem.kind {
+		case PRESERVED_TOKEN:
+			tokens = append(tokens, elem.token)
+		case FUNCTION:
+			tokens = append(tokens, Token{kind: FUNCTION_TOKEN, value: []rune(elem.name)})
+			tokens = append(tokens, tokens_from_component_values(elem.value)...)
+			tokens = append(tokens, Token{kind: CLOSE_PAREN_TOKEN})
+		case SIMPLE_BLOCK:
+			tokens = append(tokens, elem.token)
+			tokens = append(tokens, tokens_from_component_values(elem.value)...)
+			tokens = append(tokens, Token{kind: mirror(elem.token.kind)})
+		}
+	}
+
+	return tokens
+}
+
+// Remove any leading and trailing <whitespace-token>s from a list of component values.
+func trim_whitespace(list []ComponentValue) []ComponentValue {
+	for len(list) > 0 && list[0].is_token(WHITESPACE_TOKEN) {
+		list = list[1:]
+	}
+	for len(list) > 0 && list[len(list)-1].is_token(WHITESPACE_TOKEN) {
+		list = list[:len(list)-1]
+	}
+
+	return list
+}
+
 func parse_stylesheet(input io.Reader) Stylesheet {
 	bytes, err := io.ReadAll(input)
 	if err != nil {
@@ -1322,14 +1515,28 @@ func parse_stylesheet(input io.Reader) Stylesheet {
 	tokens := NewTokenizer(code_points).Tokenize()
 
 	token_stream := NewTokenStream(tokens)
+	token_stream.source = code_points
+	token_stream.validate_declarations = true
 	rules := token_stream.consume_stylesheet_contents()
 
-	return Stylesheet{rules: rules}
+	return Stylesheet{rules: rules, namespaces: token_stream.namespaces}
+}
+
+// https://drafts.csswg.org/css-syntax/#parse-list-of-component-values
+func parse_component_value_list(input string) []ComponentValue {
+	// Normalize input, and set input to the result.
+	code_points := preprocess_input_stream([]byte(input))
+	token_stream := NewTokenStream(NewTokenizer(code_points).Tokenize())
+	// Consume a list of component values from input, and return the result.
+	return token_stream.consume_component_value_list(false)
 }
 
 // https://drafts.csswg.org/css-syntax/#css-stylesheet
 type Stylesheet struct {
 	rules []Rule
+	// https://drafts.csswg.org/css-namespaces/#prefixes
+	// The namespace URLs declared by the stylesheet's @namespace rules, keyed by prefix, with "" for the default namespace.
+	namespaces map[string]string
 }
 
 // https://drafts.csswg.org/css-syntax/#css-rule
@@ -1342,6 +1549,8 @@ type Rule struct {
 	// <at-rule>, <qualified_rule>
 	decls    []Declaration
 	children []Rule
+	// <at-rule>: whether the rule ended in a {}-block rather than a semicolon.
+	has_block bool
 }
 
 type RuleKind uint8
@@ -1350,6 +1559,10 @@ type RuleKind uint8
 const (
 	AT_RULE RuleKind = iota
 	QUALIFIED_RULE
+	// https://drafts.csswg.org/css-nesting/#nested-declarations-rule
+	// The declarations of a style rule that follow a nested rule, or that are directly inside a group rule nested in a style rule.
+	// It has no prelude, and applies its declarations to the elements its parent style rule matches.
+	NESTED_DECLARATIONS_RULE
 )
 
 func (k RuleKind) String() string {
@@ -1358,6 +1571,8 @@ func (k RuleKind) String() string {
 		return "AT_RULE"
 	case QUALIFIED_RULE:
 		return "QUALIFIED_RULE"
+	case NESTED_DECLARATIONS_RULE:
+		return "NESTED_DECLARATIONS_RULE"
 	}
 	return "<UNKNOWN RULE>"
 }
@@ -1419,6 +1634,9 @@ type ComponentValue struct {
 	name string
 	// <function>, <simple-block>
 	value []ComponentValue
+	// The indexes of the input code points the component value was consumed from, so that its original text can be read again.
+	start int
+	end   int
 }
 
 type ComponentValueKind uint8
@@ -1457,12 +1675,39 @@ func (v ComponentValue) String() string {
 	return sb.String()
 }
 
+func (v ComponentValue) is_token(kind TokenKind) bool {
+	return v.kind == PRESERVED_TOKEN && v.token.kind == kind
+}
+
+// Check if the value is an <ident-token> that is an ASCII case-insensitive match for name.
+func (v ComponentValue) is_ident(name string) bool {
+	return v.is_token(IDENT_TOKEN) && strings.EqualFold(string(v.token.value), name)
+}
+
+// Check if the value is a <delim-token> with the given code point.
+func (v ComponentValue) is_delim(char rune) bool {
+	return v.is_token(DELIM_TOKEN) && len(v.token.value) == 1 && v.token.value[0] == char
+}
+
+// Check if the value is a simple block whose associated token is of the given kind.
+func (v ComponentValue) is_block(kind TokenKind) bool {
+	return v.kind == SIMPLE_BLOCK && v.token.kind == kind
+}
+
+// Check if the value is a function whose name is an ASCII case-insensitive match for name.
+func (v ComponentValue) is_function(name string) bool {
+	return v.kind == FUNCTION && strings.EqualFold(v.name, name)
+}
+
 // https://drafts.csswg.org/css-syntax/#declaration
 type Declaration struct {
 	name          string
 	value         []ComponentValue
 	important     bool
 	original_text string
+	// Set on the longhands of a shorthand whose value contains var(): the name of the shorthand.
+	// The longhand's value is then the shorthand's whole value, pending substitution.
+	pending_shorthand string
 }
 
 func (d Declaration) String() string {
@@ -1480,19 +1725,40 @@ func (d Declaration) String() string {
 	return sb.String()
 }
 
-func (d Declaration) is_valid() bool {
-	// @TODO: implement this properly.
-	return true
-}
-
+// https://drafts.csswg.org/css-variables/#custom-property
+// A name starting with two dashes, except "--" itself, which is reserved.
 func is_custom_property_name(name string) bool {
-	// @TODO: implement
-	return false
+	return len(name) > 2 && strings.HasPrefix(name, "--")
 }
 
 func is_unicode_range(name string) bool {
-	// @TODO: implement
-	return false
+	return strings.EqualFold(name, "unicode-range")
+}
+
+// The segment of the original source text corresponding to the component values.
+// When the source text isn't known, it's the serialization of the values instead.
+func (ts *TokenStream) original_text(value []ComponentValue) string {
+	if ts.source == nil || len(value) == 0 {
+		return serialize_component_value_list(value)
+	}
+
+	return string(ts.source[value[0].start:value[len(value)-1].end])
+}
+
+// https://drafts.csswg.org/css-syntax/#consume-unicode-range-value
+// Tokenize the original text of the value again with unicode ranges allowed, as e.g. "U+0-7F" is otherwise an ident and numbers.
+// When the source text isn't known, the value is kept as it is.
+func (ts *TokenStream) consume_unicode_range_value(value []ComponentValue) []ComponentValue {
+	if ts.source == nil || len(value) == 0 || value[0].kind != PRESERVED_TOKEN || value[len(value)-1].kind != PRESERVED_TOKEN {
+		return value
+	}
+
+	// Let tokens be the result of tokenizing input with unicode ranges allowed set to true.
+	tokenizer := NewTokenizer(ts.source[value[0].token.start:value[len(value)-1].token.end])
+	tokenizer.unicode_ranges_allowed = true
+	// Consume a list of component values from tokens, and return the result.
+	stream := NewTokenStream(tokenizer.Tokenize())
+	return stream.consume_component_value_list(false)
 }
 
 func ends_with_important(list []ComponentValue) bool {
@@ -1524,14 +1790,31 @@ func ends_with_important(list []ComponentValue) bool {
 }
 
 func contains_non_empty_block(list []ComponentValue) bool {
-	// @TODO: implement
-	return false
+	// Return true if the list has a top-level {}-block, and also any other non-<whitespace-token> value.
+	// (e.g. a nested rule's prelude and block, like "p::before { ... }", which at first looks like a declaration.)
+	has_block := false
+	count := 0
+	for _, value := range list {
+		if value.is_token(WHITESPACE_TOKEN) {
+			continue
+		}
+		if value.is_block(OPEN_CURLY_TOKEN) {
+			has_block = true
+		}
+		count += 1
+	}
+
+	return has_block && count > 1
 }
 
 // https://drafts.csswg.org/css-syntax/#consume-stylesheet-contents
 func (ts *TokenStream) consume_stylesheet_contents() []Rule {
 	// Let rules be an initially empty list of rules.
 	var rules []Rule
+	// @import rules are only valid until any other rule is seen, besides @charset and @layer statements.
+	imports_allowed := true
+	// @namespace rules are only valid until any other rule is seen, besides @charset, @import and @layer statements.
+	namespaces_allowed := true
 
 	for {
 		switch next := ts.next_token(); next.kind {
@@ -1548,8 +1831,26 @@ func (ts *TokenStream) consume_stylesheet_contents() []Rule {
 		case AT_KEYWORD_TOKEN:
 			// Consume an at-rule from input. If anything is returned, append it to rules.
 			rule, ok := ts.consume_at_rule_default()
+			if ok && is_import_rule(rule) {
+				if imports_allowed == false {
+					fmt.Println("Parse Error: Encountered @import rule after other rules")
+					ok = false
+				} else {
+					ok = is_valid_import_rule(rule)
+				}
+			}
+			if ok && is_namespace_rule(rule) {
+				if namespaces_allowed == false {
+					fmt.Println("Parse Error: Encountered @namespace rule after other rules")
+					ok = false
+				} else {
+					ok = ts.declare_namespace(rule)
+				}
+			}
 			if ok {
 				rules = append(rules, rule)
+				imports_allowed = imports_allowed && allows_following_import(rule)
+				namespaces_allowed = namespaces_allowed && allows_following_namespace(rule)
 			}
 		// anything else
 		default:
@@ -1557,6 +1858,8 @@ func (ts *TokenStream) consume_stylesheet_contents() []Rule {
 			rule, ok := ts.consume_qualified_rule_default()
 			if ok {
 				rules = append(rules, rule)
+				imports_allowed = false
+				namespaces_allowed = false
 			}
 		}
 	}
@@ -1594,9 +1897,12 @@ func (ts *TokenStream) consume_at_rule(nested bool) (Rule, bool) {
 		// <{-token>
 		case OPEN_CURLY_TOKEN:
 			// Consume a block from input, and assign the results to rule’s lists of declarations and child rules.
+			ts.block_contexts = append(ts.block_contexts, strings.ToLower(rule.name))
 			decls, children := ts.consume_block()
+			ts.block_contexts = ts.block_contexts[:len(ts.block_contexts)-1]
 			rule.decls = decls
 			rule.children = children
+			rule.has_block = true
 			// If rule is valid in the current context, return it. Otherwise, return nothing.
 			return rule, rule.is_valid()
 		// anything else
@@ -1653,9 +1959,17 @@ func (ts *TokenStream) consume_qualified_rule(nested bool, params ...TokenKind)
 				return rule, false
 			} else {
 				// Otherwise, consume a block from input, and assign the results to rule’s lists of declarations and child rules.
+				ts.block_contexts = append(ts.block_contexts, "")
 				decls, rules := ts.consume_block()
+				ts.block_contexts = ts.block_contexts[:len(ts.block_contexts)-1]
 				rule.decls = decls
 				rule.children = rules
+				// https://drafts.csswg.org/css-namespaces/#syntax
+				// A style rule whose selectors use a namespace prefix that wasn't declared is invalid.
+				if prefix, ok := ts.undeclared_namespace_prefix(rule); ok {
+					fmt.Printf("Parse Error: Encountered undeclared namespace prefix '%s' in selector\n", prefix)
+					return rule, false
+				}
 				// If rule is valid in the current context, return it; otherwise return nothing.
 				return rule, rule.is_valid()
 			}
@@ -1709,6 +2023,16 @@ func (ts *TokenStream) consume_block_contents() ([]Declaration, []Rule) {
 		case AT_KEYWORD_TOKEN:
 			// Consume an at-rule from input, with nested set to true. If a rule was returned, append it to rules.
 			rule, ok := ts.consume_at_rule(true)
+			// @import rules are only valid at the top level of a stylesheet.
+			if ok && is_import_rule(rule) {
+				fmt.Println("Parse Error: Encountered @import rule inside a block")
+				ok = false
+			}
+			// As are @namespace rules.
+			if ok && is_namespace_rule(rule) {
+				fmt.Println("Parse Error: Encountered @namespace rule inside a block")
+				ok = false
+			}
 			if ok {
 				rules = append(rules, rule)
 			}
@@ -1721,7 +2045,14 @@ func (ts *TokenStream) consume_block_contents() ([]Declaration, []Rule) {
 			decl, ok := ts.consume_declaration(true)
 			// If a declaration was returned, append it to decls, and discard a mark from input.
 			if ok {
-				decls = append(decls, decl)
+				// https://drafts.csswg.org/css-nesting/#nested-declarations-rule
+				// Declarations after a nested rule, and declarations in a group rule nested in a style rule,
+				// are wrapped in nested declarations rules, which keeps their order relative to the nested rules.
+				if ts.in_nested_group_rule() || (len(rules) > 0 && ts.in_style_rule()) {
+					rules = append_nested_declaration(rules, decl)
+				} else {
+					decls = append(decls, decl)
+				}
 				ts.discard_mark()
 			} else {
 				// Otherwise, restore a mark from input, then consume a qualified rule from input, with nested set to true, and <semicolon-token> as the stop token.
@@ -1765,6 +2096,8 @@ func (ts *TokenStream) consume_declaration(nested bool) (Declaration, bool) {
 	//    followed by an <ident-token> with a value that is an ASCII case-insensitive match for "important",
 	//    remove them from decl’s value and set decl’s important flag.
 	if ends_with_important(decl.value) {
+		// The value may still end in whitespace when the declaration isn't followed by a semicolon.
+		decl.value = trim_whitespace(decl.value)
 		decl.value = decl.value[:len(decl.value)-2]
 		decl.important = true
 	}
@@ -1774,7 +2107,7 @@ func (ts *TokenStream) consume_declaration(nested bool) (Declaration, bool) {
 	}
 	// 8. If decl’s name is a custom property name string, then set decl’s original text to the segment of the original source text string corresponding to the tokens of decl’s value.
 	if is_custom_property_name(decl.name) {
-		// @TODO
+		decl.original_text = ts.original_text(decl.value)
 	} else if contains_non_empty_block(decl.value) {
 		// Otherwise, if decl’s value contains a top-level simple block with an associated token of <{-token>, and also contains any other non-<whitespace-token> value, return nothing.
 		// (That is, a top-level {}-block is only allowed as the entire value of a non-custom property.)
@@ -1783,11 +2116,63 @@ func (ts *TokenStream) consume_declaration(nested bool) (Declaration, bool) {
 		// Otherwise, if decl’s name is an ASCII case-insensitive match for "unicode-range",
 		// consume the value of a unicode-range descriptor from the segment of the original source text string corresponding to the tokens returned by the consume a list of component values call,
 		// and replace decl’s value with the result.
-		// @TODO: We currently do not support unicode ranges
-		panic("Parse Error: Unicode Ranges are currently not supported")
+		decl.value = ts.consume_unicode_range_value(decl.value)
 	}
 	// 9. If decl is valid in the current context, return it; otherwise return nothing.
-	return decl, decl.is_valid()
+	if ts.validate_declarations && ts.in_property_context() {
+		if err := ValidateDeclaration(decl); err != nil {
+			fmt.Println(err)
+			return decl, false
+		}
+	}
+
+	return decl, true
+}
+
+// The at-rules whose declarations are properties that apply to the elements their rules match.
+var property_context_at_rules = map[string]bool{
+	"media": true, "supports": true, "layer": true, "container": true, "scope": true, "starting-style": true,
+}
+
+// Whether the declarations being consumed are properties, i.e. in a style rule, a conditional group rule or a style attribute,
+// rather than the descriptors of an at-rule such as @font-face.
+func (ts *TokenStream) in_property_context() bool {
+	if len(ts.block_contexts) == 0 {
+		return true
+	}
+	context := ts.block_contexts[len(ts.block_contexts)-1]
+
+	return context == "" || property_context_at_rules[context]
+}
+
+// Whether the block being consumed is a style rule's.
+func (ts *TokenStream) in_style_rule() bool {
+	return len(ts.block_contexts) > 0 && ts.block_contexts[len(ts.block_contexts)-1] == ""
+}
+
+// Whether the block being consumed is a group rule's (e.g. @media), nested in a style rule, possibly through other group rules.
+func (ts *TokenStream) in_nested_group_rule() bool {
+	for i := len(ts.block_contexts) - 1; i >= 0; i -= 1 {
+		context := ts.block_contexts[i]
+		if context == "" {
+			return i < len(ts.block_contexts)-1
+		}
+		if property_context_at_rules[context] == false {
+			return false
+		}
+	}
+
+	return false
+}
+
+// Append the declaration to the nested declarations rule at the end of rules, starting a new one if the last rule isn't one.
+func append_nested_declaration(rules []Rule, decl Declaration) []Rule {
+	if len(rules) > 0 && rules[len(rules)-1].kind == NESTED_DECLARATIONS_RULE {
+		rules[len(rules)-1].decls = append(rules[len(rules)-1].decls, decl)
+		return rules
+	}
+
+	return append(rules, Rule{kind: NESTED_DECLARATIONS_RULE, decls: []Declaration{decl}})
 }
 
 func (ts *TokenStream) consume_bad_declaration_remnants(nested bool) {
@@ -1846,7 +2231,15 @@ func (ts *TokenStream) consume_component_value_list(nested bool, params ...Token
 }
 
 // https://drafts.csswg.org/css-syntax/#consume-component-value
-func (ts *TokenStream) consume_component_value() ComponentValue {
+func (ts *TokenStream) consume_component_value() (value ComponentValue) {
+	// The component value starts at the next token, and ends with the last token consumed for it.
+	start := ts.next_token().start
+	defer func() {
+		value.start, value.end = start, start
+		if last := min(ts.index, ts.length) - 1; last >= 0 {
+			value.end = ts.tokens[last].end
+		}
+	}()
 	for {
 		switch next := ts.next_token(); next.kind {
 		// <{-token>, <[-token>, <(-token>
@@ -1944,27 +2337,35 @@ func stringify_rule(sb *strings.Builder, rule Rule) {
 		sb.WriteString(fmt.Sprintf("%c%s", AT_CHAR, rule.name))
 		stringify_component_value_list(sb, rule.prelude)
 
-		if len(rule.children) > 0 {
+		if len(rule.children) > 0 || rule.has_block {
 			sb.WriteString(fmt.Sprintf("%c%c", OPEN_CURLY_CHAR, LINE_FEED_CHAR))
-			for _, child_rule := range rule.children {
-				stringify_rule(sb, child_rule)
-			}
+			stringify_block_contents(sb, rule)
 			sb.WriteString(fmt.Sprintf("%c%c", CLOSE_CURLY_CHAR, LINE_FEED_CHAR))
 		} else {
 			sb.WriteRune(SEMICOLON_CHAR)
 		}
 
+	} else if rule.kind == NESTED_DECLARATIONS_RULE {
+		// A nested declarations rule serializes as its bare declarations.
+		stringify_block_contents(sb, rule)
 	} else {
 		stringify_component_value_list(sb, rule.prelude)
 		sb.WriteString(fmt.Sprintf("%c%c", OPEN_CURLY_CHAR, LINE_FEED_CHAR))
-		for _, decl := range rule.decls {
-			stringify_declaration(sb, decl)
-			sb.WriteRune(LINE_FEED_CHAR)
-		}
+		stringify_block_contents(sb, rule)
 		sb.WriteString(fmt.Sprintf("%c%c", CLOSE_CURLY_CHAR, LINE_FEED_CHAR))
 	}
 }
 
+func stringify_block_contents(sb *strings.Builder, rule Rule) {
+	for _, decl := range rule.decls {
+		stringify_declaration(sb, decl)
+		sb.WriteRune(LINE_FEED_CHAR)
+	}
+	for _, child_rule := range rule.children {
+		stringify_rule(sb, child_rule)
+	}
+}
+
 func stringify_declaration(sb *strings.Builder, decl Declaration) {
 	sb.WriteString(fmt.Sprintf("%s%c%c", decl.name, COLON_CHAR, SPACE_CHAR))
 	stringify_component_value_list(sb, decl.value)
@@ -1980,7 +2381,7 @@ func stringify_preserved_token(sb *strings.Builder, token Token) {
 	case IDENT_TOKEN, DELIM_TOKEN:
 		sb.WriteString(string(token.value))
 	case STRING_TOKEN:
-		sb.WriteString(fmt.Sprintf("%c%s%c", QUOTATION_MARK_CHAR, string(token.value), QUOTATION_MARK_CHAR))
+		stringify_string(sb, string(token.value))
 	case DIMENSION_TOKEN:
 		stringify_numeric(sb, token)
 		sb.WriteString(string(token.unit))
@@ -1989,7 +2390,8 @@ func stringify_preserved_token(sb *strings.Builder, token Token) {
 	case NUMBER_TOKEN:
 		stringify_numeric(sb, token)
 	case HASH_TOKEN:
-		sb.WriteString(fmt.Sprintf("%c%s", NUMBER_SIGN_CHAR, string(token.value)))
+		sb.WriteRune(NUMBER_SIGN_CHAR)
+		stringify_name(sb, string(token.value))
 	case URL_TOKEN:
 		sb.WriteString(fmt.Sprintf("url%c%s%c", OPEN_PAREN_CHAR, string(token.value), CLOSE_PAREN_CHAR))
 	case COMMA_TOKEN:
@@ -1997,6 +2399,11 @@ func stringify_preserved_token(sb *strings.Builder, token Token) {
 	case PERCENTAGE_TOKEN:
 		stringify_numeric(sb, token)
 		sb.WriteRune(PERCENT_SIGN_CHAR)
+	case UNICODE_RANGE_TOKEN:
+		sb.WriteString(fmt.Sprintf("U+%X", token.range_start))
+		if token.range_end != token.range_start {
+			sb.WriteString(fmt.Sprintf("-%X", token.range_end))
+		}
 	case OPEN_SQUARE_TOKEN:
 		sb.WriteRune(OPEN_SQUARE_CHAR)
 	case CLOSE_SQUARE_TOKEN:
@@ -2007,10 +2414,26 @@ func stringify_preserved_token(sb *strings.Builder, token Token) {
 		sb.WriteRune(CLOSE_PAREN_CHAR)
 	case COLON_TOKEN:
 		sb.WriteRune(COLON_CHAR)
+	case SEMICOLON_TOKEN:
+		sb.WriteRune(SEMICOLON_CHAR)
+	case OPEN_CURLY_TOKEN:
+		sb.WriteRune(OPEN_CURLY_CHAR)
+	case CLOSE_CURLY_TOKEN:
+		sb.WriteRune(CLOSE_CURLY_CHAR)
+	case AT_KEYWORD_TOKEN:
+		sb.WriteString(fmt.Sprintf("%c%s", AT_CHAR, string(token.value)))
+	case CDO_TOKEN:
+		sb.WriteString("<!--")
+	case CDC_TOKEN:
+		sb.WriteString("-->")
 	}
 }
 
 func stringify_numeric(sb *strings.Builder, token Token) {
+	// A leading "+" is part of the value (e.g. in "2n+1"), so it must be preserved.
+	if len(token.sign) > 0 && token.sign[0] == PLUS_SIGN_CHAR {
+		sb.WriteRune(PLUS_SIGN_CHAR)
+	}
 	if token.type_flag == TYPE_INTEGER {
 		sb.WriteString(fmt.Sprintf("%d", int(token.numeric)))
 	} else {
@@ -2020,12 +2443,7 @@ func stringify_numeric(sb *strings.Builder, token Token) {
 
 func stringify_function(sb *strings.Builder, function ComponentValue) {
 	sb.WriteString(fmt.Sprintf("%s%c", function.name, OPEN_PAREN_CHAR))
-	for _, func_value := range function.value {
-		switch func_value.kind {
-		case PRESERVED_TOKEN:
-			stringify_preserved_token(sb, func_value.token)
-		}
-	}
+	stringify_component_value_list(sb, function.value)
 	sb.WriteRune(CLOSE_PAREN_CHAR)
 }
 
@@ -2043,3 +2461,103 @@ func stringify_component_value_list(sb *strings.Builder, list []ComponentValue)
 		}
 	}
 }
+
+func serialize_component_value_list(list []ComponentValue) string {
+	var sb strings.Builder
+	stringify_component_value_list(&sb, list)
+	return sb.String()
+}
+
+// https://drafts.csswg.org/cssom/#serialize-a-string
+func stringify_string(sb *strings.Builder, str string) {
+	// The return value of serializing a string is the string enclosed in double quotes,
+	// with any '"' and '\' escaped with a preceding '\', and control characters escaped as code points.
+	sb.WriteRune(QUOTATION_MARK_CHAR)
+	for _, char := range str {
+		switch {
+		case char == NULL_CHAR:
+			sb.WriteRune(REPLACEMENT_CHAR)
+		case (char >= '\u0001' && char <= '\u001F') || char == DELETE_CHAR:
+			sb.WriteString(fmt.Sprintf("%c%x%c", BACKWARD_SLASH_CHAR, char, SPACE_CHAR))
+		case char == QUOTATION_MARK_CHAR, char == BACKWARD_SLASH_CHAR:
+			sb.WriteRune(BACKWARD_SLASH_CHAR)
+			sb.WriteRune(char)
+		default:
+			sb.WriteRune(char)
+		}
+	}
+	sb.WriteRune(QUOTATION_MARK_CHAR)
+}
+
+// https://drafts.csswg.org/cssom/#serialize-an-identifier
+func stringify_identifier(sb *strings.Builder, ident string) {
+	runes := []rune(ident)
+	for i, char := range runes {
+		switch {
+		// If the character is NULL (U+0000), then the REPLACEMENT CHARACTER (U+FFFD).
+		case char == NULL_CHAR:
+			sb.WriteRune(REPLACEMENT_CHAR)
+		// If the character is in the range [\1-\1f] (U+0001 to U+001F) or is U+007F, then the character escaped as code point.
+		case (char >= '\u0001' && char <= '\u001F') || char == DELETE_CHAR:
+			sb.WriteString(fmt.Sprintf("%c%x%c", BACKWARD_SLASH_CHAR, char, SPACE_CHAR))
+		// If the character is the first character and is in the range [0-9] (U+0030 to U+0039),
+		// or is the second character, in the range [0-9], and the first character is a "-" (U+002D), then the character escaped as code point.
+		case (i == 0 && is_digit(char)) || (i == 1 && is_digit(char) && runes[0] == HYPHEN_MINUS_CHAR):
+			sb.WriteString(fmt.Sprintf("%c%x%c", BACKWARD_SLASH_CHAR, char, SPACE_CHAR))
+		// If the character is the first character and is a "-" (U+002D), and there is no second character, then the escaped character.
+		case i == 0 && char == HYPHEN_MINUS_CHAR && len(runes) == 1:
+			sb.WriteRune(BACKWARD_SLASH_CHAR)
+			sb.WriteRune(char)
+		// If the character is not handled by one of the above rules and is greater than or equal to U+0080, is "-" (U+002D) or "_" (U+005F),
+		// or is in one of the ranges [0-9] (U+0030 to U+0039), [A-Z] (U+0041 to U+005A), or [a-z] (U+0061 to U+007A), then the character itself.
+		case char >= CONTROL_CHAR, char == HYPHEN_MINUS_CHAR, char == LOW_LINE_CHAR, is_digit(char), is_letter(char):
+			sb.WriteRune(char)
+		// Otherwise, the escaped character.
+		default:
+			sb.WriteRune(BACKWARD_SLASH_CHAR)
+			sb.WriteRune(char)
+		}
+	}
+}
+
+// Serialize the code points of a name (e.g. after the "#" of a hash), escaping those that aren't ident code points.
+// Unlike an identifier, a name may start with a digit.
+func stringify_name(sb *strings.Builder, name string) {
+	for _, char := range name {
+		switch {
+		case char == NULL_CHAR:
+			sb.WriteRune(REPLACEMENT_CHAR)
+		case (char >= '\u0001' && char <= '\u001F') || char == DELETE_CHAR:
+			sb.WriteString(fmt.Sprintf("%c%x%c", BACKWARD_SLASH_CHAR, char, SPACE_CHAR))
+		case is_ident(char):
+			sb.WriteRune(char)
+		default:
+			sb.WriteRune(BACKWARD_SLASH_CHAR)
+			sb.WriteRune(char)
+		}
+	}
+}
+
+// A short human-readable description of a component value, for use in error messages.
+func describe_value(value ComponentValue) string {
+	switch {
+	case value.is_token(EOF_TOKEN):
+		return "end of input"
+	case value.is_token(WHITESPACE_TOKEN):
+		return "whitespace"
+	}
+
+	return fmt.Sprintf("'%s'", serialize_component_value_list([]ComponentValue{value}))
+}
+
+// https://drafts.csswg.org/css-syntax/#parse-block-contents
+// e.g. the value of an element's style attribute.
+func parse_block_contents(input string) ([]Declaration, []Rule) {
+	// Normalize input, and set input to the result.
+	code_points := preprocess_input_stream([]byte(input))
+	token_stream := NewTokenStream(NewTokenizer(code_points).Tokenize())
+	token_stream.source = code_points
+	token_stream.validate_declarations = true
+	// Consume a block's contents from input, and return the result.
+	return token_stream.consume_block_contents()
+}
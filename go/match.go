@@ -0,0 +1,645 @@
+package main
+
+import (
+	"strings"
+)
+
+// https://dom.spec.whatwg.org/#concept-element
+// The view of a document tree needed to match selectors against its elements.
+// Elements are compared with ==, so implementations must be comparable (e.g. pointers, or structs wrapping a pointer).
+type Element interface {
+	// The element's local name, e.g. "div".
+	LocalName() string
+	// The element's namespace URL, e.g. "http://www.w3.org/1999/xhtml".
+	NamespaceURI() string
+	Attributes() []ElementAttribute
+	// The parent element, if there is one.
+	Parent() (Element, bool)
+	// The closest preceding sibling element, if there is one.
+	PreviousSibling() (Element, bool)
+	// The closest following sibling element, if there is one.
+	NextSibling() (Element, bool)
+	// The child elements, in document order.
+	Children() []Element
+	// Whether the element has any child nodes other than elements and comments, e.g. text.
+	HasTextContent() bool
+	// Whether the element belongs to an HTML document, which makes element and attribute names case-insensitive.
+	IsHTML() bool
+}
+
+type ElementAttribute struct {
+	namespace string
+	name      string
+	value     string
+}
+
+const (
+	HTML_NAMESPACE   = "http://www.w3.org/1999/xhtml"
+	SVG_NAMESPACE    = "http://www.w3.org/2000/svg"
+	MATHML_NAMESPACE = "http://www.w3.org/1998/Math/MathML"
+)
+
+// Whether any selector in the list matches the element.
+func (l SelectorList) Matches(element Element) bool {
+	for _, selector := range l {
+		if matches_complex_selector(selector, element) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// https://drafts.csswg.org/selectors-4/#match-a-complex-selector-against-an-element
+func matches_complex_selector(selector ComplexSelector, element Element) bool {
+	if len(selector.compounds) == 0 {
+		return false
+	}
+
+	return matches_from(selector, len(selector.compounds)-1, element, nil)
+}
+
+// Match the compounds of the selector up to and including index, right-to-left, with the compound at index matched against element.
+// When anchor is not nil the selector is a relative selector (as in :has()), and the first compound must be related to the anchor element.
+func matches_from(selector ComplexSelector, index int, element Element, anchor Element) bool {
+	compound := selector.compounds[index]
+	if matches_compound_selector(compound, element) == false {
+		return false
+	}
+
+	if index == 0 {
+		if anchor == nil {
+			return true
+		}
+		// A relative selector without a leading combinator is a descendant of the anchor.
+		combinator := compound.combinator
+		if combinator == COMBINATOR_NONE {
+			combinator = COMBINATOR_DESCENDANT
+		}
+		return is_related(element, combinator, func(other Element) bool { return other == anchor })
+	}
+
+	return is_related(element, compound.combinator, func(other Element) bool {
+		return matches_from(selector, index-1, other, anchor)
+	})
+}
+
+// Whether some element related to element by the combinator satisfies the predicate.
+func is_related(element Element, combinator Combinator, predicate func(Element) bool) bool {
+	switch combinator {
+	case COMBINATOR_DESCENDANT:
+		for parent, ok := element.Parent(); ok; parent, ok = parent.Parent() {
+			if predicate(parent) {
+				return true
+			}
+		}
+	case COMBINATOR_CHILD:
+		if parent, ok := element.Parent(); ok {
+			return predicate(parent)
+		}
+	case COMBINATOR_NEXT_SIBLING:
+		if sibling, ok := element.PreviousSibling(); ok {
+			return predicate(sibling)
+		}
+	case COMBINATOR_SUBSEQUENT_SIBLING:
+		for sibling, ok := element.PreviousSibling(); ok; sibling, ok = sibling.PreviousSibling() {
+			if predicate(sibling) {
+				return true
+			}
+		}
+	// @NOTE: The column combinator requires knowledge of table layout, which the Element interface doesn't model.
+	case COMBINATOR_COLUMN:
+		return false
+	}
+
+	return false
+}
+
+func matches_compound_selector(compound CompoundSelector, element Element) bool {
+	if compound.has_default_namespace && element.NamespaceURI() != compound.default_namespace {
+		return false
+	}
+	for _, simple := range compound.selectors {
+		if matches_simple_selector(simple, element) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matches_simple_selector(selector SimpleSelector, element Element) bool {
+	switch selector.kind {
+	case TYPE_SELECTOR:
+		return matches_namespace(selector, element.NamespaceURI()) && names_equal(element, selector.name, element.LocalName())
+	case UNIVERSAL_SELECTOR:
+		return matches_namespace(selector, element.NamespaceURI())
+	case ID_SELECTOR:
+		id, ok := get_attribute(element, "id")
+		return ok && id == selector.name
+	case CLASS_SELECTOR:
+		classes, ok := get_attribute(element, "class")
+		return ok && contains_word(classes, selector.name, false)
+	case ATTRIBUTE_SELECTOR:
+		return matches_attribute_selector(selector, element)
+	case PSEUDO_CLASS_SELECTOR:
+		return matches_pseudo_class(selector, element)
+	// A nesting selector that wasn't resolved against a parent style rule represents the scoping root, like :scope.
+	case NESTING_SELECTOR:
+		_, has_parent := element.Parent()
+		return has_parent == false
+	}
+
+	// Pseudo-elements select parts of an element, rather than the element itself.
+	return false
+}
+
+// Named namespace prefixes can only be resolved against the stylesheet's @namespace rules,
+// so without them only the "*" (any namespace) and "" (no namespace) prefixes can match.
+func matches_namespace(selector SimpleSelector, namespace string) bool {
+	if selector.has_namespace_url {
+		return namespace == selector.namespace_url
+	}
+	if selector.has_namespace == false || selector.namespace == "*" {
+		return true
+	}
+	if selector.namespace == "" {
+		return namespace == ""
+	}
+
+	return false
+}
+
+// Element and attribute names are compared case-insensitively in HTML documents.
+func names_equal(element Element, a string, b string) bool {
+	if element.IsHTML() {
+		return strings.EqualFold(a, b)
+	}
+
+	return a == b
+}
+
+// The value of the attribute with no namespace and the given name.
+func get_attribute(element Element, name string) (string, bool) {
+	for _, attribute := range element.Attributes() {
+		if attribute.namespace == "" && names_equal(element, attribute.name, name) {
+			return attribute.value, true
+		}
+	}
+
+	return "", false
+}
+
+func has_attribute(element Element, name string) bool {
+	_, ok := get_attribute(element, name)
+	return ok
+}
+
+// https://drafts.csswg.org/selectors-4/#attribute-representation
+func matches_attribute_selector(selector SimpleSelector, element Element) bool {
+	insensitive := selector.case_sensitive == ATTRIBUTE_CASE_INSENSITIVE
+	for _, attribute := range element.Attributes() {
+		if names_equal(element, attribute.name, selector.name) == false {
+			continue
+		}
+		// Without a namespace prefix, attribute selectors only match attributes with no namespace.
+		switch {
+		case selector.has_namespace_url:
+			if attribute.namespace != selector.namespace_url {
+				continue
+			}
+		case selector.has_namespace == false || selector.namespace == "":
+			if attribute.namespace != "" {
+				continue
+			}
+		case selector.namespace != "*":
+			continue
+		}
+
+		if matches_attribute_value(selector.matcher, attribute.value, selector.value, insensitive) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matches_attribute_value(matcher AttributeMatcher, actual string, expected string, insensitive bool) bool {
+	if insensitive {
+		actual = strings.ToLower(actual)
+		expected = strings.ToLower(expected)
+	}
+
+	switch matcher {
+	case ATTRIBUTE_EXISTS:
+		return true
+	// Represents an element with the att attribute whose value is exactly "val".
+	case ATTRIBUTE_EQUALS:
+		return actual == expected
+	// Represents an element with the att attribute whose value is a whitespace-separated list of words, one of which is exactly "val".
+	case ATTRIBUTE_INCLUDES:
+		return contains_word(actual, expected, false)
+	// Represents an element with the att attribute, its value either being exactly "val" or beginning with "val" immediately followed by "-".
+	case ATTRIBUTE_DASH_MATCH:
+		return actual == expected || strings.HasPrefix(actual, expected+"-")
+	// If "val" is the empty string then the selector does not represent anything.
+	case ATTRIBUTE_PREFIX:
+		return expected != "" && strings.HasPrefix(actual, expected)
+	case ATTRIBUTE_SUFFIX:
+		return expected != "" && strings.HasSuffix(actual, expected)
+	case ATTRIBUTE_SUBSTRING:
+		return expected != "" && strings.Contains(actual, expected)
+	}
+
+	return false
+}
+
+// Whether a whitespace-separated list contains the word.
+func contains_word(list string, word string, insensitive bool) bool {
+	if word == "" || strings.ContainsAny(word, " \t\n\f\r") {
+		return false
+	}
+	for _, item := range strings.Fields(list) {
+		if item == word || (insensitive && strings.EqualFold(item, word)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// https://drafts.csswg.org/selectors-4/#pseudo-classes
+// Only the pseudo-classes that can be decided from the document tree are supported;
+// user action and time-dimensional pseudo-classes (e.g. :hover, :visited) never match.
+func matches_pseudo_class(selector SimpleSelector, element Element) bool {
+	if selector.is_function {
+		return matches_functional_pseudo_class(selector, element)
+	}
+
+	switch selector.name {
+	// https://drafts.csswg.org/selectors-4/#the-root-pseudo
+	case "root":
+		_, has_parent := element.Parent()
+		return has_parent == false
+	// https://drafts.csswg.org/selectors-4/#the-scope-pseudo
+	// When there is no scoping root, :scope represents the root of the document.
+	case "scope":
+		_, has_parent := element.Parent()
+		return has_parent == false
+	// https://drafts.csswg.org/selectors-4/#the-empty-pseudo
+	case "empty":
+		return len(element.Children()) == 0 && element.HasTextContent() == false
+	case "first-child":
+		_, has_previous := element.PreviousSibling()
+		return has_previous == false
+	case "last-child":
+		_, has_next := element.NextSibling()
+		return has_next == false
+	case "only-child":
+		_, has_previous := element.PreviousSibling()
+		_, has_next := element.NextSibling()
+		return has_previous == false && has_next == false
+	case "first-of-type":
+		return sibling_index(element, true, false, nil) == 1
+	case "last-of-type":
+		return sibling_index(element, true, true, nil) == 1
+	case "only-of-type":
+		return sibling_index(element, true, false, nil) == 1 && sibling_index(element, true, true, nil) == 1
+	// https://html.spec.whatwg.org/multipage/semantics-other.html#selector-any-link
+	case "any-link", "link":
+		switch strings.ToLower(element.LocalName()) {
+		case "a", "area", "link":
+			return element.IsHTML() && has_attribute(element, "href")
+		}
+		return false
+	// https://html.spec.whatwg.org/multipage/semantics-other.html#selector-checked
+	case "checked":
+		return is_checked(element)
+	// https://html.spec.whatwg.org/multipage/semantics-other.html#selector-default
+	case "default":
+		return is_checked(element) || is_default_button(element)
+	case "disabled":
+		return is_form_control(element) && is_disabled(element)
+	case "enabled":
+		return is_form_control(element) && is_disabled(element) == false
+	case "required":
+		return is_input_element(element) && has_attribute(element, "required")
+	case "optional":
+		return is_input_element(element) && has_attribute(element, "required") == false
+	case "read-write":
+		return is_mutable(element)
+	case "read-only":
+		return is_mutable(element) == false
+	case "placeholder-shown":
+		value, _ := get_attribute(element, "value")
+		return is_input_element(element) && has_attribute(element, "placeholder") && value == ""
+	// Every element in a parsed document is defined.
+	case "defined":
+		return true
+	}
+
+	return false
+}
+
+func matches_functional_pseudo_class(selector SimpleSelector, element Element) bool {
+	switch selector.name {
+	// https://drafts.csswg.org/selectors-4/#matches
+	case "is", "where":
+		return selector.selectors.Matches(element)
+	// https://drafts.csswg.org/selectors-4/#negation
+	case "not":
+		return selector.selectors.Matches(element) == false
+	// https://drafts.csswg.org/selectors-4/#relational
+	case "has":
+		for _, relative := range selector.selectors {
+			if matches_relative_selector(relative, element) {
+				return true
+			}
+		}
+		return false
+	// https://drafts.csswg.org/selectors-4/#child-index
+	case "nth-child", "nth-last-child":
+		var of_selector SelectorList
+		if selector.has_of_selector {
+			if selector.selectors.Matches(element) == false {
+				return false
+			}
+			of_selector = selector.selectors
+		}
+		return selector.nth.matches(sibling_index(element, false, selector.name == "nth-last-child", of_selector))
+	case "nth-of-type", "nth-last-of-type":
+		return selector.nth.matches(sibling_index(element, true, selector.name == "nth-last-of-type", nil))
+	// https://drafts.csswg.org/selectors-4/#the-lang-pseudo
+	case "lang":
+		language, ok := inherited_attribute(element, "lang")
+		if ok == false {
+			return false
+		}
+		for _, item := range split_on_commas(selector.arguments) {
+			item = trim_whitespace(item)
+			if len(item) == 1 && matches_language_range(language, string(item[0].token.value)) {
+				return true
+			}
+		}
+		return false
+	// https://drafts.csswg.org/selectors-4/#the-dir-pseudo
+	case "dir":
+		direction, ok := inherited_attribute(element, "dir")
+		if ok == false || (strings.EqualFold(direction, "ltr") == false && strings.EqualFold(direction, "rtl") == false) {
+			direction = "ltr"
+		}
+		argument, _ := single_ident_argument(selector.arguments)
+		return strings.EqualFold(direction, argument)
+	}
+
+	return false
+}
+
+// Check whether any element related to the anchor matches the relative selector.
+func matches_relative_selector(selector ComplexSelector, anchor Element) bool {
+	if len(selector.compounds) == 0 {
+		return false
+	}
+
+	// The candidates are the anchor's descendants, plus for sibling combinators its following siblings and their descendants.
+	var candidates []Element
+	var collect func(Element)
+	collect = func(element Element) {
+		for _, child := range element.Children() {
+			candidates = append(candidates, child)
+			collect(child)
+		}
+	}
+	collect(anchor)
+	for sibling, ok := anchor.NextSibling(); ok; sibling, ok = sibling.NextSibling() {
+		candidates = append(candidates, sibling)
+		collect(sibling)
+	}
+
+	last := len(selector.compounds) - 1
+	for _, candidate := range candidates {
+		if matches_from(selector, last, candidate, anchor) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// The 1-based index of the element among its siblings (or from the end, if reverse).
+// If of_type is set only siblings with the same type are counted, and if of_selector is set only siblings matching it.
+func sibling_index(element Element, of_type bool, reverse bool, of_selector SelectorList) int {
+	index := 1
+	next := element.PreviousSibling
+	if reverse {
+		next = element.NextSibling
+	}
+
+	for sibling, ok := next(); ok; {
+		counts := true
+		if of_type {
+			counts = sibling.NamespaceURI() == element.NamespaceURI() && names_equal(element, sibling.LocalName(), element.LocalName())
+		}
+		if of_selector != nil {
+			counts = counts && of_selector.Matches(sibling)
+		}
+		if counts {
+			index += 1
+		}
+
+		if reverse {
+			sibling, ok = sibling.NextSibling()
+		} else {
+			sibling, ok = sibling.PreviousSibling()
+		}
+	}
+
+	return index
+}
+
+// Whether the 1-based index is represented by An+B for some non-negative integer n.
+func (anb AnPlusB) matches(index int) bool {
+	if anb.a == 0 {
+		return index == anb.b
+	}
+
+	n := index - anb.b
+	return n%anb.a == 0 && n/anb.a >= 0
+}
+
+// The value of the attribute on the element or its closest ancestor that has it.
+func inherited_attribute(element Element, name string) (string, bool) {
+	for current, ok := element, true; ok; current, ok = current.Parent() {
+		if value, found := get_attribute(current, name); found {
+			return value, true
+		}
+	}
+
+	return "", false
+}
+
+// https://www.rfc-editor.org/rfc/rfc4647#section-3.3.2
+// Extended filtering: the language tag matches if it equals the range, or starts with it followed by "-". A range of "*" matches any tag.
+func matches_language_range(language string, language_range string) bool {
+	if language_range == "*" {
+		return language != ""
+	}
+	language = strings.ToLower(language)
+	language_range = strings.ToLower(strings.TrimPrefix(language_range, "*-"))
+
+	return language == language_range || strings.HasPrefix(language, language_range+"-")
+}
+
+func is_input_element(element Element) bool {
+	switch strings.ToLower(element.LocalName()) {
+	case "input", "select", "textarea":
+		return element.IsHTML()
+	}
+
+	return false
+}
+
+func is_form_control(element Element) bool {
+	switch strings.ToLower(element.LocalName()) {
+	case "button", "input", "select", "textarea", "optgroup", "option", "fieldset":
+		return element.IsHTML()
+	}
+
+	return false
+}
+
+// https://html.spec.whatwg.org/multipage/semantics-other.html#concept-element-disabled
+// @NOTE: Controls disabled by an ancestor <fieldset> are included, but the exception for its first <legend> is not modelled.
+func is_disabled(element Element) bool {
+	if has_attribute(element, "disabled") {
+		return true
+	}
+	for parent, ok := element.Parent(); ok; parent, ok = parent.Parent() {
+		if strings.EqualFold(parent.LocalName(), "fieldset") && has_attribute(parent, "disabled") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func is_checked(element Element) bool {
+	if element.IsHTML() == false {
+		return false
+	}
+
+	switch strings.ToLower(element.LocalName()) {
+	case "input":
+		kind, _ := get_attribute(element, "type")
+		return (strings.EqualFold(kind, "checkbox") || strings.EqualFold(kind, "radio")) && has_attribute(element, "checked")
+	case "option":
+		return has_attribute(element, "selected")
+	}
+
+	return false
+}
+
+// https://html.spec.whatwg.org/multipage/forms.html#concept-submit-button
+func is_submit_button(element Element) bool {
+	if element.IsHTML() == false {
+		return false
+	}
+
+	kind, _ := get_attribute(element, "type")
+	switch strings.ToLower(element.LocalName()) {
+	case "button":
+		// A missing or invalid type is the submit button state.
+		return strings.EqualFold(kind, "reset") == false && strings.EqualFold(kind, "button") == false
+	case "input":
+		return strings.EqualFold(kind, "submit") || strings.EqualFold(kind, "image")
+	}
+
+	return false
+}
+
+// https://html.spec.whatwg.org/multipage/form-control-infrastructure.html#form-owner
+// The form named by the element's form attribute if it has one, otherwise its nearest ancestor form.
+func form_owner(element Element) (Element, bool) {
+	if id, ok := get_attribute(element, "form"); ok {
+		root := element
+		for parent, ok := element.Parent(); ok; parent, ok = parent.Parent() {
+			root = parent
+		}
+		return find_element(root, func(candidate Element) bool {
+			value, ok := get_attribute(candidate, "id")
+			return ok && value == id && strings.EqualFold(candidate.LocalName(), "form")
+		})
+	}
+	for parent, ok := element.Parent(); ok; parent, ok = parent.Parent() {
+		if parent.IsHTML() && strings.EqualFold(parent.LocalName(), "form") {
+			return parent, true
+		}
+	}
+
+	return nil, false
+}
+
+// https://html.spec.whatwg.org/multipage/form-control-infrastructure.html#default-button
+// A form's default button is the first submit button in tree order whose form owner is the form.
+func is_default_button(element Element) bool {
+	if is_submit_button(element) == false {
+		return false
+	}
+	form, ok := form_owner(element)
+	if ok == false {
+		return false
+	}
+
+	root := form
+	for parent, ok := form.Parent(); ok; parent, ok = parent.Parent() {
+		root = parent
+	}
+	button, ok := find_element(root, func(candidate Element) bool {
+		owner, ok := form_owner(candidate)
+		return is_submit_button(candidate) && ok && owner == form
+	})
+
+	return ok && button == element
+}
+
+// The first element in the subtree rooted at root (including root) that satisfies the predicate, in tree order.
+func find_element(root Element, predicate func(Element) bool) (Element, bool) {
+	if predicate(root) {
+		return root, true
+	}
+	for _, child := range root.Children() {
+		if found, ok := find_element(child, predicate); ok {
+			return found, true
+		}
+	}
+
+	return nil, false
+}
+
+// https://html.spec.whatwg.org/multipage/semantics-other.html#selector-read-write
+func is_mutable(element Element) bool {
+	if element.IsHTML() == false {
+		return false
+	}
+
+	switch strings.ToLower(element.LocalName()) {
+	case "input", "textarea":
+		return has_attribute(element, "readonly") == false && is_disabled(element) == false
+	}
+
+	value, ok := get_attribute(element, "contenteditable")
+	return ok && (value == "" || strings.EqualFold(value, "true") || strings.EqualFold(value, "plaintext-only"))
+}
+
+// All elements in the subtree rooted at root (including root) that match the selectors, in document order.
+func query_selector_all(root Element, selectors SelectorList) []Element {
+	var result []Element
+	if selectors.Matches(root) {
+		result = append(result, root)
+	}
+	for _, child := range root.Children() {
+		result = append(result, query_selector_all(child, selectors)...)
+	}
+
+	return result
+}
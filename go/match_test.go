@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	root, err := parse_html_document(`<html lang="en-GB"><body>
+		<div id="main" class="card big" data-kind="news-item">
+			<p id="2" title="Hello World">a</p>
+			<p id="3" dir="rtl"></p>
+			<a id="4" href="/x"></a>
+			<a id="5"></a>
+		</div>
+		<form id="6">
+			<input id="7" type="checkbox" checked required>
+			<input id="8" type="text" readonly>
+			<fieldset id="9" disabled><button id="10"></button></fieldset>
+			<select id="11"><option id="12" selected></option><option id="13"></option></select>
+		</form>
+		<svg id="14"><circle id="15"></circle></svg>
+		<span id="16" lang="fr" contenteditable></span>
+	</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		selector string
+		// The ids of the matching elements, in document order.
+		want string
+	}{
+		{selector: "P", want: "2 3"},
+		{selector: "#main", want: "main"},
+		{selector: ".card.big", want: "main"},
+		{selector: ".card.small", want: ""},
+		{selector: "div > p", want: "2 3"},
+		{selector: "body p", want: "2 3"},
+		{selector: "p + p", want: "3"},
+		{selector: "p ~ a", want: "4 5"},
+		{selector: "html > p", want: ""},
+		{selector: "[data-kind]", want: "main"},
+		{selector: "[data-kind=news-item]", want: "main"},
+		{selector: "[class~=big]", want: "main"},
+		{selector: "[data-kind|=news]", want: "main"},
+		{selector: "[title^=Hello]", want: "2"},
+		{selector: "[title$=world i]", want: "2"},
+		{selector: "[title*='o W']", want: "2"},
+		{selector: "[title^='']", want: ""},
+		{selector: "[TITLE]", want: "2"},
+		{selector: "p:empty", want: "3"},
+		{selector: "div > :first-child", want: "2"},
+		{selector: "div > :last-child", want: "5"},
+		{selector: "select > :only-of-type", want: ""},
+		{selector: "div > a:last-of-type", want: "5"},
+		{selector: ":root", want: ""},
+		{selector: ":any-link", want: "4"},
+		{selector: ":checked", want: "7 12"},
+		{selector: ":default", want: "7 10 12"},
+		{selector: ":disabled", want: "9 10"},
+		{selector: "button:enabled", want: ""},
+		{selector: ":required", want: "7"},
+		{selector: "input:optional", want: "8"},
+		{selector: "input:read-only", want: "8"},
+		{selector: ":read-write", want: "7 16"},
+		{selector: "p:hover", want: ""},
+		{selector: "div > :is(p, a[href])", want: "2 3 4"},
+		{selector: "div > :where([href])", want: "4"},
+		{selector: "div > :not(p, :not([href]))", want: "4"},
+		{selector: ":has(> [checked])", want: "6"},
+		{selector: "p:has(+ p)", want: "2"},
+		{selector: "div:has(a:not([href]))", want: "main"},
+		{selector: "span:lang(fr)", want: "16"},
+		{selector: "p:lang(en)", want: "2 3"},
+		{selector: "p:lang(en-GB, de)", want: "2 3"},
+		{selector: "p:dir(rtl)", want: "3"},
+		{selector: "p:dir(ltr)", want: "2"},
+		{selector: "circle", want: "15"},
+		{selector: "*|circle", want: "15"},
+		{selector: "|circle", want: ""},
+		{selector: "p::before", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selectors, err := parse_selector_list(parse_component_value_list(test.selector))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, element := range query_selector_all(root, selectors) {
+				id, _ := get_attribute(element, "id")
+				ids = append(ids, id)
+			}
+			if got := strings.Join(ids, " "); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMatchesDefault(t *testing.T) {
+	root, err := parse_html_document(`<html><body>
+		<form id="a">
+			<button id="1" type="reset"></button>
+			<button id="2" type="bogus"></button>
+			<input id="3" type="submit">
+		</form>
+		<form id="b">
+			<input id="4" type="image">
+			<input id="5" type="radio" checked>
+			<input id="6" type="text" checked>
+		</form>
+		<input id="7" type="submit" form="b">
+		<button id="8" form="missing"></button>
+		<button id="9"></button>
+		<form id="c"><button id="10" type="button"></button></form>
+		<button id="11" form="c"></button>
+	</body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	selectors, err := parse_selector_list(parse_component_value_list(":default"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, element := range query_selector_all(root, selectors) {
+		id, _ := get_attribute(element, "id")
+		ids = append(ids, id)
+	}
+	if got, want := strings.Join(ids, " "), "2 4 5 11"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"math/bits"
+	"strings"
+)
+
+// A shorthand that longhand declarations can be merged into.
+type longhand_merge struct {
+	shorthand string
+	// The properties that may overlap with the shorthand's longhands are the ones whose names start with the family,
+	// which also covers the logical and physical longhands that share a value.
+	family string
+	// Whether the longhand values are separated by a '/' in the shorthand, rather than by whitespace.
+	slash_separated bool
+}
+
+// The order matters: longhands merged into one shorthand aren't available to the next, so the more compact shorthands come first.
+var longhand_merges = []longhand_merge{
+	{shorthand: "margin", family: "margin"},
+	{shorthand: "margin-block", family: "margin"},
+	{shorthand: "margin-inline", family: "margin"},
+	{shorthand: "padding", family: "padding"},
+	{shorthand: "padding-block", family: "padding"},
+	{shorthand: "padding-inline", family: "padding"},
+	{shorthand: "inset", family: "inset"},
+	{shorthand: "inset-block", family: "inset"},
+	{shorthand: "inset-inline", family: "inset"},
+	{shorthand: "border-width", family: "border"},
+	{shorthand: "border-style", family: "border"},
+	{shorthand: "border-color", family: "border"},
+	{shorthand: "border-top", family: "border"},
+	{shorthand: "border-right", family: "border"},
+	{shorthand: "border-bottom", family: "border"},
+	{shorthand: "border-left", family: "border"},
+	{shorthand: "border-radius", family: "border"},
+	{shorthand: "outline", family: "outline"},
+	{shorthand: "gap", family: "gap"},
+	{shorthand: "overflow", family: "overflow"},
+	{shorthand: "list-style", family: "list-style"},
+	{shorthand: "text-decoration", family: "text-decoration"},
+	{shorthand: "flex-flow", family: "flex"},
+	{shorthand: "flex", family: "flex"},
+	{shorthand: "grid-area", family: "grid", slash_separated: true},
+	{shorthand: "grid-row", family: "grid", slash_separated: true},
+	{shorthand: "grid-column", family: "grid", slash_separated: true},
+}
+
+// Replace the longhand declarations of each style rule with the shortest equivalent shorthand, wherever all of a shorthand's longhands are declared.
+// A merge is only made when it can't change which declarations win in the cascade.
+func (s Stylesheet) MergeLonghands() Stylesheet {
+	return Stylesheet{rules: merge_longhands_in_rules(s.rules), namespaces: s.namespaces}
+}
+
+func merge_longhands_in_rules(rules []Rule) []Rule {
+	var result []Rule
+	for _, rule := range rules {
+		rule.children = merge_longhands_in_rules(rule.children)
+		// The declarations of at-rules (e.g. @font-face) are descriptors, not properties.
+		if rule.kind == QUALIFIED_RULE || rule.kind == NESTED_DECLARATIONS_RULE {
+			rule.decls = merge_longhands(rule.decls)
+		}
+		result = append(result, rule)
+	}
+
+	return result
+}
+
+func merge_longhands(decls []Declaration) []Declaration {
+	for _, merge := range longhand_merges {
+		decls = merge.apply(decls)
+	}
+
+	return decls
+}
+
+// Merge the declarations of the shorthand's longhands into a single shorthand declaration, placed where the last of them was.
+func (m longhand_merge) apply(decls []Declaration) []Declaration {
+	longhands := shorthand_properties[m.shorthand].longhands
+	is_longhand := map[string]bool{}
+	for _, longhand := range longhands {
+		is_longhand[longhand] = true
+	}
+
+	// The declarations of each longhand that win within the block.
+	var winners []int
+	for _, longhand := range longhands {
+		index := winning_declaration(decls, longhand)
+		if index < 0 {
+			return decls
+		}
+		winners = append(winners, index)
+	}
+
+	important := decls[winners[0]].important
+	first, last := len(decls), -1
+	is_winner := map[int]bool{}
+	var values [][]ComponentValue
+	for _, index := range winners {
+		decl := decls[index]
+		// The value of a var() isn't known, so we can't know what the shorthand would expand to.
+		if decl.important != important || decl.pending_shorthand != "" || contains_substitution_function(decl.value) {
+			return decls
+		}
+		values = append(values, trim_whitespace(decl.value))
+		is_winner[index] = true
+		first = min(first, index)
+		last = max(last, index)
+	}
+
+	// Moving a longhand to the position of the last one changes its order relative to the declarations in between,
+	// so that's only safe if none of them could set the same value with the same importance.
+	// Any other declaration of the longhands themselves has already lost, by coming earlier or being less important.
+	for i := first + 1; i < last; i += 1 {
+		decl := decls[i]
+		if is_winner[i] == false && decl.important == important && is_longhand[property_name(decl.name)] == false && is_related_property(decl.name, m.family) {
+			return decls
+		}
+	}
+
+	value, ok := m.shortest_value(values)
+	if ok == false {
+		return decls
+	}
+
+	// The other declarations of the longhands have lost to the winners, so they're dropped along with them.
+	var result []Declaration
+	for i, decl := range decls {
+		switch {
+		case i == last:
+			result = append(result, Declaration{name: m.shorthand, value: value, important: important})
+		case is_winner[i] == false && is_longhand[property_name(decl.name)] == false:
+			result = append(result, decl)
+		}
+	}
+
+	return result
+}
+
+// The index of the declaration of the property that wins within the block: the last !important one, or the last one if none are.
+func winning_declaration(decls []Declaration, name string) int {
+	winner := -1
+	for i, decl := range decls {
+		if property_name(decl.name) != name {
+			continue
+		}
+		if winner < 0 || decl.important || decls[winner].important == false {
+			winner = i
+		}
+	}
+
+	return winner
+}
+
+// Whether a declaration of the property could override the value of a longhand in the family.
+func is_related_property(name string, family string) bool {
+	name = property_name(name)
+	if strings.HasPrefix(name, "--") {
+		return false
+	}
+	// A vendor prefix doesn't change which value the property sets.
+	if strings.HasPrefix(name, "-") {
+		if index := strings.Index(name[1:], "-"); index >= 0 {
+			name = name[index+2:]
+		}
+	}
+
+	switch name {
+	case "all":
+		return true
+	case "top", "right", "bottom", "left":
+		name = "inset-" + name
+	case "row-gap", "column-gap", "grid-gap", "grid-row-gap", "grid-column-gap":
+		name = "gap"
+	}
+
+	return name == family || strings.HasPrefix(name, family+"-")
+}
+
+// The shortest shorthand value that expands back into exactly the longhand values.
+// Candidates are made from each subset of the longhand values, from the smallest up, so that values which are implied
+// (e.g. an initial value, or a side that copies the opposite side) are left out wherever the expansion allows it.
+func (m longhand_merge) shortest_value(values [][]ComponentValue) ([]ComponentValue, bool) {
+	for size := 1; size <= len(values); size += 1 {
+		for mask := 1; mask < 1<<len(values); mask += 1 {
+			if bits.OnesCount(uint(mask)) != size {
+				continue
+			}
+
+			var parts [][]ComponentValue
+			for i, value := range values {
+				if mask&(1<<i) != 0 {
+					parts = append(parts, value)
+				}
+			}
+			candidate := m.join(parts)
+			if expands_to(m.shorthand, candidate, values) {
+				return candidate, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+func (m longhand_merge) join(parts [][]ComponentValue) []ComponentValue {
+	if m.slash_separated == false {
+		return join_values(parts...)
+	}
+
+	var list []ComponentValue
+	for i, part := range parts {
+		if i > 0 {
+			list = append(list, whitespace_value(), ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: DELIM_TOKEN, value: []rune{FORWARD_SLASH_CHAR}}}, whitespace_value())
+		}
+		list = append(list, part...)
+	}
+
+	return list
+}
+
+// Whether the shorthand value expands into exactly the longhand values.
+func expands_to(shorthand string, value []ComponentValue, longhand_values [][]ComponentValue) bool {
+	longhands, err := ExpandShorthand(Declaration{name: shorthand, value: value})
+	if err != nil || len(longhands) != len(longhand_values) {
+		return false
+	}
+
+	for i, longhand := range longhands {
+		if serialize_component_value_list(trim_whitespace(longhand.value)) != serialize_component_value_list(longhand_values[i]) {
+			return false
+		}
+	}
+
+	return true
+}
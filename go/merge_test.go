@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMergeLonghands(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want string
+	}{
+		{
+			name: "merges the four sides",
+			css:  `a { margin-top: 1px; margin-right: 2px; margin-bottom: 3px; margin-left: 4px }`,
+			want: "a {\nmargin: 1px 2px 3px 4px;\n}\n",
+		},
+		{
+			name: "leaves out the sides that copy the opposite side",
+			css:  `a { padding-top: 1px; padding-right: 2px; padding-bottom: 1px; padding-left: 2px }`,
+			want: "a {\npadding: 1px 2px;\n}\n",
+		},
+		{
+			name: "places the shorthand where the last longhand was",
+			css:  `a { margin-top: 0; color: red; margin-right: 0; margin-bottom: 0; margin-left: 0; display: block }`,
+			want: "a {\ncolor: red;\nmargin: 0;\ndisplay: block;\n}\n",
+		},
+		{
+			name: "uses the winning declaration of each longhand",
+			css:  `a { margin-top: 1px !important; margin-right: 1px !important; margin-bottom: 1px !important; margin-left: 1px !important; margin-top: 9px }`,
+			want: "a {\nmargin: 1px !important;\n}\n",
+		},
+		{
+			name: "drops the longhands the shorthand overrides",
+			css:  `a { padding-top: 1px; padding-right: 1px; padding-bottom: 1px; padding-left: 1px; padding-top: 2px }`,
+			want: "a {\npadding: 2px 1px 1px;\n}\n",
+		},
+		{
+			name: "merges important longhands into an important shorthand",
+			css:  `a { gap: 0; row-gap: 1px !important; column-gap: 2px !important }`,
+			want: "a {\ngap: 0;\ngap: 1px 2px !important;\n}\n",
+		},
+		{
+			name: "merges logical longhands",
+			css:  `a { margin-block-start: 1px; margin-block-end: 2px }`,
+			want: "a {\nmargin-block: 1px 2px;\n}\n",
+		},
+		{
+			name: "separates grid lines with a slash",
+			css:  `a { grid-row-start: 1; grid-row-end: 3 }`,
+			want: "a {\ngrid-row: 1 / 3;\n}\n",
+		},
+		{
+			name: "leaves out initial values",
+			css:  `a { flex-direction: column; flex-wrap: nowrap }`,
+			want: "a {\nflex-flow: column;\n}\n",
+		},
+		{
+			name: "needs every longhand",
+			css:  `a { margin-top: 1px; margin-right: 2px; margin-bottom: 3px }`,
+			want: "a {\nmargin-top: 1px;\nmargin-right: 2px;\nmargin-bottom: 3px;\n}\n",
+		},
+		{
+			name: "keeps longhands of mixed importance",
+			css:  `a { overflow-x: hidden; overflow-y: auto !important }`,
+			want: "a {\noverflow-x: hidden;\noverflow-y: auto !important;\n}\n",
+		},
+		{
+			name: "keeps longhands with var()",
+			css:  `a { overflow-x: var(--x); overflow-y: auto }`,
+			want: "a {\noverflow-x: var(--x);\noverflow-y: auto;\n}\n",
+		},
+		{
+			name: "keeps longhands around a related declaration",
+			css:  `a { inset-block-start: 1px; top: 5px; inset-block-end: 2px }`,
+			want: "a {\ninset-block-start: 1px;\ntop: 5px;\ninset-block-end: 2px;\n}\n",
+		},
+		{
+			name: "keeps longhands around all",
+			css:  `a { overflow-x: hidden; all: unset; overflow-y: auto }`,
+			want: "a {\noverflow-x: hidden;\nall: unset;\noverflow-y: auto;\n}\n",
+		},
+		{
+			name: "merges longhands around unrelated declarations",
+			css:  `a { overflow-x: hidden; --overflow: auto; color: red; overflow-y: auto }`,
+			want: "a {\n--overflow: auto;\ncolor: red;\noverflow: hidden auto;\n}\n",
+		},
+		{
+			name: "merges nested rules",
+			css:  `@media print { a { overflow-x: clip; overflow-y: clip } }`,
+			want: "@media print {\na {\noverflow: clip;\n}\n}\n",
+		},
+		{
+			name: "leaves descriptors alone",
+			css:  `@page { margin-top: 0; margin-right: 0; margin-bottom: 0; margin-left: 0 }`,
+			want: "@page {\nmargin-top: 0;\nmargin-right: 0;\nmargin-bottom: 0;\nmargin-left: 0;\n}\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := parse_stylesheet(strings.NewReader(test.css)).MergeLonghands().Stringify(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
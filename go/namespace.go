@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-namespaces/#declaration
+// @namespace <namespace-prefix>? [ <string> | <url> ] ;
+type NamespaceRule struct {
+	// The prefix, or empty when the rule declares the default namespace.
+	prefix string
+	url    string
+}
+
+func is_namespace_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && strings.EqualFold(rule.name, "namespace")
+}
+
+// Whether an @namespace rule may still follow this rule.
+func allows_following_namespace(rule Rule) bool {
+	return allows_following_import(rule) || is_namespace_rule(rule)
+}
+
+// Extract the typed model of an @namespace rule from its prelude.
+func (r Rule) namespace_rule() (NamespaceRule, error) {
+	if is_namespace_rule(r) == false {
+		return NamespaceRule{}, fmt.Errorf("Parse Error: rule is not an @namespace rule")
+	}
+	if r.has_block {
+		return NamespaceRule{}, fmt.Errorf("Parse Error: @namespace rule must not have a block")
+	}
+
+	var rule NamespaceRule
+	values := non_whitespace_values(r.prelude)
+	// <namespace-prefix> = <ident>
+	if len(values) == 2 && values[0].is_token(IDENT_TOKEN) {
+		rule.prefix = string(values[0].token.value)
+		values = values[1:]
+	}
+	url, ok := "", false
+	if len(values) == 1 {
+		url, ok = url_value(values[0])
+	}
+	if ok == false {
+		return NamespaceRule{}, fmt.Errorf("Parse Error: @namespace rule must be an optional prefix followed by a url or string")
+	}
+	rule.url = url
+
+	return rule, nil
+}
+
+// Add the namespace the rule declares to those of the stylesheet being consumed, if the rule is valid.
+// A later declaration of the same prefix replaces the earlier one.
+func (ts *TokenStream) declare_namespace(rule Rule) bool {
+	namespace, err := rule.namespace_rule()
+	if err != nil {
+		fmt.Println(err)
+		return false
+	}
+	if ts.namespaces == nil {
+		ts.namespaces = map[string]string{}
+	}
+	ts.namespaces[namespace.prefix] = namespace.url
+
+	return true
+}
+
+// The first namespace prefix the style rule's selectors use that the stylesheet being consumed hasn't declared.
+// Only style rules have selectors: the qualified rules of other at-rules (e.g. the keyframes of @keyframes) aren't checked,
+// and neither are selectors that can't be parsed, which are reported where they're used.
+func (ts *TokenStream) undeclared_namespace_prefix(rule Rule) (string, bool) {
+	if ts.in_property_context() == false {
+		return "", false
+	}
+	selectors, err := parse_relative_selector_list(rule.prelude)
+	if err != nil {
+		return "", false
+	}
+	_, err = selectors.resolve_namespaces(ts.namespaces)
+	undeclared, ok := err.(undeclared_namespace_error)
+
+	return undeclared.prefix, ok
+}
+
+type undeclared_namespace_error struct {
+	prefix string
+}
+
+func (e undeclared_namespace_error) Error() string {
+	return fmt.Sprintf("Parse Error: undeclared namespace prefix '%s' in selector", e.prefix)
+}
+
+// https://drafts.csswg.org/selectors-4/#type-nmsp
+// A copy of the selector list with its namespace prefixes resolved to the namespace URLs, for matching elements.
+// When a default namespace is declared, type and universal selectors without a prefix only match elements in it, as do
+// compound selectors without either, except in the arguments of pseudo-classes such as :is() and :not().
+// Using a prefix that isn't declared is an error.
+func (l SelectorList) resolve_namespaces(namespaces map[string]string) (SelectorList, error) {
+	return resolve_selector_list_namespaces(l, namespaces, false)
+}
+
+func resolve_selector_list_namespaces(selectors SelectorList, namespaces map[string]string, in_argument bool) (SelectorList, error) {
+	resolved := make(SelectorList, len(selectors))
+	for i, selector := range selectors {
+		compounds := make([]CompoundSelector, len(selector.compounds))
+		for j, compound := range selector.compounds {
+			compound.selectors = slices.Clone(compound.selectors)
+			for k := range compound.selectors {
+				if err := resolve_simple_selector_namespace(&compound.selectors[k], namespaces); err != nil {
+					return nil, err
+				}
+			}
+
+			default_namespace, has_default_namespace := namespaces[""]
+			if has_default_namespace && in_argument == false && has_type_selector(compound) == false {
+				compound.has_default_namespace, compound.default_namespace = true, default_namespace
+			}
+			compounds[j] = compound
+		}
+		resolved[i] = ComplexSelector{compounds: compounds}
+	}
+
+	return resolved, nil
+}
+
+func resolve_simple_selector_namespace(selector *SimpleSelector, namespaces map[string]string) error {
+	if len(selector.selectors) > 0 {
+		arguments, err := resolve_selector_list_namespaces(selector.selectors, namespaces, true)
+		if err != nil {
+			return err
+		}
+		selector.selectors = arguments
+	}
+
+	switch {
+	case selector.kind != TYPE_SELECTOR && selector.kind != UNIVERSAL_SELECTOR && selector.kind != ATTRIBUTE_SELECTOR:
+	// Any namespace.
+	case selector.has_namespace && selector.namespace == "*":
+	// No namespace.
+	case selector.has_namespace && selector.namespace == "":
+		selector.has_namespace_url, selector.namespace_url = true, ""
+	case selector.has_namespace:
+		url, ok := namespaces[selector.namespace]
+		if ok == false {
+			return undeclared_namespace_error{prefix: selector.namespace}
+		}
+		selector.has_namespace_url, selector.namespace_url = true, url
+	// The default namespace doesn't apply to attributes.
+	case selector.kind != ATTRIBUTE_SELECTOR:
+		if url, ok := namespaces[""]; ok {
+			selector.has_namespace_url, selector.namespace_url = true, url
+		}
+	}
+
+	return nil
+}
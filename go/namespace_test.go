@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNamespaceRules(t *testing.T) {
+	tests := []struct {
+		css string
+		// The declared namespaces, as formatted by fmt.
+		want string
+	}{
+		{css: `@namespace url(http://www.w3.org/1999/xhtml);`, want: "map[:http://www.w3.org/1999/xhtml]"},
+		{css: `@namespace svg "http://www.w3.org/2000/svg";`, want: "map[svg:http://www.w3.org/2000/svg]"},
+		{css: `@namespace svg url("http://www.w3.org/2000/svg"); @namespace svg "x";`, want: "map[svg:x]"},
+		{css: `@charset "utf-8"; @import "a.css"; @layer a; @namespace a "x"; @namespace b "y";`, want: "map[a:x b:y]"},
+		{css: `@namespace a "x"; @import "a.css";`, want: "map[a:x]"},
+		{css: `a {} @namespace a "x";`, want: "map[]"},
+		{css: `@layer a {} @namespace a "x";`, want: "map[]"},
+		{css: `@media print {} @namespace a "x";`, want: "map[]"},
+		{css: `@namespace a b "x";`, want: "map[]"},
+		{css: `@namespace a;`, want: "map[]"},
+		{css: `@namespace a "x" {}`, want: "map[]"},
+		{css: `@namespace a b; @namespace c "z";`, want: "map[c:z]"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			sheet := parse_stylesheet(strings.NewReader(test.css))
+			if got := fmt.Sprint(sheet.namespaces); got != test.want {
+				t.Errorf("got %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+func TestNamespacesAfterImport(t *testing.T) {
+	// An @import rule following an @namespace rule is invalid.
+	if imports := parse_stylesheet(strings.NewReader(`@namespace a "x"; @import "a.css";`)).import_rules(); len(imports) != 0 {
+		t.Errorf("got %v", imports)
+	}
+}
+
+func TestResolveNamespaces(t *testing.T) {
+	root, err := parse_html_document(`<html><body><a id="html-a"></a><svg id="svg"><a id="svg-a"></a><circle id="circle"></circle></svg></body></html>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		css string
+		// The ids of the elements the style rule matches, in document order.
+		want string
+	}{
+		{css: `a {}`, want: "html-a svg-a"},
+		{css: `*|a {}`, want: "html-a svg-a"},
+		{css: `|a {}`, want: ""},
+		{css: `@namespace svg "http://www.w3.org/2000/svg"; svg|circle {}`, want: "circle"},
+		{css: `@namespace svg "http://www.w3.org/2000/svg"; svg|a {}`, want: "svg-a"},
+		{css: `@namespace svg "http://www.w3.org/2000/svg"; svg|* {}`, want: "svg svg-a circle"},
+		{css: `@namespace url(http://www.w3.org/2000/svg); a {}`, want: "svg-a"},
+		{css: `@namespace url(http://www.w3.org/2000/svg); #html-a, #circle {}`, want: "circle"},
+		{css: `@namespace url(http://www.w3.org/2000/svg); :is(#html-a) {}`, want: ""},
+		{css: `@namespace url(http://www.w3.org/2000/svg); *|*:is(#html-a) {}`, want: "html-a"},
+		{css: `@namespace url(http://www.w3.org/2000/svg); :not(a)[id^=svg] {}`, want: "svg"},
+		{css: `@namespace html "http://www.w3.org/1999/xhtml"; body > html|a, svg > html|a {}`, want: "html-a"},
+		{css: `@namespace x "urn:x"; [x|id] {}`, want: ""},
+		{css: `@namespace x "urn:x"; [*|id=circle] {}`, want: "circle"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			rules, errs := parse_stylesheet(strings.NewReader(test.css)).StyleRules()
+			if len(errs) > 0 || len(rules) != 1 {
+				t.Fatalf("got %d style rules and errors %v", len(rules), errs)
+			}
+			var ids []string
+			for _, element := range query_selector_all(root, rules[0].selectors) {
+				id, _ := get_attribute(element, "id")
+				ids = append(ids, id)
+			}
+			if got := strings.Join(ids, " "); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestUndeclaredNamespacePrefix(t *testing.T) {
+	tests := []struct {
+		css string
+		// The serialization of the stylesheet.
+		want string
+	}{
+		{css: `svg|circle { fill: red } a { color: red }`, want: "a {\ncolor: red;\n}\n"},
+		{css: `@namespace svg "x"; svg|circle { fill: red }`, want: "@namespace svg \"x\";svg|circle {\nfill: red;\n}\n"},
+		{css: `a:is(x|b) { color: red }`, want: ""},
+		{css: `[x|href] { color: red }`, want: ""},
+		{css: `@keyframes a { x|b { color: red } }`, want: "@keyframes a {\nx|b {\ncolor: red;\n}\n}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			if got := parse_stylesheet(strings.NewReader(test.css)).Stringify(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+
+	selectors, err := parse_selector_list(parse_component_value_list("a, x|a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := selectors.resolve_namespaces(nil); err == nil || err.Error() != "Parse Error: undeclared namespace prefix 'x' in selector" {
+		t.Errorf("got %v", err)
+	}
+}
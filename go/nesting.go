@@ -0,0 +1,245 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-nesting/
+// A style rule, possibly nested in other style rules, with its selector list resolved against theirs.
+type ResolvedStyleRule struct {
+	// The QUALIFIED_RULE or NESTED_DECLARATIONS_RULE.
+	rule Rule
+	// The selector list with every nesting selector replaced by the parent rule's selectors.
+	selectors SelectorList
+	// The group rules (e.g. @media, @supports) the rule is inside, outermost first.
+	conditions []Rule
+}
+
+// The style rules of the stylesheet in order of appearance, including nested style rules and nested declarations rules,
+// each with its fully-resolved selector list, including its namespace prefixes.
+// A rule with an invalid selector is reported, and left out along with the rules nested in it.
+func (s Stylesheet) StyleRules() ([]ResolvedStyleRule, []error) {
+	var result []ResolvedStyleRule
+	var errs []error
+
+	var walk func(rules []Rule, parent SelectorList, conditions []Rule)
+	walk = func(rules []Rule, parent SelectorList, conditions []Rule) {
+		for _, rule := range rules {
+			switch {
+			case rule.kind == QUALIFIED_RULE || rule.kind == NESTED_DECLARATIONS_RULE:
+				selectors, err := rule.nested_selectors(parent)
+				if err == nil {
+					selectors, err = selectors.resolve_namespaces(s.namespaces)
+				}
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				result = append(result, ResolvedStyleRule{rule: rule, selectors: selectors, conditions: conditions})
+				walk(rule.children, selectors, conditions)
+			// The children of other at-rules (e.g. the keyframes of @keyframes) aren't style rules.
+			case property_context_at_rules[strings.ToLower(rule.name)]:
+				walk(rule.children, parent, append(slices.Clip(conditions), rule))
+			}
+		}
+	}
+	walk(s.rules, nil, nil)
+
+	return result, errs
+}
+
+// The selector list of a style rule nested in a style rule with the parent selectors, with its nesting selectors resolved.
+// A nested declarations rule matches the same elements as its parent, with the same specificity.
+// A top-level style rule (with no parent) has its selector list parsed as it is.
+func (r Rule) nested_selectors(parent SelectorList) (SelectorList, error) {
+	if r.kind == NESTED_DECLARATIONS_RULE {
+		if parent == nil {
+			return nil, fmt.Errorf("Parse Error: nested declarations must be inside a style rule")
+		}
+		return parent, nil
+	}
+	if parent == nil {
+		return r.selectors()
+	}
+
+	selectors, err := parse_nested_selector_list(r.prelude)
+	if err != nil {
+		return nil, err
+	}
+	parent, err = nestable_selectors(parent)
+	if err != nil {
+		return nil, err
+	}
+
+	return resolve_nesting_selectors(selectors, parent), nil
+}
+
+// https://drafts.csswg.org/css-nesting/#nest-selector
+// The nesting selector can't represent pseudo-elements, so it only stands for the parent's selectors that don't end in one.
+// If they all do, a rule nested in the parent can't match anything.
+func nestable_selectors(parent SelectorList) (SelectorList, error) {
+	var result SelectorList
+	for _, selector := range parent {
+		if ends_in_pseudo_element(selector) == false {
+			result = append(result, selector)
+		}
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("Parse Error: the nesting selector cannot represent the pseudo-elements of '%s'", parent.String())
+	}
+
+	return result, nil
+}
+
+func ends_in_pseudo_element(selector ComplexSelector) bool {
+	last := selector.compounds[len(selector.compounds)-1]
+	return slices.ContainsFunc(last.selectors, func(simple SimpleSelector) bool {
+		return simple.kind == PSEUDO_ELEMENT_SELECTOR
+	})
+}
+
+// https://drafts.csswg.org/css-nesting/#syntax
+// The prelude of a nested style rule is a <relative-selector-list>, and each relative selector is made absolute.
+func parse_nested_selector_list(list []ComponentValue) (SelectorList, error) {
+	selectors, err := parse_relative_selector_list(list)
+	if err != nil {
+		return nil, err
+	}
+	for i, selector := range selectors {
+		selectors[i] = absolutize_nested_selector(selector)
+	}
+
+	return selectors, nil
+}
+
+// A relative selector that starts with a combinator, or that has no nesting selector, is relative to the parent rule:
+// '&' is inserted before it, with the descendant combinator if it had none (e.g. ".a" becomes "& .a", "> .a" becomes "& > .a").
+func absolutize_nested_selector(selector ComplexSelector) ComplexSelector {
+	if selector.compounds[0].combinator == COMBINATOR_NONE && count_nesting_selectors(selector) > 0 {
+		return selector
+	}
+
+	compounds := []CompoundSelector{{selectors: []SimpleSelector{{kind: NESTING_SELECTOR}}}}
+	compounds = append(compounds, selector.compounds...)
+	if compounds[1].combinator == COMBINATOR_NONE {
+		compounds[1].combinator = COMBINATOR_DESCENDANT
+	}
+
+	return ComplexSelector{compounds: compounds}
+}
+
+// The number of nesting selectors in the selector, including those in the arguments of pseudo-classes.
+func count_nesting_selectors(selector ComplexSelector) int {
+	count := 0
+	for _, compound := range selector.compounds {
+		for _, simple := range compound.selectors {
+			if simple.kind == NESTING_SELECTOR {
+				count += 1
+			}
+			for _, argument := range simple.selectors {
+				count += count_nesting_selectors(argument)
+			}
+		}
+	}
+
+	return count
+}
+
+func resolve_nesting_selectors(selectors SelectorList, parent SelectorList) SelectorList {
+	resolved := make(SelectorList, len(selectors))
+	for i, selector := range selectors {
+		resolved[i] = resolve_nesting_selector(selector, parent)
+	}
+
+	return resolved
+}
+
+// https://drafts.csswg.org/css-nesting/#nest-selector
+// The nesting selector matches the elements the parent selector list matches, with the specificity of :is(<parent>),
+// so that's what it's replaced with. When the parent is a single selector, it's merged into the compound selector
+// in place of '&' where possible instead, which means the same with no :is().
+func resolve_nesting_selector(selector ComplexSelector, parent SelectorList) ComplexSelector {
+	if merged, ok := merge_nesting_selector(selector, parent); ok {
+		return merged
+	}
+
+	compounds := make([]CompoundSelector, len(selector.compounds))
+	for i, compound := range selector.compounds {
+		simples := make([]SimpleSelector, len(compound.selectors))
+		for j, simple := range compound.selectors {
+			switch {
+			case simple.kind == NESTING_SELECTOR:
+				simple = SimpleSelector{
+					kind:        PSEUDO_CLASS_SELECTOR,
+					name:        "is",
+					is_function: true,
+					arguments:   parse_component_value_list(parent.String()),
+					selectors:   parent,
+				}
+			case len(simple.selectors) > 0:
+				simple.selectors = resolve_nesting_selectors(simple.selectors, parent)
+			}
+			simples[j] = simple
+		}
+		compounds[i] = CompoundSelector{combinator: compound.combinator, selectors: simples}
+	}
+
+	return ComplexSelector{compounds: compounds}
+}
+
+// Merge the parent selector into the compound selector holding the selector's only nesting selector,
+// e.g. "&.b > .c" nested in ".a .b" is ".a .b.b > .c", and ".c &" nested in ".a" is ".c .a".
+// A parent with combinators can only be merged into the first compound selector, where its combinators lead up to it.
+// Nor is merging possible if both compound selectors have a type selector.
+func merge_nesting_selector(selector ComplexSelector, parent SelectorList) (ComplexSelector, bool) {
+	if len(parent) != 1 || count_nesting_selectors(selector) != 1 {
+		return selector, false
+	}
+	position, index := -1, -1
+	for i, compound := range selector.compounds {
+		j := slices.IndexFunc(compound.selectors, func(simple SimpleSelector) bool {
+			return simple.kind == NESTING_SELECTOR
+		})
+		if j >= 0 {
+			position, index = i, j
+		}
+	}
+
+	parent_compounds := parent[0].compounds
+	compound := selector.compounds[max(position, 0)]
+	if position < 0 || (len(parent_compounds) > 1 && (position > 0 || compound.combinator != COMBINATOR_NONE)) {
+		return selector, false
+	}
+
+	last := parent_compounds[len(parent_compounds)-1]
+	rest := slices.Delete(slices.Clone(compound.selectors), index, index+1)
+	if has_type_selector(last) && has_type_selector(CompoundSelector{selectors: rest}) {
+		return selector, false
+	}
+
+	// The type selector comes first, wherever it came from.
+	var merged []SimpleSelector
+	if has_type_selector(CompoundSelector{selectors: rest}) {
+		merged = append(merged, rest[0])
+		rest = rest[1:]
+	}
+	merged = append(merged, last.selectors...)
+	merged = append(merged, rest...)
+
+	combinator := compound.combinator
+	if len(parent_compounds) > 1 {
+		combinator = last.combinator
+	}
+	compounds := slices.Clone(selector.compounds[:position])
+	compounds = append(compounds, parent_compounds[:len(parent_compounds)-1]...)
+	compounds = append(compounds, CompoundSelector{combinator: combinator, selectors: merged})
+	compounds = append(compounds, selector.compounds[position+1:]...)
+
+	return ComplexSelector{compounds: compounds}, true
+}
+
+func has_type_selector(compound CompoundSelector) bool {
+	return len(compound.selectors) > 0 && (compound.selectors[0].kind == TYPE_SELECTOR || compound.selectors[0].kind == UNIVERSAL_SELECTOR)
+}
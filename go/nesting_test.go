@@ -0,0 +1,63 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStyleRules(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		// The resolved selector list of each style rule, in order of appearance.
+		want []string
+		// The text of the error reported, if any.
+		wantErr string
+	}{
+		{name: "relative selectors", css: `.a { .b {} > .c {} + .d {} ~ .e {} }`, want: []string{".a", ".a .b", ".a > .c", ".a + .d", ".a ~ .e"}},
+		{name: "nesting selector", css: `.a { &:hover {} .e & {} :not(&) {} & & {} }`, want: []string{".a", ".a:hover", ".e .a", ":not(.a)", ":is(.a) :is(.a)"}},
+		{name: "type selector merged first", css: `div { span& {} }`, want: []string{"div", "span:is(div)"}},
+		{name: "parent selector list", css: `.a, #b { .c & {} }`, want: []string{".a, #b", ".c :is(.a, #b)"}},
+		{name: "deep nesting", css: `.a { .b { .c {} } }`, want: []string{".a", ".a .b", ".a .b .c"}},
+		{name: "nested declarations", css: `.a { .b {} color: red; @media screen { color: blue } }`, want: []string{".a", ".a .b", ".a", ".a"}},
+		{name: "group rules", css: `.a { @media screen { .b {} } }`, want: []string{".a", ".a .b"}},
+		{name: "namespaces", css: `@namespace svg url(x); svg|a { & svg|b {} }`, want: []string{"svg|a", "svg|a svg|b"}},
+		{name: "invalid nested selector", css: `.a { .b! { .d {} } .c {} }`, want: []string{".a", ".a .c"}, wantErr: "unexpected '!' in selector"},
+		{name: "pseudo-element parent", css: `.a::before { .b {} }`, want: []string{".a::before"}, wantErr: "cannot represent the pseudo-elements of '.a::before'"},
+		{name: "pseudo-element in a parent selector list", css: `.a::before, .c { .b & {} }`, want: []string{".a::before, .c", ".b .c"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rules, errs := parse_stylesheet(strings.NewReader(test.css)).StyleRules()
+			var got []string
+			for _, rule := range rules {
+				got = append(got, rule.selectors.String())
+			}
+			if strings.Join(got, "; ") != strings.Join(test.want, "; ") {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			switch {
+			case test.wantErr == "" && len(errs) > 0:
+				t.Errorf("unexpected errors: %v", errs)
+			case test.wantErr != "" && (len(errs) != 1 || strings.Contains(errs[0].Error(), test.wantErr) == false):
+				t.Errorf("expected an error containing %q, got %v", test.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestStyleRulesConditions(t *testing.T) {
+	rules, _ := parse_stylesheet(strings.NewReader(`@media screen { @supports (display: grid) { .a { .b { color: red } } } }`)).StyleRules()
+	if len(rules) != 2 {
+		t.Fatalf("expected 2 style rules, got %d", len(rules))
+	}
+	for _, rule := range rules {
+		if len(rule.conditions) != 2 || rule.conditions[0].name != "media" || rule.conditions[1].name != "supports" {
+			t.Errorf("%s: unexpected conditions %v", rule.selectors, rule.conditions)
+		}
+	}
+	if rules[1].rule.kind != QUALIFIED_RULE {
+		t.Errorf("expected a style rule, got %v", rules[1].rule.kind)
+	}
+}
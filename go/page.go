@@ -0,0 +1,327 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-page-3/#at-page-rule
+// @page <page-selector-list>? { <declaration-rule-list> }
+type PageRule struct {
+	// The pages the rule applies to. An empty list applies to every page.
+	selectors []PageSelector
+	decls     []Declaration
+	// The valid margin rules, in order of appearance.
+	margin_rules []MarginRule
+}
+
+// https://drafts.csswg.org/css-page-3/#typedef-page-selector
+// <page-selector> = [ <ident-token>? <pseudo-page>* ]!
+// <pseudo-page> = : [ left | right | first | blank ]
+type PageSelector struct {
+	// The named page type, or empty for any page.
+	name string
+	// The lowercased pseudo-classes, without their colons.
+	pseudo_pages []string
+}
+
+// https://drafts.csswg.org/css-page-3/#margin-at-rules
+// An at-rule for one of the 16 margin boxes of a page, e.g. @top-center { content: "x" }.
+type MarginRule struct {
+	// The lowercased name of the margin box, e.g. "top-center".
+	name  string
+	decls []Declaration
+}
+
+// A page of the paginated document, as far as page selectors are concerned.
+type Page struct {
+	// The index of the page in the document, from 0.
+	index int
+	// The page type, from the page property of the elements on the page, or empty.
+	name string
+	// Whether the page is a left page, which depends on the page progression direction of the document.
+	left bool
+	// Whether the page is a blank page, inserted by a forced break so that content starts on a left or right page.
+	blank bool
+}
+
+// The declarations that apply to a page, and to each of its margin boxes, after the cascade.
+type PageStyle struct {
+	decls        map[string]Declaration
+	margin_boxes map[string]map[string]Declaration
+}
+
+// The margin boxes, in the order the spec defines them in.
+var margin_box_names = []string{
+	"top-left-corner", "top-left", "top-center", "top-right", "top-right-corner",
+	"bottom-left-corner", "bottom-left", "bottom-center", "bottom-right", "bottom-right-corner",
+	"left-top", "left-middle", "left-bottom", "right-top", "right-middle", "right-bottom",
+}
+
+func is_page_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && strings.EqualFold(rule.name, "page")
+}
+
+func is_margin_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && slices.Contains(margin_box_names, strings.ToLower(rule.name))
+}
+
+// Extract the selectors, declarations and margin rules of an @page rule. The rule is invalid if its selector list is.
+// At-rules other than margin rules aren't allowed in @page, and no at-rules are allowed in margin rules:
+// they're reported and ignored.
+func (r Rule) page_rule() (PageRule, []error) {
+	if is_page_rule(r) == false {
+		return PageRule{}, []error{fmt.Errorf("Parse Error: rule is not an @page rule")}
+	}
+	selectors, err := parse_page_selector_list(r.prelude)
+	if err != nil {
+		return PageRule{}, []error{err}
+	}
+
+	page := PageRule{selectors: selectors, decls: r.decls}
+	var errs []error
+	for _, child := range r.children {
+		if is_margin_rule(child) == false {
+			errs = append(errs, fmt.Errorf("Parse Error: @%s is not allowed in @page", child.name))
+			continue
+		}
+		if len(trim_whitespace(child.prelude)) > 0 || child.has_block == false {
+			errs = append(errs, fmt.Errorf("Parse Error: @%s must have an empty prelude and a block", child.name))
+			continue
+		}
+		for _, nested := range child.children {
+			errs = append(errs, fmt.Errorf("Parse Error: @%s is not allowed in @%s", nested.name, child.name))
+		}
+		page.margin_rules = append(page.margin_rules, MarginRule{name: strings.ToLower(child.name), decls: child.decls})
+	}
+
+	return page, errs
+}
+
+// The stylesheet's valid @page rules, in order, including those in conditional group rules and layers, whose conditions
+// aren't evaluated. @page rules nested in style rules or in other @page rules, and margin rules outside of @page, are reported.
+func (s Stylesheet) PageRules() ([]PageRule, []error) {
+	var pages []PageRule
+	var errors []error
+	var walk func(rules []Rule, in_style_rule bool)
+	walk = func(rules []Rule, in_style_rule bool) {
+		for _, rule := range rules {
+			switch {
+			case is_page_rule(rule) && in_style_rule:
+				errors = append(errors, fmt.Errorf("Parse Error: @page is not allowed in a style rule"))
+			case is_page_rule(rule):
+				page, errs := rule.page_rule()
+				errors = append(errors, errs...)
+				// The errors of a valid rule are only about its nested rules.
+				if _, err := parse_page_selector_list(rule.prelude); err == nil {
+					pages = append(pages, page)
+				}
+			case is_margin_rule(rule):
+				errors = append(errors, fmt.Errorf("Parse Error: @%s is only allowed in @page", rule.name))
+			default:
+				walk(rule.children, in_style_rule || rule.kind != AT_RULE)
+			}
+		}
+	}
+	walk(s.rules, false)
+
+	return pages, errors
+}
+
+// <page-selector-list> = <page-selector>#
+// There's no whitespace within a page selector, e.g. "toc:first" but not "toc :first".
+func parse_page_selector_list(prelude []ComponentValue) ([]PageSelector, error) {
+	if len(trim_whitespace(prelude)) == 0 {
+		return nil, nil
+	}
+
+	var selectors []PageSelector
+	for _, item := range split_on_commas(prelude) {
+		text := strings.TrimSpace(serialize_component_value_list(item))
+		values := trim_whitespace(item)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("Parse Error: empty page selector in '%s'", strings.TrimSpace(serialize_component_value_list(prelude)))
+		}
+
+		var selector PageSelector
+		if values[0].is_token(IDENT_TOKEN) {
+			selector.name = string(values[0].token.value)
+			values = values[1:]
+		}
+		for len(values) > 0 {
+			if len(values) < 2 || values[0].is_token(COLON_TOKEN) == false || values[1].is_token(IDENT_TOKEN) == false {
+				return nil, fmt.Errorf("Parse Error: invalid page selector '%s'", text)
+			}
+			if is_ident_in(values[1], "left", "right", "first", "blank") == false {
+				return nil, fmt.Errorf("Parse Error: unknown page pseudo-class ':%s' in '%s'", string(values[1].token.value), text)
+			}
+			selector.pseudo_pages = append(selector.pseudo_pages, strings.ToLower(string(values[1].token.value)))
+			values = values[2:]
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+// https://drafts.csswg.org/css-page-3/#cascading-and-page-context
+// A page selector's specificity counts its page type name, then its :first and :blank pseudo-classes,
+// then its :left and :right pseudo-classes.
+func (s PageSelector) specificity() specificity {
+	result := specificity{}
+	if s.name != "" {
+		result.a = 1
+	}
+	for _, pseudo := range s.pseudo_pages {
+		if pseudo == "first" || pseudo == "blank" {
+			result.b += 1
+		} else {
+			result.c += 1
+		}
+	}
+
+	return result
+}
+
+func (s PageSelector) matches(page Page) bool {
+	if s.name != "" && s.name != page.name {
+		return false
+	}
+	for _, pseudo := range s.pseudo_pages {
+		switch {
+		case pseudo == "first" && page.index != 0,
+			pseudo == "blank" && page.blank == false,
+			pseudo == "left" && page.left == false,
+			pseudo == "right" && page.left:
+			return false
+		}
+	}
+
+	return true
+}
+
+// Whether the rule applies to the page, and the specificity of its most specific selector that matches it.
+func (p PageRule) Matches(page Page) (bool, specificity) {
+	if len(p.selectors) == 0 {
+		return true, specificity{}
+	}
+
+	matched := false
+	var best specificity
+	for _, selector := range p.selectors {
+		if selector.matches(page) {
+			if s := selector.specificity(); matched == false || s.compare(best) > 0 {
+				best = s
+			}
+			matched = true
+		}
+	}
+
+	return matched, best
+}
+
+// Cascade the declarations of the @page rules (in order of appearance) that apply to the page, and those of their margin rules.
+// !important declarations win over normal ones, then those from more specific selectors, then those that come last.
+func ComputePageStyle(rules []PageRule, page Page) PageStyle {
+	type page_candidate struct {
+		decl        Declaration
+		margin_box  string
+		specificity specificity
+		order       int
+	}
+	var candidates []page_candidate
+	order := 0
+	for _, rule := range rules {
+		matched, specificity := rule.Matches(page)
+		if matched == false {
+			continue
+		}
+		// Shorthands are cascaded as their longhands, as in the cascade of elements.
+		for _, decl := range rule.decls {
+			for _, longhand := range cascaded_longhands(decl) {
+				candidates = append(candidates, page_candidate{decl: longhand, specificity: specificity, order: order})
+			}
+			order += 1
+		}
+		for _, margin_rule := range rule.margin_rules {
+			for _, decl := range margin_rule.decls {
+				for _, longhand := range cascaded_longhands(decl) {
+					candidates = append(candidates, page_candidate{decl: longhand, margin_box: margin_rule.name, specificity: specificity, order: order})
+				}
+				order += 1
+			}
+		}
+	}
+
+	// Sort the declarations by ascending precedence, so each one overrides those before it.
+	sort.SliceStable(candidates, func(i int, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.decl.important != b.decl.important {
+			return b.decl.important
+		}
+		if c := a.specificity.compare(b.specificity); c != 0 {
+			return c < 0
+		}
+		return a.order < b.order
+	})
+
+	style := PageStyle{decls: map[string]Declaration{}, margin_boxes: map[string]map[string]Declaration{}}
+	for _, candidate := range candidates {
+		name := property_name(candidate.decl.name)
+		if candidate.margin_box == "" {
+			style.decls[name] = candidate.decl
+			continue
+		}
+		if style.margin_boxes[candidate.margin_box] == nil {
+			style.margin_boxes[candidate.margin_box] = map[string]Declaration{}
+		}
+		style.margin_boxes[candidate.margin_box][name] = candidate.decl
+	}
+
+	return style
+}
+
+// The @page rule, with its margin rules in the order of the margin boxes.
+func (p PageRule) Rule() Rule {
+	var selectors []string
+	for _, selector := range p.selectors {
+		selectors = append(selectors, selector.String())
+	}
+	prelude := []ComponentValue{whitespace_value()}
+	if len(selectors) > 0 {
+		prelude = append(prelude, parse_component_value_list(strings.Join(selectors, ", "))...)
+		prelude = append(prelude, whitespace_value())
+	}
+	rule := Rule{kind: AT_RULE, name: "page", prelude: prelude, decls: p.decls, has_block: true}
+
+	for _, name := range margin_box_names {
+		for _, margin_rule := range p.margin_rules {
+			if margin_rule.name == name {
+				rule.children = append(rule.children, Rule{kind: AT_RULE, name: name, prelude: []ComponentValue{whitespace_value()}, decls: margin_rule.decls, has_block: true})
+			}
+		}
+	}
+
+	return rule
+}
+
+func (p PageRule) String() string {
+	var sb strings.Builder
+	stringify_rule(&sb, p.Rule())
+	return sb.String()
+}
+
+func (s PageSelector) String() string {
+	var sb strings.Builder
+	if s.name != "" {
+		stringify_identifier(&sb, s.name)
+	}
+	for _, pseudo := range s.pseudo_pages {
+		sb.WriteRune(COLON_CHAR)
+		sb.WriteString(pseudo)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// The page style's declarations, sorted by name, with those of the margin boxes prefixed by the box's name.
+func describe_page_style(style PageStyle) string {
+	var decls []string
+	for name, decl := range style.decls {
+		decls = append(decls, fmt.Sprintf("%s: %s", name, serialize_component_value_list(decl.value)))
+	}
+	for box, box_decls := range style.margin_boxes {
+		for name, decl := range box_decls {
+			decls = append(decls, fmt.Sprintf("@%s %s: %s", box, name, serialize_component_value_list(decl.value)))
+		}
+	}
+	sort.Strings(decls)
+
+	return strings.Join(decls, "; ")
+}
+
+func TestPageRules(t *testing.T) {
+	tests := []struct {
+		css string
+		// The serialization of the @page rules.
+		want string
+		// The texts of the errors reported.
+		errors []string
+	}{
+		{css: `@page { margin: 1in }`, want: "@page {\nmargin: 1in;\n}\n"},
+		{css: `@page :FIRST, toc:left:blank { margin: 1in }`, want: "@page :first, toc:left:blank {\nmargin: 1in;\n}\n"},
+		{
+			css:  `@page { @bottom-center { content: counter(page) } @top-left { content: "a" } }`,
+			want: "@page {\n@top-left {\ncontent: \"a\";\n}\n@bottom-center {\ncontent: counter(page);\n}\n}\n",
+		},
+		{css: `@page :middle { margin: 0 }`, errors: []string{"unknown page pseudo-class ':middle' in ':middle'"}},
+		{css: `@page toc :first { margin: 0 }`, errors: []string{"invalid page selector 'toc :first'"}},
+		{css: `@page a, { margin: 0 }`, errors: []string{"empty page selector in 'a,'"}},
+		{css: `@page { @media print {} }`, want: "@page {\n}\n", errors: []string{"@media is not allowed in @page"}},
+		{css: `@page { @top-left x {} }`, want: "@page {\n}\n", errors: []string{"@top-left must have an empty prelude and a block"}},
+		{css: `@top-left {}`, errors: []string{"@top-left is only allowed in @page"}},
+		{css: `@media print { @page :left { margin: 0 } }`, want: "@page :left {\nmargin: 0;\n}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			pages, errs := parse_stylesheet(strings.NewReader(test.css)).PageRules()
+			var sb strings.Builder
+			for _, page := range pages {
+				sb.WriteString(page.String())
+			}
+			if got := sb.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			if len(errs) != len(test.errors) {
+				t.Fatalf("got errors %v, want %q", errs, test.errors)
+			}
+			for i, err := range errs {
+				if strings.Contains(err.Error(), test.errors[i]) == false {
+					t.Errorf("got error %q, want one containing %q", err, test.errors[i])
+				}
+			}
+		})
+	}
+}
+
+func TestComputePageStyle(t *testing.T) {
+	css := `@page { margin-top: 1in; size: A4 }
+		@page :left { margin-top: 2in }
+		@page :first { margin-top: 3in !important }
+		@page toc { size: letter; @top-center { content: "Contents" } }
+		@page toc:right { size: legal }
+		@page toc:blank { @top-center { content: none } }`
+	rules, errs := parse_stylesheet(strings.NewReader(css)).PageRules()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+
+	tests := []struct {
+		page Page
+		want string
+	}{
+		{page: Page{index: 0}, want: "margin-top: 3in; size: A4"},
+		{page: Page{index: 1, left: true}, want: "margin-top: 2in; size: A4"},
+		{page: Page{index: 2}, want: "margin-top: 1in; size: A4"},
+		{page: Page{index: 3, name: "toc", left: true}, want: "@top-center content: \"Contents\"; margin-top: 2in; size: letter"},
+		{page: Page{index: 4, name: "toc"}, want: "@top-center content: \"Contents\"; margin-top: 1in; size: legal"},
+		{page: Page{index: 5, name: "toc", blank: true}, want: "@top-center content: none; margin-top: 1in; size: legal"},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%+v", test.page), func(t *testing.T) {
+			if got := describe_page_style(ComputePageStyle(rules, test.page)); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestComputePageStyleShorthands(t *testing.T) {
+	tests := []struct {
+		css  string
+		page Page
+		want string
+	}{
+		{
+			css:  `@page :first { margin-top: 0 } @page { margin: 1in }`,
+			page: Page{index: 0},
+			want: "margin-bottom: 1in; margin-left: 1in; margin-right: 1in; margin-top: 0",
+		},
+		{
+			css:  `@page { margin-top: 0; margin: 1in 2in }`,
+			page: Page{index: 0},
+			want: "margin-bottom: 1in; margin-left: 2in; margin-right: 2in; margin-top: 1in",
+		},
+		{
+			css:  `@page { @top-center { padding: 1px; padding-top: 2px } }`,
+			page: Page{index: 0},
+			want: "@top-center padding-bottom: 1px; @top-center padding-left: 1px; @top-center padding-right: 1px; @top-center padding-top: 2px",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			rules, errs := parse_stylesheet(strings.NewReader(test.css)).PageRules()
+			if len(errs) > 0 {
+				t.Fatalf("unexpected errors: %v", errs)
+			}
+			if got := describe_page_style(ComputePageStyle(rules, test.page)); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
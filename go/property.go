@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://drafts.css-houdini.org/css-properties-values-api/#at-property-rule
+// @property <custom-property-name> { <declaration-list> }
+type PropertyRule struct {
+	name     string
+	syntax   PropertySyntax
+	inherits bool
+	// The initial value is only optional when the syntax is the universal syntax definition.
+	has_initial_value bool
+	initial_value     []ComponentValue
+}
+
+// Why an @property rule is invalid, and which descriptor made it so. Descriptor is empty when the prelude is invalid.
+type PropertyRuleError struct {
+	Property   string
+	Descriptor string
+	Reason     string
+}
+
+func (e PropertyRuleError) Error() string {
+	if e.Descriptor == "" {
+		return fmt.Sprintf("Parse Error: invalid @property rule: %s", e.Reason)
+	}
+
+	return fmt.Sprintf("Parse Error: invalid '%s' descriptor in @property %s: %s", e.Descriptor, e.Property, e.Reason)
+}
+
+// https://drafts.css-houdini.org/css-properties-values-api/#syntax-strings
+// The universal syntax definition "*", or a list of alternative components separated by '|'.
+type PropertySyntax struct {
+	universal  bool
+	components []SyntaxComponent
+}
+
+// A data type name such as <length>, or an identifier, with an optional multiplier.
+type SyntaxComponent struct {
+	// The data type name without its angle brackets, or the identifier.
+	name    string
+	is_type bool
+	// '+' for a space-separated list, '#' for a comma-separated list, or 0 for a single value.
+	multiplier rune
+}
+
+// https://drafts.css-houdini.org/css-properties-values-api/#supported-names
+var syntax_data_types = map[string]bool{
+	"length": true, "number": true, "percentage": true, "length-percentage": true, "color": true, "image": true,
+	"url": true, "integer": true, "angle": true, "time": true, "resolution": true, "transform-function": true,
+	"custom-ident": true, "transform-list": true,
+}
+
+// https://drafts.csswg.org/css-transforms-2/#transform-functions
+var transform_functions = map[string]bool{
+	"matrix": true, "translate": true, "translatex": true, "translatey": true, "scale": true, "scalex": true,
+	"scaley": true, "rotate": true, "skew": true, "skewx": true, "skewy": true, "matrix3d": true, "translate3d": true,
+	"translatez": true, "scale3d": true, "scalez": true, "rotate3d": true, "rotatex": true, "rotatey": true,
+	"rotatez": true, "perspective": true,
+}
+
+func is_property_rule(rule Rule) bool {
+	return rule.kind == AT_RULE && strings.EqualFold(rule.name, "property")
+}
+
+// Extract the registration of a custom property from an @property rule.
+func (r Rule) property_rule() (PropertyRule, error) {
+	if is_property_rule(r) == false {
+		return PropertyRule{}, PropertyRuleError{Reason: "rule is not an @property rule"}
+	}
+
+	prelude := trim_whitespace(r.prelude)
+	if len(prelude) != 1 || prelude[0].is_token(IDENT_TOKEN) == false || is_custom_property_name(string(prelude[0].token.value)) == false {
+		return PropertyRule{}, PropertyRuleError{Reason: "expected a custom property name"}
+	}
+	if r.has_block == false {
+		return PropertyRule{}, PropertyRuleError{Reason: "@property rule must have a block"}
+	}
+
+	rule := PropertyRule{name: string(prelude[0].token.value)}
+	invalid := func(descriptor string, format string, args ...any) error {
+		return PropertyRuleError{Property: rule.name, Descriptor: descriptor, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	// Later declarations of a descriptor override earlier ones, and unknown descriptors are ignored.
+	descriptors := map[string][]ComponentValue{}
+	for _, decl := range r.decls {
+		descriptors[strings.ToLower(decl.name)] = trim_whitespace(decl.value)
+	}
+
+	syntax, ok := descriptors["syntax"]
+	if ok == false {
+		return rule, invalid("syntax", "the descriptor is required")
+	}
+	if len(syntax) != 1 || syntax[0].is_token(STRING_TOKEN) == false {
+		return rule, invalid("syntax", "expected a string")
+	}
+	parsed, err := ParsePropertySyntax(string(syntax[0].token.value))
+	if err != nil {
+		return rule, invalid("syntax", "%s", strings.TrimPrefix(err.Error(), "Parse Error: "))
+	}
+	rule.syntax = parsed
+
+	inherits, ok := descriptors["inherits"]
+	switch {
+	case ok == false:
+		return rule, invalid("inherits", "the descriptor is required")
+	case len(inherits) == 1 && inherits[0].is_ident("true"):
+		rule.inherits = true
+	case len(inherits) == 1 && inherits[0].is_ident("false"):
+		rule.inherits = false
+	default:
+		return rule, invalid("inherits", "expected true or false")
+	}
+
+	initial_value, ok := descriptors["initial-value"]
+	switch {
+	case ok == false && rule.syntax.universal:
+	case ok == false:
+		return rule, invalid("initial-value", "the descriptor is required unless the syntax is '*'")
+	case rule.syntax.Matches(initial_value) == false || contains_substitution_function(initial_value):
+		return rule, invalid("initial-value", "'%s' doesn't match the syntax '%s'", serialize_component_value_list(initial_value), rule.syntax)
+	case is_computationally_independent(initial_value) == false:
+		return rule, invalid("initial-value", "'%s' depends on the element it's used on", serialize_component_value_list(initial_value))
+	default:
+		rule.has_initial_value = true
+		rule.initial_value = initial_value
+	}
+
+	return rule, nil
+}
+
+// The valid @property rules of the stylesheet, keyed by the name of the registered custom property,
+// and the errors of the invalid ones. When a property is registered more than once, the last registration wins.
+func (s Stylesheet) PropertyRegistrations() (map[string]PropertyRule, []error) {
+	registrations := map[string]PropertyRule{}
+	var errors []error
+	var walk func(rules []Rule)
+	walk = func(rules []Rule) {
+		for _, rule := range rules {
+			if is_property_rule(rule) == false {
+				walk(rule.children)
+				continue
+			}
+			registration, err := rule.property_rule()
+			if err != nil {
+				errors = append(errors, err)
+				continue
+			}
+			registrations[registration.name] = registration
+		}
+	}
+	walk(s.rules)
+
+	return registrations, errors
+}
+
+// Check the value of the registered custom property against its syntax. A value containing var() can only be checked after substitution.
+func (p PropertyRule) Validate(value []ComponentValue) error {
+	value = trim_whitespace(value)
+	if contains_substitution_function(value) || p.syntax.Matches(value) {
+		return nil
+	}
+
+	return fmt.Errorf("Parse Error: '%s' doesn't match the syntax '%s' of %s", serialize_component_value_list(value), p.syntax, p.name)
+}
+
+// https://drafts.css-houdini.org/css-properties-values-api/#parsing-syntax
+func ParsePropertySyntax(syntax string) (PropertySyntax, error) {
+	syntax = strings.Trim(syntax, " \t\n\r\f")
+	if syntax == "" {
+		return PropertySyntax{}, fmt.Errorf("Parse Error: syntax string must not be empty")
+	}
+	if syntax == "*" {
+		return PropertySyntax{universal: true}, nil
+	}
+
+	var result PropertySyntax
+	for _, part := range strings.Split(syntax, "|") {
+		component, err := parse_syntax_component(part)
+		if err != nil {
+			return PropertySyntax{}, err
+		}
+		result.components = append(result.components, component)
+	}
+
+	return result, nil
+}
+
+// <syntax-component> = [ <syntax-single-component> <syntax-multiplier>? ] | [ '<' transform-list '>' ]
+// <syntax-single-component> = '<' <syntax-type-name> '>' | <ident>
+func parse_syntax_component(part string) (SyntaxComponent, error) {
+	values := trim_whitespace(parse_component_value_list(part))
+	if len(values) == 0 {
+		return SyntaxComponent{}, fmt.Errorf("Parse Error: syntax string has an empty component")
+	}
+
+	var component SyntaxComponent
+	switch {
+	case values[0].is_delim('<'):
+		if len(values) < 3 || values[1].is_token(IDENT_TOKEN) == false || values[2].is_delim('>') == false {
+			return component, fmt.Errorf("Parse Error: invalid data type name in syntax component '%s'", strings.TrimSpace(part))
+		}
+		component.name = string(values[1].token.value)
+		if syntax_data_types[component.name] == false {
+			return component, fmt.Errorf("Parse Error: unsupported data type name '<%s>'", component.name)
+		}
+		component.is_type = true
+		values = values[3:]
+	case values[0].is_token(IDENT_TOKEN):
+		component.name = string(values[0].token.value)
+		if is_css_wide_keyword(component.name) || strings.EqualFold(component.name, "default") {
+			return component, fmt.Errorf("Parse Error: '%s' can't be used as an identifier in a syntax string", component.name)
+		}
+		values = values[1:]
+	default:
+		return component, fmt.Errorf("Parse Error: unexpected %s in syntax component", describe_value(values[0]))
+	}
+
+	if len(values) > 0 && (values[0].is_delim('+') || values[0].is_delim('#')) {
+		// <transform-list> is already a list, so it can't be multiplied.
+		if component.is_type && component.name == "transform-list" {
+			return component, fmt.Errorf("Parse Error: '<transform-list>' can't have a multiplier")
+		}
+		component.multiplier = values[0].token.value[0]
+		values = values[1:]
+	}
+	if len(values) > 0 {
+		return component, fmt.Errorf("Parse Error: unexpected %s in syntax component", describe_value(values[0]))
+	}
+
+	return component, nil
+}
+
+// Whether the value matches any of the syntax's components.
+func (s PropertySyntax) Matches(value []ComponentValue) bool {
+	if s.universal {
+		return true
+	}
+	for _, component := range s.components {
+		if component.matches(trim_whitespace(value)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c SyntaxComponent) matches(value []ComponentValue) bool {
+	if len(value) == 0 {
+		return false
+	}
+
+	var items []ComponentValue
+	switch {
+	case c.multiplier == '#':
+		for _, part := range split_on_commas(value) {
+			part = trim_whitespace(part)
+			if len(part) != 1 {
+				return false
+			}
+			items = append(items, part[0])
+		}
+	case c.multiplier == '+' || (c.is_type && c.name == "transform-list"):
+		items = non_whitespace_values(value)
+	default:
+		if len(value) != 1 {
+			return false
+		}
+		items = value
+	}
+
+	for _, item := range items {
+		if c.matches_single(item) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c SyntaxComponent) matches_single(value ComponentValue) bool {
+	if c.is_type == false {
+		// Identifiers in a syntax string are matched case-sensitively.
+		return value.is_token(IDENT_TOKEN) && string(value.token.value) == c.name
+	}
+
+	switch c.name {
+	case "length":
+		return is_length(value)
+	case "number":
+		return is_number(value)
+	case "percentage":
+		return value.is_token(PERCENTAGE_TOKEN) || math_function_resolves_to_percentage(value)
+	case "length-percentage":
+		return is_length_percentage(value)
+	case "color":
+		return is_color(value)
+	case "image":
+		return is_image(value)
+	case "url":
+		_, ok := url_value(value)
+		return ok && value.is_token(STRING_TOKEN) == false
+	case "integer":
+		return (value.is_token(NUMBER_TOKEN) && value.token.type_flag == TYPE_INTEGER) || math_function_resolves_to_number(value)
+	case "angle":
+		return is_angle(value)
+	case "time":
+		return is_time(value)
+	case "resolution":
+		_, ok := parse_resolution(value)
+		return ok || math_function_resolves_to(value, RESOLUTION, false)
+	case "transform-function", "transform-list":
+		return value.kind == FUNCTION && transform_functions[strings.ToLower(value.name)]
+	case "custom-ident":
+		return value.is_token(IDENT_TOKEN) && is_css_wide_keyword(string(value.token.value)) == false && value.is_ident("default") == false
+	}
+
+	return false
+}
+
+// https://drafts.css-houdini.org/css-properties-values-api/#computationally-independent
+// A value is computationally independent if it doesn't depend on the element, e.g. through font-relative or viewport units.
+func is_computationally_independent(list []ComponentValue) bool {
+	for _, value := range list {
+		if value.is_token(DIMENSION_TOKEN) {
+			definition, ok := units[strings.ToLower(string(value.token.unit))]
+			if ok && definition.kind == LENGTH && definition.category != ABSOLUTE_UNIT {
+				return false
+			}
+		}
+		if (value.kind == FUNCTION || value.kind == SIMPLE_BLOCK) && is_computationally_independent(value.value) == false {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s PropertySyntax) String() string {
+	if s.universal {
+		return "*"
+	}
+
+	var parts []string
+	for _, component := range s.components {
+		part := component.name
+		if component.is_type {
+			part = "<" + part + ">"
+		}
+		if component.multiplier != 0 {
+			part += string(component.multiplier)
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, " | ")
+}
@@ -0,0 +1,277 @@
+package main
+
+// The grammars of the properties that declarations are validated against: a hand-picked subset of the commonly used
+// properties, copied from the specification linked above each group, rather than generated from webref. As the table is
+// incomplete, a value is only judged where it can be: properties that aren't listed here, and values with vendor-prefixed
+// keywords or functions that no grammar here knows (e.g. -webkit-center or anchor()), are never dropped.
+// A few prefixed values that browsers still accept, e.g. -webkit-box, are spelled out in the grammars.
+var property_grammars = map[string]string{
+	// https://drafts.csswg.org/css-display/
+	"display":    "[ <display-outside> || <display-inside> ] | <display-listitem> | <display-internal> | <display-box> | <display-legacy> | -webkit-box | -webkit-inline-box",
+	"visibility": "visible | hidden | collapse",
+	"order":      "<integer>",
+	// https://drafts.csswg.org/css-position/
+	"position":           "static | relative | absolute | sticky | fixed",
+	"top":                "auto | <length-percentage>",
+	"right":              "auto | <length-percentage>",
+	"bottom":             "auto | <length-percentage>",
+	"left":               "auto | <length-percentage>",
+	"inset-block-start":  "auto | <length-percentage>",
+	"inset-block-end":    "auto | <length-percentage>",
+	"inset-inline-start": "auto | <length-percentage>",
+	"inset-inline-end":   "auto | <length-percentage>",
+	"inset-block":        "[ auto | <length-percentage> ]{1,2}",
+	"inset-inline":       "[ auto | <length-percentage> ]{1,2}",
+	"inset":              "[ auto | <length-percentage> ]{1,4}",
+	"z-index":            "auto | <integer>",
+	// https://drafts.csswg.org/css2/#floats
+	"float": "left | right | none | inline-start | inline-end",
+	"clear": "inline-start | inline-end | block-start | block-end | left | right | top | bottom | both-inline | both-block | both | none",
+	// https://drafts.csswg.org/css-sizing/
+	"box-sizing":      "content-box | border-box",
+	"width":           "auto | <length-percentage [0,∞]> | <sizing-keyword>",
+	"height":          "auto | <length-percentage [0,∞]> | <sizing-keyword>",
+	"min-width":       "auto | <length-percentage [0,∞]> | <sizing-keyword>",
+	"min-height":      "auto | <length-percentage [0,∞]> | <sizing-keyword>",
+	"max-width":       "none | <length-percentage [0,∞]> | <sizing-keyword>",
+	"max-height":      "none | <length-percentage [0,∞]> | <sizing-keyword>",
+	"inline-size":     "<'width'>",
+	"block-size":      "<'width'>",
+	"min-inline-size": "<'min-width'>",
+	"min-block-size":  "<'min-width'>",
+	"max-inline-size": "<'max-width'>",
+	"max-block-size":  "<'max-width'>",
+	"aspect-ratio":    "auto || <ratio>",
+	// https://drafts.csswg.org/css-box/
+	"margin-top":           "<length-percentage> | auto",
+	"margin-right":         "<length-percentage> | auto",
+	"margin-bottom":        "<length-percentage> | auto",
+	"margin-left":          "<length-percentage> | auto",
+	"margin-block-start":   "<length-percentage> | auto",
+	"margin-block-end":     "<length-percentage> | auto",
+	"margin-inline-start":  "<length-percentage> | auto",
+	"margin-inline-end":    "<length-percentage> | auto",
+	"margin-block":         "[ <length-percentage> | auto ]{1,2}",
+	"margin-inline":        "[ <length-percentage> | auto ]{1,2}",
+	"margin":               "[ <length-percentage> | auto ]{1,4}",
+	"padding-top":          "<length-percentage [0,∞]>",
+	"padding-right":        "<length-percentage [0,∞]>",
+	"padding-bottom":       "<length-percentage [0,∞]>",
+	"padding-left":         "<length-percentage [0,∞]>",
+	"padding-block-start":  "<length-percentage [0,∞]>",
+	"padding-block-end":    "<length-percentage [0,∞]>",
+	"padding-inline-start": "<length-percentage [0,∞]>",
+	"padding-inline-end":   "<length-percentage [0,∞]>",
+	"padding-block":        "<length-percentage [0,∞]>{1,2}",
+	"padding-inline":       "<length-percentage [0,∞]>{1,2}",
+	"padding":              "<length-percentage [0,∞]>{1,4}",
+	// https://drafts.csswg.org/css-overflow/
+	"overflow-x":      "visible | hidden | clip | scroll | auto",
+	"overflow-y":      "visible | hidden | clip | scroll | auto",
+	"overflow-block":  "visible | hidden | clip | scroll | auto",
+	"overflow-inline": "visible | hidden | clip | scroll | auto",
+	"overflow":        "[ visible | hidden | clip | scroll | auto ]{1,2}",
+	"text-overflow":   "[ clip | ellipsis | <string> ]{1,2}",
+	"scroll-behavior": "auto | smooth",
+	// https://drafts.csswg.org/css-backgrounds/
+	"border-top-width":           "<line-width>",
+	"border-right-width":         "<line-width>",
+	"border-bottom-width":        "<line-width>",
+	"border-left-width":          "<line-width>",
+	"border-top-style":           "<line-style>",
+	"border-right-style":         "<line-style>",
+	"border-bottom-style":        "<line-style>",
+	"border-left-style":          "<line-style>",
+	"border-top-color":           "<color>",
+	"border-right-color":         "<color>",
+	"border-bottom-color":        "<color>",
+	"border-left-color":          "<color>",
+	"border-width":               "<line-width>{1,4}",
+	"border-style":               "<line-style>{1,4}",
+	"border-color":               "<color>{1,4}",
+	"border-top":                 "<line-width> || <line-style> || <color>",
+	"border-right":               "<line-width> || <line-style> || <color>",
+	"border-bottom":              "<line-width> || <line-style> || <color>",
+	"border-left":                "<line-width> || <line-style> || <color>",
+	"border":                     "<line-width> || <line-style> || <color>",
+	"border-top-left-radius":     "<length-percentage [0,∞]>{1,2}",
+	"border-top-right-radius":    "<length-percentage [0,∞]>{1,2}",
+	"border-bottom-right-radius": "<length-percentage [0,∞]>{1,2}",
+	"border-bottom-left-radius":  "<length-percentage [0,∞]>{1,2}",
+	"border-radius":              "<length-percentage [0,∞]>{1,4} [ / <length-percentage [0,∞]>{1,4} ]?",
+	"background-color":           "<color>",
+	"background-image":           "[ <image> | none ]#",
+	"background-repeat":          "<repeat-style>#",
+	"background-attachment":      "<attachment>#",
+	"background-position":        "<position>#",
+	"background-size":            "<bg-size>#",
+	"background-clip":            "[ <visual-box> | border-area | text ]#",
+	"background-origin":          "<visual-box>#",
+	"box-shadow":                 "none | <shadow>#",
+	"box-decoration-break":       "slice | clone",
+	// https://drafts.csswg.org/css-ui/
+	"outline-width":  "<line-width>",
+	"outline-style":  "auto | <line-style>",
+	"outline-color":  "auto | <color> | invert",
+	"outline-offset": "<length>",
+	"outline":        "[ <color> | auto | invert ] || [ auto | <line-style> ] || <line-width>",
+	"cursor":         "[ [ <url> [ <number> <number> ]? , ]* <cursor-keyword> ]",
+	"pointer-events": "auto | none | visiblePainted | visibleFill | visibleStroke | visible | painted | fill | stroke | all",
+	"user-select":    "auto | text | none | contain | all",
+	"resize":         "none | both | horizontal | vertical | block | inline",
+	"caret-color":    "auto | <color>",
+	"accent-color":   "auto | <color>",
+	// https://drafts.csswg.org/css-color/
+	"color":        "<color>",
+	"opacity":      "<number> | <percentage>",
+	"color-scheme": "normal | [ light | dark | <custom-ident> ]+ && only?",
+	// https://drafts.csswg.org/css-fonts/
+	"font-size":   "<absolute-size> | <relative-size> | <length-percentage [0,∞]>",
+	"font-weight": "normal | bold | <number [1,1000]> | bolder | lighter",
+	"font-style":  "normal | italic | oblique <angle [-90deg,90deg]>?",
+	"font-family": "[ <string> | <custom-ident>+ ]#",
+	// https://drafts.csswg.org/css-inline/
+	"line-height":    "normal | <number [0,∞]> | <length-percentage [0,∞]>",
+	"vertical-align": "baseline | sub | super | text-top | text-bottom | middle | top | bottom | <length-percentage>",
+	// https://drafts.csswg.org/css-text/
+	"text-align":           "start | end | left | right | center | justify | match-parent | justify-all",
+	"text-align-last":      "auto | start | end | left | right | center | justify | match-parent",
+	"text-transform":       "none | math-auto | [ capitalize | uppercase | lowercase ] || full-width || full-size-kana",
+	"text-indent":          "<length-percentage> && hanging? && each-line?",
+	"white-space":          "normal | pre | nowrap | pre-wrap | pre-line | break-spaces | <'white-space-collapse'> || <'text-wrap-mode'>",
+	"white-space-collapse": "collapse | discard | preserve | preserve-breaks | preserve-spaces | break-spaces",
+	"text-wrap-mode":       "wrap | nowrap",
+	"letter-spacing":       "normal | <length-percentage>",
+	"word-spacing":         "normal | <length-percentage>",
+	"word-break":           "normal | break-all | keep-all | break-word | auto-phrase",
+	"overflow-wrap":        "normal | break-word | anywhere",
+	"hyphens":              "none | manual | auto",
+	"tab-size":             "<number [0,∞]> | <length [0,∞]>",
+	// https://drafts.csswg.org/css-text-decor/
+	"text-decoration-line":  "none | [ underline || overline || line-through || blink ]",
+	"text-decoration-style": "solid | double | dotted | dashed | wavy",
+	"text-decoration-color": "<color>",
+	"text-shadow":           "none | [ <color>? && <length>{2,3} ]#",
+	// https://drafts.csswg.org/css-writing-modes/
+	"direction":    "ltr | rtl",
+	"unicode-bidi": "normal | embed | isolate | bidi-override | isolate-override | plaintext",
+	"writing-mode": "horizontal-tb | vertical-rl | vertical-lr | sideways-rl | sideways-lr",
+	// https://drafts.csswg.org/css-flexbox/
+	"flex-direction": "row | row-reverse | column | column-reverse",
+	"flex-wrap":      "nowrap | wrap | wrap-reverse",
+	"flex-flow":      "<'flex-direction'> || <'flex-wrap'>",
+	"flex-grow":      "<number [0,∞]>",
+	"flex-shrink":    "<number [0,∞]>",
+	"flex-basis":     "content | <'width'>",
+	"flex":           "none | [ <'flex-grow'> <'flex-shrink'>? || <'flex-basis'> ]",
+	// https://drafts.csswg.org/css-align/
+	"align-items":     "normal | stretch | <baseline-position> | <overflow-position>? <self-position>",
+	"align-self":      "auto | normal | stretch | <baseline-position> | <overflow-position>? <self-position>",
+	"align-content":   "normal | <baseline-position> | <content-distribution> | <overflow-position>? <content-position>",
+	"justify-items":   "normal | stretch | <baseline-position> | <overflow-position>? [ <self-position> | left | right ] | legacy | legacy && [ left | right | center ]",
+	"justify-self":    "auto | normal | stretch | <baseline-position> | <overflow-position>? [ <self-position> | left | right ]",
+	"justify-content": "normal | <content-distribution> | <overflow-position>? [ <content-position> | left | right ]",
+	"place-items":     "<'align-items'> <'justify-items'>?",
+	"place-self":      "<'align-self'> <'justify-self'>?",
+	"place-content":   "<'align-content'> <'justify-content'>?",
+	"row-gap":         "normal | <length-percentage [0,∞]>",
+	"column-gap":      "normal | <length-percentage [0,∞]>",
+	"gap":             "<'row-gap'> <'column-gap'>?",
+	// https://drafts.csswg.org/css-grid/
+	"grid-auto-flow":    "[ row | column ] || dense",
+	"grid-row-start":    "<grid-line>",
+	"grid-row-end":      "<grid-line>",
+	"grid-column-start": "<grid-line>",
+	"grid-column-end":   "<grid-line>",
+	"grid-row":          "<grid-line> [ / <grid-line> ]?",
+	"grid-column":       "<grid-line> [ / <grid-line> ]?",
+	"grid-area":         "<grid-line> [ / <grid-line> ]{0,3}",
+	// https://drafts.csswg.org/css-lists/
+	"list-style-position": "inside | outside",
+	"list-style-type":     "<counter-style> | <string> | none",
+	"list-style-image":    "<image> | none",
+	"counter-reset":       "[ <custom-ident> <integer>? | reversed( <custom-ident> ) <integer>? ]+ | none",
+	"counter-increment":   "[ <custom-ident> <integer>? ]+ | none",
+	"counter-set":         "[ <custom-ident> <integer>? ]+ | none",
+	// https://drafts.csswg.org/css-content/
+	"quotes": "none | auto | [ <string> <string> ]+",
+	// https://drafts.csswg.org/css-tables/
+	"table-layout":    "auto | fixed",
+	"border-collapse": "collapse | separate",
+	"border-spacing":  "<length [0,∞]>{1,2}",
+	"empty-cells":     "show | hide",
+	"caption-side":    "top | bottom",
+	// https://drafts.csswg.org/css-images/
+	"object-fit":      "fill | contain | cover | none | scale-down",
+	"object-position": "<position>",
+	// https://drafts.csswg.org/css-transforms/
+	"transform":           "none | <transform-function>+",
+	"transform-origin":    "[ left | center | right | top | bottom | <length-percentage> ] | [ left | center | right | <length-percentage> ] [ top | center | bottom | <length-percentage> ] <length>? | [ [ center | left | right ] && [ center | top | bottom ] ] <length>?",
+	"transform-style":     "flat | preserve-3d",
+	"backface-visibility": "visible | hidden",
+	"perspective":         "none | <length [0,∞]>",
+	// https://drafts.csswg.org/css-transitions/
+	"transition-property":        "none | <single-transition-property>#",
+	"transition-duration":        "<time [0s,∞]>#",
+	"transition-timing-function": "<easing-function>#",
+	"transition-delay":           "<time>#",
+	"transition-behavior":        "[ normal | allow-discrete ]#",
+	// https://drafts.csswg.org/css-animations/
+	"animation-name":            "[ none | <custom-ident> | <string> ]#",
+	"animation-duration":        "[ auto | <time [0s,∞]> ]#",
+	"animation-timing-function": "<easing-function>#",
+	"animation-delay":           "<time>#",
+	"animation-iteration-count": "[ infinite | <number [0,∞]> ]#",
+	"animation-direction":       "[ normal | reverse | alternate | alternate-reverse ]#",
+	"animation-fill-mode":       "[ none | forwards | backwards | both ]#",
+	"animation-play-state":      "[ running | paused ]#",
+	// https://drafts.fxtf.org/compositing/
+	"isolation":      "auto | isolate",
+	"mix-blend-mode": "<blend-mode> | plus-darker | plus-lighter",
+	// https://drafts.csswg.org/css-will-change/
+	"will-change": "auto | [ scroll-position | contents | <custom-ident> ]#",
+	// https://drafts.csswg.org/css-contain/
+	"contain":            "none | strict | content | [ [ size | inline-size ] || layout || style || paint ]",
+	"content-visibility": "visible | auto | hidden",
+	// https://drafts.csswg.org/css-scroll-snap/
+	"scroll-snap-type":    "none | [ x | y | block | inline | both ] [ mandatory | proximity ]?",
+	"scroll-snap-align":   "[ none | start | end | center ]{1,2}",
+	"overscroll-behavior": "[ contain | none | auto ]{1,2}",
+	// https://w3c.github.io/pointerevents/#the-touch-action-css-property
+	"touch-action": "auto | none | [ [ pan-x | pan-left | pan-right ] || [ pan-y | pan-up | pan-down ] || pinch-zoom ] | manipulation",
+}
+
+// The grammars of the data types that are defined in terms of other components.
+var data_type_grammars = map[string]string{
+	"display-outside":            "block | inline | run-in",
+	"display-inside":             "flow | flow-root | table | flex | grid | ruby | math",
+	"display-listitem":           "<display-outside>? && [ flow | flow-root ]? && list-item",
+	"display-internal":           "table-row-group | table-header-group | table-footer-group | table-row | table-cell | table-column-group | table-column | table-caption | ruby-base | ruby-text | ruby-base-container | ruby-text-container",
+	"display-box":                "contents | none",
+	"display-legacy":             "inline-block | inline-table | inline-flex | inline-grid",
+	"sizing-keyword":             "min-content | max-content | fit-content | fit-content( <length-percentage [0,∞]> ) | stretch | -webkit-fill-available | -moz-available",
+	"ratio":                      "<number [0,∞]> [ / <number [0,∞]> ]?",
+	"line-width":                 "<length [0,∞]> | thin | medium | thick",
+	"line-style":                 "none | hidden | dotted | dashed | solid | double | groove | ridge | inset | outset",
+	"visual-box":                 "content-box | padding-box | border-box",
+	"attachment":                 "scroll | fixed | local",
+	"repeat-style":               "repeat-x | repeat-y | [ repeat | space | round | no-repeat ]{1,2}",
+	"bg-size":                    "[ <length-percentage [0,∞]> | auto ]{1,2} | cover | contain",
+	"position":                   "[ left | center | right | top | bottom | <length-percentage> ] | [ left | center | right | <length-percentage> ] [ top | center | bottom | <length-percentage> ] | [ center | [ left | right ] <length-percentage>? ] && [ center | [ top | bottom ] <length-percentage>? ]",
+	"shadow":                     "<color>? && [ <length>{2} <length [0,∞]>? <length>? ] && inset?",
+	"absolute-size":              "xx-small | x-small | small | medium | large | x-large | xx-large | xxx-large",
+	"relative-size":              "larger | smaller",
+	"cursor-keyword":             "auto | default | none | context-menu | help | pointer | progress | wait | cell | crosshair | text | vertical-text | alias | copy | move | no-drop | not-allowed | grab | grabbing | e-resize | n-resize | ne-resize | nw-resize | s-resize | se-resize | sw-resize | w-resize | ew-resize | ns-resize | nesw-resize | nwse-resize | col-resize | row-resize | all-scroll | zoom-in | zoom-out",
+	"baseline-position":          "[ first | last ]? && baseline",
+	"overflow-position":          "unsafe | safe",
+	"self-position":              "center | start | end | self-start | self-end | flex-start | flex-end",
+	"content-position":           "center | start | end | flex-start | flex-end",
+	"content-distribution":       "space-between | space-around | space-evenly | stretch",
+	"grid-line":                  "auto | <custom-ident> | [ <integer> && <custom-ident>? ] | [ span && [ <integer [1,∞]> || <custom-ident> ] ]",
+	"counter-style":              "<custom-ident> | symbols( <symbols-type>? [ <string> | <image> ]+ )",
+	"symbols-type":               "cyclic | numeric | alphabetic | symbolic | fixed",
+	"easing-function":            "linear | ease | ease-in | ease-out | ease-in-out | step-start | step-end | cubic-bezier( <number [0,1]> , <number> , <number [0,1]> , <number> ) | steps( <integer> [ , <step-position> ]? ) | linear( [ <number> && <percentage>{0,2} ]# )",
+	"step-position":              "jump-start | jump-end | jump-none | jump-both | start | end",
+	"single-transition-property": "all | <custom-ident>",
+	"blend-mode":                 "normal | multiply | screen | overlay | darken | lighten | color-dodge | color-burn | hard-light | soft-light | difference | exclusion | hue | saturation | color | luminosity",
+}
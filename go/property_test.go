@@ -0,0 +1,176 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePropertySyntax(t *testing.T) {
+	tests := []struct {
+		syntax string
+		// The serialization of the parsed syntax.
+		want string
+	}{
+		{syntax: "*", want: "*"},
+		{syntax: " <length> ", want: "<length>"},
+		{syntax: "<length>+", want: "<length>+"},
+		{syntax: "<color>#", want: "<color>#"},
+		{syntax: "<length> | <percentage>", want: "<length> | <percentage>"},
+		{syntax: "small|medium|large", want: "small | medium | large"},
+		{syntax: "auto | <integer>#", want: "auto | <integer>#"},
+		{syntax: "<transform-list>", want: "<transform-list>"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.syntax, func(t *testing.T) {
+			syntax, err := ParsePropertySyntax(test.syntax)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := syntax.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParsePropertySyntaxErrors(t *testing.T) {
+	for _, syntax := range []string{"", " ", "<length", "<unknown>", "<length>++", "<length> <number>", "<transform-list>+", "inherit", "default", "<length> |", "'a'"} {
+		t.Run(syntax, func(t *testing.T) {
+			if parsed, err := ParsePropertySyntax(syntax); err == nil {
+				t.Errorf("expected an error, got %q", parsed.String())
+			}
+		})
+	}
+}
+
+func TestPropertySyntaxMatches(t *testing.T) {
+	tests := []struct {
+		syntax string
+		value  string
+		want   bool
+	}{
+		{syntax: "*", value: "anything { at: all }", want: true},
+		{syntax: "<length>", value: "10px", want: true},
+		{syntax: "<length>", value: "0", want: true},
+		{syntax: "<length>", value: "calc(1px + 2em)", want: true},
+		{syntax: "<length>", value: "10%", want: false},
+		{syntax: "<length>", value: "10px 20px", want: false},
+		{syntax: "<length>+", value: "10px 20px", want: true},
+		{syntax: "<length>+", value: "10px, 20px", want: false},
+		{syntax: "<length>#", value: "10px, 20px", want: true},
+		{syntax: "<length>#", value: "10px 20px", want: false},
+		{syntax: "<length> | <percentage>", value: "10%", want: true},
+		{syntax: "<length-percentage>", value: "calc(10% - 1px)", want: true},
+		{syntax: "<integer>", value: "3", want: true},
+		{syntax: "<integer>", value: "3.5", want: false},
+		{syntax: "<color>", value: "rebeccapurple", want: true},
+		{syntax: "<color>", value: "oklch(0.5 0.1 30)", want: true},
+		{syntax: "<image>", value: "linear-gradient(red, blue)", want: true},
+		{syntax: "<url>", value: "url(a.png)", want: true},
+		{syntax: "<url>", value: "\"a.png\"", want: false},
+		{syntax: "<angle>", value: "1turn", want: true},
+		{syntax: "<time>", value: "200ms", want: true},
+		{syntax: "<resolution>", value: "2x", want: true},
+		{syntax: "small | large", value: "small", want: true},
+		{syntax: "small | large", value: "SMALL", want: false},
+		{syntax: "<custom-ident>", value: "foo", want: true},
+		{syntax: "<custom-ident>", value: "inherit", want: false},
+		{syntax: "<transform-function>", value: "rotate(45deg)", want: true},
+		{syntax: "<transform-function>", value: "rotate(45deg) scale(2)", want: false},
+		{syntax: "<transform-list>", value: "rotate(45deg) scale(2)", want: true},
+		{syntax: "<transform-list>", value: "rotate(45deg) blur(2px)", want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.syntax+" "+test.value, func(t *testing.T) {
+			syntax, err := ParsePropertySyntax(test.syntax)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := syntax.Matches(parse_component_value_list(test.value)); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestPropertyRule(t *testing.T) {
+	tests := []struct {
+		css string
+		// The text of the error, if the rule is invalid.
+		wantErr string
+	}{
+		{css: `@property --x { syntax: "<length>"; inherits: false; initial-value: 0px }`},
+		{css: `@property --x { syntax: "*"; inherits: true }`},
+		{css: `@property --x { syntax: "<color>"; inherits: false; initial-value: red; unknown: 1 }`},
+		{css: `@property --x { syntax: "<length>"; inherits: false; initial-value: 1in }`},
+		{css: `@property x { syntax: "*"; inherits: true }`, wantErr: "expected a custom property name"},
+		{css: `@property --x { inherits: true }`, wantErr: "invalid 'syntax' descriptor in @property --x: the descriptor is required"},
+		{css: `@property --x { syntax: <length>; inherits: true; initial-value: 0px }`, wantErr: "invalid 'syntax' descriptor in @property --x: expected a string"},
+		{css: `@property --x { syntax: "<lenght>"; inherits: true; initial-value: 0px }`, wantErr: "unsupported data type name '<lenght>'"},
+		{css: `@property --x { syntax: "*" }`, wantErr: "invalid 'inherits' descriptor in @property --x: the descriptor is required"},
+		{css: `@property --x { syntax: "*"; inherits: yes }`, wantErr: "invalid 'inherits' descriptor in @property --x: expected true or false"},
+		{css: `@property --x { syntax: "<length>"; inherits: false }`, wantErr: "the descriptor is required unless the syntax is '*'"},
+		{css: `@property --x { syntax: "<length>"; inherits: false; initial-value: red }`, wantErr: "'red' doesn't match the syntax '<length>'"},
+		{css: `@property --x { syntax: "<length>"; inherits: false; initial-value: var(--y) }`, wantErr: "doesn't match the syntax"},
+		{css: `@property --x { syntax: "<length>"; inherits: false; initial-value: 2em }`, wantErr: "'2em' depends on the element it's used on"},
+		{css: `@property --x { syntax: "<length>"; inherits: false; initial-value: calc(1px + 10vw) }`, wantErr: "depends on the element it's used on"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			registrations, errs := parse_stylesheet(strings.NewReader(test.css)).PropertyRegistrations()
+			switch {
+			case test.wantErr == "" && (len(errs) > 0 || len(registrations) != 1):
+				t.Errorf("unexpected errors: %v", errs)
+			case test.wantErr != "" && (len(errs) != 1 || strings.Contains(errs[0].Error(), test.wantErr) == false):
+				t.Errorf("expected an error containing %q, got %v", test.wantErr, errs)
+			}
+		})
+	}
+}
+
+func TestPropertyRegistrations(t *testing.T) {
+	css := `@property --a { syntax: "<length>"; inherits: false; initial-value: 1px }
+		@media print { @property --b { syntax: "*"; inherits: true } }
+		@property --a { syntax: "<color>"; inherits: true; initial-value: red }`
+	registrations, errs := parse_stylesheet(strings.NewReader(css)).PropertyRegistrations()
+	if len(errs) > 0 {
+		t.Fatalf("unexpected errors: %v", errs)
+	}
+	if len(registrations) != 2 {
+		t.Fatalf("got %d registrations", len(registrations))
+	}
+	if a := registrations["--a"]; a.syntax.String() != "<color>" || a.inherits == false || serialize_component_value_list(a.initial_value) != "red" {
+		t.Errorf("the last registration of --a should win, got %+v", a)
+	}
+	if b := registrations["--b"]; b.syntax.universal == false || b.has_initial_value {
+		t.Errorf("got %+v", b)
+	}
+}
+
+func TestPropertyRuleValidate(t *testing.T) {
+	rule, err := parse_stylesheet(strings.NewReader(`@property --x { syntax: "<length>#"; inherits: false; initial-value: 0px }`)).rules[0].property_rule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		value string
+		valid bool
+	}{
+		{value: "1px, 2px", valid: true},
+		{value: " 1px ", valid: true},
+		{value: "var(--y), 2px", valid: true},
+		{value: "1px 2px", valid: false},
+		{value: "red", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			if err := rule.Validate(parse_component_value_list(test.value)); (err == nil) != test.valid {
+				t.Errorf("got %v, want valid: %v", err, test.valid)
+			}
+		})
+	}
+}
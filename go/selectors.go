@@ -0,0 +1,854 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://drafts.csswg.org/selectors-4/#typedef-selector-list
+type SelectorList []ComplexSelector
+
+// https://drafts.csswg.org/selectors-4/#complex
+type ComplexSelector struct {
+	// A sequence of compound selectors, each related to the one before it by its combinator.
+	compounds []CompoundSelector
+}
+
+// https://drafts.csswg.org/selectors-4/#compound
+type CompoundSelector struct {
+	// The combinator relating this compound selector to the previous one in the complex selector.
+	// For the first compound selector this is COMBINATOR_NONE, unless the selector is a relative selector (e.g. in :has()).
+	combinator Combinator
+	selectors  []SimpleSelector
+	// Whether the compound selector, having no type or universal selector, only matches elements in the default namespace
+	// declared by the stylesheet, once its selectors are resolved against the stylesheet's @namespace rules.
+	has_default_namespace bool
+	default_namespace     string
+}
+
+type Combinator uint8
+
+// https://drafts.csswg.org/selectors-4/#selector-combinator
+const (
+	COMBINATOR_NONE Combinator = iota
+	// A B
+	COMBINATOR_DESCENDANT
+	// A > B
+	COMBINATOR_CHILD
+	// A + B
+	COMBINATOR_NEXT_SIBLING
+	// A ~ B
+	COMBINATOR_SUBSEQUENT_SIBLING
+	// A || B
+	COMBINATOR_COLUMN
+)
+
+func (c Combinator) String() string {
+	switch c {
+	case COMBINATOR_NONE:
+		return ""
+	case COMBINATOR_DESCENDANT:
+		return " "
+	case COMBINATOR_CHILD:
+		return ">"
+	case COMBINATOR_NEXT_SIBLING:
+		return "+"
+	case COMBINATOR_SUBSEQUENT_SIBLING:
+		return "~"
+	case COMBINATOR_COLUMN:
+		return "||"
+	}
+	return "<UNKNOWN COMBINATOR>"
+}
+
+type SimpleSelectorKind uint8
+
+// https://drafts.csswg.org/selectors-4/#simple
+const (
+	TYPE_SELECTOR SimpleSelectorKind = iota
+	UNIVERSAL_SELECTOR
+	ID_SELECTOR
+	CLASS_SELECTOR
+	ATTRIBUTE_SELECTOR
+	PSEUDO_CLASS_SELECTOR
+	PSEUDO_ELEMENT_SELECTOR
+	// https://drafts.csswg.org/css-nesting/#nest-selector
+	// '&', which stands for the elements matched by the parent style rule's selector list.
+	NESTING_SELECTOR
+)
+
+func (k SimpleSelectorKind) String() string {
+	switch k {
+	case TYPE_SELECTOR:
+		return "TYPE_SELECTOR"
+	case UNIVERSAL_SELECTOR:
+		return "UNIVERSAL_SELECTOR"
+	case ID_SELECTOR:
+		return "ID_SELECTOR"
+	case CLASS_SELECTOR:
+		return "CLASS_SELECTOR"
+	case ATTRIBUTE_SELECTOR:
+		return "ATTRIBUTE_SELECTOR"
+	case PSEUDO_CLASS_SELECTOR:
+		return "PSEUDO_CLASS_SELECTOR"
+	case PSEUDO_ELEMENT_SELECTOR:
+		return "PSEUDO_ELEMENT_SELECTOR"
+	case NESTING_SELECTOR:
+		return "NESTING_SELECTOR"
+	}
+	return "<UNKNOWN SELECTOR>"
+}
+
+type AttributeMatcher uint8
+
+// https://drafts.csswg.org/selectors-4/#attribute-selectors
+const (
+	// [attr]
+	ATTRIBUTE_EXISTS AttributeMatcher = iota
+	// [attr=value]
+	ATTRIBUTE_EQUALS
+	// [attr~=value]
+	ATTRIBUTE_INCLUDES
+	// [attr|=value]
+	ATTRIBUTE_DASH_MATCH
+	// [attr^=value]
+	ATTRIBUTE_PREFIX
+	// [attr$=value]
+	ATTRIBUTE_SUFFIX
+	// [attr*=value]
+	ATTRIBUTE_SUBSTRING
+)
+
+func (m AttributeMatcher) String() string {
+	switch m {
+	case ATTRIBUTE_EXISTS:
+		return ""
+	case ATTRIBUTE_EQUALS:
+		return "="
+	case ATTRIBUTE_INCLUDES:
+		return "~="
+	case ATTRIBUTE_DASH_MATCH:
+		return "|="
+	case ATTRIBUTE_PREFIX:
+		return "^="
+	case ATTRIBUTE_SUFFIX:
+		return "$="
+	case ATTRIBUTE_SUBSTRING:
+		return "*="
+	}
+	return "<UNKNOWN MATCHER>"
+}
+
+type AttributeCase uint8
+
+// https://drafts.csswg.org/selectors-4/#attribute-case
+const (
+	// The document language decides how the value is compared.
+	ATTRIBUTE_CASE_DEFAULT AttributeCase = iota
+	// [attr=value i]
+	ATTRIBUTE_CASE_INSENSITIVE
+	// [attr=value s]
+	ATTRIBUTE_CASE_SENSITIVE
+)
+
+// https://drafts.csswg.org/selectors-4/#simple
+type SimpleSelector struct {
+	kind SimpleSelectorKind
+	// TYPE_SELECTOR, UNIVERSAL_SELECTOR, ATTRIBUTE_SELECTOR: whether a namespace prefix was given.
+	// The prefix is "*" for any namespace, and "" for no namespace (e.g. "|a").
+	has_namespace bool
+	namespace     string
+	// TYPE_SELECTOR, UNIVERSAL_SELECTOR, ATTRIBUTE_SELECTOR: the namespace URL the prefix (or, for type and universal selectors
+	// without one, the default namespace) resolved to, once resolved against the stylesheet's @namespace rules.
+	has_namespace_url bool
+	namespace_url     string
+	// TYPE_SELECTOR: the element name, ID_SELECTOR: the id, CLASS_SELECTOR: the class name,
+	// ATTRIBUTE_SELECTOR: the attribute name, PSEUDO_CLASS_SELECTOR, PSEUDO_ELEMENT_SELECTOR: the lowercased name.
+	name string
+	// ATTRIBUTE_SELECTOR
+	matcher        AttributeMatcher
+	value          string
+	case_sensitive AttributeCase
+	// PSEUDO_CLASS_SELECTOR, PSEUDO_ELEMENT_SELECTOR: whether the pseudo is functional, and its raw arguments.
+	is_function bool
+	arguments   []ComponentValue
+	// The selector argument of :is(), :where(), :not(), :has(), :host(), :host-context(), ::slotted() and :nth-child(An+B of S).
+	selectors SelectorList
+	// The An+B argument of :nth-child() and the other :nth-*() pseudo-classes.
+	nth AnPlusB
+	// Whether :nth-child() and :nth-last-child() were given an "of S" selector list.
+	has_of_selector bool
+}
+
+// Parse a qualified rule's prelude as a <selector-list>.
+func (r Rule) selectors() (SelectorList, error) {
+	if r.kind != QUALIFIED_RULE {
+		return nil, fmt.Errorf("Parse Error: only qualified rules have selectors")
+	}
+
+	return parse_selector_list(r.prelude)
+}
+
+// https://drafts.csswg.org/selectors-4/#parse-a-selector
+// <selector-list> = <complex-selector-list>
+func parse_selector_list(list []ComponentValue) (SelectorList, error) {
+	return parse_complex_selector_list(list, false)
+}
+
+// https://drafts.csswg.org/selectors-4/#parse-a-relative-selector
+// <relative-selector-list> = <relative-selector>#
+func parse_relative_selector_list(list []ComponentValue) (SelectorList, error) {
+	return parse_complex_selector_list(list, true)
+}
+
+// https://drafts.csswg.org/selectors-4/#typedef-forgiving-selector-list
+// Each selector in the list is parsed individually, and any that fail to parse are dropped rather than invalidating the whole list.
+func parse_forgiving_selector_list(list []ComponentValue) SelectorList {
+	var selectors SelectorList
+	for _, item := range split_on_commas(list) {
+		selector, err := parse_complex_selector(item, false)
+		if err == nil {
+			selectors = append(selectors, selector)
+		}
+	}
+
+	return selectors
+}
+
+func parse_complex_selector_list(list []ComponentValue, relative bool) (SelectorList, error) {
+	var selectors SelectorList
+	for _, item := range split_on_commas(list) {
+		selector, err := parse_complex_selector(item, relative)
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, selector)
+	}
+
+	return selectors, nil
+}
+
+// <complex-selector> = <compound-selector> [ <combinator>? <compound-selector> ]*
+// <relative-selector> = <combinator>? <complex-selector>
+func parse_complex_selector(list []ComponentValue, relative bool) (ComplexSelector, error) {
+	var selector ComplexSelector
+	stream := NewComponentValueStream(list)
+	stream.discard_whitespace()
+	if stream.empty() {
+		return selector, fmt.Errorf("Parse Error: expected a selector, found end of input")
+	}
+
+	combinator := COMBINATOR_NONE
+	if relative {
+		if leading, ok := stream.consume_combinator(); ok {
+			combinator = leading
+			stream.discard_whitespace()
+		}
+	}
+
+	for {
+		compound, err := stream.consume_compound_selector()
+		if err != nil {
+			return selector, err
+		}
+		if len(compound.selectors) == 0 {
+			if combinator != COMBINATOR_NONE {
+				return selector, fmt.Errorf("Parse Error: expected a selector after combinator '%s', found %s", combinator, describe_value(stream.next_value()))
+			}
+			return selector, fmt.Errorf("Parse Error: unexpected %s in selector", describe_value(stream.next_value()))
+		}
+		compound.combinator = combinator
+		selector.compounds = append(selector.compounds, compound)
+
+		// Whitespace between two compound selectors is the descendant combinator, unless another combinator is present.
+		had_whitespace := stream.next_value().is_token(WHITESPACE_TOKEN)
+		stream.discard_whitespace()
+		if stream.empty() {
+			return selector, nil
+		}
+
+		next, ok := stream.consume_combinator()
+		switch {
+		case ok:
+			combinator = next
+			stream.discard_whitespace()
+		case had_whitespace:
+			combinator = COMBINATOR_DESCENDANT
+		default:
+			return selector, fmt.Errorf("Parse Error: unexpected %s in selector", describe_value(stream.next_value()))
+		}
+	}
+}
+
+// <combinator> = '>' | '+' | '~' | [ '|' '|' ]
+func (cs *ComponentValueStream) consume_combinator() (Combinator, bool) {
+	next := cs.next_value()
+	switch {
+	case next.is_delim(GREATER_THAN_CHAR):
+		cs.discard_value()
+		return COMBINATOR_CHILD, true
+	case next.is_delim(PLUS_SIGN_CHAR):
+		cs.discard_value()
+		return COMBINATOR_NEXT_SIBLING, true
+	case next.is_delim('~'):
+		cs.discard_value()
+		return COMBINATOR_SUBSEQUENT_SIBLING, true
+	case next.is_delim('|'):
+		cs.mark()
+		cs.discard_value()
+		if cs.next_value().is_delim('|') {
+			cs.discard_value()
+			cs.discard_mark()
+			return COMBINATOR_COLUMN, true
+		}
+		cs.restore_mark()
+	}
+
+	return COMBINATOR_NONE, false
+}
+
+// <compound-selector> = [ <type-selector>? <subclass-selector>* [ <pseudo-element-selector> <pseudo-class-selector>* ]* ]!
+// An empty compound selector is returned when the stream doesn't start with a simple selector.
+func (cs *ComponentValueStream) consume_compound_selector() (CompoundSelector, error) {
+	var compound CompoundSelector
+
+	if selector, ok := cs.consume_type_selector(); ok {
+		compound.selectors = append(compound.selectors, selector)
+	}
+
+	after_pseudo_element := false
+	for {
+		next := cs.next_value()
+		switch {
+		// <id-selector> = <hash-token>
+		case next.is_token(HASH_TOKEN):
+			if next.token.hash_flag != HASH_ID {
+				return compound, fmt.Errorf("Parse Error: %s is not a valid id selector", describe_value(next))
+			}
+			cs.discard_value()
+			compound.selectors = append(compound.selectors, SimpleSelector{kind: ID_SELECTOR, name: string(next.token.value)})
+		// <class-selector> = '.' <ident-token>
+		case next.is_delim(FULL_STOP_CHAR):
+			cs.discard_value()
+			name := cs.consume_value()
+			if name.is_token(IDENT_TOKEN) == false {
+				return compound, fmt.Errorf("Parse Error: expected a class name after '.', found %s", describe_value(name))
+			}
+			compound.selectors = append(compound.selectors, SimpleSelector{kind: CLASS_SELECTOR, name: string(name.token.value)})
+		// <attribute-selector>
+		case next.is_block(OPEN_SQUARE_TOKEN):
+			cs.discard_value()
+			selector, err := parse_attribute_selector(next.value)
+			if err != nil {
+				return compound, err
+			}
+			compound.selectors = append(compound.selectors, selector)
+		// https://drafts.csswg.org/css-nesting/#nest-selector
+		case next.is_delim('&'):
+			cs.discard_value()
+			compound.selectors = append(compound.selectors, SimpleSelector{kind: NESTING_SELECTOR})
+		// <pseudo-class-selector>, <pseudo-element-selector>
+		case next.is_token(COLON_TOKEN):
+			cs.discard_value()
+			selector, err := cs.consume_pseudo_selector()
+			if err != nil {
+				return compound, err
+			}
+			if selector.kind == PSEUDO_ELEMENT_SELECTOR {
+				after_pseudo_element = true
+			}
+			compound.selectors = append(compound.selectors, selector)
+			continue
+		case next.is_token(IDENT_TOKEN), next.is_delim(ASTERISK_CHAR):
+			return compound, fmt.Errorf("Parse Error: type selector %s must come first in a compound selector", describe_value(next))
+		default:
+			return compound, nil
+		}
+
+		// Only pseudo-classes and pseudo-elements may follow a pseudo-element.
+		if after_pseudo_element {
+			return compound, fmt.Errorf("Parse Error: %s cannot follow a pseudo-element", describe_value(next))
+		}
+	}
+}
+
+// <type-selector> = <wq-name> | <ns-prefix>? '*'
+// <wq-name> = <ns-prefix>? <ident-token>
+// <ns-prefix> = [ <ident-token> | '*' ]? '|'
+func (cs *ComponentValueStream) consume_type_selector() (SimpleSelector, bool) {
+	cs.mark()
+	selector := SimpleSelector{}
+
+	first := cs.consume_value()
+	switch {
+	// [ <ident-token> | '*' ] '|'
+	case first.is_token(IDENT_TOKEN) || first.is_delim(ASTERISK_CHAR):
+		if cs.next_value().is_delim('|') && is_name_or_asterisk(cs.peek_value(1)) {
+			cs.discard_value()
+			selector.has_namespace = true
+			selector.namespace = string(first.token.value)
+			first = cs.consume_value()
+		}
+	// '|' with an empty prefix, selecting elements without a namespace.
+	case first.is_delim('|') && is_name_or_asterisk(cs.next_value()):
+		selector.has_namespace = true
+		first = cs.consume_value()
+	default:
+		cs.restore_mark()
+		return selector, false
+	}
+	cs.discard_mark()
+
+	if first.is_delim(ASTERISK_CHAR) {
+		selector.kind = UNIVERSAL_SELECTOR
+	} else {
+		selector.kind = TYPE_SELECTOR
+		selector.name = string(first.token.value)
+	}
+
+	return selector, true
+}
+
+// The value at an offset after the next value, or an <eof-token>.
+func (cs *ComponentValueStream) peek_value(offset int) ComponentValue {
+	if cs.index+offset >= cs.length {
+		return ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: EOF_TOKEN}}
+	}
+
+	return cs.values[cs.index+offset]
+}
+
+func is_name_or_asterisk(value ComponentValue) bool {
+	return value.is_token(IDENT_TOKEN) || value.is_delim(ASTERISK_CHAR)
+}
+
+// <attribute-selector> = '[' <wq-name> ']' | '[' <wq-name> <attr-matcher> [ <string-token> | <ident-token> ] <attr-modifier>? ']'
+func parse_attribute_selector(list []ComponentValue) (SimpleSelector, error) {
+	selector := SimpleSelector{kind: ATTRIBUTE_SELECTOR}
+	stream := NewComponentValueStream(list)
+	stream.discard_whitespace()
+
+	// <wq-name> = <ns-prefix>? <ident-token>
+	first := stream.consume_value()
+	switch {
+	case (first.is_token(IDENT_TOKEN) || first.is_delim(ASTERISK_CHAR)) && stream.next_value().is_delim('|') && stream.peek_value(1).is_token(IDENT_TOKEN):
+		stream.discard_value()
+		selector.has_namespace = true
+		selector.namespace = string(first.token.value)
+		first = stream.consume_value()
+	case first.is_delim('|') && stream.next_value().is_token(IDENT_TOKEN):
+		selector.has_namespace = true
+		first = stream.consume_value()
+	}
+	if first.is_token(IDENT_TOKEN) == false {
+		return selector, fmt.Errorf("Parse Error: expected an attribute name, found %s", describe_value(first))
+	}
+	selector.name = string(first.token.value)
+	stream.discard_whitespace()
+
+	if stream.empty() {
+		selector.matcher = ATTRIBUTE_EXISTS
+		return selector, nil
+	}
+
+	// <attr-matcher> = [ '~' | '|' | '^' | '$' | '*' ]? '='
+	matcher := stream.consume_value()
+	switch {
+	case matcher.is_delim('='):
+		selector.matcher = ATTRIBUTE_EQUALS
+	case matcher.is_token(DELIM_TOKEN) && stream.next_value().is_delim('='):
+		switch matcher.token.value[0] {
+		case '~':
+			selector.matcher = ATTRIBUTE_INCLUDES
+		case '|':
+			selector.matcher = ATTRIBUTE_DASH_MATCH
+		case '^':
+			selector.matcher = ATTRIBUTE_PREFIX
+		case '$':
+			selector.matcher = ATTRIBUTE_SUFFIX
+		case '*':
+			selector.matcher = ATTRIBUTE_SUBSTRING
+		default:
+			return selector, fmt.Errorf("Parse Error: invalid attribute matcher '%s='", string(matcher.token.value))
+		}
+		stream.discard_value()
+	default:
+		return selector, fmt.Errorf("Parse Error: expected an attribute matcher, found %s", describe_value(matcher))
+	}
+	stream.discard_whitespace()
+
+	value := stream.consume_value()
+	if value.is_token(STRING_TOKEN) == false && value.is_token(IDENT_TOKEN) == false {
+		return selector, fmt.Errorf("Parse Error: expected a string or identifier attribute value, found %s", describe_value(value))
+	}
+	selector.value = string(value.token.value)
+	stream.discard_whitespace()
+
+	// <attr-modifier> = i | s
+	if stream.empty() == false {
+		modifier := stream.consume_value()
+		switch {
+		case modifier.is_ident("i"):
+			selector.case_sensitive = ATTRIBUTE_CASE_INSENSITIVE
+		case modifier.is_ident("s"):
+			selector.case_sensitive = ATTRIBUTE_CASE_SENSITIVE
+		default:
+			return selector, fmt.Errorf("Parse Error: unexpected %s in attribute selector", describe_value(modifier))
+		}
+		stream.discard_whitespace()
+	}
+
+	if stream.empty() == false {
+		return selector, fmt.Errorf("Parse Error: unexpected %s in attribute selector", describe_value(stream.next_value()))
+	}
+
+	return selector, nil
+}
+
+// https://drafts.csswg.org/selectors-4/#legacy-pseudo-element-selector
+// These pseudo-elements may be written with a single colon for compatibility with CSS Level 1 and 2.
+func is_legacy_pseudo_element(name string) bool {
+	switch name {
+	case "before", "after", "first-line", "first-letter":
+		return true
+	}
+
+	return false
+}
+
+// <pseudo-class-selector> = ':' <ident-token> | ':' <function-token> <any-value> ')'
+// <pseudo-element-selector> = ':' <pseudo-class-selector> | <legacy-pseudo-element-selector>
+// The leading ':' has already been consumed.
+func (cs *ComponentValueStream) consume_pseudo_selector() (SimpleSelector, error) {
+	selector := SimpleSelector{kind: PSEUDO_CLASS_SELECTOR}
+	if cs.next_value().is_token(COLON_TOKEN) {
+		cs.discard_value()
+		selector.kind = PSEUDO_ELEMENT_SELECTOR
+	}
+
+	next := cs.consume_value()
+	switch {
+	case next.is_token(IDENT_TOKEN):
+		selector.name = strings.ToLower(string(next.token.value))
+	case next.kind == FUNCTION:
+		selector.name = strings.ToLower(next.name)
+		selector.is_function = true
+		selector.arguments = next.value
+	default:
+		return selector, fmt.Errorf("Parse Error: expected a pseudo-class or pseudo-element name after ':', found %s", describe_value(next))
+	}
+
+	if selector.kind == PSEUDO_CLASS_SELECTOR && selector.is_function == false && is_legacy_pseudo_element(selector.name) {
+		selector.kind = PSEUDO_ELEMENT_SELECTOR
+	}
+
+	if err := validate_pseudo_selector(&selector); err != nil {
+		return selector, err
+	}
+
+	return selector, nil
+}
+
+// https://drafts.csswg.org/selectors-4/#pseudo-classes
+var pseudo_classes = map[string]bool{
+	"active": true, "any-link": true, "autofill": true, "buffering": true, "checked": true,
+	"closed": true, "current": true, "default": true, "defined": true, "disabled": true, "empty": true,
+	"enabled": true, "first-child": true, "first-of-type": true, "focus": true,
+	"focus-visible": true, "focus-within": true, "fullscreen": true, "future": true, "host": true,
+	"hover": true, "in-range": true, "indeterminate": true, "invalid": true, "last-child": true,
+	"last-of-type": true, "link": true, "local-link": true, "modal": true, "muted": true,
+	"only-child": true, "only-of-type": true, "open": true, "optional": true, "out-of-range": true,
+	"past": true, "paused": true, "picture-in-picture": true, "placeholder-shown": true, "playing": true,
+	"popover-open": true, "read-only": true, "read-write": true, "required": true,
+	"root": true, "scope": true, "seeking": true, "stalled": true, "target": true, "target-within": true,
+	"user-invalid": true, "user-valid": true, "valid": true, "visited": true, "volume-locked": true,
+	"target-current": true,
+}
+
+var functional_pseudo_classes = map[string]bool{
+	"is": true, "where": true, "not": true, "has": true, "nth-child": true, "nth-last-child": true,
+	"nth-of-type": true, "nth-last-of-type": true, "nth-col": true, "nth-last-col": true, "lang": true,
+	"dir": true, "host": true, "host-context": true, "state": true, "current": true,
+	"active-view-transition-type": true,
+}
+
+// https://drafts.csswg.org/css-pseudo-4/
+var pseudo_elements = map[string]bool{
+	"after": true, "backdrop": true, "before": true, "checkmark": true, "column": true, "cue": true,
+	"cue-region": true, "details-content": true, "file-selector-button": true, "first-letter": true,
+	"first-line": true, "grammar-error": true, "marker": true, "picker-icon": true, "placeholder": true,
+	"scroll-marker": true, "scroll-marker-group": true, "search-text": true, "selection": true,
+	"spelling-error": true, "target-text": true, "view-transition": true,
+}
+
+var functional_pseudo_elements = map[string]bool{
+	"cue": true, "cue-region": true, "highlight": true, "part": true, "picker": true, "slotted": true,
+	"view-transition-group": true, "view-transition-image-pair": true, "view-transition-new": true,
+	"view-transition-old": true,
+}
+
+// Check the pseudo-class or pseudo-element is known, and parse the arguments of the functional ones.
+// Vendor-prefixed pseudos are accepted as they are, since only the vendor knows their grammar.
+func validate_pseudo_selector(selector *SimpleSelector) error {
+	if strings.HasPrefix(selector.name, "-") {
+		return nil
+	}
+
+	prefix := ":"
+	known := pseudo_classes
+	if selector.is_function {
+		known = functional_pseudo_classes
+	}
+	if selector.kind == PSEUDO_ELEMENT_SELECTOR {
+		prefix = "::"
+		known = pseudo_elements
+		if selector.is_function {
+			known = functional_pseudo_elements
+		}
+	}
+
+	if known[selector.name] == false {
+		kind := "pseudo-class"
+		if selector.kind == PSEUDO_ELEMENT_SELECTOR {
+			kind = "pseudo-element"
+		}
+		if selector.is_function {
+			return fmt.Errorf("Parse Error: unknown functional %s '%s%s()'", kind, prefix, selector.name)
+		}
+		return fmt.Errorf("Parse Error: unknown %s '%s%s'", kind, prefix, selector.name)
+	}
+
+	if selector.is_function == false {
+		return nil
+	}
+
+	var err error
+	switch selector.name {
+	// :is() and :where() take a <forgiving-selector-list>.
+	case "is", "where":
+		selector.selectors = parse_forgiving_selector_list(selector.arguments)
+	// :not() takes a <complex-real-selector-list>.
+	case "not":
+		selector.selectors, err = parse_selector_list(selector.arguments)
+	// :has() takes a <relative-selector-list>, which may not contain pseudo-elements or another :has().
+	case "has":
+		selector.selectors, err = parse_relative_selector_list(selector.arguments)
+		if err == nil {
+			err = check_has_argument(selector.selectors)
+		}
+	// :nth-child() and :nth-last-child() take <an+b> [ of <complex-real-selector-list> ]?
+	case "nth-child", "nth-last-child":
+		an_plus_b, of_selector := split_nth_of_selector(selector.arguments)
+		selector.nth, err = parse_an_plus_b(an_plus_b)
+		if err == nil && of_selector != nil {
+			selector.has_of_selector = true
+			selector.selectors, err = parse_selector_list(of_selector)
+		}
+	case "nth-of-type", "nth-last-of-type", "nth-col", "nth-last-col":
+		selector.nth, err = parse_an_plus_b(selector.arguments)
+	// :host(), :host-context() and ::slotted() take a <compound-selector>.
+	case "host", "host-context", "slotted":
+		selector.selectors, err = parse_compound_selector_argument(selector.arguments)
+	// :dir(), :state() and ::highlight() take a single <ident>.
+	case "dir", "state", "highlight":
+		if _, ok := single_ident_argument(selector.arguments); ok == false {
+			err = fmt.Errorf("Parse Error: %s() expects a single identifier", selector.name)
+		}
+	// :lang() takes a list of <ident> or <string> language ranges.
+	case "lang":
+		for _, item := range split_on_commas(selector.arguments) {
+			item = trim_whitespace(item)
+			if len(item) != 1 || (item[0].is_token(IDENT_TOKEN) == false && item[0].is_token(STRING_TOKEN) == false) {
+				err = fmt.Errorf("Parse Error: :lang() expects a comma-separated list of language ranges")
+				break
+			}
+		}
+	// ::part() takes one or more <ident>s.
+	case "part":
+		idents := 0
+		for _, value := range selector.arguments {
+			if value.is_token(IDENT_TOKEN) {
+				idents += 1
+			} else if value.is_token(WHITESPACE_TOKEN) == false {
+				err = fmt.Errorf("Parse Error: ::part() expects a list of identifiers, found %s", describe_value(value))
+				break
+			}
+		}
+		if err == nil && idents == 0 {
+			err = fmt.Errorf("Parse Error: ::part() expects at least one identifier")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w (in %s%s())", err, prefix, selector.name)
+	}
+
+	return nil
+}
+
+// Split the arguments of :nth-child() on the "of" keyword, returning the An+B part and the selector list (or nil).
+func split_nth_of_selector(list []ComponentValue) ([]ComponentValue, []ComponentValue) {
+	for i, value := range list {
+		if value.is_ident("of") {
+			return list[:i], list[i+1:]
+		}
+	}
+
+	return list, nil
+}
+
+func parse_compound_selector_argument(list []ComponentValue) (SelectorList, error) {
+	selector, err := parse_complex_selector(list, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(selector.compounds) != 1 {
+		return nil, fmt.Errorf("Parse Error: expected a compound selector, found a complex selector")
+	}
+
+	return SelectorList{selector}, nil
+}
+
+// https://drafts.csswg.org/selectors-4/#relational
+// The :has() pseudo-class cannot be nested; :has() is not valid within :has().
+// Pseudo-elements are also not valid selectors within :has().
+func check_has_argument(selectors SelectorList) error {
+	for _, complex := range selectors {
+		for _, compound := range complex.compounds {
+			for _, simple := range compound.selectors {
+				if simple.kind == PSEUDO_ELEMENT_SELECTOR {
+					return fmt.Errorf("Parse Error: pseudo-elements are not allowed in :has()")
+				}
+				if simple.kind == PSEUDO_CLASS_SELECTOR && simple.name == "has" {
+					return fmt.Errorf("Parse Error: :has() cannot be nested inside :has()")
+				}
+				if err := check_has_argument(simple.selectors); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// https://drafts.csswg.org/cssom/#serialize-a-group-of-selectors
+func (l SelectorList) String() string {
+	var sb strings.Builder
+	stringify_selector_list(&sb, l)
+	return sb.String()
+}
+
+func (s ComplexSelector) String() string {
+	var sb strings.Builder
+	stringify_complex_selector(&sb, s)
+	return sb.String()
+}
+
+func stringify_selector_list(sb *strings.Builder, selectors SelectorList) {
+	for i, selector := range selectors {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		stringify_complex_selector(sb, selector)
+	}
+}
+
+// https://drafts.csswg.org/cssom/#serialize-a-selector
+func stringify_complex_selector(sb *strings.Builder, selector ComplexSelector) {
+	for i, compound := range selector.compounds {
+		switch compound.combinator {
+		case COMBINATOR_NONE:
+		case COMBINATOR_DESCENDANT:
+			sb.WriteRune(SPACE_CHAR)
+		default:
+			if i > 0 {
+				sb.WriteRune(SPACE_CHAR)
+			}
+			sb.WriteString(fmt.Sprintf("%s%c", compound.combinator, SPACE_CHAR))
+		}
+		stringify_compound_selector(sb, compound)
+	}
+}
+
+func stringify_compound_selector(sb *strings.Builder, compound CompoundSelector) {
+	for _, simple := range compound.selectors {
+		stringify_simple_selector(sb, simple)
+	}
+}
+
+// https://drafts.csswg.org/cssom/#serialize-a-simple-selector
+func stringify_simple_selector(sb *strings.Builder, selector SimpleSelector) {
+	switch selector.kind {
+	case TYPE_SELECTOR, UNIVERSAL_SELECTOR:
+		stringify_namespace_prefix(sb, selector)
+		if selector.kind == UNIVERSAL_SELECTOR {
+			sb.WriteRune(ASTERISK_CHAR)
+		} else {
+			stringify_identifier(sb, selector.name)
+		}
+	case ID_SELECTOR:
+		sb.WriteRune(NUMBER_SIGN_CHAR)
+		stringify_identifier(sb, selector.name)
+	case CLASS_SELECTOR:
+		sb.WriteRune(FULL_STOP_CHAR)
+		stringify_identifier(sb, selector.name)
+	case ATTRIBUTE_SELECTOR:
+		sb.WriteRune(OPEN_SQUARE_CHAR)
+		stringify_namespace_prefix(sb, selector)
+		stringify_identifier(sb, selector.name)
+		if selector.matcher != ATTRIBUTE_EXISTS {
+			sb.WriteString(selector.matcher.String())
+			stringify_string(sb, selector.value)
+			switch selector.case_sensitive {
+			case ATTRIBUTE_CASE_INSENSITIVE:
+				sb.WriteString(" i")
+			case ATTRIBUTE_CASE_SENSITIVE:
+				sb.WriteString(" s")
+			}
+		}
+		sb.WriteRune(CLOSE_SQUARE_CHAR)
+	case NESTING_SELECTOR:
+		sb.WriteRune('&')
+	case PSEUDO_CLASS_SELECTOR, PSEUDO_ELEMENT_SELECTOR:
+		sb.WriteRune(COLON_CHAR)
+		if selector.kind == PSEUDO_ELEMENT_SELECTOR {
+			sb.WriteRune(COLON_CHAR)
+		}
+		stringify_identifier(sb, selector.name)
+		if selector.is_function {
+			sb.WriteRune(OPEN_PAREN_CHAR)
+			stringify_pseudo_arguments(sb, selector)
+			sb.WriteRune(CLOSE_PAREN_CHAR)
+		}
+	}
+}
+
+func stringify_namespace_prefix(sb *strings.Builder, selector SimpleSelector) {
+	if selector.has_namespace == false {
+		return
+	}
+	if selector.namespace == "*" {
+		sb.WriteRune(ASTERISK_CHAR)
+	} else {
+		stringify_identifier(sb, selector.namespace)
+	}
+	sb.WriteRune('|')
+}
+
+func stringify_pseudo_arguments(sb *strings.Builder, selector SimpleSelector) {
+	switch selector.name {
+	case "is", "where", "not", "has", "host", "host-context", "slotted":
+		stringify_selector_list(sb, selector.selectors)
+	case "nth-child", "nth-last-child":
+		stringify_an_plus_b(sb, selector.nth)
+		if selector.has_of_selector {
+			sb.WriteString(" of ")
+			stringify_selector_list(sb, selector.selectors)
+		}
+	case "nth-of-type", "nth-last-of-type", "nth-col", "nth-last-col":
+		stringify_an_plus_b(sb, selector.nth)
+	default:
+		stringify_component_value_list(sb, trim_whitespace(selector.arguments))
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestParseSelectorList(t *testing.T) {
+	tests := []struct {
+		selector string
+		// The serialization of the parsed selector list.
+		want string
+	}{
+		{selector: "a", want: "a"},
+		{selector: "  a  ", want: "a"},
+		{selector: "A.b#c", want: "A.b#c"},
+		{selector: "*", want: "*"},
+		{selector: "ns|a, *|a, |a", want: "ns|a, *|a, |a"},
+		{selector: "a>b+c~d e", want: "a > b + c ~ d e"},
+		{selector: "a , b", want: "a, b"},
+		{selector: "[href]", want: "[href]"},
+		{selector: "[href='x' i]", want: `[href="x" i]`},
+		{selector: "[ href ^= x s ]", want: `[href^="x" s]`},
+		{selector: "[a~=b][a|=b][a$=b][a*=b]", want: `[a~="b"][a|="b"][a$="b"][a*="b"]`},
+		{selector: "a:hover", want: "a:hover"},
+		{selector: "a::before", want: "a::before"},
+		{selector: "a:before", want: "a::before"},
+		{selector: ".a::before:hover", want: ".a::before:hover"},
+		{selector: ":not(.a, .b)", want: ":not(.a, .b)"},
+		{selector: ":is(.a, :bogus)", want: ":is(.a)"},
+		{selector: ":where(a b)", want: ":where(a b)"},
+		{selector: ":has(> a, + b)", want: ":has(> a, + b)"},
+		{selector: ":lang(en)", want: ":lang(en)"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selectors, err := parse_selector_list(parse_component_value_list(test.selector))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := selectors.String(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSelectorListErrors(t *testing.T) {
+	for _, selector := range []string{
+		"",
+		"a,",
+		", a",
+		"a..b",
+		"#1a",
+		"a|b|c",
+		"[a=]",
+		"[a='b' x]",
+		"a:unknown",
+		"p:left",
+		"a:first",
+		"a:blank",
+		"::before.a",
+		".a > > .b",
+		":not()",
+		":has(:has(a))",
+		"a > ",
+	} {
+		t.Run(selector, func(t *testing.T) {
+			if selectors, err := parse_selector_list(parse_component_value_list(selector)); err == nil {
+				t.Errorf("expected an error, got %q", selectors.String())
+			}
+		})
+	}
+}
+
+func TestParseRelativeSelectorList(t *testing.T) {
+	selectors, err := parse_relative_selector_list(parse_component_value_list("> a, ~ b c, d"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := selectors.String(); got != "> a, ~ b c, d" {
+		t.Errorf("got %q", got)
+	}
+}
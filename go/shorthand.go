@@ -0,0 +1,1010 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// https://drafts.csswg.org/css-cascade-5/#shorthand-property
+type shorthand_property struct {
+	// The longhands the shorthand sets, in canonical order.
+	longhands []string
+	// Parse the shorthand's value into a value for each of its longhands, in the same order.
+	// Longhands that are omitted from the value are set to their initial value.
+	expand func(value []ComponentValue) ([][]ComponentValue, error)
+}
+
+var shorthand_properties = map[string]shorthand_property{
+	"margin":          {longhands: side_longhands("margin-%s"), expand: expand_four_sides(is_margin_value)},
+	"padding":         {longhands: side_longhands("padding-%s"), expand: expand_four_sides(is_length_percentage)},
+	"inset":           {longhands: []string{"top", "right", "bottom", "left"}, expand: expand_four_sides(is_margin_value)},
+	"margin-block":    {longhands: []string{"margin-block-start", "margin-block-end"}, expand: expand_pair(is_margin_value)},
+	"margin-inline":   {longhands: []string{"margin-inline-start", "margin-inline-end"}, expand: expand_pair(is_margin_value)},
+	"padding-block":   {longhands: []string{"padding-block-start", "padding-block-end"}, expand: expand_pair(is_length_percentage)},
+	"padding-inline":  {longhands: []string{"padding-inline-start", "padding-inline-end"}, expand: expand_pair(is_length_percentage)},
+	"inset-block":     {longhands: []string{"inset-block-start", "inset-block-end"}, expand: expand_pair(is_margin_value)},
+	"inset-inline":    {longhands: []string{"inset-inline-start", "inset-inline-end"}, expand: expand_pair(is_margin_value)},
+	"border-width":    {longhands: side_longhands("border-%s-width"), expand: expand_four_sides(is_line_width)},
+	"border-style":    {longhands: side_longhands("border-%s-style"), expand: expand_four_sides(is_line_style)},
+	"border-color":    {longhands: side_longhands("border-%s-color"), expand: expand_four_sides(is_color)},
+	"border-top":      {longhands: border_side_longhands("top"), expand: expand_border_side},
+	"border-right":    {longhands: border_side_longhands("right"), expand: expand_border_side},
+	"border-bottom":   {longhands: border_side_longhands("bottom"), expand: expand_border_side},
+	"border-left":     {longhands: border_side_longhands("left"), expand: expand_border_side},
+	"border":          {longhands: border_longhands(), expand: expand_border},
+	"border-radius":   {longhands: []string{"border-top-left-radius", "border-top-right-radius", "border-bottom-right-radius", "border-bottom-left-radius"}, expand: expand_border_radius},
+	"outline":         {longhands: []string{"outline-width", "outline-style", "outline-color"}, expand: expand_outline},
+	"font":            {longhands: font_longhands, expand: expand_font},
+	"background":      {longhands: background_longhands, expand: expand_background},
+	"flex":            {longhands: []string{"flex-grow", "flex-shrink", "flex-basis"}, expand: expand_flex},
+	"flex-flow":       {longhands: []string{"flex-direction", "flex-wrap"}, expand: expand_flex_flow},
+	"gap":             {longhands: []string{"row-gap", "column-gap"}, expand: expand_pair(is_gap_value)},
+	"grid-area":       {longhands: []string{"grid-row-start", "grid-column-start", "grid-row-end", "grid-column-end"}, expand: expand_grid_area},
+	"grid-row":        {longhands: []string{"grid-row-start", "grid-row-end"}, expand: expand_grid_line_pair},
+	"grid-column":     {longhands: []string{"grid-column-start", "grid-column-end"}, expand: expand_grid_line_pair},
+	"overflow":        {longhands: []string{"overflow-x", "overflow-y"}, expand: expand_pair(is_overflow_keyword)},
+	"list-style":      {longhands: []string{"list-style-position", "list-style-image", "list-style-type"}, expand: expand_list_style},
+	"text-decoration": {longhands: []string{"text-decoration-line", "text-decoration-style", "text-decoration-color", "text-decoration-thickness"}, expand: expand_text_decoration},
+	"transition":      {longhands: transition_longhands, expand: expand_transition},
+}
+
+func is_shorthand_property(name string) bool {
+	_, ok := shorthand_properties[property_name(name)]
+	return ok
+}
+
+// Expand a shorthand declaration into a declaration for each of its longhands, which keep the shorthand's importance.
+// Declarations of any other property are returned as they are.
+func ExpandShorthand(decl Declaration) ([]Declaration, error) {
+	name := property_name(decl.name)
+	shorthand, ok := shorthand_properties[name]
+	if ok == false {
+		return []Declaration{decl}, nil
+	}
+
+	var values [][]ComponentValue
+	switch {
+	// A CSS-wide keyword sets every longhand to that keyword.
+	case css_wide_keyword(decl) != "":
+		for range shorthand.longhands {
+			values = append(values, trim_whitespace(decl.value))
+		}
+	// https://drafts.csswg.org/css-variables-2/#variables-in-shorthands
+	// The value can't be split until var() has been substituted, so every longhand is pending on the shorthand's value.
+	case contains_substitution_function(decl.value):
+		var longhands []Declaration
+		for _, longhand := range shorthand.longhands {
+			longhands = append(longhands, Declaration{name: longhand, value: decl.value, important: decl.important, pending_shorthand: name})
+		}
+		return longhands, nil
+	default:
+		var err error
+		values, err = shorthand.expand(trim_whitespace(decl.value))
+		if err != nil {
+			return nil, fmt.Errorf("%w (in '%s')", err, name)
+		}
+	}
+
+	longhands := make([]Declaration, len(shorthand.longhands))
+	for i, longhand := range shorthand.longhands {
+		longhands[i] = Declaration{name: longhand, value: values[i], important: decl.important}
+	}
+
+	return longhands, nil
+}
+
+// Whether the value contains an arbitrary substitution function, whose value isn't known until computed-value time.
+func contains_substitution_function(list []ComponentValue) bool {
+	for _, value := range list {
+		if value.is_function("var") || value.is_function("env") || value.is_function("attr") {
+			return true
+		}
+		if (value.kind == FUNCTION || value.kind == SIMPLE_BLOCK) && contains_substitution_function(value.value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func side_longhands(format string) []string {
+	var longhands []string
+	for _, side := range []string{"top", "right", "bottom", "left"} {
+		longhands = append(longhands, fmt.Sprintf(format, side))
+	}
+
+	return longhands
+}
+
+// The values of a list, without the whitespace between them.
+func non_whitespace_values(list []ComponentValue) []ComponentValue {
+	var values []ComponentValue
+	for _, value := range list {
+		if value.is_token(WHITESPACE_TOKEN) == false {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// Join values into a single whitespace-separated value.
+func join_values(parts ...[]ComponentValue) []ComponentValue {
+	var list []ComponentValue
+	for i, part := range parts {
+		if i > 0 {
+			list = append(list, whitespace_value())
+		}
+		list = append(list, part...)
+	}
+
+	return list
+}
+
+// Join the values of each layer of a comma-separated shorthand into a comma-separated value.
+func join_layers(layers [][]ComponentValue) []ComponentValue {
+	var list []ComponentValue
+	for i, layer := range layers {
+		if i > 0 {
+			list = append(list, ComponentValue{kind: PRESERVED_TOKEN, token: Token{kind: COMMA_TOKEN}}, whitespace_value())
+		}
+		list = append(list, layer...)
+	}
+
+	return list
+}
+
+// Split a list of component values on its top-level <delim-token>s with the given code point.
+func split_on_delim(list []ComponentValue, char rune) [][]ComponentValue {
+	var result [][]ComponentValue
+	start := 0
+	for i, value := range list {
+		if value.is_delim(char) {
+			result = append(result, list[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(result, list[start:])
+}
+
+// The value of an omitted longhand.
+func initial_value(value string) []ComponentValue {
+	return parse_component_value_list(value)
+}
+
+// <top> <right>? <bottom>? <left>?, where an omitted right copies top, an omitted bottom copies top, and an omitted left copies right.
+func expand_four_sides(accepts func(ComponentValue) bool) func([]ComponentValue) ([][]ComponentValue, error) {
+	return func(value []ComponentValue) ([][]ComponentValue, error) {
+		items, err := single_values(value, 4, accepts)
+		if err != nil {
+			return nil, err
+		}
+
+		return four_sides(items), nil
+	}
+}
+
+func four_sides(items []ComponentValue) [][]ComponentValue {
+	top := items[0]
+	right, bottom := top, top
+	if len(items) > 1 {
+		right = items[1]
+	}
+	if len(items) > 2 {
+		bottom = items[2]
+	}
+	left := right
+	if len(items) > 3 {
+		left = items[3]
+	}
+
+	return [][]ComponentValue{{top}, {right}, {bottom}, {left}}
+}
+
+// <first> <second>?, where an omitted second value copies the first.
+func expand_pair(accepts func(ComponentValue) bool) func([]ComponentValue) ([][]ComponentValue, error) {
+	return func(value []ComponentValue) ([][]ComponentValue, error) {
+		items, err := single_values(value, 2, accepts)
+		if err != nil {
+			return nil, err
+		}
+		if len(items) == 1 {
+			return [][]ComponentValue{{items[0]}, {items[0]}}, nil
+		}
+
+		return [][]ComponentValue{{items[0]}, {items[1]}}, nil
+	}
+}
+
+// Between one and max whitespace-separated values, each of which must be accepted.
+func single_values(value []ComponentValue, max int, accepts func(ComponentValue) bool) ([]ComponentValue, error) {
+	items := non_whitespace_values(value)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("Parse Error: expected a value, found end of input")
+	}
+	if len(items) > max {
+		return nil, fmt.Errorf("Parse Error: expected at most %d values, found %d", max, len(items))
+	}
+	for _, item := range items {
+		if accepts(item) == false {
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	return items, nil
+}
+
+func border_side_longhands(side string) []string {
+	return []string{"border-" + side + "-width", "border-" + side + "-style", "border-" + side + "-color"}
+}
+
+// The border shorthand sets all four sides, and resets border-image to its initial value.
+func border_longhands() []string {
+	longhands := append(side_longhands("border-%s-width"), side_longhands("border-%s-style")...)
+	longhands = append(longhands, side_longhands("border-%s-color")...)
+	return append(longhands, "border-image-source", "border-image-slice", "border-image-width", "border-image-outset", "border-image-repeat")
+}
+
+// https://drafts.csswg.org/css-backgrounds/#border-shorthands
+// <line-width> || <line-style> || <color>
+func expand_border_side(value []ComponentValue) ([][]ComponentValue, error) {
+	var width, style, color []ComponentValue
+	for _, item := range non_whitespace_values(value) {
+		switch {
+		case width == nil && is_line_width(item):
+			width = []ComponentValue{item}
+		case style == nil && is_line_style(item):
+			style = []ComponentValue{item}
+		case color == nil && is_color(item):
+			color = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	if width == nil {
+		width = initial_value("medium")
+	}
+	if style == nil {
+		style = initial_value("none")
+	}
+	if color == nil {
+		color = initial_value("currentcolor")
+	}
+
+	return [][]ComponentValue{width, style, color}, nil
+}
+
+func expand_border(value []ComponentValue) ([][]ComponentValue, error) {
+	side, err := expand_border_side(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]ComponentValue
+	for _, longhand := range side {
+		values = append(values, longhand, longhand, longhand, longhand)
+	}
+
+	return append(values, initial_value("none"), initial_value("100%"), initial_value("1"), initial_value("0"), initial_value("stretch")), nil
+}
+
+// https://drafts.csswg.org/css-backgrounds/#border-radius
+// <length-percentage>{1,4} [ / <length-percentage>{1,4} ]?
+func expand_border_radius(value []ComponentValue) ([][]ComponentValue, error) {
+	parts := split_on_delim(value, FORWARD_SLASH_CHAR)
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("Parse Error: unexpected '/'")
+	}
+
+	var radii [][][]ComponentValue
+	for _, part := range parts {
+		items, err := single_values(part, 4, is_length_percentage)
+		if err != nil {
+			return nil, err
+		}
+		radii = append(radii, four_sides(items))
+	}
+
+	if len(radii) == 1 {
+		return radii[0], nil
+	}
+	var values [][]ComponentValue
+	for i := range radii[0] {
+		values = append(values, join_values(radii[0][i], radii[1][i]))
+	}
+
+	return values, nil
+}
+
+// https://drafts.csswg.org/css-ui/#outline
+// <'outline-width'> || <'outline-style'> || <'outline-color'>
+func expand_outline(value []ComponentValue) ([][]ComponentValue, error) {
+	var width, style, color []ComponentValue
+	for _, item := range non_whitespace_values(value) {
+		switch {
+		case width == nil && is_line_width(item):
+			width = []ComponentValue{item}
+		case style == nil && (item.is_ident("auto") || (is_line_style(item) && item.is_ident("hidden") == false)):
+			style = []ComponentValue{item}
+		case color == nil && (is_color(item) || item.is_ident("auto")):
+			color = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	if width == nil {
+		width = initial_value("medium")
+	}
+	if style == nil {
+		style = initial_value("none")
+	}
+	if color == nil {
+		color = initial_value("auto")
+	}
+
+	return [][]ComponentValue{width, style, color}, nil
+}
+
+// The font shorthand sets the font-variant-* longhands other than font-variant-caps, and the other font longhands, to their initial values.
+var font_longhands = []string{
+	"font-style", "font-variant-caps", "font-weight", "font-stretch", "font-size", "line-height", "font-family",
+	"font-size-adjust", "font-kerning", "font-feature-settings", "font-language-override", "font-optical-sizing",
+	"font-variation-settings", "font-variant-alternates", "font-variant-east-asian", "font-variant-ligatures",
+	"font-variant-numeric", "font-variant-position", "font-variant-emoji",
+}
+
+var font_reset_values = []string{"none", "auto", "normal", "normal", "auto", "normal", "normal", "normal", "normal", "normal", "normal", "normal"}
+
+// https://drafts.csswg.org/css-fonts/#font-prop
+// [ [ <'font-style'> || <font-variant-css2> || <'font-weight'> || <font-width-css3> ]? <'font-size'> [ / <'line-height'> ]? <'font-family'># ] | <system-family-name>
+func expand_font(value []ComponentValue) ([][]ComponentValue, error) {
+	stream := NewComponentValueStream(value)
+	if first := stream.next_value(); first.is_token(IDENT_TOKEN) && is_system_family_name(string(first.token.value)) {
+		return nil, fmt.Errorf("Parse Error: system font '%s' can't be expanded without a user agent", string(first.token.value))
+	}
+
+	var style, variant, weight, stretch []ComponentValue
+	for count := 0; count < 4; count += 1 {
+		stream.discard_whitespace()
+		item := stream.next_value()
+		switch {
+		// "normal" is valid for any of them, and leaves the longhand to be set to its initial value.
+		case item.is_ident("normal"):
+		case style == nil && (item.is_ident("italic") || item.is_ident("oblique")):
+			style = []ComponentValue{stream.consume_value()}
+			// oblique <angle>?
+			if item.is_ident("oblique") {
+				stream.mark()
+				stream.discard_whitespace()
+				if is_angle(stream.next_value()) {
+					style = join_values(style, []ComponentValue{stream.consume_value()})
+					stream.discard_mark()
+				} else {
+					stream.restore_mark()
+				}
+			}
+			continue
+		case variant == nil && item.is_ident("small-caps"):
+			variant = []ComponentValue{item}
+		case weight == nil && is_font_weight(item):
+			weight = []ComponentValue{item}
+		case stretch == nil && is_font_width_keyword(item):
+			stretch = []ComponentValue{item}
+		default:
+			count = 4
+			continue
+		}
+		stream.discard_value()
+	}
+
+	stream.discard_whitespace()
+	size := stream.consume_value()
+	if is_font_size(size) == false {
+		return nil, fmt.Errorf("Parse Error: expected a font size, found %s", describe_value(size))
+	}
+
+	line_height := initial_value("normal")
+	stream.discard_whitespace()
+	if stream.next_value().is_delim(FORWARD_SLASH_CHAR) {
+		stream.discard_value()
+		stream.discard_whitespace()
+		item := stream.consume_value()
+		if item.is_ident("normal") == false && is_number(item) == false && is_length_percentage(item) == false {
+			return nil, fmt.Errorf("Parse Error: expected a line height, found %s", describe_value(item))
+		}
+		line_height = []ComponentValue{item}
+	}
+
+	family := trim_whitespace(stream.remaining())
+	if err := check_font_family_list(family); err != nil {
+		return nil, err
+	}
+
+	values := [][]ComponentValue{style, variant, weight, stretch, {size}, line_height, family}
+	for i := 0; i < 4; i += 1 {
+		if values[i] == nil {
+			values[i] = initial_value("normal")
+		}
+	}
+	for _, reset := range font_reset_values {
+		values = append(values, initial_value(reset))
+	}
+
+	return values, nil
+}
+
+// <family-name> = <string> | <custom-ident>+, separated by commas.
+func check_font_family_list(list []ComponentValue) error {
+	if len(list) == 0 {
+		return fmt.Errorf("Parse Error: expected a font family, found end of input")
+	}
+
+	for _, family := range split_on_commas(list) {
+		items := non_whitespace_values(family)
+		if len(items) == 0 {
+			return fmt.Errorf("Parse Error: empty font family in font family list")
+		}
+		if len(items) == 1 && items[0].is_token(STRING_TOKEN) {
+			continue
+		}
+		for _, item := range items {
+			if item.is_token(IDENT_TOKEN) == false || (len(items) == 1 && is_css_wide_keyword(string(item.token.value))) {
+				return fmt.Errorf("Parse Error: unexpected %s in font family", describe_value(item))
+			}
+		}
+	}
+
+	return nil
+}
+
+func is_system_family_name(name string) bool {
+	switch strings.ToLower(name) {
+	case "caption", "icon", "menu", "message-box", "small-caption", "status-bar":
+		return true
+	}
+
+	return false
+}
+
+func is_font_weight(value ComponentValue) bool {
+	if value.is_token(NUMBER_TOKEN) {
+		return value.token.numeric >= 1 && value.token.numeric <= 1000
+	}
+
+	return value.is_ident("bold") || value.is_ident("bolder") || value.is_ident("lighter") || math_function_resolves_to_number(value)
+}
+
+func is_font_width_keyword(value ComponentValue) bool {
+	return is_ident_in(value, "ultra-condensed", "extra-condensed", "condensed", "semi-condensed", "semi-expanded", "expanded", "extra-expanded", "ultra-expanded")
+}
+
+func is_font_size(value ComponentValue) bool {
+	return is_length_percentage(value) || is_ident_in(value, "xx-small", "x-small", "small", "medium", "large", "x-large", "xx-large", "xxx-large", "larger", "smaller", "math")
+}
+
+var background_longhands = []string{
+	"background-image", "background-position", "background-size", "background-repeat",
+	"background-attachment", "background-origin", "background-clip", "background-color",
+}
+
+// https://drafts.csswg.org/css-backgrounds/#background
+// <bg-layer>#? , <final-bg-layer>
+func expand_background(value []ComponentValue) ([][]ComponentValue, error) {
+	layers := split_on_commas(value)
+	values := make([][][]ComponentValue, len(background_longhands)-1)
+	color := initial_value("transparent")
+	for i, layer := range layers {
+		final := i == len(layers)-1
+		layer_values, layer_color, err := parse_background_layer(layer, final)
+		if err != nil {
+			return nil, err
+		}
+		for j, layer_value := range layer_values {
+			values[j] = append(values[j], layer_value)
+		}
+		if final && layer_color != nil {
+			color = layer_color
+		}
+	}
+
+	var longhands [][]ComponentValue
+	for _, layer_values := range values {
+		longhands = append(longhands, join_layers(layer_values))
+	}
+
+	return append(longhands, color), nil
+}
+
+// <bg-layer> = <bg-image> || <bg-position> [ / <bg-size> ]? || <repeat-style> || <attachment> || <visual-box> || <visual-box>
+// <final-bg-layer> = <bg-layer> || <'background-color'>
+func parse_background_layer(layer []ComponentValue, final bool) ([][]ComponentValue, []ComponentValue, error) {
+	items := non_whitespace_values(layer)
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("Parse Error: empty background layer")
+	}
+
+	var image, position, size, repeat, attachment, origin, clip, color []ComponentValue
+	for i := 0; i < len(items); i += 1 {
+		item := items[i]
+		switch {
+		// <bg-image> = <image> | none
+		case image == nil && (is_image(item) || item.is_ident("none")):
+			image = []ComponentValue{item}
+		case position == nil && is_position_component(item):
+			for ; i < len(items) && len(position) < 4 && is_position_component(items[i]); i += 1 {
+				position = append(position, items[i])
+			}
+			if i < len(items) && items[i].is_delim(FORWARD_SLASH_CHAR) {
+				i += 1
+				if i < len(items) && is_ident_in(items[i], "cover", "contain") {
+					size = []ComponentValue{items[i]}
+					i += 1
+				} else {
+					for ; i < len(items) && len(size) < 2 && is_background_size_component(items[i]); i += 1 {
+						size = append(size, items[i])
+					}
+				}
+				if size == nil {
+					return nil, nil, fmt.Errorf("Parse Error: expected a background size after '/'")
+				}
+			}
+			i -= 1
+			position = join_values(split_values(position)...)
+			size = join_values(split_values(size)...)
+		case repeat == nil && is_ident_in(item, "repeat-x", "repeat-y"):
+			repeat = []ComponentValue{item}
+		case repeat == nil && is_repeat_keyword(item):
+			repeat = []ComponentValue{item}
+			if i+1 < len(items) && is_repeat_keyword(items[i+1]) {
+				repeat = join_values(repeat, []ComponentValue{items[i+1]})
+				i += 1
+			}
+		case attachment == nil && is_ident_in(item, "scroll", "fixed", "local"):
+			attachment = []ComponentValue{item}
+		case clip == nil && is_ident_in(item, "border-box", "padding-box", "content-box"):
+			if origin == nil {
+				origin = []ComponentValue{item}
+			} else {
+				clip = []ComponentValue{item}
+			}
+		case final && color == nil && is_color(item):
+			color = []ComponentValue{item}
+		default:
+			return nil, nil, fmt.Errorf("Parse Error: unexpected %s in background layer", describe_value(item))
+		}
+	}
+
+	// A single <visual-box> sets both the origin and the clip.
+	if clip == nil && origin != nil {
+		clip = origin
+	}
+	values := [][]ComponentValue{image, position, size, repeat, attachment, origin, clip}
+	for i, initial := range []string{"none", "0% 0%", "auto", "repeat", "scroll", "padding-box", "border-box"} {
+		if values[i] == nil {
+			values[i] = initial_value(initial)
+		}
+	}
+
+	return values, color, nil
+}
+
+// Each value of the list as a list of its own.
+func split_values(list []ComponentValue) [][]ComponentValue {
+	var parts [][]ComponentValue
+	for _, value := range list {
+		parts = append(parts, []ComponentValue{value})
+	}
+
+	return parts
+}
+
+func is_position_component(value ComponentValue) bool {
+	return is_length_percentage(value) || is_ident_in(value, "left", "right", "top", "bottom", "center")
+}
+
+func is_background_size_component(value ComponentValue) bool {
+	return is_length_percentage(value) || value.is_ident("auto")
+}
+
+func is_repeat_keyword(value ComponentValue) bool {
+	return is_ident_in(value, "repeat", "space", "round", "no-repeat")
+}
+
+// https://drafts.csswg.org/css-flexbox/#flex-property
+// none | [ <'flex-grow'> <'flex-shrink'>? || <'flex-basis'> ]
+func expand_flex(value []ComponentValue) ([][]ComponentValue, error) {
+	items := non_whitespace_values(value)
+	if len(items) == 1 && items[0].is_ident("none") {
+		return [][]ComponentValue{initial_value("0"), initial_value("0"), initial_value("auto")}, nil
+	}
+	if len(items) == 1 && items[0].is_ident("auto") {
+		return [][]ComponentValue{initial_value("1"), initial_value("1"), initial_value("auto")}, nil
+	}
+
+	var grow, shrink, basis []ComponentValue
+	for i := 0; i < len(items); i += 1 {
+		item := items[i]
+		switch {
+		// A unitless zero is a flex factor, unless it comes after both flex factors.
+		case grow == nil && is_number(item):
+			grow = []ComponentValue{item}
+			if i+1 < len(items) && is_number(items[i+1]) {
+				shrink = []ComponentValue{items[i+1]}
+				i += 1
+			}
+		case basis == nil && is_flex_basis(item):
+			basis = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	if grow == nil {
+		grow = initial_value("1")
+	}
+	if shrink == nil {
+		shrink = initial_value("1")
+	}
+	// When the flex basis is omitted, it's 0 rather than its initial value of auto.
+	if basis == nil {
+		basis = initial_value("0%")
+	}
+
+	return [][]ComponentValue{grow, shrink, basis}, nil
+}
+
+func is_flex_basis(value ComponentValue) bool {
+	return is_length_percentage(value) || is_ident_in(value, "auto", "content", "min-content", "max-content") || value.is_function("fit-content")
+}
+
+// <'flex-direction'> || <'flex-wrap'>
+func expand_flex_flow(value []ComponentValue) ([][]ComponentValue, error) {
+	var direction, wrap []ComponentValue
+	for _, item := range non_whitespace_values(value) {
+		switch {
+		case direction == nil && is_ident_in(item, "row", "row-reverse", "column", "column-reverse"):
+			direction = []ComponentValue{item}
+		case wrap == nil && is_ident_in(item, "nowrap", "wrap", "wrap-reverse"):
+			wrap = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	if direction == nil {
+		direction = initial_value("row")
+	}
+	if wrap == nil {
+		wrap = initial_value("nowrap")
+	}
+
+	return [][]ComponentValue{direction, wrap}, nil
+}
+
+// https://drafts.csswg.org/css-grid/#propdef-grid-area
+// <grid-line> [ / <grid-line> ]{0,3}
+func expand_grid_area(value []ComponentValue) ([][]ComponentValue, error) {
+	lines, err := parse_grid_lines(value, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	// An omitted line copies the opposing line if that is a <custom-ident>, and is auto otherwise.
+	row_start := lines[0]
+	column_start := omitted_grid_line(lines, 1, row_start)
+	row_end := omitted_grid_line(lines, 2, row_start)
+	column_end := omitted_grid_line(lines, 3, column_start)
+
+	return [][]ComponentValue{row_start, column_start, row_end, column_end}, nil
+}
+
+// <grid-line> [ / <grid-line> ]?
+func expand_grid_line_pair(value []ComponentValue) ([][]ComponentValue, error) {
+	lines, err := parse_grid_lines(value, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return [][]ComponentValue{lines[0], omitted_grid_line(lines, 1, lines[0])}, nil
+}
+
+func parse_grid_lines(value []ComponentValue, max int) ([][]ComponentValue, error) {
+	parts := split_on_delim(value, FORWARD_SLASH_CHAR)
+	if len(parts) > max {
+		return nil, fmt.Errorf("Parse Error: expected at most %d grid lines, found %d", max, len(parts))
+	}
+
+	var lines [][]ComponentValue
+	for _, part := range parts {
+		part = trim_whitespace(part)
+		if is_grid_line(part) == false {
+			return nil, fmt.Errorf("Parse Error: invalid grid line '%s'", serialize_component_value_list(part))
+		}
+		lines = append(lines, part)
+	}
+
+	return lines, nil
+}
+
+func omitted_grid_line(lines [][]ComponentValue, index int, opposite []ComponentValue) []ComponentValue {
+	if index < len(lines) {
+		return lines[index]
+	}
+	if len(opposite) == 1 && is_grid_custom_ident(opposite[0]) {
+		return opposite
+	}
+
+	return initial_value("auto")
+}
+
+// <grid-line> = auto | <custom-ident> | [ [ <integer [-∞,-1]> | <integer [1,∞]> ] && <custom-ident>? ] | [ span && [ <integer [1,∞]> || <custom-ident> ] ]
+func is_grid_line(part []ComponentValue) bool {
+	items := non_whitespace_values(part)
+	if len(items) == 1 && items[0].is_ident("auto") {
+		return true
+	}
+
+	spans, integers, idents := 0, 0, 0
+	for _, item := range items {
+		switch {
+		case item.is_ident("span"):
+			spans += 1
+		case item.is_token(NUMBER_TOKEN) && item.token.type_flag == TYPE_INTEGER && item.token.numeric != 0:
+			integers += 1
+		case is_grid_custom_ident(item):
+			idents += 1
+		default:
+			return false
+		}
+	}
+
+	return spans <= 1 && integers <= 1 && idents <= 1 && integers+idents > 0
+}
+
+func is_grid_custom_ident(value ComponentValue) bool {
+	return value.is_token(IDENT_TOKEN) && is_ident_in(value, "auto", "span") == false && is_css_wide_keyword(string(value.token.value)) == false
+}
+
+// https://drafts.csswg.org/css-lists/#list-style-property
+// <'list-style-position'> || <'list-style-image'> || <'list-style-type'>
+func expand_list_style(value []ComponentValue) ([][]ComponentValue, error) {
+	var position, image, kind []ComponentValue
+	nones := 0
+	for _, item := range non_whitespace_values(value) {
+		switch {
+		// "none" could be the image or the type, so it's resolved once the other values are known.
+		case item.is_ident("none"):
+			nones += 1
+		case position == nil && is_ident_in(item, "inside", "outside"):
+			position = []ComponentValue{item}
+		case image == nil && is_image(item):
+			image = []ComponentValue{item}
+		case kind == nil && (item.is_token(IDENT_TOKEN) || item.is_token(STRING_TOKEN) || item.is_function("symbols")):
+			kind = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	none := initial_value("none")
+	switch {
+	case nones > 2, nones == 2 && (image != nil || kind != nil), nones == 1 && image != nil && kind != nil:
+		return nil, fmt.Errorf("Parse Error: unexpected 'none'")
+	case nones == 2, nones == 1 && image == nil && kind == nil:
+		image, kind = none, none
+	case nones == 1 && image == nil:
+		image = none
+	case nones == 1:
+		kind = none
+	}
+
+	if position == nil {
+		position = initial_value("outside")
+	}
+	if image == nil {
+		image = none
+	}
+	if kind == nil {
+		kind = initial_value("disc")
+	}
+
+	return [][]ComponentValue{position, image, kind}, nil
+}
+
+// https://drafts.csswg.org/css-text-decor/#text-decoration-property
+// <'text-decoration-line'> || <'text-decoration-style'> || <'text-decoration-color'> || <'text-decoration-thickness'>
+func expand_text_decoration(value []ComponentValue) ([][]ComponentValue, error) {
+	items := non_whitespace_values(value)
+	var line, style, color, thickness []ComponentValue
+	for i := 0; i < len(items); i += 1 {
+		item := items[i]
+		switch {
+		case line == nil && item.is_ident("none"):
+			line = []ComponentValue{item}
+		// none | [ underline || overline || line-through || blink ]
+		case line == nil && is_text_decoration_line(item):
+			seen := map[string]bool{}
+			for ; i < len(items) && is_text_decoration_line(items[i]); i += 1 {
+				name := strings.ToLower(string(items[i].token.value))
+				if seen[name] {
+					return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(items[i]))
+				}
+				seen[name] = true
+				line = append(line, items[i])
+			}
+			i -= 1
+			line = join_values(split_values(line)...)
+		case style == nil && is_ident_in(item, "solid", "double", "dotted", "dashed", "wavy"):
+			style = []ComponentValue{item}
+		case thickness == nil && (is_ident_in(item, "auto", "from-font") || is_length_percentage(item)):
+			thickness = []ComponentValue{item}
+		case color == nil && is_color(item):
+			color = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	values := [][]ComponentValue{line, style, color, thickness}
+	for i, initial := range []string{"none", "solid", "currentcolor", "auto"} {
+		if values[i] == nil {
+			values[i] = initial_value(initial)
+		}
+	}
+
+	return values, nil
+}
+
+func is_text_decoration_line(value ComponentValue) bool {
+	return is_ident_in(value, "underline", "overline", "line-through", "blink")
+}
+
+var transition_longhands = []string{"transition-property", "transition-duration", "transition-timing-function", "transition-delay", "transition-behavior"}
+
+// https://drafts.csswg.org/css-transitions-2/#transition-shorthand-property
+// <single-transition>#
+func expand_transition(value []ComponentValue) ([][]ComponentValue, error) {
+	layers := split_on_commas(value)
+	values := make([][][]ComponentValue, len(transition_longhands))
+	for _, layer := range layers {
+		layer_values, err := parse_single_transition(layer)
+		if err != nil {
+			return nil, err
+		}
+		// none is only valid as the property of a single transition.
+		if len(layers) > 1 && layer_values[0][0].is_ident("none") {
+			return nil, fmt.Errorf("Parse Error: 'none' is only valid in a single transition")
+		}
+		for i, layer_value := range layer_values {
+			values[i] = append(values[i], layer_value)
+		}
+	}
+
+	var longhands [][]ComponentValue
+	for _, layer_values := range values {
+		longhands = append(longhands, join_layers(layer_values))
+	}
+
+	return longhands, nil
+}
+
+// <single-transition> = [ none | <single-transition-property> ] || <time> || <easing-function> || <time> || <transition-behavior-value>
+func parse_single_transition(layer []ComponentValue) ([][]ComponentValue, error) {
+	items := non_whitespace_values(layer)
+	if len(items) == 0 {
+		return nil, fmt.Errorf("Parse Error: empty transition in transition list")
+	}
+
+	var property, duration, easing, delay, behavior []ComponentValue
+	for _, item := range items {
+		switch {
+		// The first time is the duration, and the second is the delay.
+		case duration == nil && is_time(item):
+			duration = []ComponentValue{item}
+		case delay == nil && is_time(item):
+			delay = []ComponentValue{item}
+		case easing == nil && is_easing_function(item):
+			easing = []ComponentValue{item}
+		case behavior == nil && is_ident_in(item, "normal", "allow-discrete"):
+			behavior = []ComponentValue{item}
+		case property == nil && item.is_token(IDENT_TOKEN) && is_css_wide_keyword(string(item.token.value)) == false:
+			property = []ComponentValue{item}
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected %s", describe_value(item))
+		}
+	}
+
+	values := [][]ComponentValue{property, duration, easing, delay, behavior}
+	for i, initial := range []string{"all", "0s", "ease", "0s", "normal"} {
+		if values[i] == nil {
+			values[i] = initial_value(initial)
+		}
+	}
+
+	return values, nil
+}
+
+func is_easing_function(value ComponentValue) bool {
+	return is_ident_in(value, "linear", "ease", "ease-in", "ease-out", "ease-in-out", "step-start", "step-end") ||
+		value.is_function("cubic-bezier") || value.is_function("steps") || value.is_function("linear")
+}
+
+// Whether the value is an <ident-token> that is an ASCII case-insensitive match for one of the names.
+func is_ident_in(value ComponentValue, names ...string) bool {
+	for _, name := range names {
+		if value.is_ident(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// <length>, where a unitless zero is also a length.
+func is_length(value ComponentValue) bool {
+	_, ok := parse_length(value)
+	return ok || math_function_resolves_to(value, LENGTH, false)
+}
+
+func is_length_percentage(value ComponentValue) bool {
+	return is_length(value) || value.is_token(PERCENTAGE_TOKEN) || math_function_resolves_to(value, LENGTH, true)
+}
+
+func is_number(value ComponentValue) bool {
+	return value.is_token(NUMBER_TOKEN) || math_function_resolves_to_number(value)
+}
+
+func is_time(value ComponentValue) bool {
+	_, ok := parse_time(value)
+	return ok || math_function_resolves_to(value, TIME, false)
+}
+
+func is_angle(value ComponentValue) bool {
+	_, ok := parse_angle(value)
+	return ok || math_function_resolves_to(value, ANGLE, false)
+}
+
+// <length-percentage> | auto
+func is_margin_value(value ComponentValue) bool {
+	return is_length_percentage(value) || value.is_ident("auto")
+}
+
+// <length-percentage> | normal
+func is_gap_value(value ComponentValue) bool {
+	return is_length_percentage(value) || value.is_ident("normal")
+}
+
+// https://drafts.csswg.org/css-backgrounds/#typedef-line-width
+func is_line_width(value ComponentValue) bool {
+	return is_length(value) || is_ident_in(value, "thin", "medium", "thick")
+}
+
+// https://drafts.csswg.org/css-backgrounds/#typedef-line-style
+func is_line_style(value ComponentValue) bool {
+	return is_ident_in(value, "none", "hidden", "dotted", "dashed", "solid", "double", "groove", "ridge", "inset", "outset")
+}
+
+func is_overflow_keyword(value ComponentValue) bool {
+	return is_ident_in(value, "visible", "hidden", "clip", "scroll", "auto")
+}
+
+// https://drafts.csswg.org/css-images/#typedef-image
+func is_image(value ComponentValue) bool {
+	if value.is_token(URL_TOKEN) {
+		return true
+	}
+	if value.kind != FUNCTION {
+		return false
+	}
+
+	name := strings.ToLower(value.name)
+	switch name {
+	case "url", "src", "image", "image-set", "-webkit-image-set", "cross-fade", "element", "paint":
+		return true
+	}
+
+	return strings.HasSuffix(name, "gradient")
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// A declaration from its text, e.g. "margin: 1px !important", without validating its value.
+func parse_test_declaration(t *testing.T, text string) Declaration {
+	t.Helper()
+	name, value, ok := strings.Cut(text, ":")
+	if ok == false {
+		t.Fatalf("expected a declaration, got %q", text)
+	}
+	value, important := strings.CutSuffix(strings.TrimSpace(value), "!important")
+
+	return Declaration{name: strings.TrimSpace(name), value: trim_whitespace(parse_component_value_list(value)), important: important}
+}
+
+func TestExpandShorthand(t *testing.T) {
+	tests := []struct {
+		decl string
+		// The expected values of some of the longhands, or nil if the value is invalid.
+		want map[string]string
+	}{
+		{decl: "margin: 1px", want: map[string]string{"margin-top": "1px", "margin-right": "1px", "margin-bottom": "1px", "margin-left": "1px"}},
+		{decl: "margin: 1px 2px", want: map[string]string{"margin-top": "1px", "margin-right": "2px", "margin-bottom": "1px", "margin-left": "2px"}},
+		{decl: "margin: 1px 2px 3px", want: map[string]string{"margin-top": "1px", "margin-right": "2px", "margin-bottom": "3px", "margin-left": "2px"}},
+		{decl: "margin: 1px 2px 3px auto", want: map[string]string{"margin-top": "1px", "margin-right": "2px", "margin-bottom": "3px", "margin-left": "auto"}},
+		{decl: "margin: 1px 2px 3px 4px 5px", want: nil},
+		{decl: "padding: auto", want: nil},
+		{decl: "margin: inherit", want: map[string]string{"margin-top": "inherit", "margin-left": "inherit"}},
+		{decl: "border: 1px solid red", want: map[string]string{"border-top-width": "1px", "border-left-style": "solid", "border-bottom-color": "red"}},
+		{decl: "border-top: dashed", want: map[string]string{"border-top-width": "medium", "border-top-style": "dashed", "border-top-color": "currentcolor"}},
+		{decl: "border-radius: 1px 2px / 3px", want: map[string]string{"border-top-left-radius": "1px 3px", "border-top-right-radius": "2px 3px"}},
+		{decl: "background: none", want: map[string]string{"background-image": "none", "background-color": "transparent", "background-repeat": "repeat"}},
+		{decl: "background: none red", want: map[string]string{"background-image": "none", "background-color": "red"}},
+		{decl: "background: url(a.png), none", want: map[string]string{"background-image": "url(a.png), none", "background-position": "0% 0%, 0% 0%"}},
+		{decl: "background: url(a.png) no-repeat center / cover fixed", want: map[string]string{"background-position": "center", "background-size": "cover", "background-repeat": "no-repeat", "background-attachment": "fixed"}},
+		{decl: "background: content-box padding-box", want: map[string]string{"background-origin": "content-box", "background-clip": "padding-box"}},
+		{decl: "background: red, url(a.png)", want: nil},
+		{decl: "background: none none", want: nil},
+		{decl: "font: italic bold 12px/2 serif", want: map[string]string{"font-style": "italic", "font-weight": "bold", "font-size": "12px", "line-height": "2", "font-family": "serif", "font-kerning": "auto"}},
+		{decl: `font: 12px "Helvetica Neue", sans-serif`, want: map[string]string{"font-size": "12px", "font-family": `"Helvetica Neue", sans-serif`}},
+		{decl: "font: 12px", want: nil},
+		{decl: "flex: 1", want: map[string]string{"flex-grow": "1", "flex-shrink": "1", "flex-basis": "0%"}},
+		{decl: "flex: none", want: map[string]string{"flex-grow": "0", "flex-shrink": "0", "flex-basis": "auto"}},
+		{decl: "list-style: none", want: map[string]string{"list-style-image": "none", "list-style-type": "none"}},
+		{decl: "overflow: hidden auto", want: map[string]string{"overflow-x": "hidden", "overflow-y": "auto"}},
+		{decl: "gap: 1px", want: map[string]string{"row-gap": "1px", "column-gap": "1px"}},
+		{decl: "color: red", want: map[string]string{"color": "red"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.decl, func(t *testing.T) {
+			longhands, err := ExpandShorthand(parse_test_declaration(t, test.decl))
+			if test.want == nil {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", longhands)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got := map[string]string{}
+			for _, longhand := range longhands {
+				got[longhand.name] = strings.TrimSpace(serialize_component_value_list(longhand.value))
+			}
+			for name, want := range test.want {
+				if got[name] != want {
+					t.Errorf("%s: got %q, want %q", name, got[name], want)
+				}
+			}
+		})
+	}
+}
+
+func TestExpandShorthandKeepsImportance(t *testing.T) {
+	longhands, err := ExpandShorthand(parse_test_declaration(t, "margin: 1px !important"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, longhand := range longhands {
+		if longhand.important == false {
+			t.Errorf("%s is not !important", longhand.name)
+		}
+	}
+}
+
+func TestExpandShorthandWithVar(t *testing.T) {
+	longhands, err := ExpandShorthand(parse_test_declaration(t, "margin: var(--x) 1px"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(longhands) != 4 {
+		t.Fatalf("expected 4 longhands, got %d", len(longhands))
+	}
+	for _, longhand := range longhands {
+		if longhand.pending_shorthand != "margin" {
+			t.Errorf("%s is not pending on margin", longhand.name)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+// https://drafts.csswg.org/selectors-4/#specificity-rules
+// A selector's specificity is calculated for a given element as the count of
+//   - A: ID selectors
+//   - B: class selectors, attribute selectors, and pseudo-classes
+//   - C: type selectors and pseudo-elements
+type specificity struct {
+	a int
+	b int
+	c int
+}
+
+// Calculate the specificity of a complex selector, returning the (A, B, C) triple.
+func Specificity(selector ComplexSelector) (int, int, int) {
+	s := complex_specificity(selector)
+	return s.a, s.b, s.c
+}
+
+func (s specificity) add(other specificity) specificity {
+	return specificity{a: s.a + other.a, b: s.b + other.b, c: s.c + other.c}
+}
+
+// Specificities are compared by comparing the three components in order.
+// Returns a negative number if s is less specific than other, a positive number if it is more specific, and zero if they are equal.
+func (s specificity) compare(other specificity) int {
+	switch {
+	case s.a != other.a:
+		return s.a - other.a
+	case s.b != other.b:
+		return s.b - other.b
+	}
+	return s.c - other.c
+}
+
+// The specificity of the most specific selector in the list.
+func (l SelectorList) max_specificity() specificity {
+	var max specificity
+	for _, selector := range l {
+		if s := complex_specificity(selector); s.compare(max) > 0 {
+			max = s
+		}
+	}
+
+	return max
+}
+
+func complex_specificity(selector ComplexSelector) specificity {
+	var result specificity
+	for _, compound := range selector.compounds {
+		for _, simple := range compound.selectors {
+			result = result.add(simple_specificity(simple))
+		}
+	}
+
+	return result
+}
+
+func simple_specificity(selector SimpleSelector) specificity {
+	switch selector.kind {
+	case ID_SELECTOR:
+		return specificity{a: 1}
+	case CLASS_SELECTOR, ATTRIBUTE_SELECTOR:
+		return specificity{b: 1}
+	case TYPE_SELECTOR:
+		return specificity{c: 1}
+	// The universal selector doesn't count towards specificity, nor does a nesting selector with no parent style rule.
+	case UNIVERSAL_SELECTOR, NESTING_SELECTOR:
+		return specificity{}
+	case PSEUDO_ELEMENT_SELECTOR:
+		// The specificity of ::slotted() is that of a pseudo-element, plus the specificity of its argument.
+		if selector.is_function && selector.name == "slotted" {
+			return specificity{c: 1}.add(selector.selectors.max_specificity())
+		}
+		return specificity{c: 1}
+	}
+
+	switch selector.name {
+	// The specificity of an :is(), :not(), or :has() pseudo-class is replaced by the specificity of the most specific complex selector in its argument.
+	case "is", "not", "has":
+		if selector.is_function {
+			return selector.selectors.max_specificity()
+		}
+	// The specificity of a :where() pseudo-class is replaced by zero.
+	case "where":
+		if selector.is_function {
+			return specificity{}
+		}
+	// The specificity of an :nth-child() or :nth-last-child() selector is the specificity of the pseudo-class itself (counting as one pseudo-class selector)
+	// plus the specificity of the most specific complex selector in its selector list argument (if any).
+	case "nth-child", "nth-last-child":
+		if selector.has_of_selector {
+			return specificity{b: 1}.add(selector.selectors.max_specificity())
+		}
+	// The specificity of :host() and :host-context() is that of a pseudo-class, plus the specificity of its argument.
+	// https://drafts.csswg.org/css-scoping-1/#host-selector
+	case "host", "host-context":
+		if selector.is_function {
+			return specificity{b: 1}.add(selector.selectors.max_specificity())
+		}
+	}
+
+	return specificity{b: 1}
+}
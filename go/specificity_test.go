@@ -0,0 +1,76 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestSpecificity(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     specificity
+	}{
+		{selector: "*", want: specificity{}},
+		{selector: "a", want: specificity{c: 1}},
+		{selector: "a b > c", want: specificity{c: 3}},
+		{selector: ".a.b", want: specificity{b: 2}},
+		{selector: "#a", want: specificity{a: 1}},
+		{selector: "A.b#c", want: specificity{a: 1, b: 1, c: 1}},
+		{selector: "[href]", want: specificity{b: 1}},
+		{selector: "a:hover", want: specificity{b: 1, c: 1}},
+		{selector: "a::before", want: specificity{c: 2}},
+		{selector: "a:before", want: specificity{c: 2}},
+		{selector: "*|a", want: specificity{c: 1}},
+		{selector: ":where(#a, .b)", want: specificity{}},
+		{selector: ":is(#a, .b)", want: specificity{a: 1}},
+		{selector: ":not(.a, a)", want: specificity{b: 1}},
+		{selector: ":has(> a, + .b)", want: specificity{b: 1}},
+		{selector: ":is(.a, :bogus)", want: specificity{b: 1}},
+		{selector: ":nth-child(2n+1)", want: specificity{b: 1}},
+		{selector: ":nth-child(2n+1 of #a, .b)", want: specificity{a: 1, b: 1}},
+		{selector: ":nth-of-type(2n+1)", want: specificity{b: 1}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.selector, func(t *testing.T) {
+			selectors, err := parse_selector_list(parse_component_value_list(test.selector))
+			if err != nil {
+				t.Fatal(err)
+			}
+			a, b, c := Specificity(selectors[0])
+			if got := (specificity{a: a, b: b, c: c}); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSpecificityCompare(t *testing.T) {
+	tests := []struct {
+		s     specificity
+		other specificity
+		// The sign of the comparison.
+		want int
+	}{
+		{s: specificity{a: 1}, other: specificity{b: 10, c: 10}, want: 1},
+		{s: specificity{b: 1}, other: specificity{c: 10}, want: 1},
+		{s: specificity{c: 1}, other: specificity{b: 1}, want: -1},
+		{s: specificity{a: 1, b: 2, c: 3}, other: specificity{a: 1, b: 2, c: 3}, want: 0},
+	}
+
+	for _, test := range tests {
+		got := test.s.compare(test.other)
+		if (got > 0) != (test.want > 0) || (got < 0) != (test.want < 0) {
+			t.Errorf("%v compared to %v: got %d, want the sign of %d", test.s, test.other, got, test.want)
+		}
+	}
+}
+
+func TestMaxSpecificity(t *testing.T) {
+	selectors, err := parse_selector_list(parse_component_value_list("a, .b, a.c, d e f g"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := selectors.max_specificity(); got != (specificity{b: 1, c: 1}) {
+		t.Errorf("got %v", got)
+	}
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+type SupportsConditionKind uint8
+
+// https://drafts.csswg.org/css-conditional-5/#typedef-supports-condition
+const (
+	// The zero value, which isn't a condition: it evaluates to false and serializes as nothing.
+	SUPPORTS_INVALID SupportsConditionKind = iota
+	SUPPORTS_NOT
+	SUPPORTS_AND
+	SUPPORTS_OR
+	// ( <declaration> )
+	SUPPORTS_DECLARATION
+	// selector( <complex-selector> )
+	SUPPORTS_SELECTOR
+	// font-tech( <font-tech> )
+	SUPPORTS_FONT_TECH
+	// font-format( <font-format> )
+	SUPPORTS_FONT_FORMAT
+	// <general-enclosed>
+	SUPPORTS_GENERAL_ENCLOSED
+)
+
+func (k SupportsConditionKind) String() string {
+	switch k {
+	case SUPPORTS_INVALID:
+		return "SUPPORTS_INVALID"
+	case SUPPORTS_NOT:
+		return "SUPPORTS_NOT"
+	case SUPPORTS_AND:
+		return "SUPPORTS_AND"
+	case SUPPORTS_OR:
+		return "SUPPORTS_OR"
+	case SUPPORTS_DECLARATION:
+		return "SUPPORTS_DECLARATION"
+	case SUPPORTS_SELECTOR:
+		return "SUPPORTS_SELECTOR"
+	case SUPPORTS_FONT_TECH:
+		return "SUPPORTS_FONT_TECH"
+	case SUPPORTS_FONT_FORMAT:
+		return "SUPPORTS_FONT_FORMAT"
+	case SUPPORTS_GENERAL_ENCLOSED:
+		return "SUPPORTS_GENERAL_ENCLOSED"
+	}
+	return "<UNKNOWN SUPPORTS CONDITION>"
+}
+
+// https://drafts.csswg.org/css-conditional-5/#at-supports
+type SupportsCondition struct {
+	kind SupportsConditionKind
+	// SUPPORTS_NOT has exactly one child, SUPPORTS_AND and SUPPORTS_OR have two or more.
+	children []SupportsCondition
+	// SUPPORTS_DECLARATION
+	decl Declaration
+	// SUPPORTS_FONT_TECH, SUPPORTS_FONT_FORMAT: the keyword argument.
+	keyword string
+	// SUPPORTS_SELECTOR: the selector argument, SUPPORTS_GENERAL_ENCLOSED: the whole function or block.
+	value []ComponentValue
+}
+
+// The questions an evaluator needs answered about the user agent being targeted.
+type SupportsOracle interface {
+	// Does the UA support the property and value of the declaration?
+	SupportsDeclaration(decl Declaration) bool
+	// Does the UA support the selector? The argument is the raw component values of the selector() function.
+	SupportsSelector(selector []ComponentValue) bool
+	// Does the UA support the font technology (e.g. "color-COLRv1", "variations")?
+	SupportsFontTech(tech string) bool
+	// Does the UA support the font format (e.g. "woff2", "opentype")?
+	SupportsFontFormat(format string) bool
+}
+
+// Parse the prelude of an @supports rule into a condition.
+// https://drafts.csswg.org/css-conditional-5/#typedef-supports-condition
+func parse_supports_condition(prelude []ComponentValue) (SupportsCondition, error) {
+	stream := NewComponentValueStream(prelude)
+	stream.discard_whitespace()
+	if stream.empty() {
+		return SupportsCondition{}, fmt.Errorf("Parse Error: @supports condition is empty")
+	}
+
+	condition, err := stream.consume_supports_condition()
+	if err != nil {
+		return SupportsCondition{}, err
+	}
+
+	stream.discard_whitespace()
+	if stream.empty() == false {
+		return SupportsCondition{}, fmt.Errorf("Parse Error: unexpected %s after @supports condition", stream.next_value())
+	}
+
+	return condition, nil
+}
+
+// The condition of an @supports rule.
+func (r Rule) supports_condition() (SupportsCondition, error) {
+	if r.kind != AT_RULE || strings.EqualFold(r.name, "supports") == false {
+		return SupportsCondition{}, fmt.Errorf("Parse Error: rule is not an @supports rule")
+	}
+
+	return parse_supports_condition(r.prelude)
+}
+
+// <supports-condition> = not <supports-in-parens>
+//
+//	| <supports-in-parens> [ and <supports-in-parens> ]*
+//	| <supports-in-parens> [ or <supports-in-parens> ]*
+func (cs *ComponentValueStream) consume_supports_condition() (SupportsCondition, error) {
+	if cs.next_value().is_ident("not") {
+		cs.discard_value()
+		cs.discard_whitespace()
+		child, err := cs.consume_supports_in_parens()
+		if err != nil {
+			return SupportsCondition{}, err
+		}
+
+		return SupportsCondition{kind: SUPPORTS_NOT, children: []SupportsCondition{child}}, nil
+	}
+
+	first, err := cs.consume_supports_in_parens()
+	if err != nil {
+		return SupportsCondition{}, err
+	}
+
+	condition := SupportsCondition{children: []SupportsCondition{first}}
+	for {
+		cs.mark()
+		cs.discard_whitespace()
+
+		var kind SupportsConditionKind
+		switch next := cs.next_value(); {
+		case next.is_ident("and"):
+			kind = SUPPORTS_AND
+		case next.is_ident("or"):
+			kind = SUPPORTS_OR
+		default:
+			cs.restore_mark()
+			if len(condition.children) == 1 {
+				return first, nil
+			}
+			return condition, nil
+		}
+		cs.discard_mark()
+
+		// "and" and "or" can't be mixed at the same level without parentheses.
+		if len(condition.children) > 1 && condition.kind != kind {
+			return SupportsCondition{}, fmt.Errorf("Parse Error: cannot mix 'and' and 'or' in @supports condition without parentheses")
+		}
+		condition.kind = kind
+
+		cs.discard_value()
+		cs.discard_whitespace()
+		child, err := cs.consume_supports_in_parens()
+		if err != nil {
+			return SupportsCondition{}, err
+		}
+		condition.children = append(condition.children, child)
+	}
+}
+
+// <supports-in-parens> = ( <supports-condition> ) | <supports-feature> | <general-enclosed>
+func (cs *ComponentValueStream) consume_supports_in_parens() (SupportsCondition, error) {
+	next := cs.next_value()
+	switch {
+	case next.is_block(OPEN_PAREN_TOKEN):
+		cs.discard_value()
+		// ( <supports-condition> )
+		if condition, err := parse_supports_condition(next.value); err == nil {
+			return condition, nil
+		}
+		// <supports-decl> = ( <declaration> )
+		if decl, err := parse_supports_declaration(next.value); err == nil {
+			return SupportsCondition{kind: SUPPORTS_DECLARATION, decl: decl}, nil
+		}
+		// <general-enclosed> = ( <any-value>? )
+		return SupportsCondition{kind: SUPPORTS_GENERAL_ENCLOSED, value: []ComponentValue{next}}, nil
+	case next.kind == FUNCTION:
+		cs.discard_value()
+		switch {
+		// <supports-selector-fn> = selector( <complex-selector> )
+		case next.is_function("selector"):
+			selector := trim_whitespace(next.value)
+			if len(selector) == 0 {
+				return SupportsCondition{}, fmt.Errorf("Parse Error: selector() in @supports requires a selector")
+			}
+			return SupportsCondition{kind: SUPPORTS_SELECTOR, value: selector}, nil
+		// <supports-font-tech-fn> = font-tech( <font-tech> )
+		case next.is_function("font-tech"):
+			keyword, ok := single_ident_argument(next.value)
+			if ok == false {
+				return SupportsCondition{}, fmt.Errorf("Parse Error: font-tech() in @supports requires a single keyword")
+			}
+			return SupportsCondition{kind: SUPPORTS_FONT_TECH, keyword: keyword}, nil
+		// <supports-font-format-fn> = font-format( <font-format> )
+		case next.is_function("font-format"):
+			keyword, ok := single_ident_argument(next.value)
+			if ok == false {
+				return SupportsCondition{}, fmt.Errorf("Parse Error: font-format() in @supports requires a single keyword")
+			}
+			return SupportsCondition{kind: SUPPORTS_FONT_FORMAT, keyword: keyword}, nil
+		}
+		// <general-enclosed> = [ <function-token> <any-value>? ) ]
+		return SupportsCondition{kind: SUPPORTS_GENERAL_ENCLOSED, value: []ComponentValue{next}}, nil
+	case next.is_token(EOF_TOKEN):
+		return SupportsCondition{}, fmt.Errorf("Parse Error: unexpected end of @supports condition")
+	default:
+		return SupportsCondition{}, fmt.Errorf("Parse Error: unexpected %s in @supports condition", next)
+	}
+}
+
+// Parse the contents of a ( <declaration> ) block, re-using the core declaration parsing algorithm.
+// The value isn't validated against the property's grammar: whether it is supported is up to the caller.
+func parse_supports_declaration(list []ComponentValue) (Declaration, error) {
+	stream := NewTokenStream(tokens_from_component_values(list))
+	stream.discard_whitespace()
+	decl, ok := stream.consume_declaration(false)
+	if ok == false || stream.empty() == false {
+		return Declaration{}, fmt.Errorf("Parse Error: expected a single declaration")
+	}
+	// A declaration must have a value to be tested.
+	if len(decl.value) == 0 {
+		return Declaration{}, fmt.Errorf("Parse Error: expected a value for '%s'", decl.name)
+	}
+
+	return decl, nil
+}
+
+// The function arguments are exactly one <ident-token>, surrounded by optional whitespace.
+func single_ident_argument(list []ComponentValue) (string, bool) {
+	list = trim_whitespace(list)
+	if len(list) != 1 || list[0].is_token(IDENT_TOKEN) == false {
+		return "", false
+	}
+
+	return string(list[0].token.value), true
+}
+
+// Evaluate the condition against the capabilities described by the oracle.
+// https://drafts.csswg.org/css-conditional-5/#at-supports
+func (c SupportsCondition) Evaluate(oracle SupportsOracle) bool {
+	switch c.kind {
+	case SUPPORTS_NOT:
+		return c.children[0].Evaluate(oracle) == false
+	case SUPPORTS_AND:
+		for _, child := range c.children {
+			if child.Evaluate(oracle) == false {
+				return false
+			}
+		}
+		return true
+	case SUPPORTS_OR:
+		for _, child := range c.children {
+			if child.Evaluate(oracle) {
+				return true
+			}
+		}
+		return false
+	case SUPPORTS_DECLARATION:
+		return oracle.SupportsDeclaration(c.decl)
+	case SUPPORTS_SELECTOR:
+		return oracle.SupportsSelector(c.value)
+	case SUPPORTS_FONT_TECH:
+		return oracle.SupportsFontTech(c.keyword)
+	case SUPPORTS_FONT_FORMAT:
+		return oracle.SupportsFontFormat(c.keyword)
+	}
+
+	// A <general-enclosed> production (or an invalid condition) evaluates to false.
+	return false
+}
+
+func (c SupportsCondition) String() string {
+	var sb strings.Builder
+	stringify_supports_condition(&sb, c)
+	return sb.String()
+}
+
+func stringify_supports_condition(sb *strings.Builder, c SupportsCondition) {
+	switch c.kind {
+	case SUPPORTS_NOT:
+		sb.WriteString("not ")
+		stringify_supports_in_parens(sb, c.children[0])
+	case SUPPORTS_AND, SUPPORTS_OR:
+		keyword := " and "
+		if c.kind == SUPPORTS_OR {
+			keyword = " or "
+		}
+		for i, child := range c.children {
+			if i > 0 {
+				sb.WriteString(keyword)
+			}
+			stringify_supports_in_parens(sb, child)
+		}
+	case SUPPORTS_DECLARATION:
+		sb.WriteRune(OPEN_PAREN_CHAR)
+		sb.WriteString(fmt.Sprintf("%s%c%c", c.decl.name, COLON_CHAR, SPACE_CHAR))
+		stringify_component_value_list(sb, c.decl.value)
+		if c.decl.important {
+			sb.WriteString(fmt.Sprintf("%c%s", SPACE_CHAR, "!important"))
+		}
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	case SUPPORTS_SELECTOR:
+		sb.WriteString("selector(")
+		stringify_component_value_list(sb, c.value)
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	case SUPPORTS_FONT_TECH:
+		sb.WriteString(fmt.Sprintf("font-tech(%s)", c.keyword))
+	case SUPPORTS_FONT_FORMAT:
+		sb.WriteString(fmt.Sprintf("font-format(%s)", c.keyword))
+	case SUPPORTS_GENERAL_ENCLOSED:
+		stringify_component_value_list(sb, c.value)
+	}
+}
+
+// Compound conditions must be wrapped in parentheses when they appear as an operand.
+func stringify_supports_in_parens(sb *strings.Builder, c SupportsCondition) {
+	switch c.kind {
+	case SUPPORTS_NOT, SUPPORTS_AND, SUPPORTS_OR:
+		sb.WriteRune(OPEN_PAREN_CHAR)
+		stringify_supports_condition(sb, c)
+		sb.WriteRune(CLOSE_PAREN_CHAR)
+	default:
+		stringify_supports_condition(sb, c)
+	}
+}
+
+// Remove @supports rules whose condition is false for the oracle, and unwrap those whose condition is true.
+// Rules whose condition can't be parsed are left untouched.
+func (s Stylesheet) PruneSupports(oracle SupportsOracle) Stylesheet {
+	return Stylesheet{rules: prune_supports_rules(s.rules, oracle), namespaces: s.namespaces}
+}
+
+func prune_supports_rules(rules []Rule, oracle SupportsOracle) []Rule {
+	var result []Rule
+	for _, rule := range rules {
+		rule.children = prune_supports_rules(rule.children, oracle)
+
+		if rule.kind == AT_RULE && strings.EqualFold(rule.name, "supports") {
+			condition, err := parse_supports_condition(rule.prelude)
+			if err == nil {
+				if condition.Evaluate(oracle) == false {
+					continue
+				}
+				// The block's declarations can't be hoisted into the parent without changing their order, so keep the rule.
+				if len(rule.decls) == 0 {
+					result = append(result, rule.children...)
+					continue
+				}
+			}
+		}
+
+		result = append(result, rule)
+	}
+
+	return result
+}
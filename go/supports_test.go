@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+// An oracle for a UA that supports display: grid, :has() and woff2 fonts with variations.
+type test_supports_oracle struct{}
+
+func (test_supports_oracle) SupportsDeclaration(decl Declaration) bool {
+	return decl.name == "display" && serialize_component_value_list(decl.value) == "grid"
+}
+
+func (test_supports_oracle) SupportsSelector(selector []ComponentValue) bool {
+	return strings.HasPrefix(serialize_component_value_list(selector), ":has(")
+}
+
+func (test_supports_oracle) SupportsFontTech(tech string) bool {
+	return tech == "variations"
+}
+
+func (test_supports_oracle) SupportsFontFormat(format string) bool {
+	return format == "woff2"
+}
+
+func TestParseSupportsCondition(t *testing.T) {
+	tests := []struct {
+		condition string
+		kind      SupportsConditionKind
+		// The serialization of the parsed condition.
+		text string
+		// The result of evaluating it with test_supports_oracle.
+		want bool
+	}{
+		{condition: "(display: grid)", kind: SUPPORTS_DECLARATION, text: "(display: grid)", want: true},
+		{condition: "( display : grid )", kind: SUPPORTS_DECLARATION, text: "(display: grid)", want: true},
+		{condition: "(display: flex)", kind: SUPPORTS_DECLARATION, text: "(display: flex)", want: false},
+		{condition: "(display: bogus)", kind: SUPPORTS_DECLARATION, text: "(display: bogus)", want: false},
+		{condition: "not (display: flex)", kind: SUPPORTS_NOT, text: "not (display: flex)", want: true},
+		{condition: "(display: grid) and (display: flex)", kind: SUPPORTS_AND, text: "(display: grid) and (display: flex)", want: false},
+		{condition: "(display: flex) or (display: grid)", kind: SUPPORTS_OR, text: "(display: flex) or (display: grid)", want: true},
+		{condition: "((display: flex) or (display: grid)) and (not (display: flex))", kind: SUPPORTS_AND, text: "((display: flex) or (display: grid)) and (not (display: flex))", want: true},
+		{condition: "selector(:has(> img))", kind: SUPPORTS_SELECTOR, text: "selector(:has(> img))", want: true},
+		{condition: "selector(a:focus-visible)", kind: SUPPORTS_SELECTOR, text: "selector(a:focus-visible)", want: false},
+		{condition: "font-tech(variations)", kind: SUPPORTS_FONT_TECH, text: "font-tech(variations)", want: true},
+		{condition: "font-format(woff2)", kind: SUPPORTS_FONT_FORMAT, text: "font-format(woff2)", want: true},
+		{condition: "font-format(opentype)", kind: SUPPORTS_FONT_FORMAT, text: "font-format(opentype)", want: false},
+		{condition: "unknown(display: grid)", kind: SUPPORTS_GENERAL_ENCLOSED, text: "unknown(display: grid)", want: false},
+		{condition: "(display grid)", kind: SUPPORTS_GENERAL_ENCLOSED, text: "(display grid)", want: false},
+		{condition: "not unknown()", kind: SUPPORTS_NOT, text: "not unknown()", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.condition, func(t *testing.T) {
+			condition, err := parse_supports_condition(parse_component_value_list(test.condition))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if condition.kind != test.kind {
+				t.Errorf("parsed as %v, want %v", condition.kind, test.kind)
+			}
+			if got := condition.String(); got != test.text {
+				t.Errorf("serialized as %q, want %q", got, test.text)
+			}
+			if got := condition.Evaluate(test_supports_oracle{}); got != test.want {
+				t.Errorf("evaluated to %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseSupportsConditionErrors(t *testing.T) {
+	for _, condition := range []string{
+		"",
+		"display: grid",
+		"(display: grid) and (display: flex) or (color: red)",
+		"(display: grid) and",
+		"not not (display: grid)",
+		"(display: grid) (display: flex)",
+		"selector()",
+		"font-tech(a b)",
+		"font-format(\"woff2\")",
+	} {
+		t.Run(condition, func(t *testing.T) {
+			parsed, err := parse_supports_condition(parse_component_value_list(condition))
+			if err == nil {
+				t.Errorf("expected an error, got %q", parsed.String())
+			}
+			if parsed.kind != SUPPORTS_INVALID || parsed.Evaluate(test_supports_oracle{}) || parsed.String() != "" {
+				t.Errorf("expected an invalid condition, got %v %q", parsed.kind, parsed.String())
+			}
+		})
+	}
+}
+
+func TestPruneSupports(t *testing.T) {
+	tests := []struct {
+		css  string
+		want string
+	}{
+		{css: `@supports (display: grid) { a { color: red } }`, want: "a {\ncolor: red;\n}\n"},
+		{css: `@supports (display: flex) { a { color: red } } b { color: blue }`, want: "b {\ncolor: blue;\n}\n"},
+		{css: `@media print { @supports not (display: grid) { a { color: red } } b { color: blue } }`, want: "@media print {\nb {\ncolor: blue;\n}\n}\n"},
+		{css: `@supports display: flex { a { color: red } }`, want: "@supports display: flex {\na {\ncolor: red;\n}\n}\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.css, func(t *testing.T) {
+			sheet := parse_stylesheet(strings.NewReader(test.css)).PruneSupports(test_supports_oracle{})
+			if got := sheet.Stringify(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Moves the rules of a stylesheet out of its cascade layers, one layer at a time.
+type unlayerer struct {
+	// The index of each layer in the layer order, keyed by its position in the layer tree.
+	levels map[string]int
+	// The level of unlayered rules, which take precedence over all layers.
+	unlayered int
+	// The number of ID selectors added to a selector for each level it must win over:
+	// one more than the most any selector in the stylesheet has, so that no selector in a lower level can be more specific.
+	step int
+	errs []error
+}
+
+// https://drafts.csswg.org/css-cascade-5/#layering
+// Rewrite the stylesheet without cascade layers, for browsers that don't support them, so that the same declarations win.
+// The rules of each layer are moved after those of the layers it takes precedence over, with unlayered rules last, and
+// their selectors are made more specific with :not(#\#) (which only excludes elements with the id "#"), as otherwise
+// specificity would decide between declarations in different layers before their order does.
+// !important declarations, for which the order of layers is reversed, are split into rules of their own.
+// Nested rules are flattened first, as browsers that don't support layers don't support nesting either.
+// What can't be converted is reported: layers whose order depends on a conditional rule, stylesheets imported into layers,
+// which must be bundled first, and the revert-layer keyword.
+func (s Stylesheet) RemoveLayers() (Stylesheet, []error) {
+	rules := s.FlattenNesting(FlattenOptions{}).rules
+	tree := new_layer_tree()
+	declarer := new_layer_declarer()
+	declarer.declare_rules(rules, tree, false)
+	order := tree.order()
+	if len(order) == 0 {
+		return s, declarer.errs
+	}
+
+	u := &unlayerer{levels: map[string]int{}, unlayered: len(order), step: max_id_selectors(rules) + 1, errs: declarer.errs}
+	for i, layer := range order {
+		u.levels[fmt.Sprint(layer.path)] = i
+	}
+
+	var result []Rule
+	// These rules must stay at the start of the stylesheet.
+	for _, rule := range rules {
+		if rule.kind != AT_RULE {
+			continue
+		}
+		switch strings.ToLower(rule.name) {
+		case "import":
+			if imported, err := rule.import_rule(); err == nil && imported.has_layer {
+				u.errs = append(u.errs, fmt.Errorf("Layer Error: '%s' is imported into a layer, and must be bundled before layers can be removed", imported.url))
+			}
+			result = append(result, rule)
+		case "charset", "namespace":
+			result = append(result, rule)
+		}
+	}
+
+	// The layer tree is declared again on each pass, which gives each layer the same position every time.
+	for level := 0; level <= u.unlayered; level += 1 {
+		result = append(result, u.extract(rules, new_layer_tree(), new_layer_declarer(), level)...)
+	}
+
+	return Stylesheet{rules: result, namespaces: s.namespaces}, u.errs
+}
+
+// The rules in the level, and the conditional rules around them.
+func (u *unlayerer) extract(rules []Rule, layer *layer_tree, declarer *layer_declarer, level int) []Rule {
+	in_level := u.level(layer) == level
+	var result []Rule
+	for _, rule := range rules {
+		switch {
+		case is_layer_rule(rule):
+			if sublayer, ok := declarer.declare_rule(rule, layer, false); ok && rule.has_block {
+				result = append(result, u.extract(rule.children, sublayer, declarer, level)...)
+			}
+		case is_import_rule(rule):
+			declarer.declare_rule(rule, layer, false)
+		case rule.kind == QUALIFIED_RULE:
+			if in_level {
+				result = append(result, u.adjust_specificity(rule, level)...)
+			}
+		case property_context_at_rules[strings.ToLower(rule.name)]:
+			children := u.extract(rule.children, layer, declarer, level)
+			if len(children) > 0 || (len(rule.children) == 0 && in_level) {
+				rule.children = children
+				result = append(result, rule)
+			}
+		case strings.EqualFold(rule.name, "charset"), strings.EqualFold(rule.name, "namespace"):
+		default:
+			if in_level {
+				result = append(result, rule)
+			}
+		}
+	}
+
+	return result
+}
+
+func (u *unlayerer) level(layer *layer_tree) int {
+	if len(layer.path) == 0 {
+		return u.unlayered
+	}
+
+	return u.levels[fmt.Sprint(layer.path)]
+}
+
+// The style rule with its selectors made specific enough to win over the levels below it.
+// Important declarations win over the levels above instead, so they get a rule of their own.
+func (u *unlayerer) adjust_specificity(rule Rule, level int) []Rule {
+	selectors, err := rule.selectors()
+	if err != nil {
+		u.errs = append(u.errs, fmt.Errorf("Layer Error: cannot adjust the specificity of '%s': %w", strings.TrimSpace(serialize_component_value_list(rule.prelude)), err))
+		return []Rule{rule}
+	}
+
+	var normal []Declaration
+	var important []Declaration
+	for _, decl := range rule.decls {
+		if slices.ContainsFunc(decl.value, func(value ComponentValue) bool { return value.is_ident("revert-layer") }) {
+			u.errs = append(u.errs, fmt.Errorf("Layer Error: 'revert-layer' in '%s' cannot be converted without layers", decl.name))
+		}
+		if decl.important {
+			important = append(important, decl)
+		} else {
+			normal = append(normal, decl)
+		}
+	}
+
+	var result []Rule
+	if len(normal) > 0 || len(important) == 0 {
+		result = append(result, with_added_specificity(rule, selectors, normal, level*u.step))
+	}
+	if len(important) > 0 {
+		result = append(result, with_added_specificity(rule, selectors, important, (u.unlayered-level)*u.step))
+	}
+
+	return result
+}
+
+// A copy of the style rule with the declarations, and with the number of ID selectors added to the specificity of each selector.
+func with_added_specificity(rule Rule, selectors SelectorList, decls []Declaration, ids int) Rule {
+	rule.decls = decls
+	if ids == 0 {
+		return rule
+	}
+
+	id_selectors := make([]SimpleSelector, ids)
+	for i := range id_selectors {
+		id_selectors[i] = SimpleSelector{kind: ID_SELECTOR, name: "#"}
+	}
+	argument := SelectorList{{compounds: []CompoundSelector{{selectors: id_selectors}}}}
+	not := SimpleSelector{kind: PSEUDO_CLASS_SELECTOR, name: "not", is_function: true, arguments: parse_component_value_list(argument.String()), selectors: argument}
+
+	adjusted := make(SelectorList, len(selectors))
+	for i, selector := range selectors {
+		compounds := slices.Clone(selector.compounds)
+		last := compounds[len(compounds)-1]
+		// Only pseudo-classes such as :hover may follow a pseudo-element, so :not() goes before it.
+		index := slices.IndexFunc(last.selectors, func(simple SimpleSelector) bool { return simple.kind == PSEUDO_ELEMENT_SELECTOR })
+		if index < 0 {
+			index = len(last.selectors)
+		}
+		last.selectors = slices.Insert(slices.Clone(last.selectors), index, not)
+		compounds[len(compounds)-1] = last
+		adjusted[i] = ComplexSelector{compounds: compounds}
+	}
+
+	return rule_with_selectors(rule, adjusted)
+}
+
+// The most ID selectors any style rule's selector has.
+func max_id_selectors(rules []Rule) int {
+	result := 0
+	for _, rule := range rules {
+		if rule.kind == QUALIFIED_RULE {
+			if selectors, err := rule.selectors(); err == nil {
+				result = max(result, selectors.max_specificity().a)
+			}
+		}
+		result = max(result, max_id_selectors(rule.children))
+	}
+
+	return result
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRemoveLayers(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		want string
+		// The text of the error reported, if any.
+		wantErr string
+	}{
+		{
+			name: "leaves stylesheets without layers as they are",
+			css:  `p { color: red }`,
+			want: "p {\ncolor: red;\n}\n",
+		},
+		{
+			name: "moves unlayered rules last and makes them more specific",
+			css:  `@layer a { p { color: red } } p { color: blue }`,
+			want: "p {\ncolor: red;\n}\np:not(#\\#) {\ncolor: blue;\n}\n",
+		},
+		{
+			name: "orders layers by their first declaration",
+			css:  `@layer a, b; @layer b { .x { color: red } } @layer a { #y { color: blue } }`,
+			want: "#y {\ncolor: blue;\n}\n.x:not(#\\##\\#) {\ncolor: red;\n}\n",
+		},
+		{
+			name: "reverses the layer order of important declarations",
+			css:  `@layer a { p { color: red !important } } p { color: blue !important }`,
+			want: "p:not(#\\#) {\ncolor: red !important;\n}\np {\ncolor: blue !important;\n}\n",
+		},
+		{
+			name: "keeps conditional rules around layered rules",
+			css:  `@media screen { @layer a { p { color: red } } } @layer b { p { color: blue } }`,
+			want: "@media screen {\np {\ncolor: red;\n}\n}\np:not(#\\#) {\ncolor: blue;\n}\n",
+		},
+		{
+			name: "flattens nested rules first",
+			css:  `@layer a { .a { .b { color: red } } }`,
+			want: ".a .b {\ncolor: red;\n}\n",
+		},
+		{
+			name:    "reports revert-layer",
+			css:     `@layer a { p { color: revert-layer } }`,
+			want:    "p {\ncolor: revert-layer;\n}\n",
+			wantErr: "'revert-layer' in 'color' cannot be converted",
+		},
+		{
+			name:    "reports imports into layers",
+			css:     `@import url(x.css) layer(a); p { color: red }`,
+			want:    "@import url(x.css) layer(a);p:not(#\\#) {\ncolor: red;\n}\n",
+			wantErr: "must be bundled before layers can be removed",
+		},
+		{
+			name:    "reports layers whose order depends on a condition",
+			css:     `@media print { @layer b {} } @layer a {} @layer b {}`,
+			want:    "",
+			wantErr: "depends on the condition",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sheet, errs := parse_stylesheet(strings.NewReader(test.css)).RemoveLayers()
+			if got := sheet.Stringify(); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+			switch {
+			case test.wantErr == "" && len(errs) > 0:
+				t.Errorf("unexpected errors: %v", errs)
+			case test.wantErr != "" && (len(errs) != 1 || strings.Contains(errs[0].Error(), test.wantErr) == false):
+				t.Errorf("expected an error containing %q, got %v", test.wantErr, errs)
+			}
+		})
+	}
+}
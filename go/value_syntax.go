@@ -0,0 +1,730 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type GrammarNodeKind uint8
+
+// https://drafts.csswg.org/css-values-4/#value-defs
+const (
+	// A keyword, e.g. auto.
+	GRAMMAR_KEYWORD GrammarNodeKind = iota
+	// A literal character, e.g. ',' or '/'.
+	GRAMMAR_LITERAL
+	// A data type, e.g. <length> or <length [0,∞]>.
+	GRAMMAR_TYPE
+	// The grammar of a property, e.g. <'margin-top'>.
+	GRAMMAR_PROPERTY
+	// A functional notation, e.g. fit-content( <length-percentage> ).
+	GRAMMAR_FUNCTION
+	// Components that must all occur, in order.
+	GRAMMAR_SEQUENCE
+	// Components separated by '&&': all of them must occur, in any order.
+	GRAMMAR_ALL
+	// Components separated by '||': one or more of them must occur, in any order.
+	GRAMMAR_ANY
+	// Components separated by '|': exactly one of them must occur.
+	GRAMMAR_ONE
+)
+
+func (k GrammarNodeKind) String() string {
+	switch k {
+	case GRAMMAR_KEYWORD:
+		return "GRAMMAR_KEYWORD"
+	case GRAMMAR_LITERAL:
+		return "GRAMMAR_LITERAL"
+	case GRAMMAR_TYPE:
+		return "GRAMMAR_TYPE"
+	case GRAMMAR_PROPERTY:
+		return "GRAMMAR_PROPERTY"
+	case GRAMMAR_FUNCTION:
+		return "GRAMMAR_FUNCTION"
+	case GRAMMAR_SEQUENCE:
+		return "GRAMMAR_SEQUENCE"
+	case GRAMMAR_ALL:
+		return "GRAMMAR_ALL"
+	case GRAMMAR_ANY:
+		return "GRAMMAR_ANY"
+	case GRAMMAR_ONE:
+		return "GRAMMAR_ONE"
+	}
+	return "<UNKNOWN GRAMMAR NODE>"
+}
+
+type grammar_node struct {
+	kind GrammarNodeKind
+	// The keyword, literal, data type, property or function name.
+	name     string
+	children []grammar_node
+	// https://drafts.csswg.org/css-values-4/#component-multipliers
+	// How many times the component occurs, where a max of -1 is unbounded. Both are 1 without a multiplier.
+	min int
+	max int
+	// '#': the occurrences are separated by commas.
+	comma_separated bool
+	// '!': the group must not match an empty sequence.
+	required bool
+	// https://drafts.csswg.org/css-values-4/#numeric-ranges
+	// The range of a numeric data type, in the canonical unit of its dimension.
+	has_range bool
+	range_min float64
+	range_max float64
+}
+
+func (n grammar_node) is_multiplied() bool {
+	return n.min != 1 || n.max != 1 || n.comma_separated || n.required
+}
+
+// The parsed grammars of the properties and data types, keyed by name.
+var parsed_property_grammars = parse_grammar_table(property_grammars)
+var parsed_data_type_grammars = parse_grammar_table(data_type_grammars)
+
+// The grammars are checked in, so an invalid one is a programming error.
+func parse_grammar_table(table map[string]string) map[string]grammar_node {
+	parsed := map[string]grammar_node{}
+	for name, grammar := range table {
+		node, err := ParseGrammar(grammar)
+		if err != nil {
+			panic(fmt.Sprintf("invalid grammar for '%s': %s", name, err))
+		}
+		parsed[name] = node
+	}
+
+	return parsed
+}
+
+// The names of the functional notations that the grammars spell out, e.g. fit-content and repeat.
+var grammar_function_names = collect_grammar_function_names(parsed_property_grammars, parsed_data_type_grammars)
+
+func collect_grammar_function_names(tables ...map[string]grammar_node) map[string]bool {
+	names := map[string]bool{}
+	var walk func(node grammar_node)
+	walk = func(node grammar_node) {
+		if node.kind == GRAMMAR_FUNCTION {
+			names[node.name] = true
+		}
+		for _, child := range node.children {
+			walk(child)
+		}
+	}
+	for _, table := range tables {
+		for _, node := range table {
+			walk(node)
+		}
+	}
+
+	return names
+}
+
+// Check the declaration's value against the grammar of its property. Custom properties, values containing var(),
+// CSS-wide keywords, and properties without a known grammar are always valid, as are values that the grammars can't
+// judge, e.g. -webkit-center or anchor(bottom).
+func ValidateDeclaration(decl Declaration) error {
+	name := property_name(decl.name)
+	grammar, ok := parsed_property_grammars[name]
+	if ok == false || is_custom_property_name(name) || css_wide_keyword(decl) != "" || contains_substitution_function(decl.value) {
+		return nil
+	}
+
+	if MatchesGrammar(grammar, decl.value) == false && contains_unknown_value(decl.value) == false {
+		return fmt.Errorf("Parse Error: invalid value for '%s': '%s'", name, serialize_component_value_list(trim_whitespace(decl.value)))
+	}
+
+	return nil
+}
+
+// Whether the value contains a vendor-prefixed keyword or function, or a function that none of the grammars know.
+// The grammars are a subset of the specifications, so such a value may well be supported by a browser.
+func contains_unknown_value(list []ComponentValue) bool {
+	for _, value := range list {
+		switch {
+		case value.is_token(IDENT_TOKEN) && is_vendor_prefixed(string(value.token.value)):
+			return true
+		case value.kind == FUNCTION:
+			if is_known_function(value) == false || contains_unknown_value(value.value) {
+				return true
+			}
+		case value.kind == SIMPLE_BLOCK:
+			if contains_unknown_value(value.value) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func is_vendor_prefixed(name string) bool {
+	return strings.HasPrefix(name, "-") && strings.HasPrefix(name, "--") == false
+}
+
+func is_known_function(value ComponentValue) bool {
+	name := strings.ToLower(value.name)
+	if is_vendor_prefixed(name) {
+		return false
+	}
+
+	return grammar_function_names[name] || is_math_function(value) || is_color(value) || is_image(value) || transform_functions[name]
+}
+
+// Whether the whole value matches the grammar.
+func MatchesGrammar(grammar grammar_node, value []ComponentValue) bool {
+	input := non_whitespace_values(value)
+	return match_grammar(grammar, input, 0, func(end int) bool {
+		return end == len(input)
+	})
+}
+
+// Match the node against the input from pos, then call the continuation with the position after the match.
+// Every way the node can match is tried until the continuation accepts one, so that e.g. a '?' component can give
+// up a value that a later component needs.
+func match_grammar(node grammar_node, input []ComponentValue, pos int, k func(int) bool) bool {
+	if node.required {
+		next := k
+		k = func(end int) bool {
+			return end > pos && next(end)
+		}
+	}
+	if node.min == 1 && node.max == 1 && node.comma_separated == false {
+		return match_grammar_once(node, input, pos, k)
+	}
+
+	return match_repetition(node, input, pos, 0, k)
+}
+
+// Longer repetitions are tried first.
+func match_repetition(node grammar_node, input []ComponentValue, pos int, count int, k func(int) bool) bool {
+	if node.max < 0 || count < node.max {
+		start := pos
+		can_repeat := true
+		if count > 0 && node.comma_separated {
+			can_repeat = start < len(input) && input[start].is_token(COMMA_TOKEN)
+			start += 1
+		}
+		matched := can_repeat && match_grammar_once(node, input, start, func(end int) bool {
+			// An occurrence that matches nothing could repeat forever, so it's only allowed to reach the minimum.
+			if end == pos && count >= node.min {
+				return false
+			}
+			return match_repetition(node, input, end, count+1, k)
+		})
+		if matched {
+			return true
+		}
+	}
+
+	return count >= node.min && k(pos)
+}
+
+func match_grammar_once(node grammar_node, input []ComponentValue, pos int, k func(int) bool) bool {
+	switch node.kind {
+	case GRAMMAR_KEYWORD:
+		return pos < len(input) && input[pos].is_ident(node.name) && k(pos+1)
+	case GRAMMAR_LITERAL:
+		return pos < len(input) && matches_literal(node.name, input[pos]) && k(pos+1)
+	case GRAMMAR_TYPE:
+		if grammar, ok := parsed_data_type_grammars[node.name]; ok {
+			return match_grammar(grammar, input, pos, k)
+		}
+		return pos < len(input) && matches_data_type(node, input[pos]) && k(pos+1)
+	case GRAMMAR_PROPERTY:
+		grammar, ok := parsed_property_grammars[node.name]
+		return ok && match_grammar(grammar, input, pos, k)
+	case GRAMMAR_FUNCTION:
+		if pos >= len(input) || input[pos].is_function(node.name) == false {
+			return false
+		}
+		arguments := non_whitespace_values(input[pos].value)
+		if len(node.children) == 0 {
+			return len(arguments) == 0 && k(pos+1)
+		}
+		return MatchesGrammar(node.children[0], arguments) && k(pos+1)
+	case GRAMMAR_SEQUENCE:
+		return match_sequence(node.children, input, pos, k)
+	case GRAMMAR_ONE:
+		for _, child := range node.children {
+			if match_grammar(child, input, pos, k) {
+				return true
+			}
+		}
+		return false
+	case GRAMMAR_ALL:
+		return match_unordered(node.children, 0, true, input, pos, k)
+	case GRAMMAR_ANY:
+		return match_unordered(node.children, 0, false, input, pos, k)
+	}
+
+	return false
+}
+
+func match_sequence(children []grammar_node, input []ComponentValue, pos int, k func(int) bool) bool {
+	if len(children) == 0 {
+		return k(pos)
+	}
+
+	return match_grammar(children[0], input, pos, func(end int) bool {
+		return match_sequence(children[1:], input, end, k)
+	})
+}
+
+// Match the children that aren't in the used mask, in any order. For '&&' all of them must match, and for '||' at least one.
+func match_unordered(children []grammar_node, used uint64, all bool, input []ComponentValue, pos int, k func(int) bool) bool {
+	for i, child := range children {
+		if used&(1<<i) != 0 {
+			continue
+		}
+		matched := match_grammar(child, input, pos, func(end int) bool {
+			return match_unordered(children, used|1<<i, all, input, end, k)
+		})
+		if matched {
+			return true
+		}
+	}
+
+	if all {
+		return used == 1<<len(children)-1 && k(pos)
+	}
+	return used != 0 && k(pos)
+}
+
+func matches_literal(literal string, value ComponentValue) bool {
+	switch literal {
+	case ",":
+		return value.is_token(COMMA_TOKEN)
+	case ":":
+		return value.is_token(COLON_TOKEN)
+	case ";":
+		return value.is_token(SEMICOLON_TOKEN)
+	}
+
+	runes := []rune(literal)
+	return len(runes) == 1 && value.is_delim(runes[0])
+}
+
+// https://drafts.csswg.org/css-values-4/#component-types
+// The data types that are matched by a single component value, rather than by a grammar of their own.
+func matches_data_type(node grammar_node, value ComponentValue) bool {
+	matched := false
+	switch node.name {
+	case "length":
+		matched = is_length(value)
+	case "percentage":
+		matched = value.is_token(PERCENTAGE_TOKEN) || math_function_resolves_to_percentage(value)
+	case "length-percentage":
+		matched = is_length_percentage(value)
+	case "number":
+		matched = is_number(value)
+	case "integer":
+		matched = (value.is_token(NUMBER_TOKEN) && value.token.type_flag == TYPE_INTEGER) || math_function_resolves_to_number(value)
+	case "angle":
+		matched = is_angle(value)
+	case "time":
+		matched = is_time(value)
+	case "frequency":
+		_, ok := parse_frequency(value)
+		matched = ok || math_function_resolves_to(value, FREQUENCY, false)
+	case "resolution":
+		_, ok := parse_resolution(value)
+		matched = ok || math_function_resolves_to(value, RESOLUTION, false)
+	case "flex":
+		kind, ok := dimension_kind(value)
+		matched = (ok && kind == FLEX) || math_function_resolves_to(value, FLEX, false)
+	case "color":
+		matched = is_color(value)
+	case "image":
+		matched = is_image(value)
+	case "url":
+		matched = value.is_token(URL_TOKEN) || value.is_function("url") || value.is_function("src")
+	case "string":
+		matched = value.is_token(STRING_TOKEN)
+	case "ident":
+		matched = value.is_token(IDENT_TOKEN)
+	case "custom-ident":
+		matched = value.is_token(IDENT_TOKEN) && is_css_wide_keyword(string(value.token.value)) == false && value.is_ident("default") == false
+	case "dashed-ident":
+		matched = value.is_token(IDENT_TOKEN) && is_custom_property_name(string(value.token.value))
+	case "transform-function":
+		matched = value.kind == FUNCTION && transform_functions[strings.ToLower(value.name)]
+	}
+
+	return matched && (node.has_range == false || in_numeric_range(node, value))
+}
+
+// Math functions are clamped to the range when they're computed, so only literal values can be out of range.
+func in_numeric_range(node grammar_node, value ComponentValue) bool {
+	var number float64
+	switch {
+	case value.is_token(NUMBER_TOKEN), value.is_token(PERCENTAGE_TOKEN):
+		number = value.token.numeric
+	case value.is_token(DIMENSION_TOKEN):
+		number, _, _ = to_canonical_unit(value.token.numeric, strings.ToLower(string(value.token.unit)))
+	default:
+		return true
+	}
+
+	return number >= node.range_min && number <= node.range_max
+}
+
+// Parse a grammar written in the CSS Value Definition Syntax, e.g. "[ <length-percentage> | auto ]{1,4}".
+func ParseGrammar(grammar string) (grammar_node, error) {
+	tokens, err := tokenize_grammar(grammar)
+	if err != nil {
+		return grammar_node{}, err
+	}
+
+	parser := grammar_parser{tokens: tokens}
+	node, err := parser.parse_combination(0)
+	if err != nil {
+		return grammar_node{}, err
+	}
+	if parser.index < len(parser.tokens) {
+		return grammar_node{}, fmt.Errorf("Parse Error: unexpected '%s' in grammar", parser.tokens[parser.index].text)
+	}
+
+	return node, nil
+}
+
+type grammar_token_kind uint8
+
+const (
+	GRAMMAR_TOKEN_KEYWORD grammar_token_kind = iota
+	GRAMMAR_TOKEN_FUNCTION
+	GRAMMAR_TOKEN_TYPE
+	GRAMMAR_TOKEN_LITERAL
+	GRAMMAR_TOKEN_OPEN_BRACKET
+	GRAMMAR_TOKEN_CLOSE_BRACKET
+	GRAMMAR_TOKEN_CLOSE_PAREN
+	GRAMMAR_TOKEN_COMBINATOR
+	GRAMMAR_TOKEN_MULTIPLIER
+)
+
+type grammar_token struct {
+	kind grammar_token_kind
+	// The keyword, function name, literal, combinator or multiplier, or the contents of a data type's angle brackets.
+	text string
+}
+
+func tokenize_grammar(grammar string) ([]grammar_token, error) {
+	var tokens []grammar_token
+	runes := []rune(grammar)
+	for i := 0; i < len(runes); i += 1 {
+		char := runes[i]
+		// Reads up to the closing character, returning the contents in between.
+		read_until := func(close rune) (string, error) {
+			end := i + 1
+			for end < len(runes) && runes[end] != close {
+				end += 1
+			}
+			if end == len(runes) {
+				return "", fmt.Errorf("Parse Error: unclosed '%c' in grammar", char)
+			}
+			contents := string(runes[i+1 : end])
+			i = end
+			return contents, nil
+		}
+
+		switch {
+		case is_whitespace(char):
+		case char == '<':
+			contents, err := read_until('>')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_TYPE, text: strings.TrimSpace(contents)})
+		case char == '{':
+			contents, err := read_until('}')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_MULTIPLIER, text: "{" + contents + "}"})
+		case char == '\'':
+			contents, err := read_until('\'')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_LITERAL, text: contents})
+		case char == '[':
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_OPEN_BRACKET, text: "["})
+		case char == ']':
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_CLOSE_BRACKET, text: "]"})
+		case char == ')':
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_CLOSE_PAREN, text: ")"})
+		case char == '|' || char == '&':
+			if i+1 < len(runes) && runes[i+1] == char {
+				tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_COMBINATOR, text: string([]rune{char, char})})
+				i += 1
+			} else if char == '|' {
+				tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_COMBINATOR, text: "|"})
+			} else {
+				return nil, fmt.Errorf("Parse Error: unexpected '&' in grammar")
+			}
+		case strings.ContainsRune("?*+#!", char):
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_MULTIPLIER, text: string(char)})
+		case strings.ContainsRune(",/:;", char):
+			tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_LITERAL, text: string(char)})
+		case is_ident(char):
+			end := i
+			for end < len(runes) && is_ident(runes[end]) {
+				end += 1
+			}
+			name := string(runes[i:end])
+			if end < len(runes) && runes[end] == '(' {
+				tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_FUNCTION, text: name})
+				end += 1
+			} else {
+				tokens = append(tokens, grammar_token{kind: GRAMMAR_TOKEN_KEYWORD, text: name})
+			}
+			i = end - 1
+		default:
+			return nil, fmt.Errorf("Parse Error: unexpected '%c' in grammar", char)
+		}
+	}
+
+	return tokens, nil
+}
+
+type grammar_parser struct {
+	tokens []grammar_token
+	index  int
+}
+
+// The combinators, from the loosest binding to the tightest. Juxtaposition binds tighter than all of them.
+var grammar_combinators = []struct {
+	text string
+	kind GrammarNodeKind
+}{{"|", GRAMMAR_ONE}, {"||", GRAMMAR_ANY}, {"&&", GRAMMAR_ALL}}
+
+func (p *grammar_parser) next() (grammar_token, bool) {
+	if p.index >= len(p.tokens) {
+		return grammar_token{}, false
+	}
+
+	return p.tokens[p.index], true
+}
+
+func (p *grammar_parser) parse_combination(level int) (grammar_node, error) {
+	if level == len(grammar_combinators) {
+		return p.parse_sequence()
+	}
+
+	combinator := grammar_combinators[level]
+	first, err := p.parse_combination(level + 1)
+	if err != nil {
+		return grammar_node{}, err
+	}
+	children := []grammar_node{first}
+	for {
+		token, ok := p.next()
+		if ok == false || token.kind != GRAMMAR_TOKEN_COMBINATOR || token.text != combinator.text {
+			break
+		}
+		p.index += 1
+		child, err := p.parse_combination(level + 1)
+		if err != nil {
+			return grammar_node{}, err
+		}
+		children = append(children, child)
+	}
+
+	if len(children) == 1 {
+		return first, nil
+	}
+	if len(children) > 64 {
+		return grammar_node{}, fmt.Errorf("Parse Error: too many components combined with '%s'", combinator.text)
+	}
+	return grammar_node{kind: combinator.kind, children: children, min: 1, max: 1}, nil
+}
+
+func (p *grammar_parser) parse_sequence() (grammar_node, error) {
+	var children []grammar_node
+	for {
+		token, ok := p.next()
+		if ok == false || token.kind == GRAMMAR_TOKEN_COMBINATOR || token.kind == GRAMMAR_TOKEN_CLOSE_BRACKET || token.kind == GRAMMAR_TOKEN_CLOSE_PAREN {
+			break
+		}
+		child, err := p.parse_multiplied_component()
+		if err != nil {
+			return grammar_node{}, err
+		}
+		children = append(children, child)
+	}
+
+	switch len(children) {
+	case 0:
+		return grammar_node{}, fmt.Errorf("Parse Error: expected a component in grammar")
+	case 1:
+		return children[0], nil
+	}
+	return grammar_node{kind: GRAMMAR_SEQUENCE, children: children, min: 1, max: 1}, nil
+}
+
+func (p *grammar_parser) parse_multiplied_component() (grammar_node, error) {
+	node, err := p.parse_component()
+	if err != nil {
+		return grammar_node{}, err
+	}
+
+	for {
+		token, ok := p.next()
+		if ok == false || token.kind != GRAMMAR_TOKEN_MULTIPLIER {
+			return node, nil
+		}
+		p.index += 1
+
+		// A '{A,B}' after '#' gives the number of comma-separated occurrences.
+		if strings.HasPrefix(token.text, "{") && node.comma_separated && node.min == 1 && node.max == -1 {
+			if node.min, node.max, err = parse_multiplier_range(token.text); err != nil {
+				return grammar_node{}, err
+			}
+			continue
+		}
+		// Stacked multipliers, e.g. '#?', apply to the already multiplied component.
+		if node.is_multiplied() {
+			node = grammar_node{kind: GRAMMAR_SEQUENCE, children: []grammar_node{node}, min: 1, max: 1}
+		}
+
+		switch token.text {
+		case "?":
+			node.min, node.max = 0, 1
+		case "*":
+			node.min, node.max = 0, -1
+		case "+":
+			node.min, node.max = 1, -1
+		case "#":
+			node.min, node.max, node.comma_separated = 1, -1, true
+		case "!":
+			node.required = true
+		default:
+			if node.min, node.max, err = parse_multiplier_range(token.text); err != nil {
+				return grammar_node{}, err
+			}
+		}
+	}
+}
+
+// {A}, {A,} or {A,B}
+func parse_multiplier_range(text string) (int, int, error) {
+	contents := strings.TrimSuffix(strings.TrimPrefix(text, "{"), "}")
+	parts := strings.Split(contents, ",")
+	if len(parts) > 2 {
+		return 0, 0, fmt.Errorf("Parse Error: invalid multiplier '%s'", text)
+	}
+
+	min_count, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil {
+		return 0, 0, fmt.Errorf("Parse Error: invalid multiplier '%s'", text)
+	}
+	if len(parts) == 1 {
+		return min_count, min_count, nil
+	}
+	if strings.TrimSpace(parts[1]) == "" {
+		return min_count, -1, nil
+	}
+	max_count, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || max_count < min_count {
+		return 0, 0, fmt.Errorf("Parse Error: invalid multiplier '%s'", text)
+	}
+
+	return min_count, max_count, nil
+}
+
+func (p *grammar_parser) parse_component() (grammar_node, error) {
+	token, _ := p.next()
+	p.index += 1
+
+	switch token.kind {
+	case GRAMMAR_TOKEN_KEYWORD:
+		return grammar_node{kind: GRAMMAR_KEYWORD, name: token.text, min: 1, max: 1}, nil
+	case GRAMMAR_TOKEN_LITERAL:
+		return grammar_node{kind: GRAMMAR_LITERAL, name: token.text, min: 1, max: 1}, nil
+	case GRAMMAR_TOKEN_TYPE:
+		return parse_data_type_reference(token.text)
+	case GRAMMAR_TOKEN_OPEN_BRACKET:
+		group, err := p.parse_combination(0)
+		if err != nil {
+			return grammar_node{}, err
+		}
+		if closing, ok := p.next(); ok == false || closing.kind != GRAMMAR_TOKEN_CLOSE_BRACKET {
+			return grammar_node{}, fmt.Errorf("Parse Error: expected ']' in grammar")
+		}
+		p.index += 1
+		// The group is kept as a node of its own, so that a multiplier applies to the whole group.
+		return grammar_node{kind: GRAMMAR_SEQUENCE, children: []grammar_node{group}, min: 1, max: 1}, nil
+	case GRAMMAR_TOKEN_FUNCTION:
+		node := grammar_node{kind: GRAMMAR_FUNCTION, name: token.text, min: 1, max: 1}
+		if closing, ok := p.next(); ok && closing.kind == GRAMMAR_TOKEN_CLOSE_PAREN {
+			p.index += 1
+			return node, nil
+		}
+		arguments, err := p.parse_combination(0)
+		if err != nil {
+			return grammar_node{}, err
+		}
+		if closing, ok := p.next(); ok == false || closing.kind != GRAMMAR_TOKEN_CLOSE_PAREN {
+			return grammar_node{}, fmt.Errorf("Parse Error: expected ')' after the arguments of %s() in grammar", token.text)
+		}
+		p.index += 1
+		node.children = []grammar_node{arguments}
+		return node, nil
+	}
+
+	return grammar_node{}, fmt.Errorf("Parse Error: unexpected '%s' in grammar", token.text)
+}
+
+// <name>, <name [min,max]> or <'property'>
+func parse_data_type_reference(text string) (grammar_node, error) {
+	if strings.HasPrefix(text, "'") && strings.HasSuffix(text, "'") && len(text) > 2 {
+		return grammar_node{kind: GRAMMAR_PROPERTY, name: text[1 : len(text)-1], min: 1, max: 1}, nil
+	}
+
+	node := grammar_node{kind: GRAMMAR_TYPE, name: text, min: 1, max: 1}
+	index := strings.IndexRune(text, '[')
+	if index < 0 {
+		return node, nil
+	}
+
+	node.name = strings.TrimSpace(text[:index])
+	bounds := strings.Split(strings.TrimSuffix(strings.TrimSpace(text[index+1:]), "]"), ",")
+	if len(bounds) != 2 {
+		return grammar_node{}, fmt.Errorf("Parse Error: invalid range in '<%s>'", text)
+	}
+	var err error
+	if node.range_min, err = parse_range_bound(bounds[0]); err != nil {
+		return grammar_node{}, err
+	}
+	if node.range_max, err = parse_range_bound(bounds[1]); err != nil {
+		return grammar_node{}, err
+	}
+	node.has_range = true
+
+	return node, nil
+}
+
+// A number with an optional unit, or ∞ or -∞.
+func parse_range_bound(text string) (float64, error) {
+	text = strings.TrimSpace(text)
+	switch text {
+	case "∞", "+∞":
+		return math.Inf(1), nil
+	case "-∞":
+		return math.Inf(-1), nil
+	}
+
+	end := 0
+	for end < len(text) && (is_digit(rune(text[end])) || strings.ContainsRune("+-.", rune(text[end]))) {
+		end += 1
+	}
+	number, err := strconv.ParseFloat(text[:end], 64)
+	if err != nil {
+		return 0, fmt.Errorf("Parse Error: invalid range bound '%s'", text)
+	}
+	if unit := strings.ToLower(text[end:]); unit != "" {
+		number, _, _ = to_canonical_unit(number, unit)
+	}
+
+	return number, nil
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchesGrammar(t *testing.T) {
+	tests := []struct {
+		grammar string
+		valid   []string
+		invalid []string
+	}{
+		{grammar: "a | b", valid: []string{"a", "B"}, invalid: []string{"", "c", "a b"}},
+		{grammar: "a b", valid: []string{"a b"}, invalid: []string{"b a", "a", "a b b"}},
+		{grammar: "a && b", valid: []string{"a b", "b a"}, invalid: []string{"a", "b", "a a", "a b a"}},
+		{grammar: "a || b", valid: []string{"a", "b", "a b", "b a"}, invalid: []string{"", "a a", "c"}},
+		{grammar: "a?", valid: []string{"", "a"}, invalid: []string{"a a"}},
+		{grammar: "a*", valid: []string{"", "a", "a a a"}, invalid: []string{"b"}},
+		{grammar: "a+", valid: []string{"a", "a a"}, invalid: []string{""}},
+		{grammar: "a{2,3}", valid: []string{"a a", "a a a"}, invalid: []string{"a", "a a a a"}},
+		{grammar: "a{2}", valid: []string{"a a"}, invalid: []string{"a", "a a a"}},
+		{grammar: "a{1,}", valid: []string{"a", "a a a a"}, invalid: []string{""}},
+		{grammar: "a#", valid: []string{"a", "a, a", "a,a,a"}, invalid: []string{"a a", "a,", ", a", "a,,a"}},
+		{grammar: "a#{2}", valid: []string{"a, a"}, invalid: []string{"a", "a, a, a"}},
+		{grammar: "[ a? b? ]!", valid: []string{"a", "b", "a b"}, invalid: []string{"", "b a"}},
+		{grammar: "[ a? b? ]", valid: []string{"", "a b"}, invalid: []string{"b a"}},
+		{grammar: "a? && b", valid: []string{"b", "a b", "b a"}, invalid: []string{"a"}},
+		{grammar: "a | b c || d && e", valid: []string{"a", "b c", "d e", "e d b c", "b c d e"}, invalid: []string{"b", "d", "a b c"}},
+		{grammar: "<length>{1,2} [ / <length> ]?", valid: []string{"1px", "1px 2px / 3px"}, invalid: []string{"/ 3px", "1px /"}},
+		{grammar: "<length [0,∞]>", valid: []string{"0", "1px", "calc(1px - 2px)"}, invalid: []string{"-1px", "1", "1%"}},
+		{grammar: "<integer>", valid: []string{"1", "-2"}, invalid: []string{"1.5", "1px"}},
+		{grammar: "<number [1,1000]>", valid: []string{"1", "1000"}, invalid: []string{"0", "1001"}},
+		{grammar: "<color>", valid: []string{"red", "#fff", "rgb(0 0 0)", "currentcolor"}, invalid: []string{"1px", "#ggg"}},
+		{grammar: "f( <integer> , <integer> )", valid: []string{"f(1, 2)", "F(1,2)"}, invalid: []string{"f(1)", "f(1 2)", "g(1, 2)"}},
+		{grammar: "<'margin-top'>", valid: []string{"auto", "1px"}, invalid: []string{"none"}},
+	}
+
+	for _, test := range tests {
+		grammar, err := ParseGrammar(test.grammar)
+		if err != nil {
+			t.Fatalf("%s: %v", test.grammar, err)
+		}
+		for _, value := range test.valid {
+			if MatchesGrammar(grammar, parse_component_value_list(value)) == false {
+				t.Errorf("%s: expected %q to match", test.grammar, value)
+			}
+		}
+		for _, value := range test.invalid {
+			if MatchesGrammar(grammar, parse_component_value_list(value)) {
+				t.Errorf("%s: expected %q not to match", test.grammar, value)
+			}
+		}
+	}
+}
+
+func TestParseGrammarErrors(t *testing.T) {
+	for _, grammar := range []string{"", "a |", "[ a", "a ]", "a{2,1}", "<length", "a && || b"} {
+		if _, err := ParseGrammar(grammar); err == nil {
+			t.Errorf("%q: expected an error", grammar)
+		}
+	}
+}
+
+func TestValidateDeclaration(t *testing.T) {
+	tests := []struct {
+		property string
+		valid    []string
+		invalid  []string
+	}{
+		{property: "display", valid: []string{"block", "inline flex", "flex inline", "list-item", "inline list-item", "none", "-webkit-box", "-webkit-inline-flex"}, invalid: []string{"blocky", "block block", "flex grid"}},
+		{property: "position", valid: []string{"sticky", "-webkit-sticky", "-moz-sticky"}, invalid: []string{"stuck", "1px"}},
+		{property: "width", valid: []string{"auto", "10px", "50%", "calc(100% - 10px)", "fit-content(10px)", "-webkit-fill-available"}, invalid: []string{"-10px", "10", "auto auto"}},
+		{property: "margin", valid: []string{"0", "1px 2px", "1px auto 3px 4%"}, invalid: []string{"1px 2px 3px 4px 5px", "none"}},
+		{property: "padding", valid: []string{"1px 2px"}, invalid: []string{"auto", "-1px"}},
+		{property: "border", valid: []string{"1px solid red", "solid", "red 1px"}, invalid: []string{"1px 2px", "solid solid"}},
+		{property: "border-radius", valid: []string{"1px", "1px 2px / 3px 4px"}, invalid: []string{"/ 1px", "1px / 2px / 3px"}},
+		{property: "background-image", valid: []string{"none", "url(a.png), none"}, invalid: []string{"red", "url(a.png) url(b.png)"}},
+		{property: "box-shadow", valid: []string{"none", "1px 2px red", "inset 1px 2px 3px 4px red, 0 0 blue"}, invalid: []string{"1px", "none, 1px 2px"}},
+		{property: "font-weight", valid: []string{"bold", "400"}, invalid: []string{"1001", "heavy"}},
+		{property: "font-family", valid: []string{`"Helvetica Neue", Arial, sans-serif`, "Times New Roman"}, invalid: []string{"Arial,", "1px"}},
+		{property: "text-decoration-line", valid: []string{"none", "underline overline"}, invalid: []string{"underline underline", "none underline"}},
+		{property: "transition-timing-function", valid: []string{"ease, steps(4, end), cubic-bezier(0, 1, 1, 0)"}, invalid: []string{"cubic-bezier(2, 0, 0, 0)", "steps(4 end)"}},
+		{property: "grid-area", valid: []string{"auto", "1 / 2 / 3 / 4", "span 2 / a"}, invalid: []string{"1 / 2 / 3 / 4 / 5", "1px"}},
+		{property: "color", valid: []string{"inherit", "var(--x)", "red"}, invalid: []string{"1px", "red blue"}},
+		{property: "text-align", valid: []string{"center", "-webkit-center"}, invalid: []string{"middle"}},
+		{property: "outline", valid: []string{"2px solid red", "2px solid -webkit-focus-ring-color"}, invalid: []string{"2px solid bogus"}},
+		{property: "top", valid: []string{"anchor(bottom)", "calc(anchor(bottom) + 1px)"}, invalid: []string{"calc(1px) bogus", "url(a.png)"}},
+		{property: "--custom", valid: []string{"anything at all {}"}},
+		{property: "unknown-property", valid: []string{"anything"}},
+	}
+
+	for _, test := range tests {
+		for _, value := range test.valid {
+			if err := ValidateDeclaration(parse_test_declaration(t, test.property+": "+value)); err != nil {
+				t.Errorf("%s: expected %q to be valid, got %v", test.property, value, err)
+			}
+		}
+		for _, value := range test.invalid {
+			if ValidateDeclaration(parse_test_declaration(t, test.property+": "+value)) == nil {
+				t.Errorf("%s: expected %q to be invalid", test.property, value)
+			}
+		}
+	}
+}
+
+func TestInvalidDeclarationsAreDropped(t *testing.T) {
+	tests := []struct {
+		name string
+		css  string
+		// The declarations kept in the innermost rule, in order.
+		want []string
+	}{
+		{name: "drops invalid values", css: `a { color: red; color: 1px; width: -1px; width: 10px }`, want: []string{"color: red", "width: 10px"}},
+		{name: "keeps values it can't check", css: `a { color: var(--x) 1px; width: inherit; foo: bar; --x: {} }`, want: []string{"color: var(--x) 1px", "width: inherit", "foo: bar", "--x: {}"}},
+		{name: "keeps important values", css: `a { margin: 1px !important; margin: 1px 2px 3px 4px 5px !important }`, want: []string{"margin: 1px !important"}},
+		{name: "validates nested rules", css: `a { b { display: flex; display: flexy } }`, want: []string{"display: flex"}},
+		{name: "validates conditional rules", css: `@media screen { a { position: -webkit-sticky; position: stuck } }`, want: []string{"position: -webkit-sticky"}},
+		{name: "keeps values it can't judge", css: `a { text-align: -webkit-center; top: anchor(bottom); top: bottom }`, want: []string{"text-align: -webkit-center", "top: anchor(bottom)"}},
+		{name: "doesn't validate descriptors", css: `@font-face { font-weight: 100 900; font-display: swap }`, want: []string{"font-weight: 100 900", "font-display: swap"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule := parse_stylesheet(strings.NewReader(test.css)).rules[0]
+			for len(rule.children) > 0 {
+				rule = rule.children[0]
+			}
+
+			var got []string
+			for _, decl := range rule.decls {
+				text := decl.name + ": " + strings.TrimSpace(serialize_component_value_list(decl.value))
+				if decl.important {
+					text += " !important"
+				}
+				got = append(got, text)
+			}
+			if strings.Join(got, "; ") != strings.Join(test.want, "; ") {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
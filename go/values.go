@@ -0,0 +1,350 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+type DimensionKind uint8
+
+// https://drafts.csswg.org/css-values-4/#dimensions
+const (
+	LENGTH DimensionKind = iota
+	ANGLE
+	TIME
+	FREQUENCY
+	RESOLUTION
+	FLEX
+)
+
+func (k DimensionKind) String() string {
+	switch k {
+	case LENGTH:
+		return "LENGTH"
+	case ANGLE:
+		return "ANGLE"
+	case TIME:
+		return "TIME"
+	case FREQUENCY:
+		return "FREQUENCY"
+	case RESOLUTION:
+		return "RESOLUTION"
+	case FLEX:
+		return "FLEX"
+	}
+	return "<UNKNOWN DIMENSION>"
+}
+
+type UnitCategory uint8
+
+const (
+	// Units with a fixed size, which can be converted to the canonical unit of their dimension.
+	ABSOLUTE_UNIT UnitCategory = iota
+	// https://drafts.csswg.org/css-values-4/#font-relative-lengths
+	FONT_RELATIVE_UNIT
+	// https://drafts.csswg.org/css-values-4/#viewport-relative-lengths
+	VIEWPORT_UNIT
+	// https://drafts.csswg.org/css-conditional-5/#container-lengths
+	CONTAINER_UNIT
+	// The fr unit, whose size depends on the free space in a grid container.
+	FLEX_UNIT
+)
+
+func (c UnitCategory) String() string {
+	switch c {
+	case ABSOLUTE_UNIT:
+		return "ABSOLUTE_UNIT"
+	case FONT_RELATIVE_UNIT:
+		return "FONT_RELATIVE_UNIT"
+	case VIEWPORT_UNIT:
+		return "VIEWPORT_UNIT"
+	case CONTAINER_UNIT:
+		return "CONTAINER_UNIT"
+	case FLEX_UNIT:
+		return "FLEX_UNIT"
+	}
+	return "<UNKNOWN UNIT CATEGORY>"
+}
+
+type unit_definition struct {
+	kind     DimensionKind
+	category UnitCategory
+	// For absolute units: the number of canonical units in one of this unit.
+	factor float64
+}
+
+// The canonical unit of each dimension, which absolute units are converted to.
+var canonical_units = map[DimensionKind]string{LENGTH: "px", ANGLE: "deg", TIME: "s", FREQUENCY: "hz", RESOLUTION: "dppx"}
+
+// The units of each dimension, keyed by their lowercased name, as unit names are ASCII case-insensitive.
+var units = map[string]unit_definition{
+	// https://drafts.csswg.org/css-values-4/#absolute-lengths
+	"px": {LENGTH, ABSOLUTE_UNIT, 1}, "cm": {LENGTH, ABSOLUTE_UNIT, 96 / 2.54}, "mm": {LENGTH, ABSOLUTE_UNIT, 96 / 25.4},
+	"q": {LENGTH, ABSOLUTE_UNIT, 96 / 101.6}, "in": {LENGTH, ABSOLUTE_UNIT, 96}, "pt": {LENGTH, ABSOLUTE_UNIT, 96.0 / 72},
+	"pc": {LENGTH, ABSOLUTE_UNIT, 16},
+	"em": {LENGTH, FONT_RELATIVE_UNIT, 0}, "rem": {LENGTH, FONT_RELATIVE_UNIT, 0}, "ex": {LENGTH, FONT_RELATIVE_UNIT, 0},
+	"rex": {LENGTH, FONT_RELATIVE_UNIT, 0}, "cap": {LENGTH, FONT_RELATIVE_UNIT, 0}, "rcap": {LENGTH, FONT_RELATIVE_UNIT, 0},
+	"ch": {LENGTH, FONT_RELATIVE_UNIT, 0}, "rch": {LENGTH, FONT_RELATIVE_UNIT, 0}, "ic": {LENGTH, FONT_RELATIVE_UNIT, 0},
+	"ric": {LENGTH, FONT_RELATIVE_UNIT, 0}, "lh": {LENGTH, FONT_RELATIVE_UNIT, 0}, "rlh": {LENGTH, FONT_RELATIVE_UNIT, 0},
+	"vw": {LENGTH, VIEWPORT_UNIT, 0}, "vh": {LENGTH, VIEWPORT_UNIT, 0}, "vi": {LENGTH, VIEWPORT_UNIT, 0},
+	"vb": {LENGTH, VIEWPORT_UNIT, 0}, "vmin": {LENGTH, VIEWPORT_UNIT, 0}, "vmax": {LENGTH, VIEWPORT_UNIT, 0},
+	"svw": {LENGTH, VIEWPORT_UNIT, 0}, "svh": {LENGTH, VIEWPORT_UNIT, 0}, "svi": {LENGTH, VIEWPORT_UNIT, 0},
+	"svb": {LENGTH, VIEWPORT_UNIT, 0}, "svmin": {LENGTH, VIEWPORT_UNIT, 0}, "svmax": {LENGTH, VIEWPORT_UNIT, 0},
+	"lvw": {LENGTH, VIEWPORT_UNIT, 0}, "lvh": {LENGTH, VIEWPORT_UNIT, 0}, "lvi": {LENGTH, VIEWPORT_UNIT, 0},
+	"lvb": {LENGTH, VIEWPORT_UNIT, 0}, "lvmin": {LENGTH, VIEWPORT_UNIT, 0}, "lvmax": {LENGTH, VIEWPORT_UNIT, 0},
+	"dvw": {LENGTH, VIEWPORT_UNIT, 0}, "dvh": {LENGTH, VIEWPORT_UNIT, 0}, "dvi": {LENGTH, VIEWPORT_UNIT, 0},
+	"dvb": {LENGTH, VIEWPORT_UNIT, 0}, "dvmin": {LENGTH, VIEWPORT_UNIT, 0}, "dvmax": {LENGTH, VIEWPORT_UNIT, 0},
+	"cqw": {LENGTH, CONTAINER_UNIT, 0}, "cqh": {LENGTH, CONTAINER_UNIT, 0}, "cqi": {LENGTH, CONTAINER_UNIT, 0},
+	"cqb": {LENGTH, CONTAINER_UNIT, 0}, "cqmin": {LENGTH, CONTAINER_UNIT, 0}, "cqmax": {LENGTH, CONTAINER_UNIT, 0},
+	// https://drafts.csswg.org/css-values-4/#angles
+	"deg": {ANGLE, ABSOLUTE_UNIT, 1}, "grad": {ANGLE, ABSOLUTE_UNIT, 0.9}, "rad": {ANGLE, ABSOLUTE_UNIT, 180 / math.Pi},
+	"turn": {ANGLE, ABSOLUTE_UNIT, 360},
+	// https://drafts.csswg.org/css-values-4/#time
+	"s": {TIME, ABSOLUTE_UNIT, 1}, "ms": {TIME, ABSOLUTE_UNIT, 0.001},
+	// https://drafts.csswg.org/css-values-4/#frequency
+	"hz": {FREQUENCY, ABSOLUTE_UNIT, 1}, "khz": {FREQUENCY, ABSOLUTE_UNIT, 1000},
+	// https://drafts.csswg.org/css-values-4/#resolution
+	"dppx": {RESOLUTION, ABSOLUTE_UNIT, 1}, "x": {RESOLUTION, ABSOLUTE_UNIT, 1}, "dpi": {RESOLUTION, ABSOLUTE_UNIT, 1.0 / 96},
+	"dpcm": {RESOLUTION, ABSOLUTE_UNIT, 2.54 / 96},
+	// https://drafts.csswg.org/css-grid/#fr-unit
+	"fr": {FLEX, FLEX_UNIT, 0},
+}
+
+// https://drafts.csswg.org/css-values-4/#numbers
+type Number struct {
+	Value float64
+	// Whether the number was written as an <integer>.
+	Integer bool
+}
+
+// https://drafts.csswg.org/css-values-4/#percentages
+type Percentage struct {
+	Value float64
+}
+
+// https://drafts.csswg.org/css-values-4/#lengths
+type Length struct {
+	Value float64
+	// The lowercased unit.
+	Unit string
+}
+
+// https://drafts.csswg.org/css-values-4/#angles
+type Angle struct {
+	Value float64
+	Unit  string
+}
+
+// https://drafts.csswg.org/css-values-4/#time
+type Time struct {
+	Value float64
+	Unit  string
+}
+
+// https://drafts.csswg.org/css-values-4/#frequency
+type Frequency struct {
+	Value float64
+	Unit  string
+}
+
+// https://drafts.csswg.org/css-values-4/#resolution
+type Resolution struct {
+	Value float64
+	Unit  string
+}
+
+// https://drafts.csswg.org/css-grid/#typedef-flex
+type Flex struct {
+	Value float64
+}
+
+// Interpret a numeric token as a typed value: a Number, Percentage, Length, Angle, Time, Frequency, Resolution or Flex.
+func ParseNumericValue(value ComponentValue) (any, error) {
+	switch {
+	case value.is_token(NUMBER_TOKEN):
+		return Number{Value: value.token.numeric, Integer: value.token.type_flag == TYPE_INTEGER}, nil
+	case value.is_token(PERCENTAGE_TOKEN):
+		return Percentage{Value: value.token.numeric}, nil
+	case value.is_token(DIMENSION_TOKEN):
+		unit := strings.ToLower(string(value.token.unit))
+		definition, ok := units[unit]
+		if ok == false {
+			return nil, fmt.Errorf("Parse Error: unknown unit '%s'", string(value.token.unit))
+		}
+		number := value.token.numeric
+		switch definition.kind {
+		case LENGTH:
+			return Length{Value: number, Unit: unit}, nil
+		case ANGLE:
+			return Angle{Value: number, Unit: unit}, nil
+		case TIME:
+			return Time{Value: number, Unit: unit}, nil
+		case FREQUENCY:
+			return Frequency{Value: number, Unit: unit}, nil
+		case RESOLUTION:
+			return Resolution{Value: number, Unit: unit}, nil
+		case FLEX:
+			return Flex{Value: number}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Parse Error: expected a numeric value, found %s", describe_value(value))
+}
+
+// The dimension of a <dimension-token>, if its unit is known.
+func dimension_kind(value ComponentValue) (DimensionKind, bool) {
+	if value.is_token(DIMENSION_TOKEN) == false {
+		return 0, false
+	}
+	definition, ok := units[strings.ToLower(string(value.token.unit))]
+
+	return definition.kind, ok
+}
+
+// Parse a <length>. A unitless zero is also a length.
+func parse_length(value ComponentValue) (Length, bool) {
+	if value.is_token(NUMBER_TOKEN) && value.token.numeric == 0 {
+		return Length{Value: 0, Unit: "px"}, true
+	}
+	parsed, err := ParseNumericValue(value)
+	length, ok := parsed.(Length)
+
+	return length, err == nil && ok
+}
+
+func parse_angle(value ComponentValue) (Angle, bool) {
+	parsed, err := ParseNumericValue(value)
+	angle, ok := parsed.(Angle)
+
+	return angle, err == nil && ok
+}
+
+func parse_time(value ComponentValue) (Time, bool) {
+	parsed, err := ParseNumericValue(value)
+	time, ok := parsed.(Time)
+
+	return time, err == nil && ok
+}
+
+func parse_frequency(value ComponentValue) (Frequency, bool) {
+	parsed, err := ParseNumericValue(value)
+	frequency, ok := parsed.(Frequency)
+
+	return frequency, err == nil && ok
+}
+
+func parse_resolution(value ComponentValue) (Resolution, bool) {
+	parsed, err := ParseNumericValue(value)
+	resolution, ok := parsed.(Resolution)
+
+	return resolution, err == nil && ok
+}
+
+// Convert a value in an absolute unit to the canonical unit of its dimension.
+// Values in relative units can't be converted without knowing what they're relative to.
+func to_canonical_unit(value float64, unit string) (float64, string, bool) {
+	definition, ok := units[unit]
+	if ok == false || definition.category != ABSOLUTE_UNIT {
+		return value, unit, false
+	}
+
+	return value * definition.factor, canonical_units[definition.kind], true
+}
+
+func unit_category(unit string) UnitCategory {
+	return units[unit].category
+}
+
+// The length in px, if its unit is absolute.
+func (l Length) Canonical() (Length, bool) {
+	value, unit, ok := to_canonical_unit(l.Value, l.Unit)
+	return Length{Value: value, Unit: unit}, ok
+}
+
+func (l Length) Category() UnitCategory {
+	return unit_category(l.Unit)
+}
+
+// The angle in deg.
+func (a Angle) Canonical() (Angle, bool) {
+	value, unit, ok := to_canonical_unit(a.Value, a.Unit)
+	return Angle{Value: value, Unit: unit}, ok
+}
+
+// The time in s.
+func (t Time) Canonical() (Time, bool) {
+	value, unit, ok := to_canonical_unit(t.Value, t.Unit)
+	return Time{Value: value, Unit: unit}, ok
+}
+
+// The frequency in hz.
+func (f Frequency) Canonical() (Frequency, bool) {
+	value, unit, ok := to_canonical_unit(f.Value, f.Unit)
+	return Frequency{Value: value, Unit: unit}, ok
+}
+
+// The resolution in dppx.
+func (r Resolution) Canonical() (Resolution, bool) {
+	value, unit, ok := to_canonical_unit(r.Value, r.Unit)
+	return Resolution{Value: value, Unit: unit}, ok
+}
+
+func (n Number) String() string {
+	return format_number(n.Value)
+}
+
+func (p Percentage) String() string {
+	return format_dimension(p.Value, string(PERCENT_SIGN_CHAR))
+}
+
+func (l Length) String() string {
+	return format_dimension(l.Value, l.Unit)
+}
+
+func (a Angle) String() string {
+	return format_dimension(a.Value, a.Unit)
+}
+
+func (t Time) String() string {
+	return format_dimension(t.Value, t.Unit)
+}
+
+func (f Frequency) String() string {
+	return format_dimension(f.Value, f.Unit)
+}
+
+func (r Resolution) String() string {
+	return format_dimension(r.Value, r.Unit)
+}
+
+func (f Flex) String() string {
+	return format_dimension(f.Value, "fr")
+}
+
+// The shortest representation of the number that reads back as the same value.
+// https://drafts.csswg.org/css-values-4/#calc-serialize
+// Infinite and NaN values can't be written as numbers, so they're serialized as the math functions that produce them.
+func format_number(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "calc(infinity)"
+	case math.IsInf(value, -1):
+		return "calc(-infinity)"
+	case math.IsNaN(value):
+		return "calc(NaN)"
+	}
+
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+// The number followed by the unit, e.g. 10px, or calc(infinity * 1px) when the number is infinite or NaN.
+func format_dimension(value float64, unit string) string {
+	if math.IsInf(value, 0) || math.IsNaN(value) {
+		constant := strings.TrimSuffix(strings.TrimPrefix(format_number(value), "calc("), ")")
+		return "calc(" + constant + " * 1" + unit + ")"
+	}
+
+	return format_number(value) + unit
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestParseNumericValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  any
+		// The serialization of the parsed value.
+		text string
+	}{
+		{input: "5", want: Number{Value: 5, Integer: true}, text: "5"},
+		{input: "-0.5", want: Number{Value: -0.5}, text: "-0.5"},
+		{input: "1e3", want: Number{Value: 1000}, text: "1000"},
+		{input: "50%", want: Percentage{Value: 50}, text: "50%"},
+		{input: "10PX", want: Length{Value: 10, Unit: "px"}, text: "10px"},
+		{input: "1.5em", want: Length{Value: 1.5, Unit: "em"}, text: "1.5em"},
+		{input: "100cqi", want: Length{Value: 100, Unit: "cqi"}, text: "100cqi"},
+		{input: "4Q", want: Length{Value: 4, Unit: "q"}, text: "4q"},
+		{input: "90deg", want: Angle{Value: 90, Unit: "deg"}, text: "90deg"},
+		{input: "0.25turn", want: Angle{Value: 0.25, Unit: "turn"}, text: "0.25turn"},
+		{input: "200ms", want: Time{Value: 200, Unit: "ms"}, text: "200ms"},
+		{input: "2kHz", want: Frequency{Value: 2, Unit: "khz"}, text: "2khz"},
+		{input: "2x", want: Resolution{Value: 2, Unit: "x"}, text: "2x"},
+		{input: "96dpi", want: Resolution{Value: 96, Unit: "dpi"}, text: "96dpi"},
+		{input: "1fr", want: Flex{Value: 1}, text: "1fr"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := ParseNumericValue(parse_component_value_list(test.input)[0])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != test.want {
+				t.Errorf("got %#v, want %#v", got, test.want)
+			}
+			if text := fmt.Sprint(got); text != test.text {
+				t.Errorf("serialized as %q, want %q", text, test.text)
+			}
+		})
+	}
+}
+
+func TestParseNumericValueErrors(t *testing.T) {
+	for _, input := range []string{"10foo", "px", "\"10px\"", "calc(1px)", "#10"} {
+		t.Run(input, func(t *testing.T) {
+			if got, err := ParseNumericValue(parse_component_value_list(input)[0]); err == nil {
+				t.Errorf("expected an error, got %v", got)
+			}
+		})
+	}
+}
+
+func TestCanonicalUnits(t *testing.T) {
+	tests := []struct {
+		input string
+		value float64
+		unit  string
+		// Whether the value can be converted.
+		ok bool
+	}{
+		{input: "1in", value: 96, unit: "px", ok: true},
+		{input: "2.54cm", value: 96, unit: "px", ok: true},
+		{input: "10mm", value: 96 / 2.54, unit: "px", ok: true},
+		{input: "40Q", value: 96 / 2.54, unit: "px", ok: true},
+		{input: "72pt", value: 96, unit: "px", ok: true},
+		{input: "6pc", value: 96, unit: "px", ok: true},
+		{input: "2em", value: 2, unit: "em", ok: false},
+		{input: "10vw", value: 10, unit: "vw", ok: false},
+		{input: "100grad", value: 90, unit: "deg", ok: true},
+		{input: "0.5turn", value: 180, unit: "deg", ok: true},
+		{input: "3.141592653589793rad", value: 180, unit: "deg", ok: true},
+		{input: "1500ms", value: 1.5, unit: "s", ok: true},
+		{input: "2khz", value: 2000, unit: "hz", ok: true},
+		{input: "192dpi", value: 2, unit: "dppx", ok: true},
+		{input: "96dpcm", value: 2.54, unit: "dppx", ok: true},
+		{input: "2x", value: 2, unit: "dppx", ok: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			parsed, err := ParseNumericValue(parse_component_value_list(test.input)[0])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var value float64
+			var unit string
+			var ok bool
+			switch parsed := parsed.(type) {
+			case Length:
+				canonical, converted := parsed.Canonical()
+				value, unit, ok = canonical.Value, canonical.Unit, converted
+			case Angle:
+				canonical, converted := parsed.Canonical()
+				value, unit, ok = canonical.Value, canonical.Unit, converted
+			case Time:
+				canonical, converted := parsed.Canonical()
+				value, unit, ok = canonical.Value, canonical.Unit, converted
+			case Frequency:
+				canonical, converted := parsed.Canonical()
+				value, unit, ok = canonical.Value, canonical.Unit, converted
+			case Resolution:
+				canonical, converted := parsed.Canonical()
+				value, unit, ok = canonical.Value, canonical.Unit, converted
+			default:
+				t.Fatalf("unexpected value %#v", parsed)
+			}
+			if ok != test.ok || unit != test.unit || math.Abs(value-test.value) > 1e-9 {
+				t.Errorf("got %v%s (%v), want %v%s (%v)", value, unit, ok, test.value, test.unit, test.ok)
+			}
+		})
+	}
+}
+
+func TestLengthCategory(t *testing.T) {
+	tests := map[string]UnitCategory{"1px": ABSOLUTE_UNIT, "1rem": FONT_RELATIVE_UNIT, "1dvh": VIEWPORT_UNIT, "1cqmin": CONTAINER_UNIT}
+	for input, want := range tests {
+		length, ok := parse_length(parse_component_value_list(input)[0])
+		if ok == false {
+			t.Fatalf("%s: not a length", input)
+		}
+		if got := length.Category(); got != want {
+			t.Errorf("%s: got %v, want %v", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// The values of the custom properties declared in a block, keyed by name: the winning declaration of each,
+// i.e. the last !important one, or the last one if none are.
+func CustomProperties(decls []Declaration) map[string][]ComponentValue {
+	properties := map[string][]ComponentValue{}
+	for _, decl := range decls {
+		if is_custom_property_name(decl.name) == false {
+			continue
+		}
+		if index := winning_declaration(decls, decl.name); index >= 0 {
+			properties[decl.name] = trim_whitespace(decls[index].value)
+		}
+	}
+
+	return properties
+}
+
+// Substitutes the var() references between a set of custom properties.
+type variable_resolver struct {
+	values map[string][]ComponentValue
+	// The properties that are part of a dependency cycle, which makes them guaranteed-invalid.
+	cyclic   map[string]bool
+	resolved map[string][]ComponentValue
+	invalid  map[string]bool
+}
+
+func new_variable_resolver(properties map[string][]ComponentValue) *variable_resolver {
+	return &variable_resolver{
+		values:   properties,
+		cyclic:   dependency_cycles(CustomPropertyDependencies(properties)),
+		resolved: map[string][]ComponentValue{},
+		invalid:  map[string]bool{},
+	}
+}
+
+// https://drafts.csswg.org/css-variables/#cycles
+// Substitute the var() references in the custom properties' values with the values they refer to.
+// Properties that are part of a dependency cycle, or that refer to a guaranteed-invalid property without a fallback,
+// are guaranteed-invalid, and are left out of the result.
+func ResolveCustomProperties(properties map[string][]ComponentValue) map[string][]ComponentValue {
+	resolver := new_variable_resolver(properties)
+	result := map[string][]ComponentValue{}
+	for name := range properties {
+		if value, ok := resolver.resolve(name); ok {
+			result[name] = value
+		}
+	}
+
+	return result
+}
+
+// Substitute the var() functions in the value with the values of the custom properties they refer to,
+// whose own var() references are resolved first.
+func SubstituteVariables(value []ComponentValue, properties map[string][]ComponentValue) ([]ComponentValue, error) {
+	return new_variable_resolver(properties).substitute(value)
+}
+
+// The dependency graph of the custom properties: the custom properties each one's value refers to, including in fallbacks.
+func CustomPropertyDependencies(properties map[string][]ComponentValue) map[string][]string {
+	graph := map[string][]string{}
+	for name, value := range properties {
+		graph[name] = custom_property_references(value, nil)
+	}
+
+	return graph
+}
+
+func custom_property_references(list []ComponentValue, names []string) []string {
+	for _, value := range list {
+		if value.is_function("var") {
+			arguments := trim_whitespace(value.value)
+			if len(arguments) > 0 && arguments[0].is_token(IDENT_TOKEN) && slices.Contains(names, string(arguments[0].token.value)) == false {
+				names = append(names, string(arguments[0].token.value))
+			}
+		}
+		if value.kind == FUNCTION || value.kind == SIMPLE_BLOCK {
+			names = custom_property_references(value.value, names)
+		}
+	}
+
+	return names
+}
+
+// The properties that are part of a cycle in the dependency graph, found as its strongly connected components
+// with more than one property, or with a property that refers to itself.
+// https://en.wikipedia.org/wiki/Tarjan%27s_strongly_connected_components_algorithm
+func dependency_cycles(graph map[string][]string) map[string]bool {
+	cyclic := map[string]bool{}
+	index := map[string]int{}
+	lowlink := map[string]int{}
+	on_stack := map[string]bool{}
+	var stack []string
+
+	var connect func(name string)
+	connect = func(name string) {
+		index[name] = len(index)
+		lowlink[name] = index[name]
+		stack = append(stack, name)
+		on_stack[name] = true
+
+		for _, dependency := range graph[name] {
+			if _, ok := graph[dependency]; ok == false {
+				continue
+			}
+			if _, visited := index[dependency]; visited == false {
+				connect(dependency)
+				lowlink[name] = min(lowlink[name], lowlink[dependency])
+			} else if on_stack[dependency] {
+				lowlink[name] = min(lowlink[name], index[dependency])
+			}
+		}
+
+		if lowlink[name] != index[name] {
+			return
+		}
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			on_stack[last] = false
+			component = append(component, last)
+			if last == name {
+				break
+			}
+		}
+		if len(component) > 1 || slices.Contains(graph[name], name) {
+			for _, member := range component {
+				cyclic[member] = true
+			}
+		}
+	}
+
+	var names []string
+	for name := range graph {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, visited := index[name]; visited == false {
+			connect(name)
+		}
+	}
+
+	return cyclic
+}
+
+// Substitute the var() functions in the declaration's value. A longhand pending substitution holds its shorthand's value,
+// which is expanded after substitution to find the longhand's part of it.
+func SubstituteDeclaration(decl Declaration, properties map[string][]ComponentValue) (Declaration, error) {
+	value, err := SubstituteVariables(decl.value, properties)
+	if err != nil {
+		return decl, fmt.Errorf("%w (in '%s')", err, decl.name)
+	}
+	decl.value = value
+	if decl.pending_shorthand == "" {
+		return decl, nil
+	}
+
+	longhands, err := ExpandShorthand(Declaration{name: decl.pending_shorthand, value: value, important: decl.important})
+	if err != nil {
+		return decl, err
+	}
+	for _, longhand := range longhands {
+		if longhand.name == property_name(decl.name) {
+			return longhand, nil
+		}
+	}
+
+	return decl, fmt.Errorf("Parse Error: '%s' is not a longhand of '%s'", decl.name, decl.pending_shorthand)
+}
+
+// The value of the custom property after substitution, or false if it's guaranteed-invalid.
+// The dependency graph has no cycles left among the properties that aren't cyclic, so the recursion ends.
+func (r *variable_resolver) resolve(name string) ([]ComponentValue, bool) {
+	if value, ok := r.resolved[name]; ok {
+		return value, true
+	}
+	value, ok := r.values[name]
+	// The initial value of a custom property is the guaranteed-invalid value.
+	if ok == false || r.cyclic[name] || r.invalid[name] || (len(value) == 1 && value[0].is_ident("initial")) {
+		return nil, false
+	}
+
+	substituted, err := r.substitute(value)
+	if err != nil {
+		r.invalid[name] = true
+		return nil, false
+	}
+	r.resolved[name] = substituted
+	return substituted, true
+}
+
+// https://drafts.csswg.org/css-variables/#substitute-a-var
+func (r *variable_resolver) substitute(list []ComponentValue) ([]ComponentValue, error) {
+	var result []ComponentValue
+	for _, value := range list {
+		if value.is_function("var") {
+			substituted, err := r.substitute_var(value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, substituted...)
+			continue
+		}
+
+		if value.kind == FUNCTION || value.kind == SIMPLE_BLOCK {
+			contents, err := r.substitute(value.value)
+			if err != nil {
+				return nil, err
+			}
+			value.value = contents
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+// var() = var( <custom-property-name> , <declaration-value>? )
+// The fallback is only substituted if the custom property is guaranteed-invalid.
+func (r *variable_resolver) substitute_var(function ComponentValue) ([]ComponentValue, error) {
+	arguments := trim_whitespace(function.value)
+	if len(arguments) == 0 || arguments[0].is_token(IDENT_TOKEN) == false || is_custom_property_name(string(arguments[0].token.value)) == false {
+		return nil, fmt.Errorf("Parse Error: var() expects a custom property name, found %s", describe_var_argument(arguments))
+	}
+
+	name := string(arguments[0].token.value)
+	rest := trim_whitespace(arguments[1:])
+	has_fallback := len(rest) > 0 && rest[0].is_token(COMMA_TOKEN)
+	if len(rest) > 0 && has_fallback == false {
+		return nil, fmt.Errorf("Parse Error: unexpected %s in var()", describe_value(rest[0]))
+	}
+
+	if value, ok := r.resolve(name); ok {
+		return value, nil
+	}
+	if has_fallback == false {
+		return nil, fmt.Errorf("Parse Error: '%s' is not defined and var() has no fallback", name)
+	}
+
+	return r.substitute(trim_whitespace(rest[1:]))
+}
+
+func describe_var_argument(arguments []ComponentValue) string {
+	if len(arguments) == 0 {
+		return "nothing"
+	}
+
+	return describe_value(arguments[0])
+}
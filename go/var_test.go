@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func parse_test_custom_properties(t *testing.T, block string) map[string][]ComponentValue {
+	t.Helper()
+	decls, _ := parse_block_contents(block)
+	return CustomProperties(decls)
+}
+
+func TestResolveCustomProperties(t *testing.T) {
+	tests := []struct {
+		block string
+		// The expected values of the resolved properties, where a property that's missing is guaranteed-invalid.
+		want map[string]string
+	}{
+		{block: "--a: 1px; --b: var(--a) 2px", want: map[string]string{"--a": "1px", "--b": "1px 2px"}},
+		{block: "--a: var(--b); --b: var(--c); --c: red", want: map[string]string{"--a": "red", "--b": "red", "--c": "red"}},
+		{block: "--a: var(--missing, blue)", want: map[string]string{"--a": "blue"}},
+		{block: "--a: var(--missing)", want: map[string]string{}},
+		{block: "--a: var(--a)", want: map[string]string{}},
+		{block: "--a: var(--b); --b: var(--a); --c: 1px", want: map[string]string{"--c": "1px"}},
+		{block: "--a: var(--b); --b: var(--c); --c: var(--a)", want: map[string]string{}},
+		{block: "--a: var(--b); --b: var(--a); --c: var(--a, 3px)", want: map[string]string{"--c": "3px"}},
+		{block: "--a: var(--missing, var(--a))", want: map[string]string{}},
+		{block: "--a: initial; --b: var(--a, 4px)", want: map[string]string{"--b": "4px"}},
+		{block: "--a: 1px; --a: 2px !important; --a: 3px", want: map[string]string{"--a": "2px"}},
+		{block: "--a: f(var(--b)); --b: 5", want: map[string]string{"--a": "f(5)", "--b": "5"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.block, func(t *testing.T) {
+			resolved := ResolveCustomProperties(parse_test_custom_properties(t, test.block))
+			if len(resolved) != len(test.want) {
+				t.Errorf("got %d properties, want %d", len(resolved), len(test.want))
+			}
+			for name, want := range test.want {
+				value, ok := resolved[name]
+				if ok == false {
+					t.Errorf("%s: expected a value, got none", name)
+					continue
+				}
+				if got := strings.TrimSpace(serialize_component_value_list(value)); got != want {
+					t.Errorf("%s: got %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSubstituteVariables(t *testing.T) {
+	properties := parse_test_custom_properties(t, "--a: 1px; --b: var(--b)")
+	tests := []struct {
+		value string
+		// The expected value, or empty if the substitution fails.
+		want string
+	}{
+		{value: "var(--a) solid", want: "1px solid"},
+		{value: "calc(var(--a) * 2)", want: "calc(1px * 2)"},
+		{value: "var(--b, 2px)", want: "2px"},
+		{value: "var(--b)", want: ""},
+		{value: "var(a)", want: ""},
+		{value: "var(--a 1px)", want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			value, err := SubstituteVariables(parse_component_value_list(test.value), properties)
+			if test.want == "" {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", serialize_component_value_list(value))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.TrimSpace(serialize_component_value_list(value)); got != test.want {
+				t.Errorf("got %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCustomPropertyOriginalText(t *testing.T) {
+	sheet := parse_stylesheet(strings.NewReader("a { --x:  0.5 {a:b} ; --y: var(--z, (1 /* c */ 2)) !important; color: red }"))
+	want := map[string]string{"--x": "0.5 {a:b}", "--y": "var(--z, (1 /* c */ 2))"}
+	for _, decl := range sheet.rules[0].decls {
+		if decl.original_text != want[decl.name] {
+			t.Errorf("%s: got original text %q, want %q", decl.name, decl.original_text, want[decl.name])
+		}
+	}
+}